@@ -8,6 +8,7 @@ import (
 
 	"github.com/apprenda/kismatic/pkg/cli"
 	"github.com/apprenda/kismatic/pkg/install"
+	"github.com/apprenda/kismatic/pkg/ssh"
 	"github.com/apprenda/kismatic/pkg/util"
 )
 
@@ -21,13 +22,23 @@ func init() {
 
 func main() {
 	rand.Seed(time.Now().UnixNano())
+	os.Exit(run())
+}
+
+func run() int {
+	// stop the ssh-agent that holds decrypted SSH keys, if it was started
+	defer ssh.StopAgent()
 	cmd, err := cli.NewKismaticCommand(version, buildDate, os.Stdin, os.Stdout, os.Stderr)
 	if err != nil {
 		util.PrintColor(os.Stderr, util.Red, "Error initializing command: %v\n", err)
-		os.Exit(1)
+		return 1
 	}
 	if err := cmd.Execute(); err != nil {
 		util.PrintColor(os.Stderr, util.Red, "%v\n", err)
-		os.Exit(1)
+		if exitErr, ok := err.(cli.ExitError); ok {
+			return exitErr.Code
+		}
+		return 1
 	}
+	return 0
 }
@@ -54,6 +54,10 @@ type ClusterCatalog struct {
 
 	NewNode string `yaml:"new_node"`
 
+	JoinEtcdCluster bool `yaml:"join_etcd_cluster"`
+
+	RemovedNode string `yaml:"removed_node"`
+
 	NFSVolumes []NFSVolume `yaml:"nfs_volumes"`
 
 	EnableGluster bool `yaml:"configure_storage"`
@@ -77,6 +81,10 @@ type ClusterCatalog struct {
 	DiagnosticsDirectory string `yaml:"diagnostics_dir"`
 	DiagnosticsDateTime  string `yaml:"diagnostics_date_time"`
 
+	EtcdSnapshotName    string `yaml:"etcd_snapshot_name"`
+	EtcdBackupDirectory string `yaml:"etcd_backup_dir"`
+	EtcdSnapshotFile    string `yaml:"etcd_snapshot_file"`
+
 	Docker struct {
 		Enabled bool
 		Logs    struct {
@@ -157,6 +165,15 @@ type ClusterCatalog struct {
 		Enabled bool
 	}
 
+	Preflight struct {
+		EtcdMinimumCPUs          int `yaml:"etcd_minimum_cpus"`
+		MasterMinimumCPUs        int `yaml:"master_minimum_cpus"`
+		WorkerMinimumCPUs        int `yaml:"worker_minimum_cpus"`
+		EtcdMinimumMemoryBytes   int `yaml:"etcd_minimum_memory_bytes"`
+		MasterMinimumMemoryBytes int `yaml:"master_minimum_memory_bytes"`
+		WorkerMinimumMemoryBytes int `yaml:"worker_minimum_memory_bytes"`
+	}
+
 	InsecureNetworkingEtcd bool `yaml:"insecure_networking_etcd"`
 
 	HTTPProxy  string `yaml:"http_proxy"`
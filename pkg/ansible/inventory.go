@@ -1,8 +1,14 @@
 package ansible
 
 import (
+	"bufio"
 	"bytes"
 	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
 )
 
 // Inventory is a collection of Nodes, keyed by role.
@@ -32,6 +38,12 @@ type Node struct {
 	SSHPort int
 	// SSHUser is the SSH user for logging into the node
 	SSHUser string
+	// SSHProxyCommand is the optional ProxyCommand used to reach the node,
+	// such as when the node is behind a bastion host
+	SSHProxyCommand string
+	// Vars are the other variables of the node, such as the ones that are
+	// read from an existing inventory
+	Vars map[string]string
 }
 
 // ToINI converts the inventory into INI format
@@ -44,9 +56,276 @@ func (i Inventory) ToINI() []byte {
 			if n.InternalIP != "" {
 				internalIP = n.InternalIP
 			}
-			fmt.Fprintf(w, "%q ansible_host=%q internal_ipv4=%q ansible_ssh_private_key_file=%q ansible_port=%d ansible_user=%q\n", n.Host, n.PublicIP, internalIP, n.SSHPrivateKey, n.SSHPort, n.SSHUser)
+			fmt.Fprintf(w, "%q ansible_host=%q internal_ipv4=%q ansible_ssh_private_key_file=%q ansible_port=%d ansible_user=%q", n.Host, n.PublicIP, internalIP, n.SSHPrivateKey, n.SSHPort, n.SSHUser)
+			if n.SSHProxyCommand != "" {
+				fmt.Fprintf(w, " ansible_ssh_common_args=%q", fmt.Sprintf("-o ProxyCommand='%s'", n.SSHProxyCommand))
+			}
+			keys := make([]string, 0, len(n.Vars))
+			for k := range n.Vars {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				fmt.Fprintf(w, " %s=%q", k, n.Vars[k])
+			}
+			fmt.Fprintln(w)
 		}
 	}
 
 	return w.Bytes()
 }
+
+var proxyCommandRE = regexp.MustCompile(`^-o ProxyCommand='(.*)'$`)
+
+// iniGroup is a group of an INI inventory
+type iniGroup struct {
+	hosts    []iniHost
+	vars     map[string]string
+	children []string
+}
+
+// iniHost is a host of an INI inventory, along with its variables
+type iniHost struct {
+	name string
+	vars map[string]string
+}
+
+// ParseINI reads an inventory in INI format, such as the one produced by
+// ToINI. Every group of hosts becomes a role, in the order the groups appear.
+// The nodes of the child groups are part of the role of their parent group,
+// and the variables of the groups apply to the nodes that don't set them.
+func ParseINI(r io.Reader) (*Inventory, error) {
+	groups := map[string]*iniGroup{}
+	order := []string{}
+	group := func(name string) *iniGroup {
+		g, ok := groups[name]
+		if !ok {
+			g = &iniGroup{vars: map[string]string{}}
+			groups[name] = g
+			order = append(order, name)
+		}
+		return g
+	}
+
+	// hosts before the first section are ungrouped
+	current, section := "ungrouped", ""
+	scanner := bufio.NewScanner(r)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") {
+			if !strings.HasSuffix(line, "]") {
+				return nil, fmt.Errorf("line %d: invalid section %q", lineNumber, line)
+			}
+			current, section = line[1:len(line)-1], ""
+			if i := strings.Index(current, ":"); i >= 0 {
+				current, section = current[:i], current[i+1:]
+			}
+			if section != "" && section != "vars" && section != "children" {
+				return nil, fmt.Errorf("line %d: unknown section type %q", lineNumber, section)
+			}
+			group(current)
+			continue
+		}
+		fields, err := splitINIFields(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", lineNumber, err)
+		}
+		switch section {
+		case "vars":
+			key, value, err := parseINIVar(line)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %v", lineNumber, err)
+			}
+			group(current).vars[key] = value
+		case "children":
+			group(current).children = append(group(current).children, unquoteINIValue(fields[0]))
+		default:
+			h := iniHost{name: unquoteINIValue(fields[0]), vars: map[string]string{}}
+			for _, f := range fields[1:] {
+				key, value, err := parseINIVar(f)
+				if err != nil {
+					return nil, fmt.Errorf("line %d: %v", lineNumber, err)
+				}
+				h.vars[key] = value
+			}
+			group(current).hosts = append(group(current).hosts, h)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading inventory: %v", err)
+	}
+
+	inv := &Inventory{}
+	for _, name := range order {
+		if name == "all" || name == "ungrouped" && len(groups[name].hosts) == 0 {
+			continue
+		}
+		role := Role{Name: name}
+		hosts, err := groupHosts(groups, name, map[string]bool{})
+		if err != nil {
+			return nil, err
+		}
+		for _, h := range hosts {
+			vars := map[string]string{}
+			if all, ok := groups["all"]; ok {
+				for k, v := range all.vars {
+					vars[k] = v
+				}
+			}
+			for k, v := range h.vars {
+				vars[k] = v
+			}
+			n, err := iniNode(h.name, vars)
+			if err != nil {
+				return nil, err
+			}
+			role.Nodes = append(role.Nodes, n)
+		}
+		inv.Roles = append(inv.Roles, role)
+	}
+	return inv, nil
+}
+
+// groupHosts returns the hosts of the group and of its children, with the
+// variables of the groups merged into the variables of the hosts
+func groupHosts(groups map[string]*iniGroup, name string, visited map[string]bool) ([]iniHost, error) {
+	if visited[name] {
+		return nil, fmt.Errorf("group %q is a child of itself", name)
+	}
+	visited[name] = true
+	defer delete(visited, name)
+	g, ok := groups[name]
+	if !ok {
+		return nil, fmt.Errorf("group %q is not defined", name)
+	}
+	hosts := append([]iniHost{}, g.hosts...)
+	for _, c := range g.children {
+		ch, err := groupHosts(groups, c, visited)
+		if err != nil {
+			return nil, err
+		}
+		hosts = append(hosts, ch...)
+	}
+	result := make([]iniHost, 0, len(hosts))
+	for _, h := range hosts {
+		vars := map[string]string{}
+		for k, v := range g.vars {
+			vars[k] = v
+		}
+		for k, v := range h.vars {
+			vars[k] = v
+		}
+		result = append(result, iniHost{name: h.name, vars: vars})
+	}
+	return result, nil
+}
+
+// iniNode returns the node of a host, with the variables that are known to
+// kismatic mapped to the fields of the node
+func iniNode(host string, vars map[string]string) (Node, error) {
+	n := Node{Host: host}
+	for k, v := range vars {
+		switch k {
+		case "ansible_host", "ansible_ssh_host":
+			n.PublicIP = v
+		case "internal_ipv4":
+			n.InternalIP = v
+		case "ansible_ssh_private_key_file":
+			n.SSHPrivateKey = v
+		case "ansible_port", "ansible_ssh_port":
+			port, err := strconv.Atoi(v)
+			if err != nil {
+				return n, fmt.Errorf("host %q: invalid port %q", host, v)
+			}
+			n.SSHPort = port
+		case "ansible_user", "ansible_ssh_user":
+			n.SSHUser = v
+		case "ansible_ssh_common_args":
+			if m := proxyCommandRE.FindStringSubmatch(v); m != nil {
+				n.SSHProxyCommand = m[1]
+				continue
+			}
+			fallthrough
+		default:
+			if n.Vars == nil {
+				n.Vars = map[string]string{}
+			}
+			n.Vars[k] = v
+		}
+	}
+	if n.PublicIP == "" {
+		n.PublicIP = host
+	}
+	if n.InternalIP == n.PublicIP {
+		n.InternalIP = ""
+	}
+	return n, nil
+}
+
+// splitINIFields splits the line on whitespace, except in quoted values
+func splitINIFields(line string) ([]string, error) {
+	var fields []string
+	var field bytes.Buffer
+	var quote rune
+	escaped := false
+	for _, c := range line {
+		switch {
+		case escaped:
+			escaped = false
+		case quote == '"' && c == '\\':
+			escaped = true
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == ' ' || c == '\t':
+			if field.Len() > 0 {
+				fields = append(fields, field.String())
+				field.Reset()
+			}
+			continue
+		case c == '#' && field.Len() == 0:
+			// the rest of the line is a comment
+			return fields, nil
+		}
+		field.WriteRune(c)
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated quote in %q", line)
+	}
+	if field.Len() > 0 {
+		fields = append(fields, field.String())
+	}
+	return fields, nil
+}
+
+func parseINIVar(field string) (string, string, error) {
+	parts := strings.SplitN(field, "=", 2)
+	if len(parts) != 2 {
+		return "", "", fmt.Errorf("%q is not a variable assignment", field)
+	}
+	return strings.TrimSpace(parts[0]), unquoteINIValue(strings.TrimSpace(parts[1])), nil
+}
+
+func unquoteINIValue(v string) string {
+	if len(v) < 2 {
+		return v
+	}
+	switch {
+	case v[0] == '"' && v[len(v)-1] == '"':
+		if s, err := strconv.Unquote(v); err == nil {
+			return s
+		}
+		return v[1 : len(v)-1]
+	case v[0] == '\'' && v[len(v)-1] == '\'':
+		return v[1 : len(v)-1]
+	}
+	return v
+}
@@ -1,6 +1,11 @@
 package ansible
 
-import "testing"
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
 
 func TestInventoryINIGeneration(t *testing.T) {
 	inv := Inventory{
@@ -70,3 +75,127 @@ func TestInventoryINIGeneration(t *testing.T) {
 	}
 
 }
+
+func TestInventoryINIGenerationWithProxyCommand(t *testing.T) {
+	inv := Inventory{
+		Roles: []Role{
+			{
+				Name: "worker",
+				Nodes: []Node{
+					{
+						Host:            "worker01",
+						PublicIP:        "10.0.0.3",
+						SSHPrivateKey:   "id_rsa",
+						SSHPort:         22,
+						SSHUser:         "alice",
+						SSHProxyCommand: "ssh -W %h:%p bob@bastion",
+					},
+				},
+			},
+		},
+	}
+
+	ini := string(inv.ToINI())
+
+	expected := `[worker]
+"worker01" ansible_host="10.0.0.3" internal_ipv4="10.0.0.3" ansible_ssh_private_key_file="id_rsa" ansible_port=22 ansible_user="alice" ansible_ssh_common_args="-o ProxyCommand='ssh -W %h:%p bob@bastion'"
+`
+	if ini != expected {
+		t.Errorf("expected format differs from obtained format. Expected: \n%s\nGot: \n%s\n", expected, ini)
+	}
+}
+
+func TestParseINIRoundTrip(t *testing.T) {
+	inv := Inventory{
+		Roles: []Role{
+			{
+				Name: "etcd",
+				Nodes: []Node{
+					{
+						Host:          "etcd01",
+						PublicIP:      "10.0.0.1",
+						InternalIP:    "192.168.0.11",
+						SSHPrivateKey: "id_rsa",
+						SSHPort:       2222,
+						SSHUser:       "alice",
+					},
+				},
+			},
+			{
+				Name: "worker",
+				Nodes: []Node{
+					{
+						Host:            "worker01",
+						PublicIP:        "10.0.0.3",
+						SSHPrivateKey:   "id_rsa",
+						SSHPort:         22,
+						SSHUser:         "alice and bob",
+						SSHProxyCommand: "ssh -W %h:%p bob@bastion",
+						Vars:            map[string]string{"kismatic_labels": "env=prod"},
+					},
+				},
+			},
+		},
+	}
+	parsed, err := ParseINI(bytes.NewReader(inv.ToINI()))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(*parsed, inv) {
+		t.Errorf("expected the parsed inventory to be\n%+v\nbut got\n%+v", inv, *parsed)
+	}
+}
+
+func TestParseINIGroups(t *testing.T) {
+	ini := `# cluster inventory
+[masters]
+master01 ansible_host=10.0.0.1
+master02 ansible_host=10.0.0.2 ansible_user=bob
+
+[masters:vars]
+ansible_user=alice
+
+[nodes:children]
+masters
+
+[nodes]
+worker01 ansible_host=10.0.0.3 # the first worker
+
+[all:vars]
+ansible_port=2222
+`
+	inv, err := ParseINI(strings.NewReader(ini))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(inv.Roles) != 2 || inv.Roles[0].Name != "masters" || inv.Roles[1].Name != "nodes" {
+		t.Fatalf("expected the masters and nodes roles, but got %+v", inv.Roles)
+	}
+	masters := inv.Roles[0].Nodes
+	if len(masters) != 2 || masters[0].SSHUser != "alice" || masters[1].SSHUser != "bob" || masters[0].SSHPort != 2222 {
+		t.Errorf("expected the group variables to apply to the masters, but got %+v", masters)
+	}
+	nodes := inv.Roles[1].Nodes
+	if len(nodes) != 3 || nodes[0].Host != "worker01" || nodes[1].Host != "master01" {
+		t.Errorf("expected the nodes role to include the masters, but got %+v", nodes)
+	}
+	if nodes[1].SSHUser != "alice" {
+		t.Errorf("expected the variables of the child group to apply, but got %q", nodes[1].SSHUser)
+	}
+}
+
+func TestParseINIInvalid(t *testing.T) {
+	tests := []string{
+		"[masters\nmaster01",
+		"[masters]\nmaster01 ansible_host=\"10.0.0.1",
+		"[masters]\nmaster01 ansible_port=ssh",
+		"[masters:hosts]\nmaster01",
+		"[nodes:children]\nmasters",
+		"[masters]\nmaster01 ansible_host",
+	}
+	for _, ini := range tests {
+		if _, err := ParseINI(strings.NewReader(ini)); err == nil {
+			t.Errorf("expected an error parsing %q, but didn't get one", ini)
+		}
+	}
+}
@@ -108,7 +108,11 @@ func (r *runner) StartPlaybookOnNode(playbookFile string, inv Inventory, cc Clus
 }
 
 func (r *runner) startPlaybook(playbookFile string, inv Inventory, cc ClusterCatalog, nodes ...string) (<-chan Event, error) {
-	playbook := filepath.Join(r.ansibleDir, "playbooks", playbookFile)
+	// generated playbooks are given with their absolute path
+	playbook := playbookFile
+	if !filepath.IsAbs(playbook) {
+		playbook = filepath.Join(r.ansibleDir, "playbooks", playbookFile)
+	}
 	if _, err := os.Stat(playbook); os.IsNotExist(err) {
 		return nil, fmt.Errorf("playbook %q does not exist", playbook)
 	}
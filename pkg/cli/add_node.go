@@ -22,7 +22,7 @@ type addNodeOpts struct {
 	SkipPreFlight            bool
 }
 
-var validRoles = []string{"worker", "ingress", "storage"}
+var validRoles = []string{"worker", "ingress", "storage", "master", "etcd"}
 
 // NewCmdAddNode returns the command for adding node to the cluster
 func NewCmdAddNode(out io.Writer, installOpts *installOpts) *cobra.Command {
@@ -61,30 +61,31 @@ func NewCmdAddNode(out io.Writer, installOpts *installOpts) *cobra.Command {
 					newNode.Labels[pair[0]] = pair[1]
 				}
 			}
-			return doAddNode(out, installOpts.planFilename, opts, newNode)
+			return doAddNode(out, installOpts.planner(), opts, newNode)
 		},
 	}
-	cmd.Flags().StringSliceVar(&opts.Roles, "roles", []string{}, "roles separated by ',' (options \"worker\"|\"ingress\"|\"storage\")")
+	cmd.Flags().StringSliceVar(&opts.Roles, "roles", []string{}, "roles separated by ',' (options \"worker\"|\"ingress\"|\"storage\"|\"master\"|\"etcd\")")
 	cmd.Flags().StringSliceVarP(&opts.NodeLabels, "labels", "l", []string{}, "key=value pairs separated by ','")
 	cmd.Flags().StringVar(&opts.GeneratedAssetsDirectory, "generated-assets-dir", "generated", "path to the directory where assets generated during the installation process will be stored")
 	cmd.Flags().BoolVar(&opts.RestartServices, "restart-services", false, "force restart clusters services (Use with care)")
 	cmd.Flags().BoolVar(&opts.Verbose, "verbose", false, "enable verbose logging from the installation")
-	cmd.Flags().StringVarP(&opts.OutputFormat, "output", "o", "simple", "installation output format (options \"simple\"|\"raw\")")
+	cmd.Flags().StringVarP(&opts.OutputFormat, "output", "o", "simple", "installation output format (options \"simple\"|\"raw\"|\"json\")")
 	cmd.Flags().BoolVar(&opts.SkipPreFlight, "skip-preflight", false, "skip pre-flight checks, useful when rerunning kismatic")
+	addPlanOverlayFlags(cmd.Flags(), &installOpts.overlays, &installOpts.values)
 	return cmd
 }
 
-func doAddNode(out io.Writer, planFile string, opts *addNodeOpts, newNode install.Node) error {
-	planner := &install.FilePlanner{File: planFile}
+func doAddNode(stdout io.Writer, planner *install.FilePlanner, opts *addNodeOpts, newNode install.Node) error {
+	out := textOutput(stdout, opts.OutputFormat)
 	if !planner.PlanExists() {
-		return planFileNotFoundErr{filename: planFile}
+		return planFileNotFoundErr{filename: planner.File}
 	}
 	execOpts := install.ExecutorOptions{
 		GeneratedAssetsDirectory: opts.GeneratedAssetsDirectory,
 		OutputFormat:             opts.OutputFormat,
 		Verbose:                  opts.Verbose,
 	}
-	executor, err := install.NewExecutor(out, os.Stderr, execOpts)
+	executor, err := install.NewExecutor(stdout, os.Stderr, execOpts)
 	if err != nil {
 		return err
 	}
@@ -115,7 +116,7 @@ func doAddNode(out io.Writer, planFile string, opts *addNodeOpts, newNode instal
 	}
 	if !opts.SkipPreFlight {
 		util.PrintHeader(out, "Running Pre-Flight Checks On New Node", '=')
-		if err = executor.RunNewNodePreFlightCheck(*plan, newNode); err != nil {
+		if err = executor.RunNewNodePreFlightCheck(*plan, newNode, opts.Roles); err != nil {
 			return err
 		}
 	}
@@ -123,15 +124,41 @@ func doAddNode(out io.Writer, planFile string, opts *addNodeOpts, newNode instal
 	if err != nil {
 		return err
 	}
-	if err := planner.Write(updatedPlan); err != nil {
+	addNode := func(p install.Plan) install.Plan { return install.AddNodeToPlan(p, newNode, opts.Roles) }
+	if err := updatePlanFile(planner, updatedPlan, addNode); err != nil {
 		return fmt.Errorf("error updating plan file to include the new node: %v", err)
 	}
+	if err := install.UpdateAppliedPlan(opts.GeneratedAssetsDirectory, addNode); err != nil {
+		return fmt.Errorf("error updating the applied plan to include the new node: %v", err)
+	}
 	return nil
 }
 
 // returns an error if the plan contains a node that is "equivalent"
 // to the new node that is being added
 func ensureNodeIsNew(plan install.Plan, newNode install.Node) error {
+	for _, n := range plan.Etcd.Nodes {
+		if n.Host == newNode.Host {
+			return fmt.Errorf("according to the plan file, the host name of the new node is already being used by another etcd node")
+		}
+		if n.IP == newNode.IP {
+			return fmt.Errorf("according to the plan file, the IP of the new node is already being used by another etcd node")
+		}
+		if newNode.InternalIP != "" && n.InternalIP == newNode.InternalIP {
+			return fmt.Errorf("according to the plan file, the internal IP of the new node is already being used by another etcd node")
+		}
+	}
+	for _, n := range plan.Master.Nodes {
+		if n.Host == newNode.Host {
+			return fmt.Errorf("according to the plan file, the host name of the new node is already being used by another master node")
+		}
+		if n.IP == newNode.IP {
+			return fmt.Errorf("according to the plan file, the IP of the new node is already being used by another master node")
+		}
+		if newNode.InternalIP != "" && n.InternalIP == newNode.InternalIP {
+			return fmt.Errorf("according to the plan file, the internal IP of the new node is already being used by another master node")
+		}
+	}
 	for _, n := range plan.Worker.Nodes {
 		if n.Host == newNode.Host {
 			return fmt.Errorf("according to the plan file, the host name of the new node is already being used by another worker node")
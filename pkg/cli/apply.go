@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"strings"
 
 	"github.com/apprenda/kismatic/pkg/install"
 	"github.com/apprenda/kismatic/pkg/util"
@@ -21,6 +22,9 @@ type applyCmd struct {
 	skipPreFlight      bool
 	restartServices    bool
 	limit              []string
+	planDiff           bool
+	force              bool
+	resume             bool
 }
 
 type applyOpts struct {
@@ -30,6 +34,9 @@ type applyOpts struct {
 	outputFormat       string
 	skipPreFlight      bool
 	limit              []string
+	planDiff           bool
+	force              bool
+	resume             bool
 }
 
 // NewCmdApply creates a cluter using the plan file
@@ -38,11 +45,24 @@ func NewCmdApply(out io.Writer, installOpts *installOpts) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "apply",
 		Short: "apply your plan file to create a Kubernetes cluster",
+		Long: `Apply your plan file to create a Kubernetes cluster.
+
+A snapshot of the plan is stored in the --generated-assets-dir after it is applied successfully.
+When --plan-diff is set, the changes between the snapshot and the plan file are listed, and the
+plan is not applied.
+
+Changes that cannot be made to an existing cluster, such as changing the service CIDR block,
+are refused unless --force is set.
+
+The plays that complete successfully are recorded in the run directory of the installation.
+When --resume is set, an interrupted installation is restarted from the first included playbook
+that has a play that did not complete, as long as the plan file has not changed. The completed
+plays of that playbook run again. The pre-flight checks are skipped when resuming an installation.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if len(args) != 0 {
 				return fmt.Errorf("Unexpected args: %v", args)
 			}
-			planner := &install.FilePlanner{File: installOpts.planFilename}
+			planner := installOpts.planner()
 			executorOpts := install.ExecutorOptions{
 				GeneratedAssetsDirectory: applyOpts.generatedAssetsDir,
 				OutputFormat:             applyOpts.outputFormat,
@@ -64,6 +84,9 @@ func NewCmdApply(out io.Writer, installOpts *installOpts) *cobra.Command {
 				skipPreFlight:      applyOpts.skipPreFlight,
 				restartServices:    applyOpts.restartServices,
 				limit:              applyOpts.limit,
+				planDiff:           applyOpts.planDiff,
+				force:              applyOpts.force,
+				resume:             applyOpts.resume,
 			}
 			return applyCmd.run()
 		},
@@ -74,30 +97,47 @@ func NewCmdApply(out io.Writer, installOpts *installOpts) *cobra.Command {
 	cmd.Flags().StringVar(&applyOpts.generatedAssetsDir, "generated-assets-dir", "generated", "path to the directory where assets generated during the installation process will be stored")
 	cmd.Flags().BoolVar(&applyOpts.restartServices, "restart-services", false, "force restart cluster services (Use with care)")
 	cmd.Flags().BoolVar(&applyOpts.verbose, "verbose", false, "enable verbose logging from the installation")
-	cmd.Flags().StringVarP(&applyOpts.outputFormat, "output", "o", "simple", "installation output format (options \"simple\"|\"raw\")")
+	cmd.Flags().StringVarP(&applyOpts.outputFormat, "output", "o", "simple", "installation output format (options \"simple\"|\"raw\"|\"json\")")
 	cmd.Flags().BoolVar(&applyOpts.skipPreFlight, "skip-preflight", false, "skip pre-flight checks, useful when rerunning kismatic")
+	cmd.Flags().BoolVar(&applyOpts.planDiff, "plan-diff", false, "list the changes between the last applied plan and the plan file, without applying it")
+	cmd.Flags().BoolVar(&applyOpts.force, "force", false, "apply changes that cannot be made to an existing cluster (Use with care)")
+	cmd.Flags().BoolVar(&applyOpts.resume, "resume", false, "resume the last installation from the first included playbook that did not complete")
+	addPlanOverlayFlags(cmd.Flags(), &installOpts.overlays, &installOpts.values)
 
 	return cmd
 }
 
 func (c *applyCmd) run() error {
-	// Validate and run pre-flight
+	if c.planDiff {
+		return c.printPlanDiff()
+	}
+	out := textOutput(c.out, c.outputFormat)
+	if !c.planner.PlanExists() {
+		return planFileNotFoundErr{filename: c.planFile}
+	}
+	plan, err := c.planner.Read()
+	if err != nil {
+		return fmt.Errorf("error reading plan file: %v", err)
+	}
+	// Reject changes that cannot be made to the cluster before spending
+	// time on validation and pre-flight
+	if err := c.checkImmutableChanges(out, plan); err != nil {
+		return err
+	}
+	// Validate and run pre-flight. The pre-flight checks are skipped when
+	// resuming, as they fail on nodes that are partially installed.
 	opts := &validateOpts{
 		planFile:           c.planFile,
 		verbose:            c.verbose,
 		outputFormat:       c.outputFormat,
-		skipPreFlight:      c.skipPreFlight,
+		skipPreFlight:      c.skipPreFlight || c.resume,
 		generatedAssetsDir: c.generatedAssetsDir,
 		limit:              c.limit,
 	}
-	err := doValidate(c.out, c.planner, opts)
+	err = doValidate(c.out, c.planner, opts)
 	if err != nil {
 		return fmt.Errorf("error validating plan: %v", err)
 	}
-	plan, err := c.planner.Read()
-	if err != nil {
-		return fmt.Errorf("error reading plan file: %v", err)
-	}
 
 	// Generate certificates
 	if err := c.executor.GenerateCertificates(plan, false); err != nil {
@@ -105,15 +145,19 @@ func (c *applyCmd) run() error {
 	}
 
 	// Generate kubeconfig
-	util.PrintHeader(c.out, "Generating Kubeconfig File", '=')
+	util.PrintHeader(out, "Generating Kubeconfig File", '=')
 	err = install.GenerateKubeconfig(plan, c.generatedAssetsDir)
 	if err != nil {
 		return fmt.Errorf("error generating kubeconfig file: %v", err)
 	}
-	util.PrettyPrintOk(c.out, "Generated kubeconfig file in the %q directory", c.generatedAssetsDir)
+	util.PrettyPrintOk(out, "Generated kubeconfig file in the %q directory", c.generatedAssetsDir)
 
 	// Perform the installation
-	if err := c.executor.Install(plan, c.restartServices, c.limit...); err != nil {
+	if c.resume {
+		if err := c.executor.ResumeInstall(plan, c.restartServices, c.limit...); err != nil {
+			return fmt.Errorf("error resuming installation: %v", err)
+		}
+	} else if err := c.executor.Install(plan, c.restartServices, c.limit...); err != nil {
 		return fmt.Errorf("error installing: %v", err)
 	}
 
@@ -125,16 +169,106 @@ func (c *applyCmd) run() error {
 		}
 	}
 
-	util.PrintColor(c.out, util.Green, "\nThe cluster was installed successfully!\n")
-	fmt.Fprintln(c.out)
+	// The snapshot describes the whole cluster, so it is not updated when
+	// only some of the nodes were installed
+	if len(c.limit) == 0 {
+		if err := install.SaveAppliedPlan(plan, c.generatedAssetsDir); err != nil {
+			return err
+		}
+	}
+
+	util.PrintColor(out, util.Green, "\nThe cluster was installed successfully!\n")
+	fmt.Fprintln(out)
 
 	msg := "- To use the generated kubeconfig file with kubectl:" +
 		"\n    * use \"./kubectl --kubeconfig %s/kubeconfig\"" +
 		"\n    * or copy the config file \"cp %[1]s/kubeconfig ~/.kube/config\"\n"
-	util.PrintColor(c.out, util.Blue, msg, c.generatedAssetsDir)
-	util.PrintColor(c.out, util.Blue, "- To view the Kubernetes dashboard: \"./kismatic dashboard\"\n")
-	util.PrintColor(c.out, util.Blue, "- To SSH into a cluster node: \"./kismatic ssh etcd|master|worker|storage|$node.host\"\n")
-	fmt.Fprintln(c.out)
+	util.PrintColor(out, util.Blue, msg, c.generatedAssetsDir)
+	util.PrintColor(out, util.Blue, "- To view the Kubernetes dashboard: \"./kismatic dashboard\"\n")
+	util.PrintColor(out, util.Blue, "- To SSH into a cluster node: \"./kismatic ssh etcd|master|worker|storage|$node.host\"\n")
+	fmt.Fprintln(out)
+
+	return nil
+}
+
+// diff returns the changes between the last applied plan and the plan file.
+// Returns nil if a plan has not been applied yet.
+func (c *applyCmd) diff(plan *install.Plan) (*install.PlanDiff, error) {
+	applied, err := install.ReadAppliedPlan(c.generatedAssetsDir)
+	if err != nil {
+		return nil, err
+	}
+	if applied == nil {
+		return nil, nil
+	}
+	diff, err := install.DiffPlans(applied, plan)
+	if err != nil {
+		return nil, fmt.Errorf("error comparing plans: %v", err)
+	}
+	return &diff, nil
+}
 
+func (c *applyCmd) checkImmutableChanges(out io.Writer, plan *install.Plan) error {
+	diff, err := c.diff(plan)
+	if err != nil {
+		return err
+	}
+	if diff == nil {
+		return nil
+	}
+	immutable := diff.ImmutableChanges()
+	if len(immutable) == 0 {
+		return nil
+	}
+	if c.force {
+		util.PrettyPrintWarn(out, "Applying %d change(s) that cannot be made to an existing cluster", len(immutable))
+		return nil
+	}
+	fmt.Fprintln(out, "The following changes cannot be made to an existing cluster:")
+	for _, ch := range immutable {
+		fmt.Fprintf(out, "  %s\n", ch)
+	}
+	return fmt.Errorf("the plan file contains %d change(s) that cannot be applied, use --force to apply them anyway", len(immutable))
+}
+
+func (c *applyCmd) printPlanDiff() error {
+	if !c.planner.PlanExists() {
+		return planFileNotFoundErr{filename: c.planFile}
+	}
+	plan, err := c.planner.Read()
+	if err != nil {
+		return fmt.Errorf("error reading plan file: %v", err)
+	}
+	diff, err := c.diff(plan)
+	if err != nil {
+		return err
+	}
+	if diff == nil {
+		fmt.Fprintf(c.out, "A plan has not been applied yet from the %q directory, the whole plan will be applied\n", c.generatedAssetsDir)
+		return nil
+	}
+	if diff.Empty() {
+		fmt.Fprintln(c.out, "No changes. The plan file matches the last applied plan.")
+		return nil
+	}
+	var category string
+	for _, ch := range diff.Changes {
+		if ch.Category != category {
+			category = ch.Category
+			fmt.Fprintf(c.out, "%s:\n", strings.Title(category))
+		}
+		line := fmt.Sprintf("  %s", ch)
+		if ch.Immutable {
+			line += " (immutable, requires --force)"
+		}
+		if ch.RequiresRotation {
+			line += " (requires \"kismatic certificates rotate\")"
+		}
+		fmt.Fprintln(c.out, line)
+	}
+	fmt.Fprintf(c.out, "\n%d change(s), %d immutable\n", len(diff.Changes), len(diff.ImmutableChanges()))
+	if len(diff.RotationChanges()) > 0 {
+		fmt.Fprintln(c.out, "Existing certificates are not replaced by \"install apply\", run \"kismatic certificates rotate\" to reissue them.")
+	}
 	return nil
 }
@@ -2,6 +2,9 @@ package cli
 
 import (
 	"bytes"
+	"io/ioutil"
+	"os"
+	"strings"
 	"testing"
 
 	"github.com/apprenda/kismatic/pkg/install"
@@ -64,3 +67,45 @@ func TestApplyCmdInvalidPlanFound(t *testing.T) {
 // 		t.Errorf("did not read CA cert when skip CA generation was set to true")
 // 	}
 // }
+
+func TestApplyCmdPlanDiff(t *testing.T) {
+	dir, err := ioutil.TempDir("", "apply-plan-diff")
+	if err != nil {
+		t.Fatalf("error creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	applied := &install.Plan{}
+	applied.Cluster.Networking.ServiceCIDRBlock = "172.20.0.0/16"
+	applied.Worker.Nodes = []install.Node{{Host: "worker01", IP: "10.0.0.1"}}
+	if err := install.SaveAppliedPlan(applied, dir); err != nil {
+		t.Fatalf("error saving applied plan: %v", err)
+	}
+
+	plan := &install.Plan{}
+	plan.Cluster.Networking.ServiceCIDRBlock = "172.30.0.0/16"
+	plan.AddOns.CNI = &install.CNI{Provider: "calico"}
+	plan.Worker.Nodes = []install.Node{{Host: "worker01", IP: "10.0.0.1"}, {Host: "worker02", IP: "10.0.0.2"}}
+	out := &bytes.Buffer{}
+	fe := &fakeExecutor{}
+	applyCmd := &applyCmd{
+		out:                out,
+		planner:            &fakePlanner{exists: true, plan: plan},
+		executor:           fe,
+		generatedAssetsDir: dir,
+		planDiff:           true,
+	}
+	if err := applyCmd.run(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if fe.installCalled {
+		t.Error("install was called when printing the plan diff")
+	}
+	for _, s := range []string{
+		"+ worker.nodes[worker02]: ip=10.0.0.2",
+		"~ cluster.networking.service_cidr_block: 172.20.0.0/16 => 172.30.0.0/16 (immutable, requires --force)",
+	} {
+		if !strings.Contains(out.String(), s) {
+			t.Errorf("expected output to contain %q, but got:\n%s", s, out.String())
+		}
+	}
+}
@@ -17,6 +17,8 @@ func NewCmdCertificates(out io.Writer) *cobra.Command {
 	}
 
 	cmd.AddCommand(NewCmdGenerate(out))
+	cmd.AddCommand(NewCmdCertificatesList(out))
+	cmd.AddCommand(NewCmdRotate(out))
 
 	return cmd
 }
@@ -17,6 +17,9 @@ type certificatesGenerateOpts struct {
 	organizations      []string
 	overwrite          bool
 	generatedAssetsDir string
+	planFilename       string
+	overlays           []string
+	values             []string
 }
 
 // NewCmdGenerate creates a new certificates generate command
@@ -25,7 +28,7 @@ func NewCmdGenerate(out io.Writer) *cobra.Command {
 
 	cmd := &cobra.Command{
 		Use:   "generate <name> [options]",
-		Short: "Generate a cluster certificate, expects 'ca.pem' and 'ca-key.pem' to be in the --generated-assets-dir",
+		Short: "Generate a cluster certificate, issued by the certificates backend of the plan file, or by the 'ca.pem' and 'ca-key.pem' in the --generated-assets-dir",
 		Args: func(cmd *cobra.Command, args []string) error {
 			if len(args) == 0 || args[0] == "" {
 				cmd.Help()
@@ -52,6 +55,8 @@ func NewCmdGenerate(out io.Writer) *cobra.Command {
 	cmd.Flags().StringSliceVar(&opts.organizations, "organizations", []string{}, "comma-separated list of names that should be included in the certificate's organization field.")
 	cmd.Flags().BoolVar(&opts.overwrite, "overwrite", false, "overwrite existing certificate if it already exists in the target directory.")
 	cmd.Flags().StringVar(&opts.generatedAssetsDir, "generated-assets-dir", "generated", "path to the directory where assets generated during the installation process will be stored")
+	addPlanFileFlag(cmd.Flags(), &opts.planFilename)
+	addPlanOverlayFlags(cmd.Flags(), &opts.overlays, &opts.values)
 
 	return cmd
 }
@@ -59,10 +64,20 @@ func NewCmdGenerate(out io.Writer) *cobra.Command {
 func doCertificatesGenerate(name string, opts *certificatesGenerateOpts, out io.Writer) error {
 	ansibleDir := "ansible"
 	certsDir := filepath.Join(opts.generatedAssetsDir, "keys")
-	pki := &install.LocalPKI{
-		CACsr: filepath.Join(ansibleDir, "playbooks", "tls", "ca-csr.json"),
-		GeneratedCertsDirectory: certsDir,
-		Log: out,
+	// the certificate is issued by the CA of the plan's certificates backend,
+	// or by the local CA when there is no plan file
+	plan := &install.Plan{}
+	planner := &install.FilePlanner{File: opts.planFilename, Overlays: opts.overlays, Values: opts.values}
+	if planner.PlanExists() {
+		p, err := planner.Read()
+		if err != nil {
+			return fmt.Errorf("error reading plan file %q: %v", opts.planFilename, err)
+		}
+		plan = p
+	}
+	pki, err := install.NewPKI(plan, filepath.Join(ansibleDir, "playbooks", "tls", "ca-csr.json"), certsDir, out)
+	if err != nil {
+		return err
 	}
 	ca, err := pki.GetClusterCA()
 	if err != nil {
@@ -0,0 +1,225 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/apprenda/kismatic/pkg/install"
+	"github.com/spf13/cobra"
+)
+
+const (
+	// exit codes of the certificates list command
+	certificatesWarningExitCode = 2
+	certificatesErrorExitCode   = 3
+)
+
+type certificatesListOpts struct {
+	planFilename       string
+	overlays           []string
+	values             []string
+	generatedAssetsDir string
+	outputFormat       string
+	remote             bool
+	warningDays        int
+	errorDays          int
+}
+
+type certificateLevel string
+
+const (
+	certificateOK      certificateLevel = "ok"
+	certificateWarning certificateLevel = "warning"
+	certificateError   certificateLevel = "error"
+)
+
+type listedCertificate struct {
+	install.CertificateInfo
+	Level certificateLevel `json:"level"`
+}
+
+type listedDeployedCertificate struct {
+	install.DeployedCertificate
+	Level certificateLevel `json:"level"`
+}
+
+type certificatesListResponse struct {
+	Certificates []listedCertificate         `json:"certificates"`
+	Deployed     []listedDeployedCertificate `json:"deployed,omitempty"`
+}
+
+// NewCmdCertificatesList creates a new certificates list command
+func NewCmdCertificatesList(out io.Writer) *cobra.Command {
+	opts := &certificatesListOpts{}
+
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List the cluster certificates and when they expire",
+		Long: `List the certificates in the --generated-assets-dir, along with their subject,
+subject alternative names, issuer, serial number and the number of days until they expire.
+
+When --remote is set, the certificates deployed on the nodes are compared against
+the ones in the --generated-assets-dir, and the ones that are different or missing are reported.
+The certificates of the nodes that cannot be reached are reported as failed.
+
+The command exits with status code 2 if a certificate expires within --warning-days,
+and with status code 3 if a certificate expires within --error-days, or if a deployed
+certificate is different, missing or could not be read.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 0 {
+				return fmt.Errorf("Unexpected args: %v", args)
+			}
+			return doCertificatesList(out, opts, time.Now())
+		},
+	}
+
+	cmd.Flags().StringVar(&opts.generatedAssetsDir, "generated-assets-dir", "generated", "path to the directory where assets generated during the installation process will be stored")
+	cmd.Flags().StringVarP(&opts.outputFormat, "output", "o", "simple", `output format (options "simple"|"json")`)
+	cmd.Flags().BoolVar(&opts.remote, "remote", false, "compare the certificates deployed on the nodes against the generated ones")
+	cmd.Flags().IntVar(&opts.warningDays, "warning-days", 30, "report a warning for certificates that expire within this number of days")
+	cmd.Flags().IntVar(&opts.errorDays, "error-days", 7, "report an error for certificates that expire within this number of days")
+	addPlanFileFlag(cmd.Flags(), &opts.planFilename)
+	addPlanOverlayFlags(cmd.Flags(), &opts.overlays, &opts.values)
+
+	return cmd
+}
+
+func doCertificatesList(out io.Writer, opts *certificatesListOpts, now time.Time) error {
+	if opts.outputFormat != "simple" && opts.outputFormat != "json" {
+		return fmt.Errorf("output format %q is not supported", opts.outputFormat)
+	}
+	if opts.errorDays > opts.warningDays {
+		return fmt.Errorf("--error-days cannot be greater than --warning-days")
+	}
+	certsDir := filepath.Join(opts.generatedAssetsDir, "keys")
+	certs, err := install.ListCertificates(certsDir, now)
+	if err != nil {
+		return err
+	}
+	if len(certs) == 0 {
+		return fmt.Errorf("no certificates were found in %q", certsDir)
+	}
+	resp := certificatesListResponse{}
+	for _, c := range certs {
+		resp.Certificates = append(resp.Certificates, listedCertificate{c, opts.expiryLevel(c.DaysToExpiry)})
+	}
+
+	if opts.remote {
+		planner := &install.FilePlanner{File: opts.planFilename, Overlays: opts.overlays, Values: opts.values}
+		if !planner.PlanExists() {
+			return planFileNotFoundErr{filename: opts.planFilename}
+		}
+		plan, err := planner.Read()
+		if err != nil {
+			return fmt.Errorf("error reading plan file %q: %v", opts.planFilename, err)
+		}
+		// SSH connectivity is not validated upfront: the certificates of
+		// the unreachable nodes are reported as failed, and the other nodes
+		// are still checked
+		deployed, err := install.CheckDeployedCertificates(plan, certsDir, now)
+		if err != nil {
+			return fmt.Errorf("error checking deployed certificates: %v", err)
+		}
+		for _, d := range deployed {
+			level := certificateError
+			if d.Status == install.DeployedCertificateMatch {
+				level = opts.expiryLevel(d.Certificate.DaysToExpiry)
+			}
+			resp.Deployed = append(resp.Deployed, listedDeployedCertificate{d, level})
+		}
+	}
+
+	if err := printCertificatesList(out, resp, opts.outputFormat); err != nil {
+		return err
+	}
+	return resp.exitError()
+}
+
+func (opts certificatesListOpts) expiryLevel(daysToExpiry int) certificateLevel {
+	switch {
+	case daysToExpiry <= opts.errorDays:
+		return certificateError
+	case daysToExpiry <= opts.warningDays:
+		return certificateWarning
+	}
+	return certificateOK
+}
+
+// exitError returns an error with the exit code that corresponds to the
+// most severe level in the response
+func (resp certificatesListResponse) exitError() error {
+	var warnings, errors int
+	count := func(s certificateLevel) {
+		switch s {
+		case certificateWarning:
+			warnings++
+		case certificateError:
+			errors++
+		}
+	}
+	for _, c := range resp.Certificates {
+		count(c.Level)
+	}
+	for _, d := range resp.Deployed {
+		count(d.Level)
+	}
+	if errors > 0 {
+		return ExitError{Code: certificatesErrorExitCode, Err: fmt.Errorf("%d certificate(s) reported errors", errors)}
+	}
+	if warnings > 0 {
+		return ExitError{Code: certificatesWarningExitCode, Err: fmt.Errorf("%d certificate(s) reported warnings", warnings)}
+	}
+	return nil
+}
+
+func printCertificatesList(out io.Writer, resp certificatesListResponse, format string) error {
+	if format == "json" {
+		b, err := json.MarshalIndent(resp, "", "  ")
+		if err != nil {
+			return fmt.Errorf("error marshalling struct: %v", err)
+		}
+		fmt.Fprintln(out, string(b))
+		return nil
+	}
+
+	w := tabwriter.NewWriter(out, 0, 0, 3, ' ', 0)
+	fmt.Fprint(w, "Name\tSubject\tSANs\tIssuer\tSerial\tExpires\tDays\tLevel\n")
+	for _, c := range resp.Certificates {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%d\t%s\n", c.Name, c.Subject, strings.Join(c.SANs, ","), c.Issuer, c.SerialNumber, c.NotAfter.Format("2006-01-02"), c.DaysToExpiry, c.Level)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if len(resp.Deployed) == 0 {
+		return nil
+	}
+
+	fmt.Fprintln(out)
+	fmt.Fprintf(out, "Deployed Certificates:\n")
+	w = tabwriter.NewWriter(out, 0, 0, 3, ' ', 0)
+	fmt.Fprint(w, "Node\tName\tPath\tStatus\tDays\tLevel\n")
+	for _, d := range resp.Deployed {
+		days := "-"
+		if d.Certificate != nil {
+			days = fmt.Sprintf("%d", d.Certificate.DaysToExpiry)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", d.Node, d.Name, d.Path, d.Status, days, d.Level)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	reported := map[string]bool{}
+	for _, d := range resp.Deployed {
+		if d.Error == "" || reported[d.Node+d.Error] {
+			continue
+		}
+		reported[d.Node+d.Error] = true
+		fmt.Fprintf(out, "%s: %s\n", d.Node, d.Error)
+	}
+	return nil
+}
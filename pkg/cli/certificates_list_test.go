@@ -0,0 +1,79 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/apprenda/kismatic/pkg/tls"
+)
+
+func TestCertificatesListExitCode(t *testing.T) {
+	dir, err := ioutil.TempDir("", "certificates-list-test")
+	if err != nil {
+		t.Fatalf("error creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	key, cert, err := tls.NewCACert("../tls/test/ca-csr.json", "someName", "2400h")
+	if err != nil {
+		t.Fatalf("error generating CA: %v", err)
+	}
+	if err := tls.WriteCert(key, cert, "ca", filepath.Join(dir, "keys")); err != nil {
+		t.Fatalf("error writing CA: %v", err)
+	}
+
+	tests := []struct {
+		daysFromNow  int
+		expectedCode int
+	}{
+		{0, 0},
+		{60, 0},
+		{75, certificatesWarningExitCode},
+		{95, certificatesErrorExitCode},
+		{120, certificatesErrorExitCode},
+	}
+	for _, test := range tests {
+		opts := &certificatesListOpts{
+			generatedAssetsDir: dir,
+			outputFormat:       "json",
+			warningDays:        30,
+			errorDays:          7,
+		}
+		out := &bytes.Buffer{}
+		err := doCertificatesList(out, opts, time.Now().Add(time.Duration(test.daysFromNow)*24*time.Hour))
+		code := 0
+		if err != nil {
+			exitErr, ok := err.(ExitError)
+			if !ok {
+				t.Fatalf("%d days from now: unexpected error: %v", test.daysFromNow, err)
+			}
+			code = exitErr.Code
+		}
+		if code != test.expectedCode {
+			t.Errorf("%d days from now: expected exit code %d, but got %d", test.daysFromNow, test.expectedCode, code)
+		}
+		resp := certificatesListResponse{}
+		if err := json.Unmarshal(out.Bytes(), &resp); err != nil {
+			t.Fatalf("error unmarshaling output: %v", err)
+		}
+		if len(resp.Certificates) != 1 || resp.Certificates[0].Name != "ca" {
+			t.Errorf("%d days from now: expected the CA to be listed, but got %+v", test.daysFromNow, resp.Certificates)
+		}
+	}
+}
+
+func TestCertificatesListInvalidThresholds(t *testing.T) {
+	opts := &certificatesListOpts{
+		generatedAssetsDir: "generated",
+		outputFormat:       "simple",
+		warningDays:        7,
+		errorDays:          30,
+	}
+	if err := doCertificatesList(&bytes.Buffer{}, opts, time.Now()); err == nil {
+		t.Errorf("expected an error when --error-days is greater than --warning-days")
+	}
+}
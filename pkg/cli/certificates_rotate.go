@@ -0,0 +1,95 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/apprenda/kismatic/pkg/install"
+	"github.com/apprenda/kismatic/pkg/util"
+	"github.com/spf13/cobra"
+)
+
+type certificatesRotateOpts struct {
+	planFilename       string
+	overlays           []string
+	values             []string
+	generatedAssetsDir string
+	verbose            bool
+	outputFormat       string
+	rotateCA           bool
+}
+
+// NewCmdRotate creates a new certificates rotate command
+func NewCmdRotate(out io.Writer) *cobra.Command {
+	opts := &certificatesRotateOpts{}
+
+	cmd := &cobra.Command{
+		Use:   "rotate",
+		Short: "Reissue the cluster certificates and deploy them to the nodes",
+		Long: `Reissue all the certificates required by the cluster described in the plan file,
+deploy them to the nodes, and restart the cluster components to pick them up.
+Nodes are restarted one at a time, so that the API server remains available on clusters
+with multiple master nodes.
+
+The existing certificates are backed up in the --generated-assets-dir. The service account
+signing key is not replaced, as doing so would invalidate all service account tokens.
+
+When --ca is set, the Certificate Authorities are replaced as well. The nodes trust both
+the old and the new Certificate Authorities while the certificates are being replaced.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 0 {
+				return fmt.Errorf("Unexpected args: %v", args)
+			}
+			return doCertificatesRotate(out, opts)
+		},
+	}
+
+	cmd.Flags().BoolVar(&opts.rotateCA, "ca", false, "replace the Certificate Authorities as well")
+	cmd.Flags().StringVar(&opts.generatedAssetsDir, "generated-assets-dir", "generated", "path to the directory where assets generated during the installation process will be stored")
+	cmd.Flags().BoolVar(&opts.verbose, "verbose", false, "enable verbose logging")
+	cmd.Flags().StringVarP(&opts.outputFormat, "output", "o", "simple", `output format (options "simple"|"raw")`)
+	addPlanFileFlag(cmd.Flags(), &opts.planFilename)
+	addPlanOverlayFlags(cmd.Flags(), &opts.overlays, &opts.values)
+
+	return cmd
+}
+
+func doCertificatesRotate(out io.Writer, opts *certificatesRotateOpts) error {
+	planner := &install.FilePlanner{File: opts.planFilename, Overlays: opts.overlays, Values: opts.values}
+	if !planner.PlanExists() {
+		return planFileNotFoundErr{filename: opts.planFilename}
+	}
+	plan, err := planner.Read()
+	if err != nil {
+		return fmt.Errorf("error reading plan file %q: %v", opts.planFilename, err)
+	}
+	// Existing certificates are not validated, as they are about to be replaced
+	if err := validatePlan(out, plan); err != nil {
+		return err
+	}
+	if err := validateSSHConnectivity(out, plan); err != nil {
+		return err
+	}
+
+	execOpts := install.ExecutorOptions{
+		GeneratedAssetsDirectory: opts.generatedAssetsDir,
+		OutputFormat:             opts.outputFormat,
+		Verbose:                  opts.verbose,
+	}
+	executor, err := install.NewExecutor(out, os.Stderr, execOpts)
+	if err != nil {
+		return err
+	}
+	if err := executor.RotateCertificates(plan, opts.rotateCA); err != nil {
+		return fmt.Errorf("error rotating certificates: %v", err)
+	}
+
+	// The kubeconfig file embeds the admin certificate
+	if _, err := install.RegenerateKubeconfig(plan, opts.generatedAssetsDir); err != nil {
+		return fmt.Errorf("error generating kubeconfig file: %v", err)
+	}
+	util.PrintColor(out, util.Green, "\nThe certificates of the cluster have been rotated.\n")
+	util.PrintColor(out, util.Green, "The kubeconfig file in %q has been regenerated.\n\n", opts.generatedAssetsDir)
+	return nil
+}
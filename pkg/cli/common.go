@@ -2,7 +2,11 @@ package cli
 
 import (
 	"fmt"
+	"io"
+	"os"
+	"time"
 
+	"github.com/apprenda/kismatic/pkg/install"
 	"github.com/spf13/pflag"
 )
 
@@ -10,6 +14,61 @@ func addPlanFileFlag(flagSet *pflag.FlagSet, p *string) {
 	flagSet.StringVarP(p, "plan-file", "f", "kismatic-cluster.yaml", "path to the installation plan file")
 }
 
+// planFileOpts are the options of the commands that read the plan file
+type planFileOpts struct {
+	planFilename string
+	overlays     []string
+	values       []string
+}
+
+// planner returns the planner of the plan file, with the overlays and values
+// merged into it
+func (opts *planFileOpts) planner() *install.FilePlanner {
+	return &install.FilePlanner{File: opts.planFilename, Overlays: opts.overlays, Values: opts.values}
+}
+
+// addPlanFlags adds the plan file flag, and the flags of the overlays and
+// values that are merged into it
+func addPlanFlags(flagSet *pflag.FlagSet, opts *planFileOpts) {
+	addPlanFileFlag(flagSet, &opts.planFilename)
+	addPlanOverlayFlags(flagSet, &opts.overlays, &opts.values)
+}
+
+func addPlanOverlayFlags(flagSet *pflag.FlagSet, overlays *[]string, values *[]string) {
+	flagSet.StringArrayVar(overlays, "overlay", []string{}, "path to a plan file that is merged into the plan file, can be repeated to merge multiple files in order")
+	flagSet.StringArrayVar(values, "set", []string{}, "path=value assignment that is set in the plan after the overlays are merged, such as cluster.name=production, can be repeated")
+}
+
+// updatePlanFile writes the updated plan to the plan file. When overlays or
+// values are merged into the plan, the change is applied to the plan file on
+// its own instead, so that the overlays and values are not written into it.
+func updatePlanFile(planner *install.FilePlanner, updated *install.Plan, change func(install.Plan) install.Plan) error {
+	if len(planner.Overlays) == 0 && len(planner.Values) == 0 {
+		return planner.Write(updated)
+	}
+	base := &install.FilePlanner{File: planner.File}
+	p, err := base.Read()
+	if err != nil {
+		return err
+	}
+	changed := change(*p)
+	return base.Write(&changed)
+}
+
+func addNodeQueryFlags(flagSet *pflag.FlagSet, concurrency *int, timeout *time.Duration) {
+	flagSet.IntVar(concurrency, "concurrency", install.DefaultNodeQueryConcurrency, "the maximum number of nodes that are queried in parallel over SSH when gathering node information")
+	flagSet.DurationVar(timeout, "node-timeout", install.DefaultNodeQueryTimeout, "the time allowed for gathering information from a single node")
+}
+
+// textOutput returns the writer for human-readable output. When the output format
+// is json, stdout is reserved for the event stream, and stderr is used instead.
+func textOutput(out io.Writer, outputFormat string) io.Writer {
+	if outputFormat == "json" {
+		return os.Stderr
+	}
+	return out
+}
+
 type planFileNotFoundErr struct {
 	filename string
 }
@@ -17,3 +76,13 @@ type planFileNotFoundErr struct {
 func (e planFileNotFoundErr) Error() string {
 	return fmt.Sprintf("Plan file not found at %q. If you don't have a plan file, you may generate one with 'kismatic install plan'", e.filename)
 }
+
+// ExitError is returned by commands that must exit with a specific status code
+type ExitError struct {
+	Code int
+	Err  error
+}
+
+func (e ExitError) Error() string {
+	return e.Err.Error()
+}
@@ -0,0 +1,44 @@
+package cli
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/apprenda/kismatic/pkg/install"
+)
+
+func TestUpdatePlanFileWithOverlays(t *testing.T) {
+	dir, err := ioutil.TempDir("", "update-plan-file-test")
+	if err != nil {
+		t.Fatalf("error creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "kismatic-cluster.yaml")
+	if err := ioutil.WriteFile(file, []byte("cluster:\n  name: kubernetes\nworker:\n  expected_count: 1\n  nodes:\n  - host: worker01\n    ip: 10.0.0.1\n"), 0644); err != nil {
+		t.Fatalf("error writing plan file: %v", err)
+	}
+	planner := &install.FilePlanner{File: file, Values: []string{"cluster.name=production"}}
+	plan, err := planner.Read()
+	if err != nil {
+		t.Fatalf("error reading plan: %v", err)
+	}
+	newNode := install.Node{Host: "worker02", IP: "10.0.0.2"}
+	addNode := func(p install.Plan) install.Plan { return install.AddNodeToPlan(p, newNode, []string{"worker"}) }
+	updated := addNode(*plan)
+
+	if err := updatePlanFile(planner, &updated, addNode); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	p, err := (&install.FilePlanner{File: file}).Read()
+	if err != nil {
+		t.Fatalf("error reading updated plan: %v", err)
+	}
+	if p.Cluster.Name != "kubernetes" {
+		t.Errorf("expected the values to not be written to the plan file, but the cluster name is %q", p.Cluster.Name)
+	}
+	if len(p.Worker.Nodes) != 2 || p.Worker.Nodes[1].Host != "worker02" {
+		t.Errorf("expected the new node to be written to the plan file, but got %+v", p.Worker.Nodes)
+	}
+}
@@ -12,6 +12,8 @@ import (
 
 type diagsOpts struct {
 	planFilename string
+	overlays     []string
+	values       []string
 	verbose      bool
 	outputFormat string
 }
@@ -34,6 +36,7 @@ func NewCmdDiagnostic(out io.Writer) *cobra.Command {
 
 	// PersistentFlags
 	addPlanFileFlag(cmd.PersistentFlags(), &opts.planFilename)
+	addPlanOverlayFlags(cmd.PersistentFlags(), &opts.overlays, &opts.values)
 	cmd.Flags().BoolVar(&opts.verbose, "verbose", false, "enable verbose logging from the installation")
 	cmd.Flags().StringVarP(&opts.outputFormat, "output", "o", "simple", "installation output format (options \"simple\"|\"raw\")")
 
@@ -44,7 +47,7 @@ func doDiagnostics(out io.Writer, opts *diagsOpts) error {
 	util.PrintHeader(out, "Gathering Diagnostic Data", '=')
 
 	planFile := opts.planFilename
-	planner := install.FilePlanner{File: planFile}
+	planner := install.FilePlanner{File: planFile, Overlays: opts.overlays, Values: opts.values}
 
 	// Read plan file
 	if !planner.PlanExists() {
@@ -0,0 +1,23 @@
+package cli
+
+import (
+	"io"
+
+	"github.com/spf13/cobra"
+)
+
+// NewCmdEtcd returns the etcd command
+func NewCmdEtcd(in io.Reader, out io.Writer) *cobra.Command {
+	opts := &planFileOpts{}
+	cmd := &cobra.Command{
+		Use:   "etcd",
+		Short: "back up and restore the Kubernetes etcd cluster",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return cmd.Usage()
+		},
+	}
+	addPlanFlags(cmd.PersistentFlags(), opts)
+	cmd.AddCommand(NewCmdEtcdBackup(out, opts))
+	cmd.AddCommand(NewCmdEtcdRestore(in, out, opts))
+	return cmd
+}
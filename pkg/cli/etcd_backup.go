@@ -0,0 +1,78 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/apprenda/kismatic/pkg/install"
+	"github.com/apprenda/kismatic/pkg/util"
+	"github.com/spf13/cobra"
+)
+
+type etcdBackupOpts struct {
+	generatedAssetsDir string
+	verbose            bool
+	outputFormat       string
+	retain             int
+}
+
+// NewCmdEtcdBackup returns the command for taking etcd snapshots
+func NewCmdEtcdBackup(out io.Writer, planOpts *planFileOpts) *cobra.Command {
+	opts := etcdBackupOpts{}
+	cmd := &cobra.Command{
+		Use:   "backup",
+		Short: "take a snapshot of the Kubernetes etcd cluster",
+		Long: `Take a snapshot of the Kubernetes etcd cluster.
+
+The snapshot is stored in the etcd-backups directory of the generated assets directory,
+along with its checksum and information about the cluster it was taken from.
+The networking etcd cluster is not backed up.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 0 {
+				return fmt.Errorf("Unexpected args: %v", args)
+			}
+			return doEtcdBackup(out, planOpts.planner(), opts)
+		},
+	}
+	cmd.Flags().StringVar(&opts.generatedAssetsDir, "generated-assets-dir", "generated", "path to the directory where assets generated during the installation process will be stored")
+	cmd.Flags().BoolVar(&opts.verbose, "verbose", false, "enable verbose logging")
+	cmd.Flags().StringVarP(&opts.outputFormat, "output", "o", "simple", `output format (options simple|raw)`)
+	cmd.Flags().IntVar(&opts.retain, "retain", 5, "number of snapshots to keep, older snapshots are removed. Set to 0 to keep all snapshots")
+	return cmd
+}
+
+func doEtcdBackup(out io.Writer, planner *install.FilePlanner, opts etcdBackupOpts) error {
+	if opts.retain < 0 {
+		return fmt.Errorf("--retain must be greater than or equal to 0")
+	}
+	if !planner.PlanExists() {
+		return planFileNotFoundErr{filename: planner.File}
+	}
+	plan, err := planner.Read()
+	if err != nil {
+		return fmt.Errorf("error reading plan file %q: %v", planner.File, err)
+	}
+	if ok, errs := install.ValidatePlanSSHConnections(plan); !ok {
+		util.PrettyPrintErr(out, "Validate SSH connectivity to nodes")
+		util.PrintValidationErrors(out, errs)
+		return fmt.Errorf("SSH connectivity validation errors found")
+	}
+	util.PrettyPrintOk(out, "Validate SSH connectivity to nodes")
+
+	execOpts := install.ExecutorOptions{
+		GeneratedAssetsDirectory: opts.generatedAssetsDir,
+		OutputFormat:             opts.outputFormat,
+		Verbose:                  opts.verbose,
+	}
+	executor, err := install.NewEtcdExecutor(out, os.Stderr, execOpts)
+	if err != nil {
+		return err
+	}
+	snapshot, err := executor.BackupEtcd(plan, opts.retain)
+	if err != nil {
+		return fmt.Errorf("error backing up etcd: %v", err)
+	}
+	util.PrintColor(out, util.Green, "\nSnapshot %q was saved in the %q directory.\n\n", snapshot.Name, opts.generatedAssetsDir)
+	return nil
+}
@@ -0,0 +1,87 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/apprenda/kismatic/pkg/install"
+	"github.com/apprenda/kismatic/pkg/util"
+	"github.com/spf13/cobra"
+)
+
+type etcdRestoreOpts struct {
+	generatedAssetsDir string
+	verbose            bool
+	outputFormat       string
+	force              bool
+}
+
+// NewCmdEtcdRestore returns the command for restoring etcd from a snapshot
+func NewCmdEtcdRestore(in io.Reader, out io.Writer, planOpts *planFileOpts) *cobra.Command {
+	opts := etcdRestoreOpts{}
+	cmd := &cobra.Command{
+		Use:   "restore SNAPSHOT_FILE",
+		Short: "rebuild the Kubernetes etcd cluster from a snapshot",
+		Long: `Rebuild the Kubernetes etcd cluster from a snapshot taken with 'etcd backup'.
+
+The etcd nodes in the plan file must be the members of the running etcd cluster. The members
+are not verified if the etcd cluster is down.
+The Kubernetes control plane is stopped while the etcd cluster is restored.
+The networking etcd cluster is not restored.
+
+WARNING all changes made to the cluster after the snapshot was taken will be lost.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return fmt.Errorf("%d arguments were provided, but restore requires the snapshot file", len(args))
+			}
+			if opts.force == false {
+				ans, err := util.PromptForString(in, out, "Are you sure you want to restore the etcd cluster? All changes made after the snapshot was taken will be lost", "N", []string{"N", "y"})
+				if err != nil {
+					return fmt.Errorf("error getting user response: %v", err)
+				}
+				if strings.ToLower(ans) != "y" {
+					os.Exit(0)
+				}
+			}
+			return doEtcdRestore(out, planOpts.planner(), args[0], opts)
+		},
+	}
+	cmd.Flags().StringVar(&opts.generatedAssetsDir, "generated-assets-dir", "generated", "path to the directory where assets generated during the installation process will be stored")
+	cmd.Flags().BoolVar(&opts.verbose, "verbose", false, "enable verbose logging")
+	cmd.Flags().StringVarP(&opts.outputFormat, "output", "o", "simple", `output format (options simple|raw)`)
+	cmd.Flags().BoolVar(&opts.force, "force", false, `do not prompt`)
+	return cmd
+}
+
+func doEtcdRestore(out io.Writer, planner *install.FilePlanner, snapshotFile string, opts etcdRestoreOpts) error {
+	if !planner.PlanExists() {
+		return planFileNotFoundErr{filename: planner.File}
+	}
+	plan, err := planner.Read()
+	if err != nil {
+		return fmt.Errorf("error reading plan file %q: %v", planner.File, err)
+	}
+	if ok, errs := install.ValidatePlanSSHConnections(plan); !ok {
+		util.PrettyPrintErr(out, "Validate SSH connectivity to nodes")
+		util.PrintValidationErrors(out, errs)
+		return fmt.Errorf("SSH connectivity validation errors found")
+	}
+	util.PrettyPrintOk(out, "Validate SSH connectivity to nodes")
+
+	execOpts := install.ExecutorOptions{
+		GeneratedAssetsDirectory: opts.generatedAssetsDir,
+		OutputFormat:             opts.outputFormat,
+		Verbose:                  opts.verbose,
+	}
+	executor, err := install.NewEtcdExecutor(out, os.Stderr, execOpts)
+	if err != nil {
+		return err
+	}
+	if err := executor.RestoreEtcd(plan, snapshotFile); err != nil {
+		return fmt.Errorf("error restoring etcd: %v", err)
+	}
+	util.PrintColor(out, util.Green, "\nThe etcd cluster was restored from %q.\n\n", snapshotFile)
+	return nil
+}
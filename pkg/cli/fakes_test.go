@@ -27,14 +27,19 @@ func (fp *fakePlanner) Write(p *install.Plan) error {
 }
 
 type fakeExecutor struct {
-	installCalled bool
-	err           error
+	installCalled       bool
+	resumeInstallCalled bool
+	err                 error
 }
 
 func (fe *fakeExecutor) AddNode(p *install.Plan, newNode install.Node, roles []string, restartServices bool) (*install.Plan, error) {
 	return nil, nil
 }
 
+func (fe *fakeExecutor) RemoveNode(p *install.Plan, node install.Node) (*install.Plan, error) {
+	return nil, nil
+}
+
 func (fe *fakeExecutor) GenerateCertificates(*install.Plan, bool) error {
 	return nil
 }
@@ -44,6 +49,11 @@ func (fe *fakeExecutor) Install(p *install.Plan, restartServices bool, nodes ...
 	return fe.err
 }
 
+func (fe *fakeExecutor) ResumeInstall(p *install.Plan, restartServices bool, nodes ...string) error {
+	fe.resumeInstallCalled = true
+	return fe.err
+}
+
 func (fe *fakeExecutor) Reset(p *install.Plan, nodes ...string) error {
 	return nil
 }
@@ -52,7 +62,7 @@ func (fe *fakeExecutor) RunPreFlightCheck(p *install.Plan, nodes ...string) erro
 	return nil
 }
 
-func (fe *fakeExecutor) RunNewNodePreFlightCheck(install.Plan, install.Node) error {
+func (fe *fakeExecutor) RunNewNodePreFlightCheck(install.Plan, install.Node, []string) error {
 	return nil
 }
 
@@ -76,6 +86,10 @@ func (fe *fakeExecutor) UpgradeClusterServices(install.Plan) error {
 	return nil
 }
 
+func (fe *fakeExecutor) RotateCertificates(p *install.Plan, rotateCA bool) error {
+	return nil
+}
+
 func (fe *fakeExecutor) RunSmokeTest(p *install.Plan) error {
 	return nil
 }
@@ -0,0 +1,136 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/apprenda/kismatic/pkg/install"
+	"github.com/spf13/cobra"
+	yaml "gopkg.in/yaml.v2"
+)
+
+type historyOpts struct {
+	runsDir      string
+	outputFormat string
+	all          bool
+}
+
+// NewCmdHistory creates a new history command
+func NewCmdHistory(out io.Writer) *cobra.Command {
+	opts := &historyOpts{}
+	cmd := &cobra.Command{
+		Use:   "history",
+		Short: "Inspect the operations that were run against the cluster",
+		Long: `Inspect the operations that were run against the cluster, such as apply, upgrade, add-node,
+volume and reset operations. Every operation records the command that was run, the user that ran it,
+the hash of the plan file, the version of kismatic, the nodes it targeted, when it started and ended,
+its outcome and the path to its ansible log.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			cmd.Help()
+		},
+	}
+
+	cmd.PersistentFlags().StringVar(&opts.runsDir, "runs-dir", "runs", "path to the directory where the runs of the operations are stored")
+	cmd.PersistentFlags().StringVarP(&opts.outputFormat, "output", "o", "simple", `output format (options "simple"|"json")`)
+
+	cmd.AddCommand(NewCmdHistoryList(out, opts))
+	cmd.AddCommand(NewCmdHistoryShow(out, opts))
+
+	return cmd
+}
+
+// NewCmdHistoryList creates a new history list command
+func NewCmdHistoryList(out io.Writer, opts *historyOpts) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List the operations that were run against the cluster, most recent first",
+		Long: `List the operations that were run against the cluster, most recent first.
+
+Operations that only inspect the cluster, such as the pre-flight checks and the smoke tests,
+are only listed when --all is set.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 0 {
+				return fmt.Errorf("Unexpected args: %v", args)
+			}
+			return doHistoryList(out, opts, time.Now())
+		},
+	}
+	cmd.Flags().BoolVar(&opts.all, "all", false, "list the operations that only inspect the cluster as well")
+	return cmd
+}
+
+// NewCmdHistoryShow creates a new history show command
+func NewCmdHistoryShow(out io.Writer, opts *historyOpts) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "show ID",
+		Short: "Show the details of an operation that was run against the cluster",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return cmd.Usage()
+			}
+			return doHistoryShow(out, opts, args[0])
+		},
+	}
+	return cmd
+}
+
+func doHistoryList(out io.Writer, opts *historyOpts, now time.Time) error {
+	if opts.outputFormat != "simple" && opts.outputFormat != "json" {
+		return fmt.Errorf("output format %q is not supported", opts.outputFormat)
+	}
+	runs, err := install.ListRuns(opts.runsDir)
+	if err != nil {
+		return err
+	}
+	listed := []install.RunRecord{}
+	for _, r := range runs {
+		if opts.all || !r.ReadOnly() {
+			listed = append(listed, r)
+		}
+	}
+	if opts.outputFormat == "json" {
+		return printJSON(out, listed)
+	}
+	if len(listed) == 0 {
+		fmt.Fprintf(out, "No operations were found in %q\n", opts.runsDir)
+		return nil
+	}
+	w := tabwriter.NewWriter(out, 0, 0, 3, ' ', 0)
+	fmt.Fprint(w, "ID\tUser\tStarted\tDuration\tOutcome\tNodes\n")
+	for _, r := range listed {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", r.ID, r.User, r.Start.Format(time.RFC3339), r.Duration(now).Round(time.Second), r.Outcome, strings.Join(r.Nodes, ","))
+	}
+	return w.Flush()
+}
+
+func doHistoryShow(out io.Writer, opts *historyOpts, id string) error {
+	if opts.outputFormat != "simple" && opts.outputFormat != "json" {
+		return fmt.Errorf("output format %q is not supported", opts.outputFormat)
+	}
+	r, err := install.GetRun(opts.runsDir, id)
+	if err != nil {
+		return err
+	}
+	if opts.outputFormat == "json" {
+		return printJSON(out, r)
+	}
+	b, err := yaml.Marshal(r)
+	if err != nil {
+		return fmt.Errorf("error marshalling run: %v", err)
+	}
+	fmt.Fprint(out, string(b))
+	return nil
+}
+
+func printJSON(out io.Writer, v interface{}) error {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error marshalling struct: %v", err)
+	}
+	fmt.Fprintln(out, string(b))
+	return nil
+}
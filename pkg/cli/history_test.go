@@ -0,0 +1,73 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/apprenda/kismatic/pkg/install"
+)
+
+func writeHistoryTestRun(t *testing.T, runsDir, id, content string) {
+	dir := filepath.Join(runsDir, filepath.FromSlash(id))
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		t.Fatalf("error creating run directory: %v", err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "run.yaml"), []byte(content), 0644); err != nil {
+		t.Fatalf("error writing run record: %v", err)
+	}
+}
+
+func TestHistoryList(t *testing.T) {
+	dir, err := ioutil.TempDir("", "history-list-test")
+	if err != nil {
+		t.Fatalf("error creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	writeHistoryTestRun(t, dir, "preflight/2018-01-01-00-00-00", "id: preflight/2018-01-01-00-00-00\ntask: preflight\nstart: 2018-01-01T00:00:00Z\nend: 2018-01-01T00:01:00Z\noutcome: succeeded\n")
+	writeHistoryTestRun(t, dir, "apply/2018-01-01-00-01-00", "id: apply/2018-01-01-00-01-00\ntask: apply\nuser: alice\nstart: 2018-01-01T00:01:00Z\nend: 2018-01-01T00:11:00Z\noutcome: failed\n")
+	writeHistoryTestRun(t, dir, "apply/2018-01-02-00-00-00", "id: apply/2018-01-02-00-00-00\ntask: apply\nuser: bob\nstart: 2018-01-02T00:00:00Z\noutcome: running\n")
+
+	tests := []struct {
+		all         bool
+		expectedIDs []string
+	}{
+		{false, []string{"apply/2018-01-02-00-00-00", "apply/2018-01-01-00-01-00"}},
+		{true, []string{"apply/2018-01-02-00-00-00", "apply/2018-01-01-00-01-00", "preflight/2018-01-01-00-00-00"}},
+	}
+	for _, test := range tests {
+		out := &bytes.Buffer{}
+		opts := &historyOpts{runsDir: dir, outputFormat: "json", all: test.all}
+		if err := doHistoryList(out, opts, time.Now()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		var runs []install.RunRecord
+		if err := json.Unmarshal(out.Bytes(), &runs); err != nil {
+			t.Fatalf("error unmarshalling output: %v", err)
+		}
+		var ids []string
+		for _, r := range runs {
+			ids = append(ids, r.ID)
+		}
+		if strings.Join(ids, " ") != strings.Join(test.expectedIDs, " ") {
+			t.Errorf("all=%v: expected runs %v, but got %v", test.all, test.expectedIDs, ids)
+		}
+	}
+
+	out := &bytes.Buffer{}
+	opts := &historyOpts{runsDir: dir, outputFormat: "simple"}
+	if err := doHistoryShow(out, opts, "apply/2018-01-01-00-01-00"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(out.String(), "user: alice") || !strings.Contains(out.String(), "outcome: failed") {
+		t.Errorf("expected the details of the run, but got:\n%s", out.String())
+	}
+	if err := doHistoryShow(out, opts, "apply/2000-01-01-00-00-00"); err == nil {
+		t.Errorf("expected an error showing a run that does not exist, but didn't get one")
+	}
+}
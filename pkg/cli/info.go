@@ -6,6 +6,7 @@ import (
 	"io"
 	"strings"
 	"text/tabwriter"
+	"time"
 
 	"github.com/apprenda/kismatic/pkg/install"
 	"github.com/apprenda/kismatic/pkg/util"
@@ -14,7 +15,11 @@ import (
 
 type infoOpts struct {
 	planFilename string
+	overlays     []string
+	values       []string
 	outputFormat string
+	concurrency  int
+	nodeTimeout  time.Duration
 }
 
 // NewCmdInfo returns the info command
@@ -25,19 +30,22 @@ func NewCmdInfo(out io.Writer) *cobra.Command {
 		Short: "Display info about nodes in the cluster",
 		Long: `will list the nodes that make up the cluster, along with their current versions & roles.
 
-This will be retrieved by connecting to each node via ssh`,
+This will be retrieved by connecting to each node via ssh. The nodes are queried in parallel,
+and the nodes that could not be queried are reported once all other nodes have been listed.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return list(out, opts)
 		},
 	}
 	cmd.Flags().StringVarP(&opts.planFilename, "plan-file", "f", "kismatic-cluster.yaml", "path to the installation plan file")
+	addPlanOverlayFlags(cmd.Flags(), &opts.overlays, &opts.values)
 	cmd.Flags().StringVarP(&opts.outputFormat, "output", "o", "simple", `output format (options "simple"|"json")`)
+	addNodeQueryFlags(cmd.Flags(), &opts.concurrency, &opts.nodeTimeout)
 	return cmd
 }
 
 func list(out io.Writer, opts *infoOpts) error {
 	// Check if plan file exists
-	planner := &install.FilePlanner{File: opts.planFilename}
+	planner := &install.FilePlanner{File: opts.planFilename, Overlays: opts.overlays, Values: opts.values}
 	if !planner.PlanExists() {
 		return fmt.Errorf("plan does not exist")
 	}
@@ -58,10 +66,7 @@ func list(out io.Writer, opts *infoOpts) error {
 		return fmt.Errorf("error getting info from cluster nodes")
 	}
 
-	lv, err := install.ListVersions(plan)
-	if err != nil {
-		return fmt.Errorf("error getting version: %v", err)
-	}
+	lv := install.ListVersions(plan, install.NodeQueryOptions{Concurrency: opts.concurrency, Timeout: opts.nodeTimeout})
 
 	if opts.outputFormat == "json" {
 		b, err := json.MarshalIndent(lv, "", "  ")
@@ -69,7 +74,7 @@ func list(out io.Writer, opts *infoOpts) error {
 			return fmt.Errorf("error marshalling struct: %v", err)
 		}
 		fmt.Fprintln(out, string(b))
-		return nil
+		return lv.FailedNodesErr()
 	}
 
 	fmt.Fprintf(out, "Cluster Version: ")
@@ -85,5 +90,21 @@ func list(out io.Writer, opts *infoOpts) error {
 	for _, listNode := range lv.Nodes {
 		fmt.Fprintf(w, "%v\t%v\t%v\t%v\n", listNode.Node.Host, listNode.Node.IP, strings.Join(listNode.Roles, ","), listNode.Version)
 	}
-	return w.Flush()
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if len(lv.FailedNodes) > 0 {
+		fmt.Fprintln(out)
+		fmt.Fprintf(out, "Unreachable Nodes:\n")
+		w = tabwriter.NewWriter(out, 0, 0, 3, ' ', 0)
+		fmt.Fprint(w, "Name\tIP\tError\n")
+		for _, n := range lv.FailedNodes {
+			fmt.Fprintf(w, "%v\t%v\t%v\n", n.Node.Host, n.Node.IP, n.Error)
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+		return fmt.Errorf("error getting info from %d node(s)", len(lv.FailedNodes))
+	}
+	return nil
 }
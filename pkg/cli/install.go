@@ -7,7 +7,7 @@ import (
 )
 
 type installOpts struct {
-	planFilename string
+	planFileOpts
 }
 
 // NewCmdInstall creates a new install command
@@ -11,6 +11,8 @@ import (
 
 type ipOpts struct {
 	planFilename string
+	overlays     []string
+	values       []string
 }
 
 // NewCmdIP prints the cluster's IP
@@ -24,13 +26,14 @@ func NewCmdIP(out io.Writer) *cobra.Command {
 			if len(args) != 0 {
 				return fmt.Errorf("Unexpected args: %v", args)
 			}
-			planner := &install.FilePlanner{File: opts.planFilename}
+			planner := &install.FilePlanner{File: opts.planFilename, Overlays: opts.overlays, Values: opts.values}
 			return doIP(out, planner, opts)
 		},
 	}
 
 	// PersistentFlags
 	cmd.PersistentFlags().StringVarP(&opts.planFilename, "plan-file", "f", "kismatic-cluster.yaml", "path to the installation plan file")
+	addPlanOverlayFlags(cmd.PersistentFlags(), &opts.overlays, &opts.values)
 
 	return cmd
 }
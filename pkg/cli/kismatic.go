@@ -23,6 +23,7 @@ more documentation is available at https://github.com/apprenda/kismatic`,
 	cmd.AddCommand(NewCmdVersion(buildDate, out))
 	cmd.AddCommand(NewCmdInstall(in, out))
 	cmd.AddCommand(NewCmdReset(in, out))
+	cmd.AddCommand(NewCmdRemoveNode(in, out))
 	cmd.AddCommand(NewCmdVolume(in, out))
 	cmd.AddCommand(NewCmdIP(out))
 	cmd.AddCommand(NewCmdDashboard(in, out))
@@ -31,6 +32,9 @@ more documentation is available at https://github.com/apprenda/kismatic`,
 	cmd.AddCommand(NewCmdUpgrade(in, out))
 	cmd.AddCommand(NewCmdDiagnostic(out))
 	cmd.AddCommand(NewCmdCertificates(out))
+	cmd.AddCommand(NewCmdEtcd(in, out))
+	cmd.AddCommand(NewCmdHistory(out))
+	cmd.AddCommand(NewCmdPlanFile(out))
 	cmd.AddCommand(NewCmdSeedRegistry(out, stderr))
 
 	return cmd, nil
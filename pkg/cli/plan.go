@@ -3,99 +3,209 @@ package cli
 import (
 	"fmt"
 	"io"
+	"io/ioutil"
+	"strings"
 
 	"github.com/apprenda/kismatic/pkg/install"
 	"github.com/apprenda/kismatic/pkg/util"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	yaml "gopkg.in/yaml.v2"
 )
 
+type planOpts struct {
+	answersFile      string
+	nonInteractive   bool
+	etcdNodes        int
+	masterNodes      int
+	workerNodes      int
+	ingressNodes     int
+	storageNodes     int
+	additionalFiles  int
+	adminPassword    string
+	cniProvider      string
+	calicoMode       string
+	dnsProvider      string
+	podCIDRBlock     string
+	serviceCIDRBlock string
+	// answered are the flags that were set, either on the command line or in
+	// the answers file
+	answered map[string]bool
+}
+
+// planAnswerFlags are the flags that can be set in an answers file
+var planAnswerFlags = []string{
+	"etcd-nodes", "master-nodes", "worker-nodes", "ingress-nodes", "storage-nodes", "additional-files",
+	"admin-password", "cni-provider", "calico-mode", "dns-provider", "pod-cidr-block", "service-cidr-block",
+}
+
 // NewCmdPlan creates a new install plan command
 func NewCmdPlan(in io.Reader, out io.Writer, options *installOpts) *cobra.Command {
+	opts := &planOpts{}
 	cmd := &cobra.Command{
 		Use:   "plan",
 		Short: "plan your Kubernetes cluster and generate a plan file",
+		Long: `Plan your Kubernetes cluster and generate a plan file.
+
+The number of nodes is prompted for, unless it is set with a flag. When --non-interactive or
+--answers-file is set, nothing is prompted for, and the values that are not set are defaulted.
+The answers file is a YAML file keyed by the names of the flags, such as:
+
+  etcd-nodes: 3
+  master-nodes: 2
+  cni-provider: weave
+
+The flags that are set on the command line take precedence over the answers file.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if len(args) != 0 {
 				return fmt.Errorf("Unexpected args: %v", args)
 			}
+			if opts.answersFile != "" {
+				if err := readPlanAnswers(cmd.Flags(), opts.answersFile); err != nil {
+					return err
+				}
+				opts.nonInteractive = true
+			}
+			opts.answered = map[string]bool{}
+			cmd.Flags().Visit(func(f *pflag.Flag) {
+				opts.answered[f.Name] = true
+			})
 			planner := &install.FilePlanner{File: options.planFilename}
-			return doPlan(in, out, planner, options.planFilename)
+			return doPlan(in, out, planner, options.planFilename, opts)
 		},
 	}
+	cmd.Flags().StringVar(&opts.answersFile, "answers-file", "", "path to a YAML file with the answers to the questions, keyed by the names of the flags")
+	cmd.Flags().BoolVar(&opts.nonInteractive, "non-interactive", false, "do not prompt for the values that are not set, and use their defaults")
+	cmd.Flags().IntVar(&opts.etcdNodes, "etcd-nodes", 3, "number of etcd nodes")
+	cmd.Flags().IntVar(&opts.masterNodes, "master-nodes", 2, "number of master nodes")
+	cmd.Flags().IntVar(&opts.workerNodes, "worker-nodes", 3, "number of worker nodes")
+	cmd.Flags().IntVar(&opts.ingressNodes, "ingress-nodes", 2, "number of ingress nodes")
+	cmd.Flags().IntVar(&opts.storageNodes, "storage-nodes", 0, "number of storage nodes")
+	cmd.Flags().IntVar(&opts.additionalFiles, "additional-files", 0, "number of existing files or directories to be copied")
+	cmd.Flags().StringVar(&opts.adminPassword, "admin-password", "", "password of the admin user, or a reference to it such as env:ADMIN_PASSWORD")
+	cmd.Flags().StringVar(&opts.cniProvider, "cni-provider", "calico", "CNI provider (options \"calico\"|\"contiv\"|\"weave\"|\"custom\")")
+	cmd.Flags().StringVar(&opts.calicoMode, "calico-mode", "overlay", "Calico mode, when the CNI provider is calico (options \"overlay\"|\"routed\")")
+	cmd.Flags().StringVar(&opts.dnsProvider, "dns-provider", "kubedns", "DNS provider (options \"kubedns\"|\"coredns\")")
+	cmd.Flags().StringVar(&opts.podCIDRBlock, "pod-cidr-block", "172.16.0.0/16", "CIDR block of the pod network")
+	cmd.Flags().StringVar(&opts.serviceCIDRBlock, "service-cidr-block", "172.20.0.0/16", "CIDR block of the service network")
 
 	return cmd
 }
 
-func doPlan(in io.Reader, out io.Writer, planner install.Planner, planFile string) error {
-	fmt.Fprintln(out, "Plan your Kubernetes cluster:")
+// readPlanAnswers sets the flags that are in the answers file, unless they
+// were set on the command line
+func readPlanAnswers(flags *pflag.FlagSet, file string) error {
+	d, err := ioutil.ReadFile(file)
+	if err != nil {
+		return fmt.Errorf("error reading answers file: %v", err)
+	}
+	answers := map[string]interface{}{}
+	if err := yaml.Unmarshal(d, &answers); err != nil {
+		return fmt.Errorf("error unmarshalling answers file %q: %v", file, err)
+	}
+	for name, value := range answers {
+		if !util.Contains(name, planAnswerFlags) {
+			return fmt.Errorf("%q in the answers file is not a valid answer, the options are %s", name, strings.Join(planAnswerFlags, ", "))
+		}
+		if flags.Changed(name) {
+			continue
+		}
+		if err := flags.Set(name, fmt.Sprint(value)); err != nil {
+			return fmt.Errorf("invalid value %q of %q in the answers file: %v", value, name, err)
+		}
+	}
+	return nil
+}
 
-	etcdNodes, err := util.PromptForInt(in, out, "Number of etcd nodes", 3)
+// promptForInt prompts for the value of the flag, unless the flag was set or
+// prompting is disabled
+func (opts *planOpts) promptForInt(in io.Reader, out io.Writer, flag string, prompt string, value *int) error {
+	if opts.nonInteractive || opts.answered[flag] {
+		return nil
+	}
+	v, err := util.PromptForInt(in, out, prompt, *value)
 	if err != nil {
+		return err
+	}
+	*value = v
+	return nil
+}
+
+func doPlan(in io.Reader, out io.Writer, planner install.Planner, planFile string, opts *planOpts) error {
+	fmt.Fprintln(out, "Plan your Kubernetes cluster:")
+
+	if err := opts.promptForInt(in, out, "etcd-nodes", "Number of etcd nodes", &opts.etcdNodes); err != nil {
 		return fmt.Errorf("Error reading number of etcd nodes: %v", err)
 	}
-	if etcdNodes <= 0 {
+	if opts.etcdNodes <= 0 {
 		return fmt.Errorf("The number of etcd nodes must be greater than zero")
 	}
 
-	masterNodes, err := util.PromptForInt(in, out, "Number of master nodes", 2)
-	if err != nil {
+	if err := opts.promptForInt(in, out, "master-nodes", "Number of master nodes", &opts.masterNodes); err != nil {
 		return fmt.Errorf("Error reading number of master nodes: %v", err)
 	}
-	if masterNodes <= 0 {
+	if opts.masterNodes <= 0 {
 		return fmt.Errorf("The number of master nodes must be greater than zero")
 	}
 
-	workerNodes, err := util.PromptForInt(in, out, "Number of worker nodes", 3)
-	if err != nil {
+	if err := opts.promptForInt(in, out, "worker-nodes", "Number of worker nodes", &opts.workerNodes); err != nil {
 		return fmt.Errorf("Error reading number of worker nodes: %v", err)
 	}
-	if workerNodes <= 0 {
+	if opts.workerNodes <= 0 {
 		return fmt.Errorf("The number of worker nodes must be greater than zero")
 	}
 
-	ingressNodes, err := util.PromptForInt(in, out, "Number of ingress nodes (optional, set to 0 if not required)", 2)
-	if err != nil {
+	if err := opts.promptForInt(in, out, "ingress-nodes", "Number of ingress nodes (optional, set to 0 if not required)", &opts.ingressNodes); err != nil {
 		return fmt.Errorf("Error reading number of ingress nodes: %v", err)
 	}
-	if ingressNodes < 0 {
+	if opts.ingressNodes < 0 {
 		return fmt.Errorf("The number of ingress nodes must be greater than or equal to zero")
 	}
 
-	storageNodes, err := util.PromptForInt(in, out, "Number of storage nodes (optional, set to 0 if not required)", 0)
-	if err != nil {
+	if err := opts.promptForInt(in, out, "storage-nodes", "Number of storage nodes (optional, set to 0 if not required)", &opts.storageNodes); err != nil {
 		return fmt.Errorf("Error reading number of storage nodes: %v", err)
 	}
-	if storageNodes < 0 {
+	if opts.storageNodes < 0 {
 		return fmt.Errorf("The number of storage nodes must be greater than or equal to zero")
 	}
 
-	files, err := util.PromptForInt(in, out, "Number of existing files or directories to be copied", 0)
-	if err != nil {
+	if err := opts.promptForInt(in, out, "additional-files", "Number of existing files or directories to be copied", &opts.additionalFiles); err != nil {
 		return fmt.Errorf("Error reading number of files or directories: %v", err)
 	}
-	if files < 0 {
+	if opts.additionalFiles < 0 {
 		return fmt.Errorf("The number of files or directories must be greater than or equal to zero")
 	}
 
 	fmt.Fprintln(out)
 	fmt.Fprintf(out, "Generating installation plan file template with: \n")
-	fmt.Fprintf(out, "- %d etcd nodes\n", etcdNodes)
-	fmt.Fprintf(out, "- %d master nodes\n", masterNodes)
-	fmt.Fprintf(out, "- %d worker nodes\n", workerNodes)
-	fmt.Fprintf(out, "- %d ingress nodes\n", ingressNodes)
-	fmt.Fprintf(out, "- %d storage nodes\n", storageNodes)
-	fmt.Fprintf(out, "- %d files\n", files)
+	fmt.Fprintf(out, "- %d etcd nodes\n", opts.etcdNodes)
+	fmt.Fprintf(out, "- %d master nodes\n", opts.masterNodes)
+	fmt.Fprintf(out, "- %d worker nodes\n", opts.workerNodes)
+	fmt.Fprintf(out, "- %d ingress nodes\n", opts.ingressNodes)
+	fmt.Fprintf(out, "- %d storage nodes\n", opts.storageNodes)
+	fmt.Fprintf(out, "- %d files\n", opts.additionalFiles)
 	fmt.Fprintln(out)
 
 	planTemplate := install.PlanTemplateOptions{
-		EtcdNodes:       etcdNodes,
-		MasterNodes:     masterNodes,
-		WorkerNodes:     workerNodes,
-		IngressNodes:    ingressNodes,
-		StorageNodes:    storageNodes,
-		AdditionalFiles: files,
-	}
-	if err = install.WritePlanTemplate(planTemplate, planner); err != nil {
+		EtcdNodes:        opts.etcdNodes,
+		MasterNodes:      opts.masterNodes,
+		WorkerNodes:      opts.workerNodes,
+		IngressNodes:     opts.ingressNodes,
+		StorageNodes:     opts.storageNodes,
+		AdditionalFiles:  opts.additionalFiles,
+		AdminPassword:    opts.adminPassword,
+		CNIProvider:      opts.cniProvider,
+		DNSProvider:      opts.dnsProvider,
+		PodCIDRBlock:     opts.podCIDRBlock,
+		ServiceCIDRBlock: opts.serviceCIDRBlock,
+	}
+	// the Calico mode only applies to the calico CNI provider
+	if opts.cniProvider == "" || opts.cniProvider == "calico" {
+		planTemplate.CalicoMode = opts.calicoMode
+	} else if opts.answered["calico-mode"] {
+		return fmt.Errorf("The Calico mode cannot be set when the CNI provider is %q", opts.cniProvider)
+	}
+	if err := install.WritePlanTemplate(planTemplate, planner); err != nil {
 		return fmt.Errorf("error planning installation: %v", err)
 	}
 	fmt.Fprintf(out, "Wrote plan file template to %q\n", planFile)
@@ -0,0 +1,25 @@
+package cli
+
+import (
+	"io"
+
+	"github.com/spf13/cobra"
+)
+
+// NewCmdPlanFile creates a new plan command, for working with plan files
+func NewCmdPlanFile(out io.Writer) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "plan",
+		Short: "Work with plan files",
+		Run: func(cmd *cobra.Command, args []string) {
+			cmd.Help()
+		},
+	}
+
+	cmd.AddCommand(NewCmdPlanSchema(out))
+	cmd.AddCommand(NewCmdPlanImport(out))
+	cmd.AddCommand(NewCmdPlanMigrate(out))
+	cmd.AddCommand(NewCmdPlanRender(out))
+
+	return cmd
+}
@@ -0,0 +1,66 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/apprenda/kismatic/pkg/install"
+	"github.com/apprenda/kismatic/pkg/util"
+	"github.com/spf13/cobra"
+)
+
+type planImportOpts struct {
+	planFilename string
+	format       string
+}
+
+// NewCmdPlanImport creates a new plan import command
+func NewCmdPlanImport(out io.Writer) *cobra.Command {
+	opts := &planImportOpts{}
+	cmd := &cobra.Command{
+		Use:   "import SOURCE_FILE",
+		Short: "Generate a plan file with the nodes of an Ansible inventory, a CSV file or a Terraform state file",
+		Long: `Generate a plan file with the nodes of an Ansible inventory, a CSV file or a Terraform state file.
+
+The roles of the nodes are read from:
+- the groups of an Ansible INI inventory, such as etcd, master, worker, ingress and storage
+- the roles column of a CSV file, with the host, ip, internal_ip, roles, labels and taints columns
+- the kismatic_roles tag or label of the resources of a Terraform state file
+
+The labels and taints of the nodes are read from the kismatic_labels and kismatic_taints host
+variables, columns or tags, such as "env=prod,zone=a" and "dedicated=db:NoSchedule".
+The rest of the plan file is generated with the same defaults as "install plan".`,
+		Example: `  # Generate a plan file from an Ansible inventory
+  kismatic plan import hosts.ini
+
+  # Generate a plan file from a Terraform state file
+  kismatic plan import --format terraform terraform.tfstate`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return cmd.Usage()
+			}
+			return doPlanImport(out, args[0], opts)
+		},
+	}
+	cmd.Flags().StringVar(&opts.format, "format", "", "format of the source file, inferred from its extension if not set (options \"ini\"|\"csv\"|\"terraform\")")
+	addPlanFileFlag(cmd.Flags(), &opts.planFilename)
+	return cmd
+}
+
+func doPlanImport(out io.Writer, source string, opts *planImportOpts) error {
+	planner := &install.FilePlanner{File: opts.planFilename}
+	if planner.PlanExists() {
+		return fmt.Errorf("plan file %q already exists", opts.planFilename)
+	}
+	plan, err := install.ImportPlan(install.PlanImportOptions{Source: source, Format: opts.format})
+	if err != nil {
+		return err
+	}
+	if err := planner.Write(plan); err != nil {
+		return fmt.Errorf("error writing plan file: %v", err)
+	}
+	util.PrettyPrintOk(out, "Imported %d etcd, %d master, %d worker, %d ingress and %d storage nodes to %q",
+		len(plan.Etcd.Nodes), len(plan.Master.Nodes), len(plan.Worker.Nodes), len(plan.Ingress.Nodes), len(plan.Storage.Nodes), opts.planFilename)
+	fmt.Fprintf(out, "Edit the plan file to further describe your cluster. Once ready, execute the \"install validate\" command to proceed.\n")
+	return nil
+}
@@ -0,0 +1,40 @@
+package cli
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/apprenda/kismatic/pkg/install"
+)
+
+func TestPlanImport(t *testing.T) {
+	dir, err := ioutil.TempDir("", "plan-import-test")
+	if err != nil {
+		t.Fatalf("error creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	source := filepath.Join(dir, "nodes.csv")
+	if err := ioutil.WriteFile(source, []byte("host,ip,roles\nnode01,10.0.0.1,\"etcd,master,worker\"\n"), 0644); err != nil {
+		t.Fatalf("error writing source: %v", err)
+	}
+	file := filepath.Join(dir, "kismatic-cluster.yaml")
+
+	out := &bytes.Buffer{}
+	if err := doPlanImport(out, source, &planImportOpts{planFilename: file}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	p, err := (&install.FilePlanner{File: file}).Read()
+	if err != nil {
+		t.Fatalf("error reading imported plan: %v", err)
+	}
+	if len(p.Etcd.Nodes) != 1 || len(p.Master.Nodes) != 1 || len(p.Worker.Nodes) != 1 || p.Worker.Nodes[0].Host != "node01" {
+		t.Errorf("expected node01 in the etcd, master and worker groups, but got %+v", p)
+	}
+
+	if err := doPlanImport(out, source, &planImportOpts{planFilename: file}); err == nil {
+		t.Errorf("expected an error importing over an existing plan file, but didn't get one")
+	}
+}
@@ -0,0 +1,74 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"github.com/apprenda/kismatic/pkg/install"
+	"github.com/apprenda/kismatic/pkg/util"
+	"github.com/spf13/cobra"
+)
+
+type planMigrateOpts struct {
+	planFilename string
+	dryRun       bool
+}
+
+// NewCmdPlanMigrate creates a new plan migrate command
+func NewCmdPlanMigrate(out io.Writer) *cobra.Command {
+	opts := &planMigrateOpts{}
+	cmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "Rewrite a plan file of a previous version of kismatic to the current version",
+		Long: `Rewrite a plan file of a previous version of kismatic to the current version.
+
+The deprecated fields of the plan file are replaced by the current ones, and the differences
+between the previous and the migrated plan file are printed. The previous plan file is kept
+as a backup, next to the plan file.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 0 {
+				return fmt.Errorf("Unexpected args: %v", args)
+			}
+			return doPlanMigrate(out, opts)
+		},
+	}
+	cmd.Flags().BoolVar(&opts.dryRun, "dry-run", false, "print the differences without changing the plan file")
+	addPlanFileFlag(cmd.Flags(), &opts.planFilename)
+	return cmd
+}
+
+func doPlanMigrate(out io.Writer, opts *planMigrateOpts) error {
+	planner := &install.FilePlanner{File: opts.planFilename}
+	if !planner.PlanExists() {
+		return planFileNotFoundErr{filename: opts.planFilename}
+	}
+	m, err := install.MigratePlanFile(opts.planFilename)
+	if err != nil {
+		return fmt.Errorf("error migrating plan file %q: %v", opts.planFilename, err)
+	}
+	if m.FromVersion == m.ToVersion {
+		util.PrettyPrintOk(out, "The plan file is at the current version %d", m.ToVersion)
+		return nil
+	}
+	fmt.Fprintf(out, "Migrating the plan file from version %d to version %d:\n", m.FromVersion, m.ToVersion)
+	for _, c := range m.Changes {
+		fmt.Fprintf(out, "- %s\n", c)
+	}
+	fmt.Fprintln(out)
+	fmt.Fprint(out, util.UnifiedDiff(opts.planFilename, opts.planFilename, string(m.Original), string(m.Migrated), 3))
+	if opts.dryRun {
+		return nil
+	}
+
+	backup := fmt.Sprintf("%s.v%d.bak", opts.planFilename, m.FromVersion)
+	if err := ioutil.WriteFile(backup, m.Original, 0644); err != nil {
+		return fmt.Errorf("error backing up plan file to %q: %v", backup, err)
+	}
+	if err := ioutil.WriteFile(opts.planFilename, m.Migrated, 0644); err != nil {
+		return fmt.Errorf("error writing plan file %q: %v", opts.planFilename, err)
+	}
+	fmt.Fprintln(out)
+	util.PrettyPrintOk(out, "Migrated the plan file, the previous version was backed up to %q", backup)
+	return nil
+}
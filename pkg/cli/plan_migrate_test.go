@@ -0,0 +1,53 @@
+package cli
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPlanMigrate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "plan-migrate-test")
+	if err != nil {
+		t.Fatalf("error creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "kismatic-cluster.yaml")
+	old := "cluster:\n  name: kubernetes\nadd_ons:\n  dashbard:\n    disable: true\n"
+	if err := ioutil.WriteFile(file, []byte(old), 0644); err != nil {
+		t.Fatalf("error writing plan file: %v", err)
+	}
+
+	out := &bytes.Buffer{}
+	if err := doPlanMigrate(out, &planMigrateOpts{planFilename: file, dryRun: true}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(out.String(), "-  dashbard:") || !strings.Contains(out.String(), "add_ons.dashbard renamed to add_ons.dashboard") {
+		t.Errorf("expected the changes and the diff to be printed, but got:\n%s", out.String())
+	}
+	if b, _ := ioutil.ReadFile(file); string(b) != old {
+		t.Errorf("expected the plan file to be unchanged in a dry run")
+	}
+
+	out.Reset()
+	if err := doPlanMigrate(out, &planMigrateOpts{planFilename: file}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	backup, err := ioutil.ReadFile(file + ".v0.bak")
+	if err != nil {
+		t.Fatalf("error reading backup: %v", err)
+	}
+	if string(backup) != old {
+		t.Errorf("expected the backup to be the previous plan file, but got:\n%s", backup)
+	}
+	migrated, err := ioutil.ReadFile(file)
+	if err != nil {
+		t.Fatalf("error reading plan file: %v", err)
+	}
+	if strings.Contains(string(migrated), "dashbard") || !strings.Contains(string(migrated), "plan_version:") {
+		t.Errorf("expected the plan file to be migrated, but got:\n%s", migrated)
+	}
+}
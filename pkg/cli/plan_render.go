@@ -0,0 +1,53 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/apprenda/kismatic/pkg/install"
+	"github.com/spf13/cobra"
+)
+
+type planRenderOpts struct {
+	planFilename string
+	overlays     []string
+	values       []string
+}
+
+// NewCmdPlanRender creates a new plan render command
+func NewCmdPlanRender(out io.Writer) *cobra.Command {
+	opts := &planRenderOpts{}
+	cmd := &cobra.Command{
+		Use:   "render",
+		Short: "Print the plan that results from merging the overlays and values into the plan file",
+		Long: `Print the plan that results from merging the overlays and values into the plan file.
+
+The overlays are merged into the plan file in the order they are given: mappings are merged
+key by key, while lists and scalars replace the ones of the plan file. The values are set
+after the overlays are merged. The rendered plan is the plan that validate, apply and step
+use when given the same overlays and values.`,
+		Example: `  # Render the plan of the production environment
+  kismatic plan render --overlay production.yaml --set cluster.name=production`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 0 {
+				return fmt.Errorf("Unexpected args: %v", args)
+			}
+			return doPlanRender(out, opts)
+		},
+	}
+	addPlanFileFlag(cmd.Flags(), &opts.planFilename)
+	addPlanOverlayFlags(cmd.Flags(), &opts.overlays, &opts.values)
+	return cmd
+}
+
+func doPlanRender(out io.Writer, opts *planRenderOpts) error {
+	planner := &install.FilePlanner{File: opts.planFilename, Overlays: opts.overlays, Values: opts.values}
+	if !planner.PlanExists() {
+		return planFileNotFoundErr{filename: opts.planFilename}
+	}
+	plan, err := planner.Read()
+	if err != nil {
+		return fmt.Errorf("error reading plan file: %v", err)
+	}
+	return install.WritePlan(out, plan)
+}
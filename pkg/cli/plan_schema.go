@@ -0,0 +1,32 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/apprenda/kismatic/pkg/install"
+	"github.com/spf13/cobra"
+)
+
+// NewCmdPlanSchema creates a new plan schema command
+func NewCmdPlanSchema(out io.Writer) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "schema",
+		Short: "Print the JSON Schema of the plan file",
+		Long: `Print the JSON Schema of the plan file, for editors to complete and lint plan files.
+
+The plan file is YAML, and most editors that support JSON Schema validate YAML files as well.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 0 {
+				return fmt.Errorf("Unexpected args: %v", args)
+			}
+			b, err := install.PlanJSONSchema()
+			if err != nil {
+				return err
+			}
+			fmt.Fprintln(out, string(b))
+			return nil
+		},
+	}
+	return cmd
+}
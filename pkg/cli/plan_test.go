@@ -2,9 +2,15 @@ package cli
 
 import (
 	"bytes"
+	"fmt"
 	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+
+	"github.com/apprenda/kismatic/pkg/install"
 )
 
 func TestPlanCmdPlanNotFound(t *testing.T) {
@@ -80,7 +86,7 @@ func TestPlanCmdPlanNotFound(t *testing.T) {
 			exists: true,
 		}
 
-		err := doPlan(test.in, out, fp, "")
+		err := doPlan(test.in, out, fp, "", defaultPlanOpts())
 
 		if err != nil && !test.shouldError {
 			t.Errorf("unexpected error running command: %v", err)
@@ -101,3 +107,82 @@ func TestPlanCmdPlanNotFound(t *testing.T) {
 		}
 	}
 }
+
+// defaultPlanOpts returns the plan options with the defaults of the flags
+func defaultPlanOpts() *planOpts {
+	return &planOpts{
+		etcdNodes:        3,
+		masterNodes:      2,
+		workerNodes:      3,
+		ingressNodes:     2,
+		cniProvider:      "calico",
+		calicoMode:       "overlay",
+		dnsProvider:      "kubedns",
+		podCIDRBlock:     "172.16.0.0/16",
+		serviceCIDRBlock: "172.20.0.0/16",
+		answered:         map[string]bool{},
+	}
+}
+
+func TestPlanCmdNonInteractive(t *testing.T) {
+	dir, err := ioutil.TempDir("", "plan-answers-test")
+	if err != nil {
+		t.Fatalf("error creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	answers := filepath.Join(dir, "answers.yaml")
+	if err := ioutil.WriteFile(answers, []byte("etcd-nodes: 1\nmaster-nodes: 1\nworker-nodes: 5\ncni-provider: weave\ndns-provider: coredns\n"), 0644); err != nil {
+		t.Fatalf("error writing answers file: %v", err)
+	}
+	planFile := filepath.Join(dir, "kismatic-cluster.yaml")
+	out := &bytes.Buffer{}
+	// the input would fail the prompts, which must not be shown
+	cmd := NewCmdPlan(strings.NewReader("badInput\n"), out, &installOpts{planFileOpts{planFilename: planFile}})
+	cmd.SetArgs([]string{"--answers-file", answers, "--worker-nodes", "2", "--pod-cidr-block", "10.100.0.0/16"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Contains(out.String(), "=>") {
+		t.Errorf("expected no prompts, but got:\n%s", out.String())
+	}
+	p, err := (&install.FilePlanner{File: planFile}).Read()
+	if err != nil {
+		t.Fatalf("error reading plan: %v", err)
+	}
+	if p.Etcd.ExpectedCount != 1 || p.Master.ExpectedCount != 1 || p.Ingress.ExpectedCount != 2 {
+		t.Errorf("expected the counts of the answers file and the defaults, but got etcd %d, master %d, ingress %d", p.Etcd.ExpectedCount, p.Master.ExpectedCount, p.Ingress.ExpectedCount)
+	}
+	if p.Worker.ExpectedCount != 2 {
+		t.Errorf("expected the flag to take precedence over the answers file, but got %d worker nodes", p.Worker.ExpectedCount)
+	}
+	if p.AddOns.CNI.Provider != "weave" || p.AddOns.DNS.Provider != "coredns" || p.Cluster.Networking.PodCIDRBlock != "10.100.0.0/16" {
+		t.Errorf("expected the providers and the pod CIDR block to be set, but got %+v %+v %+v", p.AddOns.CNI, p.AddOns.DNS, p.Cluster.Networking)
+	}
+}
+
+func TestPlanCmdInvalidAnswers(t *testing.T) {
+	dir, err := ioutil.TempDir("", "plan-answers-test")
+	if err != nil {
+		t.Fatalf("error creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	tests := []string{
+		"etcd_nodes: 3\n",
+		"etcd-nodes: three\n",
+		"plan-file: other.yaml\n",
+		"cni-provider: weave\ncalico-mode: routed\n",
+	}
+	for i, answers := range tests {
+		file := filepath.Join(dir, fmt.Sprintf("answers-%d.yaml", i))
+		if err := ioutil.WriteFile(file, []byte(answers), 0644); err != nil {
+			t.Fatalf("error writing answers file: %v", err)
+		}
+		cmd := NewCmdPlan(strings.NewReader(""), &bytes.Buffer{}, &installOpts{planFileOpts{planFilename: filepath.Join(dir, "kismatic-cluster.yaml")}})
+		cmd.SetArgs([]string{"--answers-file", file})
+		cmd.SilenceUsage = true
+		cmd.SilenceErrors = true
+		if err := cmd.Execute(); err == nil {
+			t.Errorf("expected an error with the answers %q, but didn't get one", answers)
+		}
+	}
+}
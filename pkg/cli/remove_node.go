@@ -0,0 +1,152 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/apprenda/kismatic/pkg/data"
+	"github.com/apprenda/kismatic/pkg/install"
+	"github.com/apprenda/kismatic/pkg/util"
+	"github.com/spf13/cobra"
+)
+
+type removeNodeOpts struct {
+	planFilename       string
+	overlays           []string
+	values             []string
+	generatedAssetsDir string
+	verbose            bool
+	outputFormat       string
+	force              bool
+	ignoreSafetyChecks bool
+}
+
+// NewCmdRemoveNode returns the command for removing a node from the cluster
+func NewCmdRemoveNode(in io.Reader, out io.Writer) *cobra.Command {
+	opts := &removeNodeOpts{}
+	cmd := &cobra.Command{
+		Use:   "remove-node NODE_NAME",
+		Short: "remove a node from an existing Kubernetes cluster",
+		Long: `Remove a node from an existing Kubernetes cluster.
+
+The node is drained and deleted from the cluster, removed from the etcd clusters if it is an etcd node,
+and reset. The API servers are reconfigured when an etcd or master node is removed. Once the node
+has been removed, it is also removed from the plan file.
+
+Before removing the node, safety checks are run to detect conditions that could result in data
+or availability loss, such as removing a member of an etcd cluster that has less than 4 members.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return cmd.Usage()
+			}
+			return doRemoveNode(in, out, opts, args[0])
+		},
+	}
+	cmd.Flags().StringVar(&opts.generatedAssetsDir, "generated-assets-dir", "generated", "path to the directory where assets generated during the installation process will be stored")
+	cmd.Flags().BoolVar(&opts.verbose, "verbose", false, "enable verbose logging from the installation")
+	cmd.Flags().StringVarP(&opts.outputFormat, "output", "o", "simple", "installation output format (options \"simple\"|\"raw\"|\"json\")")
+	cmd.Flags().BoolVar(&opts.force, "force", false, "do not prompt")
+	cmd.Flags().BoolVar(&opts.ignoreSafetyChecks, "ignore-safety-checks", false, "ignore safety checks and continue with the removal")
+	addPlanFileFlag(cmd.PersistentFlags(), &opts.planFilename)
+	addPlanOverlayFlags(cmd.PersistentFlags(), &opts.overlays, &opts.values)
+	return cmd
+}
+
+func doRemoveNode(in io.Reader, stdout io.Writer, opts *removeNodeOpts, host string) error {
+	out := textOutput(stdout, opts.outputFormat)
+	planner := &install.FilePlanner{File: opts.planFilename, Overlays: opts.overlays, Values: opts.values}
+	if !planner.PlanExists() {
+		return planFileNotFoundErr{filename: opts.planFilename}
+	}
+	plan, err := planner.Read()
+	if err != nil {
+		return fmt.Errorf("failed to read plan file: %v", err)
+	}
+	var node *install.Node
+	for _, n := range plan.GetUniqueNodes() {
+		if n.Host == host {
+			node = &n
+			break
+		}
+	}
+	if node == nil {
+		return fmt.Errorf("node %q was not found in the plan file", host)
+	}
+
+	// The cluster must still be valid once the node is removed
+	updatedPlan := install.RemoveNodeFromPlan(*plan, *node)
+	if _, errs := install.ValidatePlan(&updatedPlan); errs != nil {
+		util.PrintValidationErrors(out, errs)
+		return errors.New("the plan file would fail validation once the node is removed")
+	}
+	if err := validateSSHConnectivity(out, plan); err != nil {
+		return err
+	}
+
+	util.PrintHeader(out, "Validate Node Removal", '=')
+	roles := plan.GetRolesForIP(node.IP)
+	util.PrettyPrint(out, "%s %v", node.Host, roles)
+	// The storage volumes are listed from the first storage node
+	var glusterClient data.GlusterClient
+	if util.Contains("storage", roles) {
+		client, err := plan.GetSSHClient("storage")
+		if err != nil {
+			return fmt.Errorf("error getting SSH client: %v", err)
+		}
+		defer client.Close()
+		glusterClient = data.RemoteGlusterCLI{SSHClient: client}
+	}
+	if errs := install.DetectNodeRemovalSafety(*plan, *node, glusterClient); len(errs) != 0 {
+		if opts.ignoreSafetyChecks {
+			util.PrintWarn(out)
+		} else {
+			util.PrintError(out)
+		}
+		fmt.Fprintln(out)
+		for _, err := range errs {
+			fmt.Fprintln(out, "-", err.Error())
+		}
+		if !opts.ignoreSafetyChecks {
+			return errors.New("Unable to remove the node due to the unsafe conditions detected.")
+		}
+		util.PrettyPrintWarn(out, "\nIgnoring safety checks and continuing with the removal")
+	} else {
+		util.PrintOkln(out)
+	}
+
+	if !opts.force {
+		ans, err := util.PromptForString(in, out, fmt.Sprintf("Are you sure you want to remove node %q? All data on the node will be lost", node.Host), "N", []string{"N", "y"})
+		if err != nil {
+			return fmt.Errorf("error getting user response: %v", err)
+		}
+		if strings.ToLower(ans) != "y" {
+			return nil
+		}
+	}
+
+	execOpts := install.ExecutorOptions{
+		GeneratedAssetsDirectory: opts.generatedAssetsDir,
+		OutputFormat:             opts.outputFormat,
+		Verbose:                  opts.verbose,
+	}
+	executor, err := install.NewExecutor(stdout, os.Stderr, execOpts)
+	if err != nil {
+		return err
+	}
+	removedPlan, err := executor.RemoveNode(plan, *node)
+	if err != nil {
+		return err
+	}
+	removeNode := func(p install.Plan) install.Plan { return install.RemoveNodeFromPlan(p, *node) }
+	if err := updatePlanFile(planner, removedPlan, removeNode); err != nil {
+		return fmt.Errorf("error updating plan file to remove the node: %v", err)
+	}
+	if err := install.UpdateAppliedPlan(opts.generatedAssetsDir, removeNode); err != nil {
+		return fmt.Errorf("error updating the applied plan to remove the node: %v", err)
+	}
+	util.PrintColor(out, util.Green, "\nThe node was removed successfully!\n")
+	return nil
+}
@@ -13,6 +13,8 @@ import (
 
 type resetOpts struct {
 	planFilename       string
+	overlays           []string
+	values             []string
 	generatedAssetsDir string
 	verbose            bool
 	outputFormat       string
@@ -52,12 +54,13 @@ func NewCmdReset(in io.Reader, out io.Writer) *cobra.Command {
 	cmd.Flags().BoolVar(&opts.removeAssets, "remove-assets", false, "remove generated-assets-dir")
 
 	addPlanFileFlag(cmd.PersistentFlags(), &opts.planFilename)
+	addPlanOverlayFlags(cmd.PersistentFlags(), &opts.overlays, &opts.values)
 
 	return cmd
 }
 
 func doReset(out io.Writer, opts *resetOpts) error {
-	planner := &install.FilePlanner{File: opts.planFilename}
+	planner := &install.FilePlanner{File: opts.planFilename, Overlays: opts.overlays, Values: opts.values}
 	if !planner.PlanExists() {
 		return planFileNotFoundErr{filename: opts.planFilename}
 	}
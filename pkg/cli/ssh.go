@@ -3,7 +3,6 @@ package cli
 import (
 	"fmt"
 	"io"
-	"strings"
 
 	"github.com/apprenda/kismatic/pkg/ssh"
 
@@ -14,6 +13,8 @@ import (
 
 type sshOpts struct {
 	planFilename string
+	overlays     []string
+	values       []string
 	host         string
 	pty          bool
 	arguments    []string
@@ -42,7 +43,7 @@ HOST must be one of the following:
 
 			opts.host = args[0]
 
-			planner := &install.FilePlanner{File: opts.planFilename}
+			planner := &install.FilePlanner{File: opts.planFilename, Overlays: opts.overlays, Values: opts.values}
 			// Check if plan file exists
 			if !planner.PlanExists() {
 				return planFileNotFoundErr{filename: opts.planFilename}
@@ -50,7 +51,10 @@ HOST must be one of the following:
 
 			err := doSSH(out, planner, opts)
 			// 130 = terminated by Control-C, so not an actual error
-			if err != nil && !strings.Contains(err.Error(), "130") {
+			if status, ok := ssh.ExitStatus(err); ok && status == 130 {
+				return nil
+			}
+			if err != nil {
 				return fmt.Errorf("SSH error %q: %v", opts.host, err)
 			}
 			return nil
@@ -58,6 +62,7 @@ HOST must be one of the following:
 	}
 
 	cmd.Flags().StringVarP(&opts.planFilename, "plan-file", "f", "kismatic-cluster.yaml", "path to the installation plan file")
+	addPlanOverlayFlags(cmd.Flags(), &opts.overlays, &opts.values)
 	cmd.Flags().BoolVarP(&opts.pty, "pty", "t", false, "force PTY \"-t\" flag on the SSH connection")
 
 	return cmd
@@ -82,14 +87,10 @@ func doSSH(out io.Writer, planner install.Planner, opts *sshOpts) error {
 		return fmt.Errorf("cannot validate SSH connection to node %q", opts.host)
 	}
 
-	client, err := ssh.NewClient(con.Node.IP, con.SSHConfig.Port, con.SSHConfig.User, con.SSHConfig.Key)
+	client, err := plan.GetSSHClient(opts.host)
 	if err != nil {
-		return fmt.Errorf("error creating SSH client: %v", err)
-	}
-
-	if err = client.Shell(opts.pty, opts.arguments...); err != nil {
-		return fmt.Errorf("error running command: %v", err)
+		return err
 	}
-
-	return err
+	defer client.Close()
+	return client.Shell(opts.pty, opts.arguments...)
 }
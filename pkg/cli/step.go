@@ -49,7 +49,7 @@ func NewCmdStep(out io.Writer, opts *installOpts) *cobra.Command {
 			}
 			stepCmd.task = args[0]
 			stepCmd.planFile = opts.planFilename
-			stepCmd.planner = &install.FilePlanner{File: stepCmd.planFile}
+			stepCmd.planner = opts.planner()
 			stepCmd.executor = executor
 			return stepCmd.run()
 		},
@@ -59,6 +59,7 @@ func NewCmdStep(out io.Writer, opts *installOpts) *cobra.Command {
 	cmd.Flags().BoolVar(&stepCmd.restartServices, "restart-services", false, "force restart cluster services (Use with care)")
 	cmd.Flags().BoolVar(&stepCmd.verbose, "verbose", false, "enable verbose logging from the installation")
 	cmd.Flags().StringVarP(&stepCmd.outputFormat, "output", "o", "simple", "installation output format (options \"simple\"|\"raw\")")
+	addPlanOverlayFlags(cmd.Flags(), &opts.overlays, &opts.values)
 	return cmd
 }
 
@@ -6,6 +6,7 @@ import (
 	"io"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/apprenda/kismatic/pkg/data"
 	"github.com/apprenda/kismatic/pkg/install"
@@ -21,10 +22,14 @@ type upgradeOpts struct {
 	ignoreSafetyChecks bool
 	online             bool
 	planFile           string
+	overlays           []string
+	values             []string
 	restartServices    bool
 	partialAllowed     bool
 	maxParallelWorkers int
 	dryRun             bool
+	concurrency        int
+	nodeTimeout        time.Duration
 }
 
 // NewCmdUpgrade returns the upgrade command
@@ -52,12 +57,14 @@ Nodes in the cluster are upgraded in the following order:
 
 	cmd.PersistentFlags().StringVar(&opts.generatedAssetsDir, "generated-assets-dir", "generated", "path to the directory where assets generated during the installation process will be stored")
 	cmd.PersistentFlags().BoolVar(&opts.verbose, "verbose", false, "enable verbose logging from the installation")
-	cmd.PersistentFlags().StringVarP(&opts.outputFormat, "output", "o", "simple", "installation output format (options \"simple\"|\"raw\")")
+	cmd.PersistentFlags().StringVarP(&opts.outputFormat, "output", "o", "simple", "installation output format (options \"simple\"|\"raw\"|\"json\")")
 	cmd.PersistentFlags().BoolVar(&opts.skipPreflight, "skip-preflight", false, "skip upgrade pre-flight checks")
 	cmd.PersistentFlags().BoolVar(&opts.restartServices, "restart-services", false, "force restart cluster services (Use with care)")
 	cmd.PersistentFlags().BoolVar(&opts.partialAllowed, "partial-ok", false, "allow the upgrade of ready nodes, and skip nodes that have been deemed unready for upgrade")
 	cmd.PersistentFlags().BoolVar(&opts.dryRun, "dry-run", false, "simulate the upgrade, but don't actually upgrade the cluster")
 	addPlanFileFlag(cmd.PersistentFlags(), &opts.planFile)
+	addPlanOverlayFlags(cmd.PersistentFlags(), &opts.overlays, &opts.values)
+	addNodeQueryFlags(cmd.PersistentFlags(), &opts.concurrency, &opts.nodeTimeout)
 
 	// Subcommands
 	cmd.AddCommand(NewCmdUpgradeOffline(in, out, &opts))
@@ -111,26 +118,27 @@ before any changes are applied.
 	return &cmd
 }
 
-func doUpgrade(in io.Reader, out io.Writer, opts *upgradeOpts) error {
+func doUpgrade(in io.Reader, stdout io.Writer, opts *upgradeOpts) error {
+	out := textOutput(stdout, opts.outputFormat)
 	if opts.maxParallelWorkers < 1 {
 		return fmt.Errorf("max-parallel-workers must be greater or equal to 1, got: %d", opts.maxParallelWorkers)
 	}
 
 	planFile := opts.planFile
-	planner := install.FilePlanner{File: planFile}
+	planner := install.FilePlanner{File: planFile, Overlays: opts.overlays, Values: opts.values}
 	executorOpts := install.ExecutorOptions{
 		GeneratedAssetsDirectory: opts.generatedAssetsDir,
 		OutputFormat:             opts.outputFormat,
 		Verbose:                  opts.verbose,
 		DryRun:                   opts.dryRun,
 	}
-	executor, err := install.NewExecutor(out, os.Stderr, executorOpts)
+	executor, err := install.NewExecutor(stdout, os.Stderr, executorOpts)
 	if err != nil {
 		return err
 	}
 	preflightExecOpts := executorOpts
 	preflightExecOpts.DryRun = false // We always want to run preflight, even if doing a dry-run
-	preflightExec, err := install.NewPreFlightExecutor(out, os.Stderr, preflightExecOpts)
+	preflightExec, err := install.NewPreFlightExecutor(stdout, os.Stderr, preflightExecOpts)
 	if err != nil {
 		return err
 	}
@@ -175,8 +183,8 @@ func doUpgrade(in io.Reader, out io.Writer, opts *upgradeOpts) error {
 	}
 
 	// Get the cluster and node versions
-	cv, err := install.ListVersions(plan)
-	if err != nil {
+	cv := install.ListVersions(plan, install.NodeQueryOptions{Concurrency: opts.concurrency, Timeout: opts.nodeTimeout})
+	if err = cv.FailedNodesErr(); err != nil {
 		return fmt.Errorf("error listing cluster versions: %v", err)
 	}
 
@@ -254,6 +262,7 @@ func upgradeNodes(in io.Reader, out io.Writer, plan install.Plan, opts upgradeOp
 		if err != nil {
 			return fmt.Errorf("error getting SSH client: %v", err)
 		}
+		defer client.Close()
 		kubeClient := data.RemoteKubectl{SSHClient: client}
 		for _, node := range nodesNeedUpgrade {
 			util.PrettyPrint(out, "%s %v", node.Node.Host, node.Roles)
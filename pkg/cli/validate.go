@@ -31,20 +31,21 @@ func NewCmdValidate(out io.Writer, installOpts *installOpts) *cobra.Command {
 			if len(args) != 0 {
 				return fmt.Errorf("Unexpected args: %v", args)
 			}
-			planner := &install.FilePlanner{File: installOpts.planFilename}
 			opts.planFile = installOpts.planFilename
-			return doValidate(out, planner, opts)
+			return doValidate(out, installOpts.planner(), opts)
 		},
 	}
 	cmd.Flags().StringSliceVar(&opts.limit, "limit", []string{}, "comma-separated list of hostnames to limit the execution to a subset of nodes")
 	cmd.Flags().StringVar(&opts.generatedAssetsDir, "generated-assets-dir", "generated", "path to the directory where assets generated during the installation process will be stored")
 	cmd.Flags().BoolVar(&opts.verbose, "verbose", false, "enable verbose logging from the installation")
-	cmd.Flags().StringVarP(&opts.outputFormat, "output", "o", "simple", "installation output format (options simple|raw)")
+	cmd.Flags().StringVarP(&opts.outputFormat, "output", "o", "simple", "installation output format (options simple|raw|json)")
 	cmd.Flags().BoolVar(&opts.skipPreFlight, "skip-preflight", false, "skip pre-flight checks")
+	addPlanOverlayFlags(cmd.Flags(), &installOpts.overlays, &installOpts.values)
 	return cmd
 }
 
-func doValidate(out io.Writer, planner install.Planner, opts *validateOpts) error {
+func doValidate(stdout io.Writer, planner install.Planner, opts *validateOpts) error {
+	out := textOutput(stdout, opts.outputFormat)
 	util.PrintHeader(out, "Validating", '=')
 	// Check if plan file exists
 	if !planner.PlanExists() {
@@ -75,7 +76,7 @@ func doValidate(out io.Writer, planner install.Planner, opts *validateOpts) erro
 	}
 
 	// get a new pki
-	pki, err := newPKI(out, opts)
+	pki, err := newPKI(out, opts, plan)
 	if err != nil {
 		return err
 	}
@@ -95,7 +96,7 @@ func doValidate(out io.Writer, planner install.Planner, opts *validateOpts) erro
 		OutputFormat: opts.outputFormat,
 		Verbose:      opts.verbose,
 	}
-	e, err := install.NewPreFlightExecutor(out, os.Stderr, options)
+	e, err := install.NewPreFlightExecutor(stdout, os.Stderr, options)
 	if err != nil {
 		return err
 	}
@@ -103,18 +104,13 @@ func doValidate(out io.Writer, planner install.Planner, opts *validateOpts) erro
 }
 
 // TODO this should really not be here
-func newPKI(stdout io.Writer, options *validateOpts) (*install.LocalPKI, error) {
+func newPKI(stdout io.Writer, options *validateOpts, plan *install.Plan) (install.PKI, error) {
 	ansibleDir := "ansible"
 	if options.generatedAssetsDir == "" {
 		return nil, fmt.Errorf("GeneratedAssetsDirectory option cannot be empty")
 	}
 	certsDir := filepath.Join(options.generatedAssetsDir, "keys")
-	pki := &install.LocalPKI{
-		CACsr: filepath.Join(ansibleDir, "playbooks", "tls", "ca-csr.json"),
-		GeneratedCertsDirectory: certsDir,
-		Log: stdout,
-	}
-	return pki, nil
+	return install.NewPKI(plan, filepath.Join(ansibleDir, "playbooks", "tls", "ca-csr.json"), certsDir, stdout)
 }
 
 func validatePlan(out io.Writer, plan *install.Plan) error {
@@ -25,7 +25,7 @@ type volumeAddOptions struct {
 }
 
 // NewCmdVolumeAdd returns the command for adding storage volumes
-func NewCmdVolumeAdd(out io.Writer, planFile *string) *cobra.Command {
+func NewCmdVolumeAdd(out io.Writer, planOpts *planFileOpts) *cobra.Command {
 	opts := volumeAddOptions{}
 	cmd := &cobra.Command{
 		Use:   "add size_in_gigabytes [volume-name]",
@@ -34,7 +34,7 @@ func NewCmdVolumeAdd(out io.Writer, planFile *string) *cobra.Command {
 
 This function requires a target cluster that has storage nodes.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return doVolumeAdd(out, opts, *planFile, args)
+			return doVolumeAdd(out, opts, planOpts.planner(), args)
 		},
 		Example: `  # Create a 10GB distributed and replicated volume named "storage01"
   # with StorageClass "durable". Grant access to the volume to any client with an IP
@@ -54,7 +54,7 @@ This function requires a target cluster that has storage nodes.`,
 	return cmd
 }
 
-func doVolumeAdd(out io.Writer, opts volumeAddOptions, planFile string, args []string) error {
+func doVolumeAdd(out io.Writer, opts volumeAddOptions, planner *install.FilePlanner, args []string) error {
 	// get volume name and size from arguments
 	var volumeName string
 	var volumeSizeStrGB string
@@ -76,9 +76,8 @@ func doVolumeAdd(out io.Writer, opts volumeAddOptions, planFile string, args []s
 	}
 
 	// setup ansible for execution
-	planner := &install.FilePlanner{File: planFile}
 	if !planner.PlanExists() {
-		return planFileNotFoundErr{filename: planFile}
+		return planFileNotFoundErr{filename: planner.File}
 	}
 	execOpts := install.ExecutorOptions{
 		OutputFormat: opts.outputFormat,
@@ -99,7 +98,7 @@ func doVolumeAdd(out io.Writer, opts volumeAddOptions, planFile string, args []s
 	vopts := &validateOpts{
 		outputFormat:       opts.outputFormat,
 		verbose:            opts.verbose,
-		planFile:           planFile,
+		planFile:           planner.File,
 		skipPreFlight:      true,
 		generatedAssetsDir: opts.generatedAssetsDir,
 	}
@@ -19,7 +19,7 @@ type volumeDeleteOptions struct {
 }
 
 // NewCmdVolumeDelete returns the command for deleting storage volumes
-func NewCmdVolumeDelete(in io.Reader, out io.Writer, planFile *string) *cobra.Command {
+func NewCmdVolumeDelete(in io.Reader, out io.Writer, planOpts *planFileOpts) *cobra.Command {
 	opts := volumeDeleteOptions{}
 	cmd := &cobra.Command{
 		Use:   "delete volume-name",
@@ -37,7 +37,7 @@ WARNING all data in the volume will be lost.`,
 					os.Exit(0)
 				}
 			}
-			return doVolumeDelete(out, opts, *planFile, args)
+			return doVolumeDelete(out, opts, planOpts.planner(), args)
 		},
 	}
 	cmd.Flags().BoolVar(&opts.verbose, "verbose", false, "enable verbose logging")
@@ -47,7 +47,7 @@ WARNING all data in the volume will be lost.`,
 	return cmd
 }
 
-func doVolumeDelete(out io.Writer, opts volumeDeleteOptions, planFile string, args []string) error {
+func doVolumeDelete(out io.Writer, opts volumeDeleteOptions, planner *install.FilePlanner, args []string) error {
 	// get volume name and size from arguments
 	var volumeName string
 	switch len(args) {
@@ -58,9 +58,8 @@ func doVolumeDelete(out io.Writer, opts volumeDeleteOptions, planFile string, ar
 	}
 
 	// setup ansible for execution
-	planner := &install.FilePlanner{File: planFile}
 	if !planner.PlanExists() {
-		return planFileNotFoundErr{filename: planFile}
+		return planFileNotFoundErr{filename: planner.File}
 	}
 	execOpts := install.ExecutorOptions{
 		OutputFormat: opts.outputFormat,
@@ -81,7 +80,7 @@ func doVolumeDelete(out io.Writer, opts volumeDeleteOptions, planFile string, ar
 	vopts := &validateOpts{
 		outputFormat:       opts.outputFormat,
 		verbose:            opts.verbose,
-		planFile:           planFile,
+		planFile:           planner.File,
 		skipPreFlight:      true,
 		generatedAssetsDir: opts.generatedAssetsDir,
 	}
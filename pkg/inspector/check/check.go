@@ -8,6 +8,14 @@ type Check interface {
 	Check() (bool, error)
 }
 
+// An InUseCheck is implemented by the checks that can succeed because the
+// resource they verify, such as a port, is already held by its expected owner.
+// In that case, the checks that depend on the resource being free are skipped.
+type InUseCheck interface {
+	Check
+	InUse() bool
+}
+
 // A ClosableCheck implements a long-running check workflow that requires closing
 type ClosableCheck interface {
 	Check
@@ -0,0 +1,105 @@
+package check
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// TimeEndpoint is the endpoint of the inspector server that reports the
+// time of the node
+const TimeEndpoint = "/time"
+
+// NodeTime is the response of the time endpoint
+type NodeTime struct {
+	Time time.Time
+}
+
+// unreachableNodeErr is returned when the inspector of a node cannot be reached
+type unreachableNodeErr struct {
+	node string
+	err  error
+}
+
+func (e unreachableNodeErr) Error() string {
+	return fmt.Sprintf("error getting the time of %q: %v", e.node, e.err)
+}
+
+// ClockSkewCheck verifies that the clock of a remote node is in sync with
+// the clock of the node running the check, and with the clocks of its peers.
+type ClockSkewCheck struct {
+	// TargetNode is the ip:port of the inspector running on the remote node
+	TargetNode string
+	// PeerNodes are the ip:port of the inspectors running on the other
+	// nodes of the cluster
+	PeerNodes []string
+	// MaximumSkew is the maximum difference allowed between two clocks
+	MaximumSkew time.Duration
+	// Timeout is the maximum amount of time the check will wait for
+	// the time of a node
+	Timeout time.Duration
+}
+
+// Check returns true if the clock of the target node is within the maximum
+// skew of the local clock and of the clocks of the peer nodes. Otherwise,
+// returns false and an error message. Peers that are not running an inspector,
+// such as the existing nodes when a node is added, are skipped.
+func (c ClockSkewCheck) Check() (bool, error) {
+	target, err := c.clockOffset(c.TargetNode)
+	if err != nil {
+		return false, err
+	}
+	if skew := absDuration(target); skew > c.MaximumSkew {
+		return false, fmt.Errorf("the clock of %q is skewed by %v from the clock of this node, the maximum allowed is %v", c.TargetNode, skew, c.MaximumSkew)
+	}
+	for _, peer := range c.PeerNodes {
+		if peer == c.TargetNode {
+			continue
+		}
+		offset, err := c.clockOffset(peer)
+		if _, ok := err.(unreachableNodeErr); ok {
+			continue
+		}
+		if err != nil {
+			return false, err
+		}
+		if skew := absDuration(target - offset); skew > c.MaximumSkew {
+			return false, fmt.Errorf("the clock of %q is skewed by %v from the clock of %q, the maximum allowed is %v", c.TargetNode, skew, peer, c.MaximumSkew)
+		}
+	}
+	return true, nil
+}
+
+// clockOffset returns the offset of the clock of the node from the local
+// clock, assuming that the node reported its time halfway through the request
+func (c ClockSkewCheck) clockOffset(node string) (time.Duration, error) {
+	timeout := c.Timeout
+	if timeout == 0 {
+		timeout = 5 * time.Second
+	}
+	client := http.Client{Timeout: timeout}
+	start := time.Now()
+	resp, err := client.Get(fmt.Sprintf("http://%s%s", node, TimeEndpoint))
+	if err != nil {
+		return 0, unreachableNodeErr{node: node, err: err}
+	}
+	defer resp.Body.Close()
+	end := time.Now()
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("error getting the time of %q: server responded with non-successful status: %q", node, resp.Status)
+	}
+	nodeTime := NodeTime{}
+	if err := json.NewDecoder(resp.Body).Decode(&nodeTime); err != nil {
+		return 0, fmt.Errorf("error decoding the time of %q: %v", node, err)
+	}
+	local := start.Add(end.Sub(start) / 2)
+	return nodeTime.Time.Sub(local), nil
+}
+
+func absDuration(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
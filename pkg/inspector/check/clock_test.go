@@ -0,0 +1,93 @@
+package check
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// startTimeServer starts a time endpoint that reports the local time
+// shifted by the given offset
+func startTimeServer(offset time.Duration) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc(TimeEndpoint, func(w http.ResponseWriter, req *http.Request) {
+		json.NewEncoder(w).Encode(NodeTime{Time: time.Now().Add(offset)})
+	})
+	return httptest.NewServer(mux)
+}
+
+func serverNode(s *httptest.Server) string {
+	return strings.TrimPrefix(s.URL, "http://")
+}
+
+func TestClockSkewCheck(t *testing.T) {
+	inSyncServer := startTimeServer(0)
+	defer inSyncServer.Close()
+	aheadServer := startTimeServer(time.Minute)
+	defer aheadServer.Close()
+	behindServer := startTimeServer(-time.Minute)
+	defer behindServer.Close()
+	inSync, ahead, behind := serverNode(inSyncServer), serverNode(aheadServer), serverNode(behindServer)
+	tests := []struct {
+		name     string
+		target   string
+		peers    []string
+		expected bool
+	}{
+		{
+			name:     "clocks in sync",
+			target:   inSync,
+			peers:    []string{inSync},
+			expected: true,
+		},
+		{
+			name:     "target ahead of the client",
+			target:   ahead,
+			expected: false,
+		},
+		{
+			name:     "peer ahead of the target",
+			target:   inSync,
+			peers:    []string{inSync, ahead},
+			expected: false,
+		},
+		{
+			name:     "peer behind the target",
+			target:   inSync,
+			peers:    []string{behind},
+			expected: false,
+		},
+		{
+			name:     "peer without an inspector",
+			target:   inSync,
+			peers:    []string{"127.0.0.1:1", inSync},
+			expected: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := ClockSkewCheck{TargetNode: test.target, PeerNodes: test.peers, MaximumSkew: 2 * time.Second}
+			ok, err := c.Check()
+			if ok != test.expected {
+				t.Errorf("expected %v, but got %v. Error was: %v", test.expected, ok, err)
+			}
+			if !ok && err == nil {
+				t.Errorf("expected an error describing the skew")
+			}
+		})
+	}
+}
+
+func TestClockSkewCheckUnreachableNode(t *testing.T) {
+	c := ClockSkewCheck{TargetNode: "127.0.0.1:1", MaximumSkew: 2 * time.Second, Timeout: time.Second}
+	ok, err := c.Check()
+	if ok {
+		t.Errorf("expected the check to fail")
+	}
+	if err == nil {
+		t.Errorf("expected an error, but didn't get one")
+	}
+}
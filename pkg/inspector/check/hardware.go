@@ -0,0 +1,107 @@
+package check
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// CPUCountCheck checks the number of CPUs of the node
+type CPUCountCheck struct {
+	MinimumCPUs int
+	// ProcPath is the mount point of procfs, defaults to /proc
+	ProcPath string
+}
+
+// Check returns true if /proc/cpuinfo lists at least the minimum number of
+// processors. Otherwise returns false.
+func (c CPUCountCheck) Check() (bool, error) {
+	cpus := 0
+	err := scanProcFile(c.ProcPath, "cpuinfo", func(line string) {
+		parts := strings.SplitN(line, ":", 2)
+		if len(parts) == 2 && strings.TrimSpace(parts[0]) == "processor" {
+			cpus++
+		}
+	})
+	if err != nil {
+		return false, err
+	}
+	return cpus >= c.MinimumCPUs, nil
+}
+
+// MemoryCheck checks the total memory of the node
+type MemoryCheck struct {
+	MinimumBytes uint64
+	// ProcPath is the mount point of procfs, defaults to /proc
+	ProcPath string
+}
+
+// Check returns true if the MemTotal of /proc/meminfo is at least the
+// minimum. Otherwise returns false.
+func (c MemoryCheck) Check() (bool, error) {
+	var total string
+	err := scanProcFile(c.ProcPath, "meminfo", func(line string) {
+		if strings.HasPrefix(line, "MemTotal:") {
+			total = strings.TrimSpace(strings.TrimPrefix(line, "MemTotal:"))
+		}
+	})
+	if err != nil {
+		return false, err
+	}
+	// MemTotal is reported in kB, such as "MemTotal:  2048000 kB"
+	fields := strings.Fields(total)
+	if len(fields) != 2 || fields[1] != "kB" {
+		return false, fmt.Errorf("unexpected MemTotal %q in meminfo", total)
+	}
+	kb, err := strconv.ParseUint(fields[0], 10, 64)
+	if err != nil {
+		return false, fmt.Errorf("unexpected MemTotal %q in meminfo: %v", total, err)
+	}
+	return kb*1024 >= c.MinimumBytes, nil
+}
+
+// SwapDisabledCheck checks that the node does not have any swap memory
+type SwapDisabledCheck struct {
+	// ProcPath is the mount point of procfs, defaults to /proc
+	ProcPath string
+}
+
+// Check returns true if /proc/swaps does not list any swap area. Otherwise
+// returns false.
+func (c SwapDisabledCheck) Check() (bool, error) {
+	lines := 0
+	err := scanProcFile(c.ProcPath, "swaps", func(line string) {
+		if strings.TrimSpace(line) != "" {
+			lines++
+		}
+	})
+	if err != nil {
+		return false, err
+	}
+	// the first line is the header
+	return lines <= 1, nil
+}
+
+// scanProcFile calls the function with every line of the file of procfs
+func scanProcFile(procPath, name string, line func(string)) error {
+	if procPath == "" {
+		procPath = defaultProcPath
+	}
+	file := filepath.Join(procPath, name)
+	f, err := os.Open(file)
+	if err != nil {
+		return fmt.Errorf("error reading %s: %v", file, err)
+	}
+	defer f.Close()
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		line(s.Text())
+	}
+	if err := s.Err(); err != nil {
+		return fmt.Errorf("error reading %s: %v", file, err)
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package check
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestHardwareChecks(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hardware-check")
+	if err != nil {
+		t.Fatalf("error creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	writeFixture(t, dir, "cpuinfo", `processor	: 0
+vendor_id	: GenuineIntel
+model name	: Intel(R) Xeon(R) CPU E5-2676 v3 @ 2.40GHz
+
+processor	: 1
+vendor_id	: GenuineIntel
+model name	: Intel(R) Xeon(R) CPU E5-2676 v3 @ 2.40GHz
+`)
+	writeFixture(t, dir, "meminfo", `MemTotal:        2046652 kB
+MemFree:          148744 kB
+MemAvailable:    1421740 kB
+`)
+	writeFixture(t, dir, "swaps", "Filename\t\t\t\tType\t\tSize\tUsed\tPriority\n")
+
+	tests := []struct {
+		name     string
+		check    Check
+		expected bool
+	}{
+		{"2 CPUs", CPUCountCheck{MinimumCPUs: 2, ProcPath: dir}, true},
+		{"4 CPUs", CPUCountCheck{MinimumCPUs: 4, ProcPath: dir}, false},
+		{"2 GB of memory", MemoryCheck{MinimumBytes: 2000000000, ProcPath: dir}, true},
+		{"4 GB of memory", MemoryCheck{MinimumBytes: 4000000000, ProcPath: dir}, false},
+		{"swap disabled", SwapDisabledCheck{ProcPath: dir}, true},
+	}
+	for _, test := range tests {
+		ok, err := test.check.Check()
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", test.name, err)
+		}
+		if ok != test.expected {
+			t.Errorf("%s: expected %t, but got %t", test.name, test.expected, ok)
+		}
+	}
+
+	writeFixture(t, dir, "swaps", "Filename\t\t\t\tType\t\tSize\tUsed\tPriority\n/swapfile                               file\t\t2097148\t0\t-2\n")
+	if ok, err := (SwapDisabledCheck{ProcPath: dir}).Check(); ok || err != nil {
+		t.Errorf("expected the check to fail with a swap file, but got %t, %v", ok, err)
+	}
+
+	writeFixture(t, dir, "meminfo", "MemTotal: lots\n")
+	if _, err := (MemoryCheck{MinimumBytes: 1, ProcPath: dir}).Check(); err == nil {
+		t.Errorf("expected an error with an invalid meminfo, but didn't get one")
+	}
+}
@@ -0,0 +1,123 @@
+package check
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ProxySettings are the proxies used by the node to reach HTTP endpoints
+type ProxySettings struct {
+	// HTTPProxy is the proxy used for http endpoints
+	HTTPProxy string
+	// HTTPSProxy is the proxy used for https endpoints
+	HTTPSProxy string
+	// NoProxy is a comma-separated list of the hosts, domains and CIDR blocks
+	// that are reached without going through the proxies
+	NoProxy string
+}
+
+// proxyForURL returns the proxy to use for the URL, or nil if the URL
+// should be reached directly
+func (p ProxySettings) proxyForURL(u *url.URL) (*url.URL, error) {
+	proxy := p.HTTPProxy
+	if u.Scheme == "https" {
+		proxy = p.HTTPSProxy
+	}
+	if proxy == "" || p.bypassProxy(u.Hostname()) {
+		return nil, nil
+	}
+	if !strings.Contains(proxy, "://") {
+		proxy = "http://" + proxy
+	}
+	proxyURL, err := url.Parse(proxy)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy address %q: %v", proxy, err)
+	}
+	return proxyURL, nil
+}
+
+// bypassProxy returns true if the host matches an entry of NoProxy
+func (p ProxySettings) bypassProxy(host string) bool {
+	host = strings.ToLower(host)
+	ip := net.ParseIP(host)
+	for _, entry := range strings.Split(p.NoProxy, ",") {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		if entry == "" {
+			continue
+		}
+		if entry == "*" {
+			return true
+		}
+		if _, cidr, err := net.ParseCIDR(entry); err == nil {
+			if ip != nil && cidr.Contains(ip) {
+				return true
+			}
+			continue
+		}
+		if h, _, err := net.SplitHostPort(entry); err == nil {
+			entry = h
+		}
+		if host == entry || strings.HasSuffix(host, "."+strings.TrimPrefix(entry, ".")) {
+			return true
+		}
+	}
+	return false
+}
+
+// HTTPEndpointCheck verifies that an HTTP endpoint can be reached from the
+// node, through the proxies of the node
+type HTTPEndpointCheck struct {
+	// URL of the endpoint
+	URL string
+	// CAFile is the CA used to verify the certificate of the endpoint. If
+	// empty, the certificate is verified against the system CAs.
+	CAFile string
+	// Proxy are the proxies used to reach the endpoint
+	Proxy ProxySettings
+	// Timeout is the maximum amount of time the check will wait
+	// for a response before bailing out
+	Timeout time.Duration
+}
+
+// Check returns true if the endpoint returned a response, regardless of its
+// status. Otherwise, returns false and an error message
+func (c HTTPEndpointCheck) Check() (bool, error) {
+	timeout := c.Timeout
+	if timeout == 0 {
+		timeout = 10 * time.Second
+	}
+	tlsConfig := &tls.Config{}
+	if c.CAFile != "" {
+		ca, err := ioutil.ReadFile(c.CAFile)
+		if err != nil {
+			return false, fmt.Errorf("error reading CA file %q: %v", c.CAFile, err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(ca) {
+			return false, fmt.Errorf("no certificates found in CA file %q", c.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	client := http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			Proxy: func(req *http.Request) (*url.URL, error) {
+				return c.Proxy.proxyForURL(req.URL)
+			},
+			TLSClientConfig: tlsConfig,
+		},
+	}
+	resp, err := client.Get(c.URL)
+	if err != nil {
+		return false, fmt.Errorf("%s is unreachable. Error was: %v", c.URL, err)
+	}
+	resp.Body.Close()
+	return true, nil
+}
@@ -0,0 +1,118 @@
+package check
+
+import (
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestProxyForURL(t *testing.T) {
+	proxy := ProxySettings{
+		HTTPProxy:  "proxy.local:3128",
+		HTTPSProxy: "https://secure-proxy.local:3129",
+		NoProxy:    "10.0.0.1,192.168.0.0/16,.internal.local,registry.local:8443",
+	}
+	tests := []struct {
+		url           string
+		expectedProxy string
+	}{
+		{url: "http://packages.cloud.google.com/apt/", expectedProxy: "http://proxy.local:3128"},
+		{url: "https://packages.cloud.google.com/apt/", expectedProxy: "https://secure-proxy.local:3129"},
+		{url: "https://10.0.0.1:8443/v2/", expectedProxy: ""},
+		{url: "https://10.0.0.2:8443/v2/", expectedProxy: "https://secure-proxy.local:3129"},
+		{url: "https://192.168.10.5/v2/", expectedProxy: ""},
+		{url: "https://mirror.internal.local/yum", expectedProxy: ""},
+		{url: "https://registry.local:8443/v2/", expectedProxy: ""},
+		{url: "https://notregistry.local/v2/", expectedProxy: "https://secure-proxy.local:3129"},
+	}
+	for _, test := range tests {
+		u, _ := url.Parse(test.url)
+		got, err := proxy.proxyForURL(u)
+		if err != nil {
+			t.Errorf("unexpected error getting the proxy of %q: %v", test.url, err)
+			continue
+		}
+		gotProxy := ""
+		if got != nil {
+			gotProxy = got.String()
+		}
+		if gotProxy != test.expectedProxy {
+			t.Errorf("expected the proxy of %q to be %q, but got %q", test.url, test.expectedProxy, gotProxy)
+		}
+	}
+}
+
+func TestHTTPEndpointCheck(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer s.Close()
+	c := HTTPEndpointCheck{URL: s.URL, Timeout: time.Second}
+	ok, err := c.Check()
+	if !ok {
+		t.Errorf("expected the endpoint to be reachable. Error was: %v", err)
+	}
+
+	s.Close()
+	ok, err = c.Check()
+	if ok {
+		t.Errorf("expected the endpoint to be unreachable")
+	}
+	if err == nil {
+		t.Errorf("expected an error, but didn't get one")
+	}
+}
+
+func TestHTTPEndpointCheckThroughProxy(t *testing.T) {
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer proxy.Close()
+	c := HTTPEndpointCheck{
+		URL:     "http://packages.kismatic.invalid/",
+		Proxy:   ProxySettings{HTTPProxy: proxy.URL},
+		Timeout: time.Second,
+	}
+	ok, err := c.Check()
+	if !ok {
+		t.Errorf("expected the endpoint to be reachable through the proxy. Error was: %v", err)
+	}
+
+	c.Proxy.NoProxy = "kismatic.invalid"
+	ok, _ = c.Check()
+	if ok {
+		t.Errorf("expected the endpoint to be unreachable without the proxy")
+	}
+}
+
+func TestHTTPEndpointCheckCAFile(t *testing.T) {
+	s := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer s.Close()
+	c := HTTPEndpointCheck{URL: s.URL, Timeout: time.Second}
+	if ok, _ := c.Check(); ok {
+		t.Errorf("expected the certificate of the endpoint to be rejected without the CA")
+	}
+
+	dir, err := ioutil.TempDir("", "http-endpoint-check")
+	if err != nil {
+		t.Fatalf("error creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	c.CAFile = filepath.Join(dir, "ca.crt")
+	ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: s.Certificate().Raw})
+	if err := ioutil.WriteFile(c.CAFile, ca, 0644); err != nil {
+		t.Fatalf("error writing CA file: %v", err)
+	}
+	ok, err := c.Check()
+	if !ok {
+		t.Errorf("expected the certificate of the endpoint to be verified with the CA. Error was: %v", err)
+	}
+}
@@ -0,0 +1,90 @@
+package check
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	defaultProcPath = "/proc"
+	defaultSysPath  = "/sys"
+)
+
+// KernelModuleLoadedCheck checks that a kernel module is loaded, or built
+// into the kernel
+type KernelModuleLoadedCheck struct {
+	Module string
+	// ProcPath is the mount point of procfs, defaults to /proc
+	ProcPath string
+	// SysPath is the mount point of sysfs, defaults to /sys
+	SysPath string
+}
+
+// Check returns true if the module is listed in /proc/modules, or if it is
+// built into the kernel. Otherwise returns false.
+func (c KernelModuleLoadedCheck) Check() (bool, error) {
+	procPath, sysPath := c.ProcPath, c.SysPath
+	if procPath == "" {
+		procPath = defaultProcPath
+	}
+	if sysPath == "" {
+		sysPath = defaultSysPath
+	}
+	// the kernel reports dashes in module names as underscores
+	module := strings.Replace(c.Module, "-", "_", -1)
+	modulesFile := filepath.Join(procPath, "modules")
+	f, err := os.Open(modulesFile)
+	if err != nil {
+		return false, fmt.Errorf("error reading %s: %v", modulesFile, err)
+	}
+	defer f.Close()
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		fields := strings.Fields(s.Text())
+		if len(fields) > 0 && fields[0] == module {
+			return true, nil
+		}
+	}
+	if err := s.Err(); err != nil {
+		return false, fmt.Errorf("error reading %s: %v", modulesFile, err)
+	}
+	// built-in modules are not listed in /proc/modules, but have an entry
+	// in /sys/module
+	if _, err := os.Stat(filepath.Join(sysPath, "module", module)); err == nil {
+		return true, nil
+	}
+	return false, nil
+}
+
+// SysctlValueCheck checks the value of a kernel parameter
+type SysctlValueCheck struct {
+	// Parameter is the name of the kernel parameter, such as net.ipv4.ip_forward
+	Parameter string
+	Value     string
+	// ProcPath is the mount point of procfs, defaults to /proc
+	ProcPath string
+}
+
+// Check returns true if the kernel parameter is set to the value. Otherwise
+// returns false. Values made of multiple fields, such as
+// net.ipv4.ip_local_port_range, are compared field by field.
+func (c SysctlValueCheck) Check() (bool, error) {
+	procPath := c.ProcPath
+	if procPath == "" {
+		procPath = defaultProcPath
+	}
+	file := filepath.Join(procPath, "sys", strings.Replace(c.Parameter, ".", "/", -1))
+	b, err := ioutil.ReadFile(file)
+	if os.IsNotExist(err) {
+		return false, fmt.Errorf("kernel parameter %s does not exist", c.Parameter)
+	}
+	if err != nil {
+		return false, fmt.Errorf("error reading kernel parameter %s: %v", c.Parameter, err)
+	}
+	actual := strings.Join(strings.Fields(string(b)), " ")
+	return actual == strings.Join(strings.Fields(c.Value), " "), nil
+}
@@ -0,0 +1,92 @@
+package check
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeFixture writes the file in the fixture directory
+func writeFixture(t *testing.T, dir, file, content string) {
+	path := filepath.Join(dir, file)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("error creating fixture directory: %v", err)
+	}
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("error writing fixture: %v", err)
+	}
+}
+
+func TestKernelModuleLoadedCheck(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kernel-module-check")
+	if err != nil {
+		t.Fatalf("error creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	procPath, sysPath := filepath.Join(dir, "proc"), filepath.Join(dir, "sys")
+	writeFixture(t, procPath, "modules", `br_netfilter 24576 0 - Live 0x0000000000000000
+bridge 155648 1 br_netfilter, Live 0x0000000000000000
+nf_conntrack_ipv4 16384 3 - Live 0x0000000000000000
+`)
+	writeFixture(t, sysPath, "module/overlay/parameters/metacopy", "N\n")
+
+	tests := []struct {
+		module   string
+		expected bool
+	}{
+		{"br_netfilter", true},
+		{"nf-conntrack-ipv4", true},
+		{"overlay", true},
+		{"ip_vs", false},
+		{"br", false},
+	}
+	for _, test := range tests {
+		c := KernelModuleLoadedCheck{Module: test.module, ProcPath: procPath, SysPath: sysPath}
+		ok, err := c.Check()
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", test.module, err)
+		}
+		if ok != test.expected {
+			t.Errorf("%s: expected %t, but got %t", test.module, test.expected, ok)
+		}
+	}
+
+	c := KernelModuleLoadedCheck{Module: "br_netfilter", ProcPath: dir}
+	if ok, err := c.Check(); ok || err == nil {
+		t.Errorf("expected an error without /proc/modules, but got %t, %v", ok, err)
+	}
+}
+
+func TestSysctlValueCheck(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sysctl-check")
+	if err != nil {
+		t.Fatalf("error creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	writeFixture(t, dir, "sys/net/ipv4/ip_forward", "1\n")
+	writeFixture(t, dir, "sys/net/bridge/bridge-nf-call-iptables", "0\n")
+	writeFixture(t, dir, "sys/net/ipv4/ip_local_port_range", "32768\t60999\n")
+
+	tests := []struct {
+		parameter   string
+		value       string
+		expected    bool
+		expectedErr bool
+	}{
+		{"net.ipv4.ip_forward", "1", true, false},
+		{"net.bridge.bridge-nf-call-iptables", "1", false, false},
+		{"net.ipv4.ip_local_port_range", "32768 60999", true, false},
+		{"net.ipv6.conf.all.forwarding", "1", false, true},
+	}
+	for _, test := range tests {
+		c := SysctlValueCheck{Parameter: test.parameter, Value: test.value, ProcPath: dir}
+		ok, err := c.Check()
+		if (err != nil) != test.expectedErr {
+			t.Errorf("%s: unexpected error: %v", test.parameter, err)
+		}
+		if ok != test.expected {
+			t.Errorf("%s: expected %t, but got %t", test.parameter, test.expected, ok)
+		}
+	}
+}
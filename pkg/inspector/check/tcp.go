@@ -133,7 +133,12 @@ func getProcNameFromTCPSockStatLine(line string) (string, error) {
 	}
 	// users:(("nginx",pid=30729,fd=10),("nginx",pid=30728,fd=10),("nginx",pid=30721,fd=10))
 	usersField := ssFields[4]
+	return getProcNameFromSockStatUsers(usersField)
+}
 
+// given the users field of an entry returned by sockstat (ss), return the
+// name of the process using the port.
+func getProcNameFromSockStatUsers(usersField string) (string, error) {
 	// This regular expression contains a single capturing group that will fish
 	// out the process name from the `ss` output. In the case that `ss` returns
 	// a list with multiple users, the left-most user will be matched.
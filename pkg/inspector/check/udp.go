@@ -0,0 +1,168 @@
+package check
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"net"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// udpEchoPayload is the datagram sent by the UDPPortClientCheck, which is
+// expected to be echoed back by the UDPPortServerCheck
+var udpEchoPayload = []byte("kismatic-inspector")
+
+// UDPPortClientCheck verifies that a given UDP port on a remote node
+// is accessible through the network
+type UDPPortClientCheck struct {
+	// IPAddress is the IP of the remote node
+	IPAddress string
+	// PortNumber is the target service port
+	PortNumber int
+	// Timeout is the maximum amount of time the check will
+	// wait for the server to echo the datagram before bailing out
+	Timeout time.Duration
+}
+
+// Check returns true if the server echoes the datagram sent to the port.
+// Otherwise, returns false and an error message
+func (c *UDPPortClientCheck) Check() (bool, error) {
+	timeout := c.Timeout
+	if timeout == 0 {
+		timeout = 5 * time.Second
+	}
+	conn, err := net.DialTimeout("udp", fmt.Sprintf("%s:%d", c.IPAddress, c.PortNumber), timeout)
+	if err != nil {
+		return false, fmt.Errorf("Port %d/udp on host %q is unreachable. Error was: %v", c.PortNumber, c.IPAddress, err)
+	}
+	defer conn.Close()
+	if err := conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+		return false, fmt.Errorf("error setting the deadline of the UDP connection: %v", err)
+	}
+	if _, err := conn.Write(udpEchoPayload); err != nil {
+		return false, fmt.Errorf("Port %d/udp on host %q is unreachable. Error was: %v", c.PortNumber, c.IPAddress, err)
+	}
+	buf := make([]byte, len(udpEchoPayload))
+	n, err := conn.Read(buf)
+	if err != nil {
+		return false, fmt.Errorf("Port %d/udp on host %q is unreachable. Error was: %v", c.PortNumber, c.IPAddress, err)
+	}
+	if !bytes.Equal(buf[:n], udpEchoPayload) {
+		return false, fmt.Errorf("Port %d/udp on host %q returned an unexpected response %q", c.PortNumber, c.IPAddress, buf[:n])
+	}
+	return true, nil
+}
+
+// UDPPortServerCheck ensures that the given UDP port is free, or bound to the
+// right process. In the case that it is free, it stands up a UDP echo server
+// that can be used to check UDP connectivity to the host using UDPPortClientCheck.
+// The port is also accepted when it is bound by a kernel socket, such as the
+// VXLAN socket of the pod network, as these are not owned by any process.
+type UDPPortServerCheck struct {
+	PortNumber     int
+	ProcName       string
+	started        bool
+	inUse          bool
+	closeListener  func() error
+	listenerClosed chan interface{}
+}
+
+// Check returns true if the port is free, or taken by the expected process.
+func (c *UDPPortServerCheck) Check() (bool, error) {
+	pc, err := net.ListenPacket("udp", fmt.Sprintf(":%d", c.PortNumber))
+	if err != nil && strings.Contains(err.Error(), "address already in use") {
+		ok, err := udpPortTakenByProc(c.PortNumber, c.ProcName)
+		c.inUse = ok
+		return ok, err
+	}
+	if err != nil {
+		return false, fmt.Errorf("error listening on port %d/udp: %v", c.PortNumber, err)
+	}
+	c.closeListener = pc.Close
+	// Setup go routine that behaves as an echo server
+	c.listenerClosed = make(chan interface{})
+	go func(closed <-chan interface{}) {
+		buf := make([]byte, 1024)
+		for {
+			n, addr, err := pc.ReadFrom(buf)
+			if err != nil {
+				select {
+				case <-closed:
+					// don't log the error, as we have closed the server and the error
+					// is related to that.
+					return
+				default:
+					log.Println(fmt.Sprintf("error occurred reading datagram: %v", err))
+					continue
+				}
+			}
+			if _, err := pc.WriteTo(buf[:n], addr); err != nil {
+				log.Println(fmt.Sprintf("error occurred echoing datagram: %v", err))
+			}
+		}
+	}(c.listenerClosed)
+	c.started = true
+	return true, nil
+}
+
+// InUse returns true if the port is held by the expected process or by the
+// kernel, in which case no echo server was started
+func (c *UDPPortServerCheck) InUse() bool {
+	return c.inUse
+}
+
+// Close the UDP server if it was started. Otherwise this is a noop.
+func (c *UDPPortServerCheck) Close() error {
+	if c.started {
+		close(c.listenerClosed)
+		return c.closeListener()
+	}
+	return nil
+}
+
+// Returns true if the UDP port is taken by a process with the given name, or
+// by a kernel socket.
+func udpPortTakenByProc(port int, procName string) (bool, error) {
+	// Use `ss` (sockstat) to find the process bound to the given port.
+	// Sample output:
+	// ~# ss -upln src :6784 | strings
+	// State      Recv-Q Send-Q Local Address:Port               Peer Address:Port
+	// UNCONN     0      0                 *:6784                          *:*                   users:(("weaver",pid=5211,fd=14))
+	//
+	// Kernel sockets, such as the ones used for VXLAN, are not owned by a process:
+	// UNCONN     0      0                 *:8472                          *:*
+	//
+	cmd := exec.Command("ss", "-upln", "src", fmt.Sprintf(":%d", port))
+	out, err := cmd.Output()
+	if err != nil {
+		return false, fmt.Errorf("error running ss: %v", err)
+	}
+	lines := strings.Split(string(out), "\n")
+	if len(lines) < 2 {
+		return false, fmt.Errorf("expected ss to return at least 2 lines, but returned %d", len(lines))
+	}
+	boundProc, err := getProcNameFromUDPSockStatLine(lines[1])
+	if err != nil {
+		return false, err
+	}
+	// an empty process name is a socket bound by the kernel
+	return boundProc == "" || boundProc == procName, nil
+}
+
+// given an entry returned by sockstat (ss), return the name of the process using the port.
+// An empty name is returned for kernel sockets, which are not owned by any process.
+// assumes ss was run with flags: -upln
+func getProcNameFromUDPSockStatLine(line string) (string, error) {
+	ssFields := strings.Fields(line)
+	switch len(ssFields) {
+	case 5:
+		return "", nil
+	case 6:
+		// The sixth field includes information about the process using the port
+		return getProcNameFromSockStatUsers(ssFields[5])
+	default:
+		return "", fmt.Errorf("unexpected output returned from ss command. output was: %s", line)
+	}
+}
@@ -0,0 +1,83 @@
+package check
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func TestUDPPortServerAndClientChecks(t *testing.T) {
+	// find a free port
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("error finding a free port: %v", err)
+	}
+	port := pc.LocalAddr().(*net.UDPAddr).Port
+	pc.Close()
+
+	server := &UDPPortServerCheck{PortNumber: port, ProcName: "foo"}
+	ok, err := server.Check()
+	if err != nil {
+		t.Fatalf("unexpected error starting the UDP server: %v", err)
+	}
+	if !ok {
+		t.Fatalf("expected the port to be available")
+	}
+	defer server.Close()
+
+	client := &UDPPortClientCheck{IPAddress: "127.0.0.1", PortNumber: port, Timeout: time.Second}
+	ok, err = client.Check()
+	if err != nil {
+		t.Errorf("unexpected error checking the UDP port: %v", err)
+	}
+	if !ok {
+		t.Errorf("expected the UDP port to be accessible")
+	}
+}
+
+func TestUDPPortClientCheckNoServer(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("error finding a free port: %v", err)
+	}
+	port := pc.LocalAddr().(*net.UDPAddr).Port
+	pc.Close()
+
+	client := &UDPPortClientCheck{IPAddress: "127.0.0.1", PortNumber: port, Timeout: time.Second}
+	ok, err := client.Check()
+	if ok {
+		t.Errorf("expected the UDP port to be inaccessible")
+	}
+	if err == nil {
+		t.Errorf("expected an error, but didn't get one")
+	}
+}
+
+func TestGetProcNameFromUDPSockStatLine(t *testing.T) {
+	line := `UNCONN     0      0                 *:6784                          *:*                   users:(("weaver",pid=5211,fd=14))`
+	got, err := getProcNameFromUDPSockStatLine(line)
+	if err != nil {
+		t.Errorf("error getting process: %v", err)
+	}
+	if got != "weaver" {
+		t.Errorf("expected process name to be %q, but got %q", "weaver", got)
+	}
+}
+
+func TestGetProcNameFromUDPSockStatLineKernelSocket(t *testing.T) {
+	// VXLAN sockets are bound by the kernel, and have no users
+	line := `UNCONN     0      0                 *:8472                          *:*`
+	got, err := getProcNameFromUDPSockStatLine(line)
+	if err != nil {
+		t.Errorf("error getting process: %v", err)
+	}
+	if got != "" {
+		t.Errorf("expected no process name for a kernel socket, but got %q", got)
+	}
+}
+
+func TestGetProcNameFromUDPSockStatLineInvalid(t *testing.T) {
+	if _, err := getProcNameFromUDPSockStatLine("UNCONN 0 0"); err == nil {
+		t.Errorf("expected an error, but didn't get one")
+	}
+}
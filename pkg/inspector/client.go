@@ -16,11 +16,15 @@ type Client struct {
 	TargetNode string
 	// TargetNodeRole is the role of the node we are inspecting
 	TargetNodeFacts []string
-	engine          *rule.Engine
+	// PeerNodes are the ip:port of the inspectors running on the other nodes
+	PeerNodes []string
+	engine    *rule.Engine
 }
 
 // NewClient returns an inspector client for running checks against remote nodes.
-func NewClient(targetNode string, targetNodeFacts []string) (*Client, error) {
+// The peer nodes are the inspectors of the other nodes, which are used by the
+// checks that compare the target node to the rest of the cluster.
+func NewClient(targetNode string, targetNodeFacts []string, peerNodes []string) (*Client, error) {
 	host, _, err := net.SplitHostPort(targetNode)
 	if err != nil {
 		return nil, err
@@ -29,11 +33,14 @@ func NewClient(targetNode string, targetNodeFacts []string) (*Client, error) {
 		RuleCheckMapper: rule.DefaultCheckMapper{
 			PackageManager: nil, // Use a no-op pkg manager here instead
 			TargetNodeIP:   host,
+			TargetNode:     targetNode,
+			PeerNodes:      peerNodes,
 		},
 	}
 	return &Client{
 		TargetNode:      targetNode,
 		TargetNodeFacts: targetNodeFacts,
+		PeerNodes:       peerNodes,
 		engine:          engine,
 	}, nil
 }
@@ -70,7 +77,7 @@ func (c Client) ExecuteRules(rules []rule.Rule) ([]rule.Result, error) {
 	}
 
 	// Execute the rules that should run from a remote node
-	clientSideRules := getClientSideRules(rules)
+	clientSideRules := skipInUsePorts(getClientSideRules(rules), serverSideRules, results)
 	remoteResults, err := c.engine.ExecuteRules(clientSideRules, c.TargetNodeFacts)
 	if err != nil {
 		return nil, err
@@ -96,6 +103,33 @@ func getServerSideRules(rules []rule.Rule) []rule.Rule {
 	return localRules
 }
 
+// skipInUsePorts removes the UDP accessibility rules of the ports that are held
+// by their expected owner on the target node, such as the pod network of an
+// installed cluster. No echo server is listening on these ports, so they
+// cannot be probed.
+func skipInUsePorts(clientSideRules []rule.Rule, serverSideRules []rule.Rule, serverResults []rule.Result) []rule.Rule {
+	inUse := map[string]bool{}
+	for _, res := range serverResults {
+		if res.InUse {
+			inUse[res.Name] = true
+		}
+	}
+	inUsePorts := map[int]bool{}
+	for _, r := range serverSideRules {
+		if p, ok := r.(rule.UDPPortAvailable); ok && inUse[p.Name()] {
+			inUsePorts[p.Port] = true
+		}
+	}
+	rules := []rule.Rule{}
+	for _, r := range clientSideRules {
+		if p, ok := r.(rule.UDPPortAccessible); ok && inUsePorts[p.Port] {
+			continue
+		}
+		rules = append(rules, r)
+	}
+	return rules
+}
+
 func getClientSideRules(rules []rule.Rule) []rule.Rule {
 	remoteRules := []rule.Rule{}
 	for _, r := range rules {
@@ -0,0 +1,32 @@
+package inspector
+
+import (
+	"testing"
+
+	"github.com/apprenda/kismatic/pkg/inspector/rule"
+)
+
+func TestSkipInUsePorts(t *testing.T) {
+	serverSideRules := []rule.Rule{
+		rule.UDPPortAvailable{Port: 6783, ProcName: "weaver"},
+		rule.UDPPortAvailable{Port: 6784, ProcName: "weaver"},
+	}
+	clientSideRules := []rule.Rule{
+		rule.UDPPortAccessible{Port: 6783, Timeout: "5s"},
+		rule.UDPPortAccessible{Port: 6784, Timeout: "5s"},
+		rule.TCPPortAccessible{Port: 6783, Timeout: "5s"},
+	}
+	// 6783 is held by weave, while 6784 is free and has an echo server
+	results := []rule.Result{
+		{Name: serverSideRules[0].Name(), Success: true, InUse: true},
+		{Name: serverSideRules[1].Name(), Success: true},
+	}
+
+	rules := skipInUsePorts(clientSideRules, serverSideRules, results)
+	if len(rules) != 2 {
+		t.Fatalf("expected 2 rules, but got %v", rules)
+	}
+	if rules[0].Name() != clientSideRules[1].Name() || rules[1].Name() != clientSideRules[2].Name() {
+		t.Errorf("expected only the accessibility rule of the UDP port in use to be skipped, but got %v", rules)
+	}
+}
@@ -15,6 +15,7 @@ type clientOpts struct {
 	nodeRoles           string
 	rulesFile           string
 	targetNode          string
+	peerNodes           []string
 	useUpgradeDefaults  bool
 	additionalVariables map[string]string
 }
@@ -26,7 +27,10 @@ kismatic-inspector client 10.0.1.24:9090 --node-roles etcd
 kismatic-inspector client 10.0.1.24:9090 --node-roles etcd -o json
 
 # Run the inspector against a remote node using a custom rules file
-kismatic-inspector client 10.0.1.24:9090 -f inspector-rules.yaml --node-roles etcd`
+kismatic-inspector client 10.0.1.24:9090 -f inspector-rules.yaml --node-roles etcd
+
+# Run the inspector against a remote node, and compare its clock to the clocks of other nodes
+kismatic-inspector client 10.0.1.24:9090 --node-roles etcd --peer-nodes 10.0.1.25:9090,10.0.1.26:9090`
 
 // NewCmdClient returns the "client" command
 func NewCmdClient(out io.Writer) *cobra.Command {
@@ -57,6 +61,7 @@ func NewCmdClient(out io.Writer) *cobra.Command {
 	cmd.Flags().StringVar(&opts.nodeRoles, "node-roles", "", "comma-separated list of the node's roles. Valid roles are 'etcd', 'master', 'worker'")
 	cmd.Flags().StringVarP(&opts.rulesFile, "file", "f", "", "the path to an inspector rules file. If blank, the inspector uses the default rules")
 	cmd.Flags().BoolVarP(&opts.useUpgradeDefaults, "upgrade", "u", false, "use defaults for upgrade, rather than install")
+	cmd.Flags().StringSliceVar(&opts.peerNodes, "peer-nodes", []string{}, "comma-separated list of the HOST:PORT of the inspector servers running on the other nodes")
 	cmd.Flags().StringSliceVar(&additionalVars, "additional-vars", []string{}, "key=value pairs separated by ',' to template ruleset")
 	return cmd
 }
@@ -72,7 +77,7 @@ func runClient(out io.Writer, opts clientOpts) error {
 	if err != nil {
 		return err
 	}
-	c, err := inspector.NewClient(opts.targetNode, roles)
+	c, err := inspector.NewClient(opts.targetNode, roles, opts.peerNodes)
 	if err != nil {
 		return fmt.Errorf("error creating inspector client: %v", err)
 	}
@@ -5,9 +5,17 @@ import (
 	"io"
 	"strings"
 
+	"github.com/apprenda/kismatic/pkg/inspector/check"
 	"github.com/apprenda/kismatic/pkg/inspector/rule"
+	"github.com/spf13/pflag"
 )
 
+func addProxyFlags(flagSet *pflag.FlagSet, proxy *check.ProxySettings) {
+	flagSet.StringVar(&proxy.HTTPProxy, "http-proxy", "", "the proxy used by the node to reach http endpoints")
+	flagSet.StringVar(&proxy.HTTPSProxy, "https-proxy", "", "the proxy used by the node to reach https endpoints")
+	flagSet.StringVar(&proxy.NoProxy, "no-proxy", "", "comma-separated list of the hosts, domains and CIDR blocks that the node reaches without a proxy")
+}
+
 func getNodeRoles(commaSepRoles string) ([]string, error) {
 	roles := strings.Split(commaSepRoles, ",")
 	for _, r := range roles {
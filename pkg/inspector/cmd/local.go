@@ -18,6 +18,7 @@ type localOpts struct {
 	packageInstallationDisabled bool
 	dockerInstallationDisabled  bool
 	disconnectedInstallation    bool
+	proxy                       check.ProxySettings
 	useUpgradeDefaults          bool
 	additionalVariables         map[string]string
 }
@@ -52,6 +53,7 @@ func NewCmdLocal(out io.Writer) *cobra.Command {
 	cmd.Flags().BoolVar(&opts.packageInstallationDisabled, "pkg-installation-disabled", false, "when true, the inspector will ensure that the necessary packages are installed on the node")
 	cmd.Flags().BoolVar(&opts.dockerInstallationDisabled, "docker-installation-disabled", false, "when true, the inspector will check for docker packages to be installed")
 	cmd.Flags().BoolVar(&opts.disconnectedInstallation, "disconnected-installation", false, "when true will check for the required packages needed during a disconnected install")
+	addProxyFlags(cmd.Flags(), &opts.proxy)
 	cmd.Flags().BoolVarP(&opts.useUpgradeDefaults, "upgrade", "u", false, "use defaults for upgrade, rather than install")
 	cmd.Flags().StringSliceVar(&additionalVars, "additional-vars", []string{}, "provide a key=value list to template ruleset")
 	return cmd
@@ -90,6 +92,7 @@ func runLocal(out io.Writer, opts localOpts) error {
 			PackageInstallationDisabled: opts.packageInstallationDisabled,
 			DockerInstallationDisabled:  opts.dockerInstallationDisabled,
 			DisconnectedInstallation:    opts.disconnectedInstallation,
+			Proxy:                       opts.proxy,
 		},
 	}
 	labels := append(roles, string(distro))
@@ -5,6 +5,7 @@ import (
 	"io"
 
 	"github.com/apprenda/kismatic/pkg/inspector"
+	"github.com/apprenda/kismatic/pkg/inspector/check"
 	"github.com/spf13/cobra"
 )
 
@@ -22,6 +23,7 @@ type serverOpts struct {
 	packageInstallationDisabled bool
 	dockerInstallationDisabled  bool
 	disconnectedInstallation    bool
+	proxy                       check.ProxySettings
 }
 
 // NewCmdServer returns the "server" command
@@ -41,6 +43,7 @@ func NewCmdServer(out io.Writer) *cobra.Command {
 	cmd.Flags().BoolVar(&opts.packageInstallationDisabled, "pkg-installation-disabled", false, "when true, the inspector will ensure that the necessary packages are installed on the node")
 	cmd.Flags().BoolVar(&opts.dockerInstallationDisabled, "docker-installation-disabled", false, "when true, the inspector will check for docker packages to be installed")
 	cmd.Flags().BoolVar(&opts.disconnectedInstallation, "disconnected-installation", false, "when true will check for the required packages needed during a disconnected install")
+	addProxyFlags(cmd.Flags(), &opts.proxy)
 	return cmd
 }
 
@@ -55,7 +58,7 @@ func runServer(out io.Writer, opts serverOpts) error {
 	if opts.disconnectedInstallation {
 		nodeFacts = append(nodeFacts, "disconnected")
 	}
-	s, err := inspector.NewServer(nodeFacts, opts.port, opts.packageInstallationDisabled, opts.dockerInstallationDisabled, opts.disconnectedInstallation)
+	s, err := inspector.NewServer(nodeFacts, opts.port, opts.packageInstallationDisabled, opts.dockerInstallationDisabled, opts.disconnectedInstallation, opts.proxy)
 	if err != nil {
 		return fmt.Errorf("error starting up inspector server: %v", err)
 	}
@@ -64,6 +67,9 @@ func runServer(out io.Writer, opts serverOpts) error {
 	fmt.Fprintf(out, "Package installation disabled: %v\n", opts.packageInstallationDisabled)
 	fmt.Fprintf(out, "Docker installation disabled: %v\n", opts.dockerInstallationDisabled)
 	fmt.Fprintf(out, "Disconnected installation: %v\n", opts.disconnectedInstallation)
+	fmt.Fprintf(out, "HTTP proxy: %s\n", opts.proxy.HTTPProxy)
+	fmt.Fprintf(out, "HTTPS proxy: %s\n", opts.proxy.HTTPSProxy)
+	fmt.Fprintf(out, "No proxy: %s\n", opts.proxy.NoProxy)
 	fmt.Fprintf(out, "Run %s from another node to run checks remotely: %[1]s client [NODE_IP]:%d\n", opts.commandName, opts.port)
 	return s.Start()
 }
@@ -19,6 +19,10 @@ type DefaultCheckMapper struct {
 	PackageManager check.PackageManager
 	// IP of the remote node that is being inspected when in client mode
 	TargetNodeIP string
+	// ip:port of the inspector running on the remote node when in client mode
+	TargetNode string
+	// ip:port of the inspectors running on the other nodes when in client mode
+	PeerNodes []string
 	// PackageInstallationDisabled determines whether Kismatic is allowed to install packages on the node
 	PackageInstallationDisabled bool
 	// DisconnectedInstallation determines whether Kismatic can access the internet
@@ -26,6 +30,8 @@ type DefaultCheckMapper struct {
 	// DockerInstallationDisabled determines whether Kismatic is expected to install docker
 	// If set to false, Kismatic will validate that a docker executable is present on the machine
 	DockerInstallationDisabled bool
+	// Proxy are the proxies used by the node to reach HTTP endpoints
+	Proxy check.ProxySettings
 }
 
 // GetCheckForRule returns the check for the given rule. If the rule
@@ -55,11 +61,42 @@ func (m DefaultCheckMapper) GetCheckForRule(rule Rule) (check.Check, error) {
 			return nil, fmt.Errorf("invalid value %q provided for the timeout field of the TCPPortAccessible rule: %v", r.Timeout, err)
 		}
 		c = &check.TCPPortClientCheck{PortNumber: r.Port, IPAddress: m.TargetNodeIP, Timeout: timeout}
+	case UDPPortAvailable:
+		c = &check.UDPPortServerCheck{PortNumber: r.Port, ProcName: r.ProcName}
+	case UDPPortAccessible:
+		timeout, err := time.ParseDuration(r.Timeout)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value %q provided for the timeout field of the UDPPortAccessible rule: %v", r.Timeout, err)
+		}
+		c = &check.UDPPortClientCheck{PortNumber: r.Port, IPAddress: m.TargetNodeIP, Timeout: timeout}
 	case Python2Version:
 		c = &check.Python2Check{SupportedVersions: r.SupportedVersions}
 	case FreeSpace:
 		bytes, _ := r.minimumBytesAsUint64() // ignore this err, as we have already validated the rule
 		c = &check.FreeSpaceCheck{Path: r.Path, MinimumBytes: bytes}
+	case KernelModuleLoaded:
+		c = check.KernelModuleLoadedCheck{Module: r.Module}
+	case SysctlValue:
+		c = check.SysctlValueCheck{Parameter: r.Parameter, Value: r.Value}
+	case MinimumCPU:
+		c = check.CPUCountCheck{MinimumCPUs: r.MinimumCPUs}
+	case MinimumMemory:
+		bytes, _ := r.minimumBytesAsUint64() // ignore this err, as we have already validated the rule
+		c = check.MemoryCheck{MinimumBytes: bytes}
+	case SwapDisabled:
+		c = check.SwapDisabledCheck{}
+	case HTTPEndpointReachable:
+		timeout, err := time.ParseDuration(r.Timeout)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value %q provided for the timeout field of the HTTPEndpointReachable rule: %v", r.Timeout, err)
+		}
+		c = check.HTTPEndpointCheck{URL: r.URL, CAFile: r.CAFile, Proxy: m.Proxy, Timeout: timeout}
+	case ClockSkew:
+		maxSkew, err := time.ParseDuration(r.MaximumSkew)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value %q provided for the maximumSkew field of the ClockSkew rule: %v", r.MaximumSkew, err)
+		}
+		c = check.ClockSkewCheck{TargetNode: m.TargetNode, PeerNodes: m.PeerNodes, MaximumSkew: maxSkew}
 	}
 	return c, nil
 }
@@ -0,0 +1,34 @@
+package rule
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// The ClockSkew rule declares that the clock of a remote node must not be
+// skewed by more than the given duration from the clock of the node running
+// the inspector client, and from the clocks of the other nodes of the cluster
+type ClockSkew struct {
+	Meta
+	MaximumSkew string
+}
+
+// Name returns the name of the rule
+func (c ClockSkew) Name() string {
+	return fmt.Sprintf("Clock Skew Within: %s", c.MaximumSkew)
+}
+
+// IsRemoteRule returns true if the rule is to be run from a remote node
+func (c ClockSkew) IsRemoteRule() bool { return true }
+
+// Validate the rule
+func (c ClockSkew) Validate() []error {
+	if c.MaximumSkew == "" {
+		return []error{errors.New("MaximumSkew cannot be empty")}
+	}
+	if _, err := time.ParseDuration(c.MaximumSkew); err != nil {
+		return []error{fmt.Errorf("Invalid duration provided %q", c.MaximumSkew)}
+	}
+	return nil
+}
@@ -0,0 +1,18 @@
+package rule
+
+import "testing"
+
+func TestClockSkewRuleValidation(t *testing.T) {
+	c := ClockSkew{}
+	if errs := c.Validate(); len(errs) != 1 {
+		t.Errorf("expected 1 error, but got %d", len(errs))
+	}
+	c.MaximumSkew = "nonDuration"
+	if errs := c.Validate(); len(errs) != 1 {
+		t.Errorf("expected 1 error, but got %d", len(errs))
+	}
+	c.MaximumSkew = "2s"
+	if errs := c.Validate(); len(errs) != 0 {
+		t.Errorf("expected 0 errors, but got %d", len(errs))
+	}
+}
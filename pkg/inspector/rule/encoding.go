@@ -53,6 +53,13 @@ type catchAllRule struct {
 	SupportedVersions        []string `yaml:"supportedVersions"`
 	Path                     string   `yaml:"path"`
 	MinimumBytes             string   `yaml:"minimumBytes"`
+	Module                   string   `yaml:"module"`
+	Parameter                string   `yaml:"parameter"`
+	Value                    string   `yaml:"value"`
+	MinimumCPUs              int      `yaml:"minimumCPUs"`
+	MaximumSkew              string   `yaml:"maximumSkew"`
+	URL                      string   `yaml:"url"`
+	CAFile                   string   `yaml:"caFile"`
 }
 
 // UnmarshalRulesYAML unmarshals the data into a list of rules
@@ -88,8 +95,9 @@ func rulesFromCatchAllRules(catchAllRules []catchAllRule) ([]Rule, error) {
 func buildRule(catchAll catchAllRule) (Rule, error) {
 	kind := strings.ToLower(strings.TrimSpace(catchAll.Kind))
 	meta := Meta{
-		Kind: kind,
-		When: catchAll.When,
+		Kind:        kind,
+		When:        catchAll.When,
+		Remediation: catchAll.Remediation,
 	}
 	switch kind {
 	default:
@@ -133,6 +141,20 @@ func buildRule(catchAll catchAllRule) (Rule, error) {
 		}
 		r.Meta = meta
 		return r, nil
+	case "udpportavailable":
+		r := UDPPortAvailable{
+			Port:     catchAll.Port,
+			ProcName: catchAll.ProcName,
+		}
+		r.Meta = meta
+		return r, nil
+	case "udpportaccessible":
+		r := UDPPortAccessible{
+			Port:    catchAll.Port,
+			Timeout: catchAll.Timeout,
+		}
+		r.Meta = meta
+		return r, nil
 	case "filecontentmatches":
 		r := FileContentMatches{
 			File:         catchAll.File,
@@ -153,6 +175,48 @@ func buildRule(catchAll catchAllRule) (Rule, error) {
 		}
 		r.Meta = meta
 		return r, nil
-
+	case "kernelmoduleloaded":
+		r := KernelModuleLoaded{
+			Module: catchAll.Module,
+		}
+		r.Meta = meta
+		return r, nil
+	case "minimumcpu":
+		r := MinimumCPU{
+			MinimumCPUs: catchAll.MinimumCPUs,
+		}
+		r.Meta = meta
+		return r, nil
+	case "minimummemory":
+		r := MinimumMemory{
+			MinimumBytes: catchAll.MinimumBytes,
+		}
+		r.Meta = meta
+		return r, nil
+	case "swapdisabled":
+		r := SwapDisabled{}
+		r.Meta = meta
+		return r, nil
+	case "sysctlvalue":
+		r := SysctlValue{
+			Parameter: catchAll.Parameter,
+			Value:     catchAll.Value,
+		}
+		r.Meta = meta
+		return r, nil
+	case "httpendpointreachable":
+		r := HTTPEndpointReachable{
+			URL:     catchAll.URL,
+			CAFile:  catchAll.CAFile,
+			Timeout: catchAll.Timeout,
+		}
+		r.Meta = meta
+		return r, nil
+	case "clockskew":
+		r := ClockSkew{
+			MaximumSkew: catchAll.MaximumSkew,
+		}
+		r.Meta = meta
+		return r, nil
 	}
 }
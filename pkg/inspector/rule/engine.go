@@ -32,13 +32,18 @@ func (e *Engine) ExecuteRules(rules []Rule, facts []string) ([]Result, error) {
 		// Run the check and report result
 		ok, err := c.Check()
 		res := Result{
-			Name:        rule.Name(),
-			Success:     ok,
-			Remediation: "",
+			Name:    rule.Name(),
+			Success: ok,
 		}
 		if err != nil {
 			res.Error = err.Error()
 		}
+		if !ok {
+			res.Remediation = rule.GetRuleMeta().Remediation
+		}
+		if c, isInUseCheck := c.(check.InUseCheck); isInUseCheck && ok {
+			res.InUse = c.InUse()
+		}
 
 		// We update the closables as we go to avoid leaking closables
 		// in the event where we have to return an error from within the loop.
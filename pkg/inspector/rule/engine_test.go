@@ -17,6 +17,13 @@ func (c fakeCheck) Check() (bool, error) {
 	return c.ok, c.err
 }
 
+// fakeInUseCheck succeeds because the resource is held by its expected owner
+type fakeInUseCheck struct {
+	fakeCheck
+}
+
+func (c fakeInUseCheck) InUse() bool { return true }
+
 type fakeRule struct {
 	Meta
 	name     string
@@ -62,6 +69,23 @@ func TestEngine(t *testing.T) {
 				},
 			},
 		},
+		// Single rule that passes because the resource is in use
+		{
+			mapper: fakeRuleCheckMapper{
+				check: fakeInUseCheck{fakeCheck{ok: true}},
+			},
+			rule: fakeRule{
+				name: "InUseRule",
+			},
+			facts: []string{},
+			expectedResults: []Result{
+				{
+					Name:    "InUseRule",
+					Success: true,
+					InUse:   true,
+				},
+			},
+		},
 		// Single rule that fails
 		{
 			mapper: fakeRuleCheckMapper{
@@ -161,6 +185,40 @@ func TestEngine(t *testing.T) {
 				},
 			},
 		},
+		// The remediation of a rule is reported when it fails
+		{
+			mapper: fakeRuleCheckMapper{
+				check: fakeCheck{ok: false},
+			},
+			rule: fakeRule{
+				Meta: Meta{Remediation: "fix it"},
+				name: "FailRule",
+			},
+			facts: []string{},
+			expectedResults: []Result{
+				{
+					Name:        "FailRule",
+					Success:     false,
+					Remediation: "fix it",
+				},
+			},
+		},
+		{
+			mapper: fakeRuleCheckMapper{
+				check: fakeCheck{ok: true},
+			},
+			rule: fakeRule{
+				Meta: Meta{Remediation: "fix it"},
+				name: "SuccessRule",
+			},
+			facts: []string{},
+			expectedResults: []Result{
+				{
+					Name:    "SuccessRule",
+					Success: true,
+				},
+			},
+		},
 		// Mapper returns an error, engine should return error
 		{
 			mapper: fakeRuleCheckMapper{
@@ -0,0 +1,77 @@
+package rule
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// The MinimumCPU rule declares that the node must have at least the given
+// number of CPUs
+type MinimumCPU struct {
+	Meta
+	MinimumCPUs int
+}
+
+// Name is the name of the rule
+func (c MinimumCPU) Name() string {
+	return fmt.Sprintf("At least %d CPUs", c.MinimumCPUs)
+}
+
+// IsRemoteRule returns true if the rule is to be run from outside of the node
+func (c MinimumCPU) IsRemoteRule() bool { return false }
+
+// Validate the rule
+func (c MinimumCPU) Validate() []error {
+	if c.MinimumCPUs < 1 {
+		return []error{fmt.Errorf("Invalid number of CPUs %d specified", c.MinimumCPUs)}
+	}
+	return nil
+}
+
+// The MinimumMemory rule declares that the node must have at least the given
+// amount of memory
+type MinimumMemory struct {
+	Meta
+	MinimumBytes string
+}
+
+// Name is the name of the rule
+func (m MinimumMemory) Name() string {
+	return fmt.Sprintf("At least %s bytes of memory", m.MinimumBytes)
+}
+
+// IsRemoteRule returns true if the rule is to be run from outside of the node
+func (m MinimumMemory) IsRemoteRule() bool { return false }
+
+// Validate the rule
+func (m MinimumMemory) Validate() []error {
+	if m.MinimumBytes == "" {
+		return []error{errors.New("MinimumBytes cannot be empty")}
+	}
+	if _, err := m.minimumBytesAsUint64(); err != nil {
+		return []error{fmt.Errorf("MinimumBytes contains an invalid unsigned integer: %v", err)}
+	}
+	return nil
+}
+
+func (m MinimumMemory) minimumBytesAsUint64() (uint64, error) {
+	return strconv.ParseUint(m.MinimumBytes, 10, 0)
+}
+
+// The SwapDisabled rule declares that the node must not have any swap
+// memory, as the kubelet fails to start otherwise
+type SwapDisabled struct {
+	Meta
+}
+
+// Name is the name of the rule
+func (s SwapDisabled) Name() string {
+	return "Swap is disabled"
+}
+
+// IsRemoteRule returns true if the rule is to be run from outside of the node
+func (s SwapDisabled) IsRemoteRule() bool { return false }
+
+// Validate the rule
+func (s SwapDisabled) Validate() []error { return nil }
@@ -0,0 +1,51 @@
+package rule
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// The HTTPEndpointReachable rule declares that the node must be able to reach
+// the given HTTP endpoint. When CAFile is set, the certificate of the endpoint
+// is verified against it.
+type HTTPEndpointReachable struct {
+	Meta
+	URL     string
+	CAFile  string
+	Timeout string
+}
+
+// Name returns the name of the rule
+func (h HTTPEndpointReachable) Name() string {
+	return fmt.Sprintf("HTTP Endpoint Reachable: %s", h.URL)
+}
+
+// IsRemoteRule returns true if the rule is to be run from outside of the node
+func (h HTTPEndpointReachable) IsRemoteRule() bool { return false }
+
+// Validate the rule
+func (h HTTPEndpointReachable) Validate() []error {
+	errs := []error{}
+	if h.URL == "" {
+		errs = append(errs, errors.New("URL cannot be empty"))
+	}
+	if h.URL != "" {
+		if u, err := url.Parse(h.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = append(errs, fmt.Errorf("Invalid URL provided %q", h.URL))
+		}
+	}
+	if h.Timeout == "" {
+		errs = append(errs, errors.New("Timeout cannot be empty"))
+	}
+	if h.Timeout != "" {
+		if _, err := time.ParseDuration(h.Timeout); err != nil {
+			errs = append(errs, fmt.Errorf("Invalid duration provided %q", h.Timeout))
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
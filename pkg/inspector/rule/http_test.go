@@ -0,0 +1,26 @@
+package rule
+
+import "testing"
+
+func TestHTTPEndpointReachableRuleValidation(t *testing.T) {
+	h := HTTPEndpointReachable{}
+	if errs := h.Validate(); len(errs) != 2 {
+		t.Errorf("expected 2 errors, but got %d", len(errs))
+	}
+	h.URL = "registry.local:8443"
+	if errs := h.Validate(); len(errs) != 2 {
+		t.Errorf("expected 2 errors, but got %d", len(errs))
+	}
+	h.URL = "https://registry.local:8443/v2/"
+	if errs := h.Validate(); len(errs) != 1 {
+		t.Errorf("expected 1 error, but got %d", len(errs))
+	}
+	h.Timeout = "nonDuration"
+	if errs := h.Validate(); len(errs) != 1 {
+		t.Errorf("expected 1 error, but got %d", len(errs))
+	}
+	h.Timeout = "10s"
+	if errs := h.Validate(); len(errs) != 0 {
+		t.Errorf("expected 0 errors, but got %d", len(errs))
+	}
+}
@@ -0,0 +1,70 @@
+package rule
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// The KernelModuleLoaded rule declares that the given kernel module must be
+// loaded, or built into the kernel
+type KernelModuleLoaded struct {
+	Meta
+	Module string
+}
+
+// Name is the name of the rule
+func (k KernelModuleLoaded) Name() string {
+	return fmt.Sprintf("Kernel module %s is loaded", k.Module)
+}
+
+// IsRemoteRule returns true if the rule is to be run from outside of the node
+func (k KernelModuleLoaded) IsRemoteRule() bool { return false }
+
+// Validate the rule
+func (k KernelModuleLoaded) Validate() []error {
+	errs := []error{}
+	if k.Module == "" {
+		errs = append(errs, errors.New("Module cannot be empty"))
+	} else if strings.ContainsAny(k.Module, "/ ") {
+		errs = append(errs, fmt.Errorf("Invalid module name %q specified", k.Module))
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// The SysctlValue rule declares that the given kernel parameter must be set
+// to the value
+type SysctlValue struct {
+	Meta
+	// Parameter is the name of the kernel parameter, such as net.ipv4.ip_forward
+	Parameter string
+	Value     string
+}
+
+// Name is the name of the rule
+func (s SysctlValue) Name() string {
+	return fmt.Sprintf("Sysctl %s is %s", s.Parameter, s.Value)
+}
+
+// IsRemoteRule returns true if the rule is to be run from outside of the node
+func (s SysctlValue) IsRemoteRule() bool { return false }
+
+// Validate the rule
+func (s SysctlValue) Validate() []error {
+	errs := []error{}
+	if s.Parameter == "" {
+		errs = append(errs, errors.New("Parameter cannot be empty"))
+	} else if strings.ContainsAny(s.Parameter, " ") || strings.Contains(s.Parameter, "..") || strings.HasPrefix(s.Parameter, "/") {
+		errs = append(errs, fmt.Errorf("Invalid parameter %q specified", s.Parameter))
+	}
+	if s.Value == "" {
+		errs = append(errs, errors.New("Value cannot be empty"))
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
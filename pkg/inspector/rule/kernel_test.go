@@ -0,0 +1,42 @@
+package rule
+
+import "testing"
+
+func TestKernelModuleLoadedRuleValidation(t *testing.T) {
+	k := KernelModuleLoaded{}
+	if errs := k.Validate(); len(errs) != 1 {
+		t.Errorf("expected 1 error, but got %d", len(errs))
+	}
+
+	k.Module = "../br_netfilter"
+	if errs := k.Validate(); len(errs) != 1 {
+		t.Errorf("expected 1 error, but got %d", len(errs))
+	}
+
+	k.Module = "br_netfilter"
+	if errs := k.Validate(); len(errs) != 0 {
+		t.Errorf("expected 0 errors, but got %d", len(errs))
+	}
+}
+
+func TestSysctlValueRuleValidation(t *testing.T) {
+	s := SysctlValue{}
+	if errs := s.Validate(); len(errs) != 2 {
+		t.Errorf("expected 2 errors, but got %d", len(errs))
+	}
+
+	s.Parameter = "net..ipv4"
+	if errs := s.Validate(); len(errs) != 2 {
+		t.Errorf("expected 2 errors, but got %d", len(errs))
+	}
+
+	s.Parameter = "net.ipv4.ip_forward"
+	if errs := s.Validate(); len(errs) != 1 {
+		t.Errorf("expected 1 error, but got %d", len(errs))
+	}
+
+	s.Value = "1"
+	if errs := s.Validate(); len(errs) != 0 {
+		t.Errorf("expected 0 errors, but got %d", len(errs))
+	}
+}
@@ -25,6 +25,105 @@ const defaultRuleSet = `---
   path: /
   minimumBytes: 1000000000
 
+# Hardware minimums per role, which can be overridden with the
+# {etcd,master,worker}_minimum_cpus and {etcd,master,worker}_minimum_memory_bytes variables.
+# The memory minimums are below the recommended sizes, as the total memory
+# reported by the kernel does not include the memory it reserves.
+- kind: MinimumCPU
+  when:
+  - ["etcd"]
+  minimumCPUs: {{ or .etcd_minimum_cpus "1" }}
+  remediation: Add CPUs to the node, or lower etcd_minimum_cpus
+- kind: MinimumCPU
+  when:
+  - ["master"]
+  minimumCPUs: {{ or .master_minimum_cpus "1" }}
+  remediation: Add CPUs to the node, or lower master_minimum_cpus
+- kind: MinimumCPU
+  when:
+  - ["worker", "ingress", "storage"]
+  minimumCPUs: {{ or .worker_minimum_cpus "1" }}
+  remediation: Add CPUs to the node, or lower worker_minimum_cpus
+- kind: MinimumMemory
+  when:
+  - ["etcd"]
+  minimumBytes: {{ or .etcd_minimum_memory_bytes "750000000" }}
+  remediation: Add memory to the node, or lower etcd_minimum_memory_bytes
+- kind: MinimumMemory
+  when:
+  - ["master"]
+  minimumBytes: {{ or .master_minimum_memory_bytes "1500000000" }}
+  remediation: Add memory to the node, or lower master_minimum_memory_bytes
+- kind: MinimumMemory
+  when:
+  - ["worker", "ingress", "storage"]
+  minimumBytes: {{ or .worker_minimum_memory_bytes "750000000" }}
+  remediation: Add memory to the node, or lower worker_minimum_memory_bytes
+
+# The kubelet fails to start when swap is enabled, unless fail-swap-on is false
+{{- if ne (print .fail_swap_on) "false" }}
+- kind: SwapDisabled
+  when:
+  - ["master", "worker", "ingress", "storage"]
+  remediation: Disable swap with "swapoff -a" and remove it from /etc/fstab, or set the fail-swap-on kubelet option to false
+{{- end }}
+
+# etcd and TLS break when the clocks of the nodes drift apart.
+# The maximum skew can be overridden with the max_clock_skew variable
+- kind: ClockSkew
+  when: []
+  maximumSkew: {{ or .max_clock_skew "2s" }}
+  remediation: Synchronize the clocks of the nodes, for example with NTP or chrony
+
+# The private docker registry and the package repositories are reachable
+# through the proxies. These are skipped in disconnected installations, and the
+# package repositories are skipped when package installation is disabled.
+{{- if ne (print .disconnected_installation) "true" }}
+{{- if .docker_registry_server }}
+- kind: HTTPEndpointReachable
+  when:
+  - ["etcd", "master", "worker", "ingress", "storage"]
+  url: https://{{ .docker_registry_server }}/v2/
+{{- if .docker_registry_ca }}
+  caFile: {{ .docker_registry_ca }}
+{{- end }}
+  timeout: 10s
+  remediation: Allow the node to reach the docker registry, or set the proxies of the plan file
+{{- end }}
+{{- if ne (print .package_installation_enabled) "false" }}
+{{- if ne (print .docker_enabled) "false" }}
+- kind: HTTPEndpointReachable
+  when:
+  - ["etcd", "master", "worker", "ingress", "storage"]
+  - ["rhel", "centos"]
+  url: {{ or .docker_yum_repository_url "https://download.docker.com/linux/centos/7/x86_64/stable/" }}
+  timeout: 10s
+  remediation: Allow the node to reach the package repository, or set the proxies of the plan file
+- kind: HTTPEndpointReachable
+  when:
+  - ["etcd", "master", "worker", "ingress", "storage"]
+  - ["ubuntu"]
+  url: {{ or .docker_deb_repository_url "https://download.docker.com/linux/ubuntu" }}
+  timeout: 10s
+  remediation: Allow the node to reach the package repository, or set the proxies of the plan file
+{{- end }}
+- kind: HTTPEndpointReachable
+  when:
+  - ["etcd", "master", "worker", "ingress", "storage"]
+  - ["rhel", "centos"]
+  url: {{ or .kubernetes_yum_repository_url "https://packages.cloud.google.com/yum/repos/kubernetes-el7-x86_64" }}
+  timeout: 10s
+  remediation: Allow the node to reach the package repository, or set the proxies of the plan file
+- kind: HTTPEndpointReachable
+  when:
+  - ["etcd", "master", "worker", "ingress", "storage"]
+  - ["ubuntu"]
+  url: {{ or .kubernetes_deb_repository_url "https://packages.cloud.google.com/apt/" }}
+  timeout: 10s
+  remediation: Allow the node to reach the package repository, or set the proxies of the plan file
+{{- end }}
+{{- end }}
+
 # Python 2.5+ is installed on all nodes
 # This is required by ansible
 - kind: Python2Version
@@ -48,6 +147,25 @@ const defaultRuleSet = `---
   - ["master", "worker", "ingress", "storage"]
   executable: iptables-restore
 
+# Kernel state required by kube-proxy and the pod network
+- kind: KernelModuleLoaded
+  when:
+  - ["master", "worker", "ingress", "storage"]
+  module: br_netfilter
+  remediation: Load the module with "modprobe br_netfilter", and list it in /etc/modules-load.d/ to load it on boot
+- kind: SysctlValue
+  when:
+  - ["master", "worker", "ingress", "storage"]
+  parameter: net.bridge.bridge-nf-call-iptables
+  value: "1"
+  remediation: Set the parameter with "sysctl -w net.bridge.bridge-nf-call-iptables=1", and in /etc/sysctl.d/ to set it on boot
+- kind: SysctlValue
+  when:
+  - ["master", "worker", "ingress", "storage"]
+  parameter: net.ipv4.ip_forward
+  value: "1"
+  remediation: Set the parameter with "sysctl -w net.ipv4.ip_forward=1", and in /etc/sysctl.d/ to set it on boot
+
 # Docker should be installed when installation is disabled
 - kind: DockerInPath
   when:
@@ -277,6 +395,42 @@ const defaultRuleSet = `---
   - ["storage"]
   port: 38467
   timeout: 5s
+
+# UDP ports used by the pod network between the nodes.
+# Weave encapsulates traffic on 6783 and 6784, and Contiv uses the VXLAN port 4789
+{{- if eq (print .cni_provider) "weave" }}
+- kind: UDPPortAvailable
+  when:
+  - ["master", "worker", "ingress", "storage"]
+  port: 6783
+  procName: weaver
+- kind: UDPPortAccessible
+  when:
+  - ["master", "worker", "ingress", "storage"]
+  port: 6783
+  timeout: 5s
+- kind: UDPPortAvailable
+  when:
+  - ["master", "worker", "ingress", "storage"]
+  port: 6784
+  procName: weaver
+- kind: UDPPortAccessible
+  when:
+  - ["master", "worker", "ingress", "storage"]
+  port: 6784
+  timeout: 5s
+{{- else if eq (print .cni_provider) "contiv" }}
+- kind: UDPPortAvailable
+  when:
+  - ["master", "worker", "ingress", "storage"]
+  port: 4789
+  procName: ovs-vswitchd
+- kind: UDPPortAccessible
+  when:
+  - ["master", "worker", "ingress", "storage"]
+  port: 4789
+  timeout: 5s
+{{- end }}
   
 - kind: PackageDependency
   when: 
@@ -471,6 +625,21 @@ const upgradeRuleSet = `---
 - kind: FreeSpace
   path: /
   minimumBytes: 1000000000
+
+# The kubelet fails to start when swap is enabled, unless fail-swap-on is false
+{{- if ne (print .fail_swap_on) "false" }}
+- kind: SwapDisabled
+  when:
+  - ["master", "worker", "ingress", "storage"]
+  remediation: Disable swap with "swapoff -a" and remove it from /etc/fstab, or set the fail-swap-on kubelet option to false
+{{- end }}
+
+# etcd and TLS break when the clocks of the nodes drift apart.
+# The maximum skew can be overridden with the max_clock_skew variable
+- kind: ClockSkew
+  when: []
+  maximumSkew: {{ or .max_clock_skew "2s" }}
+  remediation: Synchronize the clocks of the nodes, for example with NTP or chrony
   
 - kind: PackageDependency
   when: 
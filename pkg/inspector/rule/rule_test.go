@@ -1,12 +1,15 @@
 package rule
 
-import "testing"
+import (
+	"reflect"
+	"testing"
+)
 
 func TestDefaultRules(t *testing.T) {
 	// This will panic if there are errors in the default rule
 	rules := DefaultRules(map[string]string{"kubernetes_yum_version": "1.10.1-0", "kubernetes_deb_version": "1.10.1-00"})
-	if len(rules) != 76 {
-		t.Errorf("expected to have %d rules, instead got %d", 76, len(rules))
+	if len(rules) != 91 {
+		t.Errorf("expected to have %d rules, instead got %d", 91, len(rules))
 	}
 	for _, r := range rules {
 		if errs := r.Validate(); len(errs) != 0 {
@@ -15,11 +18,40 @@ func TestDefaultRules(t *testing.T) {
 	}
 }
 
+func TestDefaultRulesOverrides(t *testing.T) {
+	vars := map[string]string{
+		"kubernetes_yum_version": "1.10.1-0",
+		"kubernetes_deb_version": "1.10.1-00",
+		"master_minimum_cpus":    "4",
+		"etcd_minimum_cpus":      "",
+		"fail_swap_on":           "false",
+		"max_clock_skew":         "500ms",
+	}
+	rules := DefaultRules(vars)
+	if len(rules) != 90 {
+		t.Errorf("expected to have %d rules without the swap rule, instead got %d", 90, len(rules))
+	}
+	for _, r := range rules {
+		if c, ok := r.(MinimumCPU); ok && c.When[0][0] == "master" && c.MinimumCPUs != 4 {
+			t.Errorf("expected the minimum CPUs of the masters to be overridden, but got %d", c.MinimumCPUs)
+		}
+		if c, ok := r.(MinimumCPU); ok && c.When[0][0] == "etcd" && c.MinimumCPUs != 1 {
+			t.Errorf("expected an empty minimum CPUs of the etcd nodes to use the default, but got %d", c.MinimumCPUs)
+		}
+		if c, ok := r.(ClockSkew); ok && c.MaximumSkew != "500ms" {
+			t.Errorf("expected the maximum clock skew to be overridden, but got %q", c.MaximumSkew)
+		}
+		if _, ok := r.(SwapDisabled); ok {
+			t.Errorf("expected the swap rule to be skipped when fail-swap-on is false")
+		}
+	}
+}
+
 func TestUpgradeRules(t *testing.T) {
 	// This will panic if there are errors in the upgrade rule
 	rules := UpgradeRules(map[string]string{"kubernetes_yum_version": "1.10.1-0", "kubernetes_deb_version": "1.10.1-00"})
-	if len(rules) != 16 {
-		t.Errorf("expected to have %d rules, instead got %d", 16, len(rules))
+	if len(rules) != 18 {
+		t.Errorf("expected to have %d rules, instead got %d", 18, len(rules))
 	}
 	for _, r := range rules {
 		if errs := r.Validate(); len(errs) != 0 {
@@ -27,3 +59,109 @@ func TestUpgradeRules(t *testing.T) {
 		}
 	}
 }
+
+func TestDefaultRulesCNIProvider(t *testing.T) {
+	tests := []struct {
+		provider      string
+		expectedPorts []int
+	}{
+		{provider: "", expectedPorts: nil},
+		{provider: "calico", expectedPorts: nil},
+		{provider: "weave", expectedPorts: []int{6783, 6784}},
+		{provider: "contiv", expectedPorts: []int{4789}},
+	}
+	for _, test := range tests {
+		vars := map[string]string{
+			"kubernetes_yum_version": "1.10.1-0",
+			"kubernetes_deb_version": "1.10.1-00",
+			"cni_provider":           test.provider,
+		}
+		var available, accessible []int
+		for _, r := range DefaultRules(vars) {
+			switch p := r.(type) {
+			case UDPPortAvailable:
+				available = append(available, p.Port)
+			case UDPPortAccessible:
+				accessible = append(accessible, p.Port)
+			}
+		}
+		if !reflect.DeepEqual(available, test.expectedPorts) {
+			t.Errorf("expected the UDP ports %v to be available with provider %q, but got %v", test.expectedPorts, test.provider, available)
+		}
+		if !reflect.DeepEqual(accessible, test.expectedPorts) {
+			t.Errorf("expected the UDP ports %v to be accessible with provider %q, but got %v", test.expectedPorts, test.provider, accessible)
+		}
+	}
+}
+
+func TestDefaultRulesHTTPEndpoints(t *testing.T) {
+	tests := []struct {
+		name         string
+		vars         map[string]string
+		expectedURLs []string
+		expectedCA   string
+	}{
+		{
+			name: "package repositories",
+			vars: map[string]string{},
+			expectedURLs: []string{
+				"https://download.docker.com/linux/centos/7/x86_64/stable/",
+				"https://download.docker.com/linux/ubuntu",
+				"https://packages.cloud.google.com/yum/repos/kubernetes-el7-x86_64",
+				"https://packages.cloud.google.com/apt/",
+			},
+		},
+		{
+			name: "private registry without docker installation",
+			vars: map[string]string{
+				"docker_enabled":                "false",
+				"docker_registry_server":        "registry.local:8443",
+				"docker_registry_ca":            "/tmp/ca.crt",
+				"kubernetes_yum_repository_url": "https://mirror.local/yum",
+				"kubernetes_deb_repository_url": "https://mirror.local/apt",
+			},
+			expectedURLs: []string{
+				"https://registry.local:8443/v2/",
+				"https://mirror.local/yum",
+				"https://mirror.local/apt",
+			},
+			expectedCA: "/tmp/ca.crt",
+		},
+		{
+			name: "package installation disabled",
+			vars: map[string]string{
+				"package_installation_enabled": "false",
+				"docker_registry_server":       "registry.local:8443",
+			},
+			expectedURLs: []string{"https://registry.local:8443/v2/"},
+		},
+		{
+			name: "disconnected installation",
+			vars: map[string]string{
+				"disconnected_installation": "true",
+				"docker_registry_server":    "registry.local:8443",
+			},
+			expectedURLs: nil,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.vars["kubernetes_yum_version"] = "1.10.1-0"
+			test.vars["kubernetes_deb_version"] = "1.10.1-00"
+			var urls []string
+			for _, r := range DefaultRules(test.vars) {
+				h, ok := r.(HTTPEndpointReachable)
+				if !ok {
+					continue
+				}
+				urls = append(urls, h.URL)
+				if h.URL == "https://registry.local:8443/v2/" && h.CAFile != test.expectedCA {
+					t.Errorf("expected the CA of the registry to be %q, but got %q", test.expectedCA, h.CAFile)
+				}
+			}
+			if !reflect.DeepEqual(urls, test.expectedURLs) {
+				t.Errorf("expected the endpoints %v, but got %v", test.expectedURLs, urls)
+			}
+		})
+	}
+}
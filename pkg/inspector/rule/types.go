@@ -4,6 +4,8 @@ package rule
 type Meta struct {
 	Kind string
 	When [][]string
+	// Remediation describes how to fix the condition when the rule fails
+	Remediation string
 }
 
 // GetRuleMeta returns the rule's metadata
@@ -29,4 +31,7 @@ type Result struct {
 	Error string
 	// Remediation contains potential remediation steps for the rule
 	Remediation string
+	// InUse is true when the rule succeeded because the resource it verifies,
+	// such as a port, is already held by its expected owner
+	InUse bool `json:",omitempty"`
 }
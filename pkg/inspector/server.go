@@ -6,6 +6,7 @@ import (
 	"io/ioutil"
 	"log"
 	"net/http"
+	"time"
 
 	"github.com/apprenda/kismatic/pkg/inspector/check"
 	"github.com/apprenda/kismatic/pkg/inspector/rule"
@@ -27,10 +28,11 @@ type serverError struct {
 
 var executeEndpoint = "/execute"
 var closeEndpoint = "/close"
+var timeEndpoint = check.TimeEndpoint
 
 // NewServer returns an inspector server that has been initialized
 // with the default rules engine
-func NewServer(nodeFacts []string, port int, packageInstallationDisabled bool, dockerInstallationDisabled bool, disconnectedInstallation bool) (*Server, error) {
+func NewServer(nodeFacts []string, port int, packageInstallationDisabled bool, dockerInstallationDisabled bool, disconnectedInstallation bool, proxy check.ProxySettings) (*Server, error) {
 	s := &Server{
 		Port: port,
 	}
@@ -49,6 +51,7 @@ func NewServer(nodeFacts []string, port int, packageInstallationDisabled bool, d
 			PackageInstallationDisabled: packageInstallationDisabled,
 			DockerInstallationDisabled:  dockerInstallationDisabled,
 			DisconnectedInstallation:    disconnectedInstallation,
+			Proxy:                       proxy,
 		},
 	}
 	s.rulesEngine = engine
@@ -103,5 +106,16 @@ func (s *Server) Start() error {
 		}
 		w.WriteHeader(http.StatusOK)
 	})
+	// Time endpoint
+	mux.HandleFunc(timeEndpoint, func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		err := json.NewEncoder(w).Encode(check.NodeTime{Time: time.Now()})
+		if err != nil {
+			log.Printf("error writing server response: %v\n", err)
+		}
+	})
 	return http.ListenAndServe(fmt.Sprintf(":%d", s.Port), mux)
 }
@@ -17,6 +17,14 @@ type ClusterVersion struct {
 	LatestVersion   semver.Version
 	IsTransitioning bool
 	Nodes           []ListableNode
+	// FailedNodes are the nodes that could not be queried
+	FailedNodes []FailedNode `json:",omitempty"`
+}
+
+// FailedNode is a node that could not be queried, along with the reason
+type FailedNode struct {
+	Node  Node
+	Error string
 }
 
 // ListableNode contains version and role information about a given node
@@ -60,71 +68,98 @@ func IsLessThanVersion(this semver.Version, that string) bool {
 }
 
 // ListVersions connects to the cluster described in the plan file and
-// gathers version information about it.
-func ListVersions(plan *Plan) (ClusterVersion, error) {
-	nodes := plan.GetUniqueNodes()
-	cv := ClusterVersion{
-		Nodes: []ListableNode{},
-	}
+// gathers version information about it. The nodes are queried in parallel,
+// and the nodes that could not be queried are listed in FailedNodes.
+func ListVersions(plan *Plan, opts NodeQueryOptions) ClusterVersion {
+	results := QueryNodes(plan, plan.GetUniqueNodes(), opts, nodeVersions)
+	return clusterVersion(plan, results)
+}
 
-	sshDeets := plan.Cluster.SSH
+// nodeVersions gets the KET and component versions installed on the node
+func nodeVersions(client ssh.Client, node Node) (interface{}, error) {
 	ketVerFile := "/etc/kismatic-version"
 	componentVerFile := "/etc/component-versions"
-	for i, node := range nodes {
-		client, err := ssh.NewClient(node.IP, sshDeets.Port, sshDeets.User, sshDeets.Key)
-		if err != nil {
-			return cv, fmt.Errorf("error creating SSH client: %v", err)
-		}
+	// get KET version
+	ketOutput, err := client.Output(false, fmt.Sprintf("cat %s", ketVerFile))
+	if err != nil {
+		// the output var contains the actual error message from the cat command, which has
+		// more meaningful info
+		return nil, fmt.Errorf("error getting KET version for node %q: %q", node.Host, ketOutput)
+	}
 
-		// get KET version
-		ketOutput, err := client.Output(false, fmt.Sprintf("cat %s", ketVerFile))
-		if err != nil {
-			// the output var contains the actual error message from the cat command, which has
-			// more meaningful info
-			return cv, fmt.Errorf("error getting KET version for node %q: %q", node.Host, ketOutput)
-		}
+	thisVersion, err := parseVersion(ketOutput)
+	if err != nil {
+		return nil, fmt.Errorf("invalid version %q found in version file %q of node %s", ketOutput, ketVerFile, node.Host)
+	}
 
-		thisVersion, err := parseVersion(ketOutput)
+	// get component versions
+	versionsOutput, err := client.Output(false, fmt.Sprintf("cat %s", componentVerFile))
+	// don't fail if the file is not found, will default to empty
+	// TODO remove
+	if err != nil && !strings.Contains(versionsOutput, "No such file or directory") {
+		// the output var contains the actual error message from the cat command, which has
+		// more meaningful info
+		return nil, fmt.Errorf("error getting component versions for node %q: %q", node.Host, versionsOutput)
+	}
+	versions := ComponentVersions{}
+	if !strings.Contains(versionsOutput, "No such file or directory") {
+		err = yaml.Unmarshal([]byte(versionsOutput), &versions)
 		if err != nil {
-			return cv, fmt.Errorf("invalid version %q found in version file %q of node %s", ketOutput, ketVerFile, node.Host)
+			return nil, fmt.Errorf("error unmarshalling component versions file: %q", componentVerFile)
 		}
+	}
+	return nodeVersion{ket: thisVersion, components: versions}, nil
+}
 
-		// get component versions
-		versionsOutput, err := client.Output(false, fmt.Sprintf("cat %s", componentVerFile))
-		// don't fail if the file is not found, will default to empty
-		// TODO remove
-		if err != nil && !strings.Contains(versionsOutput, "No such file or directory") {
-			// the output var contains the actual error message from the cat command, which has
-			// more meaningful info
-			return cv, fmt.Errorf("error getting component versions for node %q: %q", node.Host, versionsOutput)
-		}
-		versions := ComponentVersions{}
-		if !strings.Contains(versionsOutput, "No such file or directory") {
-			err = yaml.Unmarshal([]byte(versionsOutput), &versions)
-			if err != nil {
-				return cv, fmt.Errorf("error unmarshalling component versions file: %q", componentVerFile)
-			}
-		}
+type nodeVersion struct {
+	ket        semver.Version
+	components ComponentVersions
+}
 
-		cv.Nodes = append(cv.Nodes, ListableNode{node, plan.GetRolesForIP(node.IP), thisVersion, versions})
+// clusterVersion builds the cluster's version information from the results of
+// querying the nodes
+func clusterVersion(plan *Plan, results []NodeQueryResult) ClusterVersion {
+	cv := ClusterVersion{
+		Nodes: []ListableNode{},
+	}
+	for _, r := range results {
+		if r.Err != nil {
+			cv.FailedNodes = append(cv.FailedNodes, FailedNode{Node: r.Node, Error: r.Err.Error()})
+			continue
+		}
+		v := r.Output.(nodeVersion)
+		cv.Nodes = append(cv.Nodes, ListableNode{r.Node, plan.GetRolesForIP(r.Node.IP), v.ket, v.components})
 
 		// If looking at the first node, set the versions and move on
-		if i == 0 {
-			cv.EarliestVersion = thisVersion
-			cv.LatestVersion = thisVersion
+		if len(cv.Nodes) == 1 {
+			cv.EarliestVersion = v.ket
+			cv.LatestVersion = v.ket
 			continue
 		}
 
-		if thisVersion.GT(cv.LatestVersion) {
-			cv.LatestVersion = thisVersion
+		if v.ket.GT(cv.LatestVersion) {
+			cv.LatestVersion = v.ket
 		}
-		if cv.EarliestVersion.GT(thisVersion) {
-			cv.EarliestVersion = thisVersion
+		if cv.EarliestVersion.GT(v.ket) {
+			cv.EarliestVersion = v.ket
 		}
 	}
 
 	cv.IsTransitioning = cv.EarliestVersion.NE(cv.LatestVersion)
-	return cv, nil
+	return cv
+}
+
+// FailedNodesErr returns an error that lists the nodes that could not be
+// queried. Returns nil if all nodes were queried successfully.
+func (cv ClusterVersion) FailedNodesErr() error {
+	if len(cv.FailedNodes) == 0 {
+		return nil
+	}
+	msgs := make([]string, 0, len(cv.FailedNodes))
+	for _, n := range cv.FailedNodes {
+		msgs = append(msgs, fmt.Sprintf("%s: %s", n.Node.Host, n.Error))
+	}
+	return fmt.Errorf("failed to get version information from %d node(s):\n%s", len(cv.FailedNodes), strings.Join(msgs, "\n"))
 }
 
 // NodesWithRoles returns a filtered list of ListableNode slice based on the node's roles
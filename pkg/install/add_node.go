@@ -4,29 +4,52 @@ import (
 	"errors"
 	"fmt"
 
+	"github.com/apprenda/kismatic/pkg/tls"
 	"github.com/apprenda/kismatic/pkg/util"
 )
 
 var errMissingClusterCA = errors.New("The Certificate Authority's private key and certificate used to install " +
-	"the cluster are required for adding worker nodes.")
+	"the cluster are required for adding nodes.")
 
-// AddNode adds a worker node to the original cluster described in the plan.
+var errMissingServiceAccountCert = errors.New("The service account signing key and certificate used to install " +
+	"the cluster are required for adding master nodes.")
+
+// AddNode adds a node to the original cluster described in the plan.
 // If successful, the updated plan is returned.
+// When the node is an etcd node, it is added as a member of the existing etcd
+// clusters before it is installed. When the node is an etcd or master node,
+// the API servers of the existing master nodes are updated with the new endpoints.
 func (ae *ansibleExecutor) AddNode(originalPlan *Plan, newNode Node, roles []string, restartServices bool) (*Plan, error) {
-	if err := checkAddNodePrereqs(ae.pki, newNode); err != nil {
+	pki, err := ae.pkiForPlan(originalPlan)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkAddNodePrereqs(pki, newNode, roles, ae.certsDir); err != nil {
 		return nil, err
 	}
 	updatedPlan := AddNodeToPlan(*originalPlan, newNode, roles)
 
 	// Generate node certificates
 	util.PrintHeader(ae.stdout, "Generating Certificate For New Node", '=')
-	ca, err := ae.pki.GetClusterCA()
+	ca, err := pki.GetClusterCA()
 	if err != nil {
 		return nil, err
 	}
-	if err = ae.pki.GenerateNodeCertificate(&updatedPlan, newNode, ca); err != nil {
+	if err = pki.GenerateNodeCertificate(&updatedPlan, newNode, ca); err != nil {
 		return nil, fmt.Errorf("error generating certificate for new node: %v", err)
 	}
+	// The certificates of the existing nodes that change with the new member
+	// are reissued, and deployed before the new endpoints are rolled out
+	reissued, err := certificatesToReissue(*originalPlan, updatedPlan, ca)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range reissued {
+		if _, err := pki.GenerateCertificate(s.filename, updatedPlan.Cluster.Certificates.Expiry, s.commonName, s.subjectAlternateNames, s.organizations, ca, true); err != nil {
+			return nil, fmt.Errorf("error reissuing certificate for %s: %v", s.description, err)
+		}
+		util.PrettyPrintOk(ae.stdout, "Reissued certificate for %s", s.description)
+	}
 
 	// Run the playbook to add the node
 	inventory := buildInventoryFromPlan(&updatedPlan)
@@ -51,13 +74,37 @@ func (ae *ansibleExecutor) AddNode(originalPlan *Plan, newNode Node, roles []str
 		}
 	}
 
+	cc.NewNode = newNode.Host
+	isEtcd := util.Contains("etcd", roles)
+	if isEtcd {
+		util.PrintHeader(ae.stdout, "Adding New Member to Etcd Clusters", '=')
+		t := task{
+			name:           "add-node-etcd-member",
+			playbook:       "etcd-member-add.yaml",
+			plan:           updatedPlan,
+			inventory:      inventory,
+			clusterCatalog: *cc,
+			explainer:      ae.defaultExplainer(),
+		}
+		if err = ae.execute(t); err != nil {
+			return nil, fmt.Errorf("error adding member to etcd clusters: %v", err)
+		}
+		// The new member joins the existing clusters when it starts
+		cc.JoinEtcdCluster = true
+	}
+
 	if restartServices {
 		cc.EnableRestart()
 	}
 	util.PrintHeader(ae.stdout, "Adding New Node to Cluster", '=')
+	// The control plane components are installed on etcd and master nodes
+	playbook := "kubernetes-node.yaml"
+	if isEtcd || util.Contains("master", roles) {
+		playbook = "kubernetes.yaml"
+	}
 	t := task{
 		name:           "add-node",
-		playbook:       "kubernetes-node.yaml",
+		playbook:       playbook,
 		plan:           updatedPlan,
 		inventory:      inventory,
 		clusterCatalog: *cc,
@@ -65,12 +112,43 @@ func (ae *ansibleExecutor) AddNode(originalPlan *Plan, newNode Node, roles []str
 		limit:          []string{newNode.Host},
 	}
 	if err = ae.execute(t); err != nil {
+		if isEtcd {
+			return nil, fmt.Errorf("error running playbook: %v. The node was added as a member of the etcd clusters, "+
+				"and must be installed or removed for the etcd clusters to remain healthy", err)
+		}
 		return nil, fmt.Errorf("error running playbook: %v", err)
 	}
+	cc.JoinEtcdCluster = false
+
+	if len(reissued) > 0 {
+		if err = ae.deployCertificates(&updatedPlan, ae.certsDir, "Deploying Reissued Certificates"); err != nil {
+			return nil, fmt.Errorf("error deploying reissued certificates: %v", err)
+		}
+	}
+
+	// Roll out the new endpoints to the existing control plane
+	if isEtcd || util.Contains("master", roles) {
+		util.PrintHeader(ae.stdout, "Updating Control Plane Endpoints", '=')
+		t = task{
+			name:           "add-node-update-endpoints",
+			playbook:       "control-plane-endpoints.yaml",
+			plan:           updatedPlan,
+			inventory:      inventory,
+			clusterCatalog: *cc,
+			explainer:      ae.defaultExplainer(),
+		}
+		if err = ae.execute(t); err != nil {
+			return nil, fmt.Errorf("error updating control plane endpoints: %v", err)
+		}
+	}
+
+	// etcd-only nodes are not registered with the API server
+	if !util.Contains("master", roles) && !util.Contains("worker", roles) && !util.Contains("ingress", roles) && !util.Contains("storage", roles) {
+		return &updatedPlan, nil
+	}
 
 	// Verify that the node registered with API server
 	util.PrintHeader(ae.stdout, "Running New Node Smoke Test", '=')
-	cc.NewNode = newNode.Host
 	t = task{
 		name:           "add-node-smoke-test",
 		playbook:       "_node-smoke-test.yaml",
@@ -103,6 +181,14 @@ func (ae *ansibleExecutor) AddNode(originalPlan *Plan, newNode Node, roles []str
 }
 
 func AddNodeToPlan(plan Plan, node Node, roles []string) Plan {
+	if util.Contains("etcd", roles) {
+		plan.Etcd.ExpectedCount++
+		plan.Etcd.Nodes = append(plan.Etcd.Nodes, node)
+	}
+	if util.Contains("master", roles) {
+		plan.Master.ExpectedCount++
+		plan.Master.Nodes = append(plan.Master.Nodes, node)
+	}
 	if util.Contains("worker", roles) {
 		plan.Worker.ExpectedCount++
 		plan.Worker.Nodes = append(plan.Worker.Nodes, node)
@@ -119,8 +205,33 @@ func AddNodeToPlan(plan Plan, node Node, roles []string) Plan {
 	return plan
 }
 
+// certificatesToReissue returns the certificates of the existing nodes whose
+// subject or SANs are different once the node has been added to the plan.
+// The certificates of the new node are not included.
+func certificatesToReissue(original Plan, updated Plan, ca *tls.CA) ([]certificateSpec, error) {
+	var reissue []certificateSpec
+	for _, n := range original.GetUniqueNodes() {
+		before, err := n.certSpecs(original, ca)
+		if err != nil {
+			return nil, err
+		}
+		after, err := n.certSpecs(updated, ca)
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range after {
+			for _, old := range before {
+				if old.filename == s.filename && !old.equal(s) && !certSpecInManifest(s, reissue) {
+					reissue = append(reissue, s)
+				}
+			}
+		}
+	}
+	return reissue, nil
+}
+
 // ensure the assumptions we are making are solid
-func checkAddNodePrereqs(pki PKI, newNode Node) error {
+func checkAddNodePrereqs(pki PKI, newNode Node, roles []string, certsDir string) error {
 	// 1. if the node certificate is not there, we need to ensure that
 	// the CA is available for generating the new nodes's cert
 	// don't check for a valid cert here since its already being done in GenerateNodeCertificate()
@@ -137,5 +248,16 @@ func checkAddNodePrereqs(pki PKI, newNode Node) error {
 			return errMissingClusterCA
 		}
 	}
+	// 2. the service account signing key is shared by all master nodes.
+	// Generating a new one would invalidate the existing service account tokens.
+	if util.Contains("master", roles) {
+		exists, err := tls.CertKeyPairExists(serviceAccountCertFilename, certsDir)
+		if err != nil {
+			return fmt.Errorf("error while checking if the service account certificate exists: %v", err)
+		}
+		if !exists {
+			return errMissingServiceAccountCert
+		}
+	}
 	return nil
 }
@@ -4,11 +4,15 @@ import (
 	"errors"
 	"io"
 	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
 	"testing"
 
 	"github.com/apprenda/kismatic/pkg/ansible"
 	"github.com/apprenda/kismatic/pkg/install/explain"
 	"github.com/apprenda/kismatic/pkg/tls"
+	"github.com/apprenda/kismatic/pkg/util"
 )
 
 func mustGetTempDir(t *testing.T) string {
@@ -497,6 +501,145 @@ func TestAddNodeHostsFilesDNSEnabled(t *testing.T) {
 	}
 }
 
+func TestAddMasterNodeServiceAccountCertMissing(t *testing.T) {
+	e := ansibleExecutor{
+		options:             ExecutorOptions{RunsDirectory: mustGetTempDir(t)},
+		stdout:              ioutil.Discard,
+		consoleOutputFormat: ansible.RawFormat,
+		pki: &fakePKI{
+			caExists: true,
+		},
+		runnerExplainerFactory: fakeRunnerExplainer(nil),
+		certsDir:               mustGetTempDir(t),
+	}
+	newPlan, err := e.AddNode(removeNodeTestPlan(), Node{Host: "master03", IP: "10.0.2.2"}, []string{"master"}, false)
+	if newPlan != nil {
+		t.Errorf("add master returned an updated plan")
+	}
+	if err != errMissingServiceAccountCert {
+		t.Errorf("AddNode did not return the expected error. Instead returned: %v", err)
+	}
+}
+
+func TestAddControlPlaneNode(t *testing.T) {
+	tests := []struct {
+		name              string
+		roles             []string
+		expectedPlaybooks []string
+	}{
+		{
+			name:  "etcd node",
+			roles: []string{"etcd"},
+			expectedPlaybooks: []string{
+				"etcd-member-add.yaml",
+				"kubernetes.yaml:new01",
+				"control-plane-endpoints.yaml",
+			},
+		},
+		{
+			name:  "master node",
+			roles: []string{"master"},
+			expectedPlaybooks: []string{
+				"kubernetes.yaml:new01",
+				"control-plane-endpoints.yaml",
+				"_node-smoke-test.yaml:new01",
+			},
+		},
+	}
+	for _, test := range tests {
+		var playbooks []string
+		var catalogs []ansible.ClusterCatalog
+		e, dir := removeNodeTestExecutor(t, &playbooks, &catalogs)
+		defer os.RemoveAll(dir)
+		pki := &fakePKI{caExists: true}
+		e.pki = pki
+		if err := ioutil.WriteFile(filepath.Join(dir, serviceAccountCertFilename+".pem"), []byte{}, 0644); err != nil {
+			t.Fatalf("error writing service account certificate: %v", err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, serviceAccountCertFilename+"-key.pem"), []byte{}, 0644); err != nil {
+			t.Fatalf("error writing service account key: %v", err)
+		}
+		p := removeNodeTestPlan()
+		newNode := Node{Host: "new01", IP: "10.0.2.1"}
+
+		updated, err := e.AddNode(p, newNode, test.roles, false)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", test.name, err)
+		}
+		if !reflect.DeepEqual(playbooks, test.expectedPlaybooks) {
+			t.Errorf("%s: expected playbooks %v, but got %v", test.name, test.expectedPlaybooks, playbooks)
+		}
+		// the certificates of the existing nodes do not name the other members
+		if len(pki.generatedCertificates) != 0 {
+			t.Errorf("%s: expected no certificates to be reissued, but got %v", test.name, pki.generatedCertificates)
+		}
+		for i, cc := range catalogs {
+			if cc.NewNode != newNode.Host {
+				t.Errorf("%s: expected the new node to be %q in %s, but got %q", test.name, newNode.Host, playbooks[i], cc.NewNode)
+			}
+			// only the new etcd member joins the existing cluster
+			joining := util.Contains("etcd", test.roles) && playbooks[i] == "kubernetes.yaml:new01"
+			if cc.JoinEtcdCluster != joining {
+				t.Errorf("%s: expected join_etcd_cluster to be %v in %s", test.name, joining, playbooks[i])
+			}
+		}
+		if roles := updated.GetRolesForIP(newNode.IP); !reflect.DeepEqual(roles, test.roles) {
+			t.Errorf("%s: expected the new node to have roles %v in the updated plan, but got %v", test.name, test.roles, roles)
+		}
+	}
+}
+
+func TestCertificatesToReissue(t *testing.T) {
+	tests := []struct {
+		name     string
+		update   func(p Plan) Plan
+		expected []string
+	}{
+		{
+			name: "new master node",
+			update: func(p Plan) Plan {
+				return AddNodeToPlan(p, Node{Host: "master03", IP: "10.0.2.1"}, []string{"master"})
+			},
+		},
+		{
+			name: "new etcd node",
+			update: func(p Plan) Plan {
+				return AddNodeToPlan(p, Node{Host: "etcd03", IP: "10.0.2.1"}, []string{"etcd"})
+			},
+		},
+		{
+			name: "existing worker becomes a master",
+			update: func(p Plan) Plan {
+				return AddNodeToPlan(p, p.Worker.Nodes[0], []string{"master"})
+			},
+		},
+		{
+			name: "API server SANs change",
+			update: func(p Plan) Plan {
+				p = AddNodeToPlan(p, Node{Host: "master03", IP: "10.0.2.1"}, []string{"master"})
+				p.Master.LoadBalancedFQDN = "lb.example.com"
+				return p
+			},
+			// the new node's certificate is generated, not reissued
+			expected: []string{"master01-apiserver", "master02-apiserver"},
+		},
+	}
+	for _, test := range tests {
+		p := removeNodeTestPlan()
+		reissued, err := certificatesToReissue(*p, test.update(*p), nil)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", test.name, err)
+		}
+		var names []string
+		for _, s := range reissued {
+			names = append(names, s.filename)
+		}
+		if !reflect.DeepEqual(names, test.expected) {
+			t.Errorf("%s: expected certificates %v to be reissued, but got %v", test.name, test.expected, names)
+		}
+	}
+}
+
 //// Fakes for testing
 type fakePKI struct {
 	caExists                    bool
@@ -505,6 +648,7 @@ type fakePKI struct {
 	generateCACalled            bool
 	generateProxyClientCACalled bool
 	generateNodeCertCalled      bool
+	generatedCertificates       []string
 }
 
 func (f *fakePKI) CertificateAuthorityExists() (bool, error)     { return f.caExists, f.err }
@@ -526,7 +670,16 @@ func (f *fakePKI) GenerateProxyClientCA(p *Plan) (*tls.CA, error) {
 func (f *fakePKI) GenerateClusterCertificates(p *Plan, clusterCA *tls.CA, proxyClientCA *tls.CA) error {
 	return f.err
 }
+func (f *fakePKI) RotateClusterCA(p *Plan) (*tls.CA, error)     { return nil, f.err }
+func (f *fakePKI) RotateProxyClientCA(p *Plan) (*tls.CA, error) { return nil, f.err }
+func (f *fakePKI) RotateClusterCertificates(p *Plan, clusterCA *tls.CA, proxyClientCA *tls.CA) error {
+	return f.err
+}
+func (f *fakePKI) ValidateClusterCertificates(p *Plan) (warns []error, errs []error) {
+	return nil, nil
+}
 func (f *fakePKI) GenerateCertificate(name string, validityPeriod string, commonName string, subjectAlternateNames []string, organizations []string, ca *tls.CA, overwrite bool) (bool, error) {
+	f.generatedCertificates = append(f.generatedCertificates, name)
 	return false, f.err
 }
 
@@ -0,0 +1,278 @@
+package install
+
+import (
+	"bytes"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/apprenda/kismatic/pkg/ssh"
+	"github.com/apprenda/kismatic/pkg/tls"
+	"github.com/cloudflare/cfssl/helpers"
+)
+
+// CertificateInfo describes a certificate
+type CertificateInfo struct {
+	// Name of the certificate in the generated keys directory
+	Name string `json:"name"`
+	// Subject is the distinguished name of the certificate's subject
+	Subject string `json:"subject"`
+	// SANs are the subject alternative names of the certificate
+	SANs []string `json:"subjectAlternativeNames"`
+	// Issuer is the distinguished name of the certificate's issuer
+	Issuer string `json:"issuer"`
+	// SerialNumber of the certificate, in hexadecimal
+	SerialNumber string `json:"serialNumber"`
+	// NotAfter is the time at which the certificate expires
+	NotAfter time.Time `json:"notAfter"`
+	// DaysToExpiry is the number of days left before the certificate expires.
+	// It is negative if the certificate has already expired.
+	DaysToExpiry int `json:"daysToExpiry"`
+}
+
+// DeployedCertificateStatus is the result of comparing a certificate deployed
+// on a node against the one in the generated keys directory
+type DeployedCertificateStatus string
+
+const (
+	// DeployedCertificateMatch means that the deployed certificate is the one
+	// in the generated keys directory
+	DeployedCertificateMatch DeployedCertificateStatus = "match"
+	// DeployedCertificateDrift means that the deployed certificate is not the
+	// one in the generated keys directory
+	DeployedCertificateDrift DeployedCertificateStatus = "drift"
+	// DeployedCertificateMissing means that the certificate was not found on the node
+	DeployedCertificateMissing DeployedCertificateStatus = "missing"
+	// DeployedCertificateFailed means that the certificate could not be read,
+	// because the node is unreachable or the certificate could not be parsed
+	DeployedCertificateFailed DeployedCertificateStatus = "failed"
+)
+
+// DeployedCertificate is a certificate that is expected to be deployed on a node
+type DeployedCertificate struct {
+	// Node on which the certificate is deployed
+	Node string `json:"node"`
+	// Name of the certificate in the generated keys directory
+	Name string `json:"name"`
+	// Path of the certificate on the node
+	Path string `json:"path"`
+	// Status of the deployed certificate
+	Status DeployedCertificateStatus `json:"status"`
+	// Certificate is the certificate found on the node. Nil if the certificate
+	// is missing.
+	Certificate *CertificateInfo `json:"certificate,omitempty"`
+	// Error is the reason why the certificate could not be read
+	Error string `json:"error,omitempty"`
+}
+
+// ListCertificates returns the certificates in the generated keys directory,
+// sorted by name. Private keys are ignored.
+func ListCertificates(certsDir string, now time.Time) ([]CertificateInfo, error) {
+	files, err := filepath.Glob(filepath.Join(certsDir, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+	var certs []CertificateInfo
+	for _, f := range files {
+		if strings.HasSuffix(f, "-key.pem") {
+			continue
+		}
+		name := strings.TrimSuffix(filepath.Base(f), ".pem")
+		cert, err := tls.ReadCert(name, certsDir)
+		if err != nil {
+			return nil, fmt.Errorf("error reading certificate %q: %v", name, err)
+		}
+		certs = append(certs, newCertificateInfo(name, cert, now))
+	}
+	sort.Slice(certs, func(i, j int) bool { return certs[i].Name < certs[j].Name })
+	return certs, nil
+}
+
+// CheckDeployedCertificates connects to the nodes over SSH, and compares the
+// certificates deployed on them against the ones in the generated keys directory.
+// The certificates of a node that cannot be reached are reported as failed.
+func CheckDeployedCertificates(p *Plan, certsDir string, now time.Time) ([]DeployedCertificate, error) {
+	// the certificates of a node are read over a single connection
+	clients := map[string]ssh.Client{}
+	defer func() {
+		for _, c := range clients {
+			c.Close()
+		}
+	}()
+	read := func(n Node, path string) ([]byte, bool, error) {
+		client, ok := clients[n.Host]
+		if !ok {
+			var err error
+			if client, err = p.sshClient(n); err != nil {
+				return nil, false, fmt.Errorf("error creating SSH client: %v", err)
+			}
+			clients[n.Host] = client
+		}
+		out, err := client.Output(true, fmt.Sprintf("sudo cat %s", path))
+		if err != nil {
+			if strings.Contains(out, "No such file or directory") {
+				return nil, false, nil
+			}
+			// the output contains the actual error message from the cat command
+			return nil, false, fmt.Errorf("error reading %s on node %q: %q", path, n.Host, out)
+		}
+		return []byte(out), true, nil
+	}
+	return checkDeployedCertificates(p, certsDir, now, read)
+}
+
+// certificateReader reads the certificate file on the node. Returns false if
+// the file does not exist.
+type certificateReader func(n Node, path string) ([]byte, bool, error)
+
+func checkDeployedCertificates(p *Plan, certsDir string, now time.Time, read certificateReader) ([]DeployedCertificate, error) {
+	var deployed []DeployedCertificate
+	for _, n := range p.GetUniqueNodes() {
+		// once a read fails, the node is not tried again
+		var nodeErr error
+		for _, c := range deployedCertificatePaths(p, n) {
+			local, err := tls.ReadCert(c.name, certsDir)
+			if err != nil {
+				return nil, fmt.Errorf("error reading certificate %q: %v", c.name, err)
+			}
+			d := DeployedCertificate{Node: n.Host, Name: c.name, Path: c.path, Status: DeployedCertificateMissing}
+			var b []byte
+			exists := false
+			if nodeErr == nil {
+				b, exists, nodeErr = read(n, c.path)
+			}
+			if nodeErr != nil {
+				d.Status = DeployedCertificateFailed
+				d.Error = nodeErr.Error()
+				deployed = append(deployed, d)
+				continue
+			}
+			if exists {
+				remote, err := helpers.ParseCertificatePEM(bytes.TrimSpace(b))
+				if err != nil {
+					// a CA bundle is left behind if a CA rotation did not complete
+					certs, err := helpers.ParseCertificatesPEM(bytes.TrimSpace(b))
+					if err != nil || len(certs) == 0 {
+						d.Status = DeployedCertificateFailed
+						d.Error = fmt.Sprintf("error parsing certificate: %v", err)
+						deployed = append(deployed, d)
+						continue
+					}
+					remote = certs[0]
+				}
+				info := newCertificateInfo(c.name, remote, now)
+				d.Certificate = &info
+				d.Status = DeployedCertificateDrift
+				if bytes.Equal(remote.Raw, local.Raw) {
+					d.Status = DeployedCertificateMatch
+				}
+			}
+			deployed = append(deployed, d)
+		}
+	}
+	return deployed, nil
+}
+
+type deployedCertificatePath struct {
+	name string
+	path string
+}
+
+// deployedCertificatePaths returns the certificates that are deployed to the
+// node, and where they are deployed. The paths must be kept in sync with the
+// ones defined in the ansible group variables.
+func deployedCertificatePaths(p *Plan, n Node) []deployedCertificatePath {
+	var certs []deployedCertificatePath
+	roles := p.GetRolesForIP(n.IP)
+	has := func(role string) bool {
+		for _, r := range roles {
+			if r == role {
+				return true
+			}
+		}
+		return false
+	}
+	if has("etcd") {
+		etcdDir := "/etc/etcd_k8s"
+		certs = append(certs,
+			deployedCertificatePath{"ca", etcdDir + "/ca.pem"},
+			deployedCertificatePath{n.Host + "-etcd", etcdDir + "/etcd.pem"},
+			deployedCertificatePath{"etcd-client", etcdDir + "/etcd-client.pem"},
+		)
+	}
+	if !has("master") && !has("worker") && !has("ingress") && !has("storage") {
+		return certs
+	}
+	k8sDir := "/etc/kubernetes/pki"
+	certs = append(certs,
+		deployedCertificatePath{"ca", k8sDir + "/ca.pem"},
+		deployedCertificatePath{"admin", k8sDir + "/admin.pem"},
+		deployedCertificatePath{n.Host + "-kubelet", k8sDir + "/kubelet.pem"},
+		deployedCertificatePath{"etcd-client", k8sDir + "/etcd-client.pem"},
+	)
+	if has("master") {
+		certs = append(certs,
+			deployedCertificatePath{"proxy-client-ca", k8sDir + "/proxy-client-ca.pem"},
+			deployedCertificatePath{n.Host + "-apiserver", k8sDir + "/api-server.pem"},
+			deployedCertificatePath{"kube-scheduler", k8sDir + "/scheduler.pem"},
+			deployedCertificatePath{"kube-controller-manager", k8sDir + "/controller-manager.pem"},
+			deployedCertificatePath{"apiserver-kubelet-client", k8sDir + "/apiserver-kubelet-client.pem"},
+			deployedCertificatePath{"proxy-client", k8sDir + "/proxy-client.pem"},
+			deployedCertificatePath{serviceAccountCertFilename, k8sDir + "/service-account.pem"},
+		)
+	}
+	return certs
+}
+
+func newCertificateInfo(name string, cert *x509.Certificate, now time.Time) CertificateInfo {
+	sans := append([]string{}, cert.DNSNames...)
+	for _, ip := range cert.IPAddresses {
+		sans = append(sans, ip.String())
+	}
+	return CertificateInfo{
+		Name:         name,
+		Subject:      distinguishedName(cert.Subject),
+		SANs:         sans,
+		Issuer:       distinguishedName(cert.Issuer),
+		SerialNumber: fmt.Sprintf("%x", cert.SerialNumber),
+		NotAfter:     cert.NotAfter,
+		DaysToExpiry: daysUntil(now, cert.NotAfter),
+	}
+}
+
+// daysUntil returns the number of whole days between now and t, rounded down
+func daysUntil(now time.Time, t time.Time) int {
+	d := t.Sub(now)
+	days := int(d / (24 * time.Hour))
+	if d < 0 && d%(24*time.Hour) != 0 {
+		days--
+	}
+	return days
+}
+
+func distinguishedName(name pkix.Name) string {
+	var parts []string
+	if name.CommonName != "" {
+		parts = append(parts, "CN="+name.CommonName)
+	}
+	for _, o := range name.Organization {
+		parts = append(parts, "O="+o)
+	}
+	for _, ou := range name.OrganizationalUnit {
+		parts = append(parts, "OU="+ou)
+	}
+	for _, l := range name.Locality {
+		parts = append(parts, "L="+l)
+	}
+	for _, st := range name.Province {
+		parts = append(parts, "ST="+st)
+	}
+	for _, c := range name.Country {
+		parts = append(parts, "C="+c)
+	}
+	return strings.Join(parts, ",")
+}
@@ -0,0 +1,202 @@
+package install
+
+import (
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func certificatesTestPlan() *Plan {
+	p := getPlan()
+	p.Etcd.Nodes = []Node{{Host: "etcd01", IP: "10.0.0.1"}}
+	p.Master.Nodes = []Node{{Host: "master01", IP: "10.0.0.2"}}
+	p.Worker.Nodes = []Node{{Host: "worker01", IP: "10.0.0.3"}}
+	p.Ingress.Nodes = nil
+	p.Storage.Nodes = nil
+	return p
+}
+
+func generateTestCertificates(t *testing.T, p *Plan) LocalPKI {
+	pki := getPKI(t)
+	ca, err := pki.GenerateClusterCA(p)
+	if err != nil {
+		t.Fatalf("error generating CA for test: %v", err)
+	}
+	proxyClientCA, err := pki.GenerateProxyClientCA(p)
+	if err != nil {
+		t.Fatalf("error generating proxy-client CA for test: %v", err)
+	}
+	if err = pki.GenerateClusterCertificates(p, ca, proxyClientCA); err != nil {
+		t.Fatalf("error generating cluster certificates: %v", err)
+	}
+	return pki
+}
+
+func TestListCertificates(t *testing.T) {
+	p := certificatesTestPlan()
+	pki := generateTestCertificates(t, p)
+	defer cleanup(pki.GeneratedCertsDirectory, t)
+
+	now := time.Now()
+	certs, err := ListCertificates(pki.GeneratedCertsDirectory, now)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	found := map[string]CertificateInfo{}
+	for i, c := range certs {
+		if i > 0 && certs[i-1].Name >= c.Name {
+			t.Errorf("expected certificates to be sorted by name, but %q is listed before %q", certs[i-1].Name, c.Name)
+		}
+		found[c.Name] = c
+	}
+	for _, name := range []string{"ca", "admin", "etcd01-etcd", "master01-apiserver", "worker01-kubelet"} {
+		if _, ok := found[name]; !ok {
+			t.Errorf("expected certificate %q to be listed", name)
+		}
+	}
+	if _, ok := found["ca-key"]; ok {
+		t.Errorf("private keys should not be listed")
+	}
+	admin := found["admin"]
+	if admin.Subject != "CN=admin,O=system:masters" {
+		t.Errorf("unexpected subject %q", admin.Subject)
+	}
+	if admin.Issuer != found["ca"].Subject {
+		t.Errorf("expected issuer %q, but got %q", found["ca"].Subject, admin.Issuer)
+	}
+	if admin.SerialNumber == "" {
+		t.Errorf("expected a serial number")
+	}
+	// the certificates expire in an hour
+	if admin.DaysToExpiry != 0 {
+		t.Errorf("expected 0 days to expiry, but got %d", admin.DaysToExpiry)
+	}
+	apiServer := found["master01-apiserver"]
+	expectedSANs := map[string]bool{"master01": true, "10.0.0.2": true}
+	for _, san := range apiServer.SANs {
+		delete(expectedSANs, san)
+	}
+	if len(expectedSANs) > 0 {
+		t.Errorf("expected SANs %v to be listed in %v", expectedSANs, apiServer.SANs)
+	}
+}
+
+func TestCheckDeployedCertificates(t *testing.T) {
+	p := certificatesTestPlan()
+	pki := generateTestCertificates(t, p)
+	defer cleanup(pki.GeneratedCertsDirectory, t)
+
+	adminCert, err := ioutil.ReadFile(filepath.Join(pki.GeneratedCertsDirectory, "admin.pem"))
+	if err != nil {
+		t.Fatalf("error reading certificate: %v", err)
+	}
+	read := func(n Node, path string) ([]byte, bool, error) {
+		switch {
+		case n.Host == "worker01" && path == "/etc/kubernetes/pki/kubelet.pem":
+			return nil, false, nil
+		case n.Host == "master01" && path == "/etc/kubernetes/pki/scheduler.pem":
+			return adminCert, true, nil
+		}
+		for _, c := range deployedCertificatePaths(p, n) {
+			if c.path == path {
+				b, err := ioutil.ReadFile(filepath.Join(pki.GeneratedCertsDirectory, c.name+".pem"))
+				return b, err == nil, err
+			}
+		}
+		t.Fatalf("unexpected path %q on node %q", path, n.Host)
+		return nil, false, nil
+	}
+	deployed, err := checkDeployedCertificates(p, pki.GeneratedCertsDirectory, time.Now(), read)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	paths := map[string]int{}
+	for _, d := range deployed {
+		paths[d.Node]++
+		expected := DeployedCertificateMatch
+		switch {
+		case d.Node == "worker01" && d.Name == "worker01-kubelet":
+			expected = DeployedCertificateMissing
+		case d.Node == "master01" && d.Name == "kube-scheduler":
+			expected = DeployedCertificateDrift
+		}
+		if d.Status != expected {
+			t.Errorf("%s on %s: expected status %q, but got %q", d.Name, d.Node, expected, d.Status)
+		}
+		if d.Status == DeployedCertificateMissing && d.Certificate != nil {
+			t.Errorf("%s on %s: expected no certificate for a missing file", d.Name, d.Node)
+		}
+	}
+	expectedPaths := map[string]int{"etcd01": 3, "master01": 11, "worker01": 4}
+	for node, count := range expectedPaths {
+		if paths[node] != count {
+			t.Errorf("expected %d certificates to be checked on %s, but got %d", count, node, paths[node])
+		}
+	}
+}
+
+func TestCheckDeployedCertificatesUnreachableNode(t *testing.T) {
+	p := certificatesTestPlan()
+	pki := generateTestCertificates(t, p)
+	defer cleanup(pki.GeneratedCertsDirectory, t)
+
+	reads := map[string]int{}
+	read := func(n Node, path string) ([]byte, bool, error) {
+		reads[n.Host]++
+		if n.Host == "etcd01" {
+			return nil, false, errors.New("error creating SSH client: connection refused")
+		}
+		for _, c := range deployedCertificatePaths(p, n) {
+			if c.path == path {
+				b, err := ioutil.ReadFile(filepath.Join(pki.GeneratedCertsDirectory, c.name+".pem"))
+				return b, err == nil, err
+			}
+		}
+		return nil, false, nil
+	}
+	deployed, err := checkDeployedCertificates(p, pki.GeneratedCertsDirectory, time.Now(), read)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if reads["etcd01"] != 1 {
+		t.Errorf("expected the unreachable node to be tried once, but got %d reads", reads["etcd01"])
+	}
+	for _, d := range deployed {
+		if d.Node == "etcd01" {
+			if d.Status != DeployedCertificateFailed || d.Error == "" {
+				t.Errorf("%s on %s: expected a failed status with an error, but got %q", d.Name, d.Node, d.Status)
+			}
+			continue
+		}
+		if d.Status != DeployedCertificateMatch {
+			t.Errorf("%s on %s: expected status %q, but got %q", d.Name, d.Node, DeployedCertificateMatch, d.Status)
+		}
+	}
+	if len(deployed) != 18 {
+		t.Errorf("expected the certificates of all nodes to be reported, but got %d", len(deployed))
+	}
+}
+
+func TestDaysUntil(t *testing.T) {
+	now := time.Date(2018, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		t        time.Time
+		expected int
+	}{
+		{now, 0},
+		{now.Add(23 * time.Hour), 0},
+		{now.Add(24 * time.Hour), 1},
+		{now.Add(90*24*time.Hour + time.Minute), 90},
+		{now.Add(-time.Minute), -1},
+		{now.Add(-24 * time.Hour), -1},
+		{now.Add(-25 * time.Hour), -2},
+	}
+	for _, test := range tests {
+		if days := daysUntil(now, test.t); days != test.expected {
+			t.Errorf("expected %d days until %v, but got %d", test.expected, test.t, days)
+		}
+	}
+}
@@ -0,0 +1,302 @@
+package install
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+
+	"github.com/apprenda/kismatic/pkg/ansible"
+	"github.com/apprenda/kismatic/pkg/install/explain"
+	"github.com/apprenda/kismatic/pkg/util"
+	yaml "gopkg.in/yaml.v2"
+)
+
+const (
+	// checkpointFilename is the name of the file in the run directory that
+	// records the plays that completed successfully
+	checkpointFilename = "checkpoint.yaml"
+	// resumePlaybookFilename is the playbook generated in the run directory
+	// for resuming an interrupted installation
+	resumePlaybookFilename = "resume-apply.yaml"
+)
+
+var errNoCheckpoint = errors.New("A checkpoint of a previous installation was not found. " +
+	"Run \"kismatic install apply\" without the --resume flag.")
+
+// checkpoint records the progress of a playbook, so that an interrupted
+// installation can be resumed
+type checkpoint struct {
+	// Playbook that was run
+	Playbook string `yaml:"playbook"`
+	// PlanHash is the hash of the plan that was installed
+	PlanHash string `yaml:"plan_hash"`
+	// Limit is the list of nodes the installation was limited to
+	Limit []string `yaml:"limit,omitempty"`
+	// CompletedPlays are the names of the plays that completed successfully,
+	// in the order they ran
+	CompletedPlays []string `yaml:"completed_plays"`
+	// Finished is true if the playbook completed successfully
+	Finished bool `yaml:"finished"`
+}
+
+// planHash returns the hash of the plan as it is written to the plan file, with
+// the references to secrets in place of the secrets they were resolved to
+func planHash(p *Plan) (string, error) {
+	b, err := yaml.Marshal(p.withSecretRefs())
+	if err != nil {
+		return "", fmt.Errorf("error marshalling plan: %v", err)
+	}
+	return fmt.Sprintf("%x", sha256.Sum256(b)), nil
+}
+
+func newCheckpoint(playbook string, p *Plan, limit []string) (*checkpoint, error) {
+	hash, err := planHash(p)
+	if err != nil {
+		return nil, err
+	}
+	if len(limit) == 0 {
+		limit = nil
+	}
+	return &checkpoint{Playbook: playbook, PlanHash: hash, Limit: limit}, nil
+}
+
+func (c checkpoint) write(runDirectory string) error {
+	b, err := yaml.Marshal(c)
+	if err != nil {
+		return fmt.Errorf("error marshalling checkpoint: %v", err)
+	}
+	return ioutil.WriteFile(filepath.Join(runDirectory, checkpointFilename), b, 0644)
+}
+
+func readCheckpoint(runDirectory string) (*checkpoint, error) {
+	b, err := ioutil.ReadFile(filepath.Join(runDirectory, checkpointFilename))
+	if err != nil {
+		return nil, err
+	}
+	c := &checkpoint{}
+	if err := yaml.Unmarshal(b, c); err != nil {
+		return nil, fmt.Errorf("error unmarshalling checkpoint: %v", err)
+	}
+	return c, nil
+}
+
+// lastCheckpoint returns the checkpoint of the most recent run of the task
+// that has one, or nil if none is found
+func lastCheckpoint(runsDirectory string, taskName string) (*checkpoint, error) {
+	dir := filepath.Join(runsDirectory, taskName)
+	files, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error listing runs in %q: %v", dir, err)
+	}
+	// run directories are named after the time the run started
+	sort.Slice(files, func(i, j int) bool { return files[i].Name() > files[j].Name() })
+	for _, f := range files {
+		if !f.IsDir() {
+			continue
+		}
+		c, err := readCheckpoint(filepath.Join(dir, f.Name()))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error reading checkpoint of run %q: %v", f.Name(), err)
+		}
+		return c, nil
+	}
+	return nil, nil
+}
+
+// checkpointExplainer records the plays that completed successfully in the
+// checkpoint, before passing the event on to the wrapped explainer
+type checkpointExplainer struct {
+	explainer       explain.AnsibleEventExplainer
+	out             io.Writer
+	checkpoint      checkpoint
+	runDirectory    string
+	currentPlay     string
+	playRunning     bool
+	failureOccurred bool
+}
+
+func (e *checkpointExplainer) ExplainEvent(ansibleEvent ansible.Event) {
+	switch event := ansibleEvent.(type) {
+	case *ansible.PlayStartEvent:
+		e.playCompleted()
+		e.currentPlay = event.Name
+		e.playRunning = true
+	case *ansible.PlaybookEndEvent:
+		e.playCompleted()
+		e.checkpoint.Finished = !e.failureOccurred
+		e.write()
+	case *ansible.RunnerFailedEvent:
+		if !event.IgnoreErrors {
+			e.failureOccurred = true
+		}
+	case *ansible.RunnerItemFailedEvent:
+		if !event.IgnoreErrors {
+			e.failureOccurred = true
+		}
+	case *ansible.RunnerUnreachableEvent:
+		e.failureOccurred = true
+	}
+	e.explainer.ExplainEvent(ansibleEvent)
+}
+
+func (e *checkpointExplainer) playCompleted() {
+	if !e.playRunning || e.failureOccurred {
+		return
+	}
+	e.checkpoint.CompletedPlays = append(e.checkpoint.CompletedPlays, e.currentPlay)
+	e.playRunning = false
+	e.write()
+}
+
+func (e *checkpointExplainer) write() {
+	// a missing checkpoint prevents resuming, but must not interrupt the playbook
+	if err := e.checkpoint.write(e.runDirectory); err != nil {
+		util.PrettyPrintWarn(e.out, "Error recording checkpoint in %q: %v", e.runDirectory, err)
+	}
+}
+
+// resumePlaybook returns a playbook that includes the playbooks included by the
+// original playbook, starting with the first one that has plays that did not
+// complete. The included playbooks are resumed as a whole: the plays of a
+// partly completed playbook run again. The includes are absolute, as the
+// playbook is written to the run directory. Returns the number of plays that
+// are skipped by the new playbook, or a nil playbook if all plays completed.
+func resumePlaybook(playbooksDir string, playbook string, completedPlays int) ([]byte, int, error) {
+	playbooksDir, err := filepath.Abs(playbooksDir)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error getting the path of the playbooks: %v", err)
+	}
+	b, err := ioutil.ReadFile(filepath.Join(playbooksDir, playbook))
+	if err != nil {
+		return nil, 0, fmt.Errorf("error reading playbook: %v", err)
+	}
+	var includes []yaml.MapSlice
+	if err := yaml.Unmarshal(b, &includes); err != nil {
+		return nil, 0, fmt.Errorf("error unmarshalling playbook %q: %v", playbook, err)
+	}
+	var skipped int
+	for len(includes) > 0 {
+		included, ok := mapSliceValue(includes[0], "include").(string)
+		if !ok {
+			return nil, 0, fmt.Errorf("playbook %q cannot be resumed, as it contains plays that are not included from other playbooks", playbook)
+		}
+		b, err := ioutil.ReadFile(filepath.Join(playbooksDir, included))
+		if err != nil {
+			return nil, 0, fmt.Errorf("error reading playbook: %v", err)
+		}
+		var plays []interface{}
+		if err := yaml.Unmarshal(b, &plays); err != nil {
+			return nil, 0, fmt.Errorf("error unmarshalling playbook %q: %v", included, err)
+		}
+		// the included playbook is run again if any of its plays did not complete
+		if skipped+len(plays) > completedPlays {
+			break
+		}
+		skipped += len(plays)
+		includes = includes[1:]
+	}
+	if len(includes) == 0 {
+		return nil, skipped, nil
+	}
+	for _, include := range includes {
+		for i := range include {
+			if include[i].Key == "include" {
+				include[i].Value = filepath.Join(playbooksDir, include[i].Value.(string))
+			}
+		}
+	}
+	out, err := yaml.Marshal(includes)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error marshalling resume playbook: %v", err)
+	}
+	return append([]byte("---\n"), out...), skipped, nil
+}
+
+func mapSliceValue(m yaml.MapSlice, key string) interface{} {
+	for _, item := range m {
+		if item.Key == key {
+			return item.Value
+		}
+	}
+	return nil
+}
+
+// ResumeInstall resumes the last installation of the cluster from the first
+// included playbook that has a play that did not complete. The plan and the
+// nodes the installation is limited to must be the same as those of the last
+// installation.
+func (ae *ansibleExecutor) ResumeInstall(p *Plan, restartServices bool, nodes ...string) error {
+	last, err := lastCheckpoint(ae.options.RunsDirectory, "apply")
+	if err != nil {
+		return err
+	}
+	if last == nil {
+		return errNoCheckpoint
+	}
+	hash, err := planHash(p)
+	if err != nil {
+		return err
+	}
+	if hash != last.PlanHash {
+		return errors.New("The plan file has changed since the last installation, and it cannot be resumed. " +
+			"Run \"kismatic install apply\" without the --resume flag.")
+	}
+	if len(nodes) == 0 {
+		nodes = nil
+	}
+	if !reflect.DeepEqual(nodes, last.Limit) {
+		return fmt.Errorf("The last installation was limited to the nodes %v, and must be resumed with the same --limit flag.", last.Limit)
+	}
+	if last.Finished {
+		util.PrettyPrintOk(ae.stdout, "The last installation completed successfully, there is nothing to resume")
+		return nil
+	}
+
+	playbook, skipped, err := resumePlaybook(filepath.Join(ae.ansibleDir, "playbooks"), last.Playbook, len(last.CompletedPlays))
+	if err != nil {
+		return err
+	}
+	if playbook == nil {
+		util.PrettyPrintOk(ae.stdout, "All the plays of the last installation completed, there is nothing to resume")
+		return nil
+	}
+	cc, err := ae.buildClusterCatalog(p)
+	if err != nil {
+		return err
+	}
+	if restartServices {
+		cc.EnableRestart()
+	}
+	// the skipped plays are recorded as completed, in case the installation
+	// is interrupted again
+	cp := *last
+	cp.CompletedPlays = last.CompletedPlays[:skipped]
+	t := task{
+		name:              "apply",
+		playbook:          resumePlaybookFilename,
+		generatedPlaybook: playbook,
+		plan:              *p,
+		inventory:         buildInventoryFromPlan(p),
+		clusterCatalog:    *cc,
+		explainer:         ae.defaultExplainer(),
+		limit:             nodes,
+		checkpoint:        &cp,
+	}
+	util.PrintHeader(ae.stdout, "Resuming Cluster Installation", '=')
+	if skipped > 0 {
+		util.PrettyPrintOk(ae.stdout, "Skipping %d completed plays, up to %q", skipped, last.CompletedPlays[skipped-1])
+	}
+	return ae.execute(t)
+}
@@ -0,0 +1,259 @@
+package install
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/apprenda/kismatic/pkg/ansible"
+	yaml "gopkg.in/yaml.v2"
+)
+
+type nopExplainer struct{}
+
+func (nopExplainer) ExplainEvent(ansible.Event) {}
+
+func playStart(name string) *ansible.PlayStartEvent {
+	e := &ansible.PlayStartEvent{}
+	e.Name = name
+	return e
+}
+
+func TestCheckpointExplainer(t *testing.T) {
+	failed := &ansible.RunnerFailedEvent{}
+	ignored := &ansible.RunnerFailedEvent{}
+	ignored.IgnoreErrors = true
+	tests := []struct {
+		name     string
+		events   []ansible.Event
+		expected checkpoint
+	}{
+		{
+			name:     "all plays complete",
+			events:   []ansible.Event{&ansible.PlaybookStartEvent{}, playStart("A"), playStart("B"), ignored, &ansible.PlaybookEndEvent{}},
+			expected: checkpoint{Playbook: "kubernetes.yaml", CompletedPlays: []string{"A", "B"}, Finished: true},
+		},
+		{
+			name:     "second play fails",
+			events:   []ansible.Event{&ansible.PlaybookStartEvent{}, playStart("A"), playStart("B"), failed, playStart("C"), &ansible.PlaybookEndEvent{}},
+			expected: checkpoint{Playbook: "kubernetes.yaml", CompletedPlays: []string{"A"}},
+		},
+	}
+	for _, test := range tests {
+		dir := mustGetTempDir(t)
+		defer os.RemoveAll(dir)
+		e := &checkpointExplainer{
+			explainer:    nopExplainer{},
+			out:          ioutil.Discard,
+			checkpoint:   checkpoint{Playbook: "kubernetes.yaml"},
+			runDirectory: dir,
+		}
+		for _, ev := range test.events {
+			e.ExplainEvent(ev)
+		}
+		c, err := readCheckpoint(dir)
+		if err != nil {
+			t.Fatalf("%s: error reading checkpoint: %v", test.name, err)
+		}
+		if !reflect.DeepEqual(*c, test.expected) {
+			t.Errorf("%s: expected checkpoint %+v, but got %+v", test.name, test.expected, *c)
+		}
+	}
+}
+
+// writeResumeTestPlaybooks writes a playbook that includes three playbooks,
+// with 1, 2 and 1 plays respectively
+func writeResumeTestPlaybooks(t *testing.T, dir string) {
+	files := map[string]string{
+		"main.yaml": `---
+  - include: _a.yaml
+  - include: _b.yaml
+    when: b_enabled|bool == true
+  - include: _c.yaml
+`,
+		"_a.yaml": "---\n  - hosts: all\n    name: A\n",
+		"_b.yaml": "---\n  - hosts: all\n    name: B1\n  - hosts: all\n    name: B2\n",
+		"_c.yaml": "---\n  - hosts: all\n    name: C\n",
+	}
+	for name, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("error writing playbook: %v", err)
+		}
+	}
+}
+
+func TestResumePlaybook(t *testing.T) {
+	tests := []struct {
+		completedPlays    int
+		expectedSkipped   int
+		expectedRemaining bool
+		expectedIncludes  []string
+	}{
+		{0, 0, true, []string{"_a.yaml", "_b.yaml", "_c.yaml"}},
+		{1, 1, true, []string{"_b.yaml", "_c.yaml"}},
+		// the second playbook is included again, as one of its plays did not complete
+		{2, 1, true, []string{"_b.yaml", "_c.yaml"}},
+		{3, 3, true, []string{"_c.yaml"}},
+		{4, 4, false, nil},
+	}
+	for _, test := range tests {
+		dir := mustGetTempDir(t)
+		defer os.RemoveAll(dir)
+		writeResumeTestPlaybooks(t, dir)
+
+		b, skipped, err := resumePlaybook(dir, "main.yaml", test.completedPlays)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		remaining := b != nil
+		if skipped != test.expectedSkipped || remaining != test.expectedRemaining {
+			t.Errorf("%d completed plays: expected %d skipped plays and remaining %v, but got %d and %v", test.completedPlays, test.expectedSkipped, test.expectedRemaining, skipped, remaining)
+		}
+		if !remaining {
+			continue
+		}
+		// the playbooks directory is left untouched
+		if _, err := os.Stat(filepath.Join(dir, resumePlaybookFilename)); !os.IsNotExist(err) {
+			t.Errorf("expected the resume playbook to not be written to the playbooks directory")
+		}
+		var includes []map[string]string
+		if err := yaml.Unmarshal(b, &includes); err != nil {
+			t.Fatalf("error unmarshalling resume playbook: %v", err)
+		}
+		var got []string
+		for _, i := range includes {
+			if !filepath.IsAbs(i["include"]) || filepath.Dir(i["include"]) != dir {
+				t.Errorf("expected the include %q to be an absolute path to the playbooks directory", i["include"])
+			}
+			got = append(got, filepath.Base(i["include"]))
+		}
+		if !reflect.DeepEqual(got, test.expectedIncludes) {
+			t.Errorf("%d completed plays: expected includes %v, but got %v", test.completedPlays, test.expectedIncludes, got)
+		}
+		// the conditions of the includes are kept
+		for _, i := range includes {
+			if filepath.Base(i["include"]) == "_b.yaml" && i["when"] != "b_enabled|bool == true" {
+				t.Errorf("expected the condition of _b.yaml to be kept, but got %v", i)
+			}
+		}
+	}
+}
+
+func TestResumeInstall(t *testing.T) {
+	p := removeNodeTestPlan()
+	changed := removeNodeTestPlan()
+	changed.Cluster.Networking.ServiceCIDRBlock = "172.30.0.0/16"
+	tests := []struct {
+		name              string
+		plan              *Plan
+		limit             []string
+		checkpoint        *checkpoint
+		expectErr         bool
+		expectedPlaybooks []string
+	}{
+		{
+			name:              "interrupted installation",
+			plan:              p,
+			checkpoint:        &checkpoint{Playbook: "main.yaml", CompletedPlays: []string{"A"}},
+			expectedPlaybooks: []string{resumePlaybookFilename},
+		},
+		{
+			name:       "completed installation",
+			plan:       p,
+			checkpoint: &checkpoint{Playbook: "main.yaml", CompletedPlays: []string{"A", "B1", "B2", "C"}, Finished: true},
+		},
+		{
+			name:      "no checkpoint",
+			plan:      p,
+			expectErr: true,
+		},
+		{
+			name:       "plan changed",
+			plan:       changed,
+			checkpoint: &checkpoint{Playbook: "main.yaml", CompletedPlays: []string{"A"}},
+			expectErr:  true,
+		},
+		{
+			name:       "different limit",
+			plan:       p,
+			limit:      []string{"worker01"},
+			checkpoint: &checkpoint{Playbook: "main.yaml", CompletedPlays: []string{"A"}},
+			expectErr:  true,
+		},
+	}
+	for _, test := range tests {
+		var playbooks []string
+		var catalogs []ansible.ClusterCatalog
+		e, dir := removeNodeTestExecutor(t, &playbooks, &catalogs)
+		defer os.RemoveAll(dir)
+		e.ansibleDir = dir
+		playbooksDir := filepath.Join(dir, "playbooks")
+		if err := os.MkdirAll(playbooksDir, 0700); err != nil {
+			t.Fatalf("error creating playbooks directory: %v", err)
+		}
+		writeResumeTestPlaybooks(t, playbooksDir)
+		if test.checkpoint != nil {
+			cp, err := newCheckpoint(test.checkpoint.Playbook, p, nil)
+			if err != nil {
+				t.Fatalf("error creating checkpoint: %v", err)
+			}
+			cp.CompletedPlays = test.checkpoint.CompletedPlays
+			cp.Finished = test.checkpoint.Finished
+			runDir := filepath.Join(dir, "apply", "2018-01-01-00-00-00")
+			if err := os.MkdirAll(runDir, 0700); err != nil {
+				t.Fatalf("error creating run directory: %v", err)
+			}
+			if err := cp.write(runDir); err != nil {
+				t.Fatalf("error writing checkpoint: %v", err)
+			}
+		}
+
+		err := e.ResumeInstall(test.plan, false, test.limit...)
+		if test.expectErr != (err != nil) {
+			t.Errorf("%s: expected error %v, but got %v", test.name, test.expectErr, err)
+		}
+		// the generated playbook is run from the run directory
+		for i, playbook := range playbooks {
+			if filepath.Dir(filepath.Dir(filepath.Dir(playbook))) != dir {
+				t.Errorf("%s: expected playbook %q to be in a run directory", test.name, playbook)
+			}
+			playbooks[i] = filepath.Base(playbook)
+		}
+		if _, err := os.Stat(filepath.Join(playbooksDir, resumePlaybookFilename)); !os.IsNotExist(err) {
+			t.Errorf("%s: expected the resume playbook to not be written to the playbooks directory", test.name)
+		}
+		if !reflect.DeepEqual(playbooks, test.expectedPlaybooks) {
+			t.Errorf("%s: expected playbooks %v, but got %v", test.name, test.expectedPlaybooks, playbooks)
+		}
+	}
+}
+
+func TestPlanHashWithSecretRefs(t *testing.T) {
+	os.Setenv("KISMATIC_TEST_REGISTRY_PASSWORD", "first")
+	defer os.Unsetenv("KISMATIC_TEST_REGISTRY_PASSWORD")
+	p := removeNodeTestPlan()
+	p.DockerRegistry.Password = "env:KISMATIC_TEST_REGISTRY_PASSWORD"
+	if err := resolveSecrets(p); err != nil {
+		t.Fatalf("error resolving secrets: %v", err)
+	}
+	first, err := planHash(p)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	os.Setenv("KISMATIC_TEST_REGISTRY_PASSWORD", "second")
+	p = removeNodeTestPlan()
+	p.DockerRegistry.Password = "env:KISMATIC_TEST_REGISTRY_PASSWORD"
+	if err := resolveSecrets(p); err != nil {
+		t.Fatalf("error resolving secrets: %v", err)
+	}
+	second, err := planHash(p)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if first != second {
+		t.Errorf("expected the hash of the plan to not depend on the value of its secrets")
+	}
+}
@@ -0,0 +1,259 @@
+package install
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/apprenda/kismatic/pkg/util"
+)
+
+const (
+	etcdSnapshotExtension         = ".db"
+	etcdSnapshotMetadataExtension = ".json"
+)
+
+// The EtcdExecutor takes and restores snapshots of the Kubernetes etcd cluster
+type EtcdExecutor interface {
+	// BackupEtcd takes a snapshot of the etcd cluster, and stores it in the
+	// generated assets directory. Only the latest retain snapshots are kept.
+	// All snapshots are kept if retain is zero.
+	BackupEtcd(plan *Plan, retain int) (*EtcdSnapshot, error)
+	// RestoreEtcd rebuilds the etcd cluster from the given snapshot file
+	RestoreEtcd(plan *Plan, snapshotFile string) error
+}
+
+// EtcdSnapshot describes a snapshot of the etcd cluster that was taken by KET
+type EtcdSnapshot struct {
+	// Name of the snapshot, which is also the base name of its files
+	Name string `json:"name"`
+	// Created is the time at which the snapshot was taken
+	Created time.Time `json:"created"`
+	// SHA256 checksum of the snapshot file
+	SHA256 string `json:"sha256"`
+	// ClusterName is the name of the cluster in the plan file
+	ClusterName string `json:"clusterName"`
+	// KubernetesVersion is the version of the cluster in the plan file
+	KubernetesVersion string `json:"kubernetesVersion"`
+	// Members of the etcd cluster the snapshot was taken from
+	Members []EtcdMember `json:"members"`
+}
+
+// EtcdMember is a member of the etcd cluster
+type EtcdMember struct {
+	Host       string `json:"host"`
+	IP         string `json:"ip"`
+	InternalIP string `json:"internalIP,omitempty"`
+}
+
+// NewEtcdExecutor returns an executor for taking and restoring etcd snapshots
+func NewEtcdExecutor(stdout io.Writer, errOut io.Writer, options ExecutorOptions) (EtcdExecutor, error) {
+	if options.GeneratedAssetsDirectory == "" {
+		return nil, fmt.Errorf("GeneratedAssetsDirectory option cannot be empty")
+	}
+	exec, err := NewExecutor(stdout, errOut, options)
+	if err != nil {
+		return nil, err
+	}
+	return exec.(*ansibleExecutor), nil
+}
+
+// BackupEtcd takes a snapshot of the etcd cluster
+func (ae *ansibleExecutor) BackupEtcd(p *Plan, retain int) (*EtcdSnapshot, error) {
+	if len(p.Etcd.Nodes) == 0 {
+		return nil, errors.New("the plan file does not contain etcd nodes")
+	}
+	backupDir, err := filepath.Abs(etcdBackupDirectory(ae.options.GeneratedAssetsDirectory))
+	if err != nil {
+		return nil, fmt.Errorf("failed to determine absolute path to the etcd backup directory: %v", err)
+	}
+	if err := os.MkdirAll(backupDir, 0700); err != nil {
+		return nil, fmt.Errorf("error creating directory %s for storing etcd snapshots: %v", backupDir, err)
+	}
+	cc, err := ae.buildClusterCatalog(p)
+	if err != nil {
+		return nil, err
+	}
+	created := time.Now().UTC()
+	cc.EtcdSnapshotName = "etcd-snapshot-" + created.Format("2006-01-02-15-04-05")
+	cc.EtcdBackupDirectory = backupDir
+	t := task{
+		name:           "etcd-backup",
+		playbook:       "etcd-backup.yaml",
+		plan:           *p,
+		inventory:      buildInventoryFromPlan(p),
+		clusterCatalog: *cc,
+		explainer:      ae.defaultExplainer(),
+	}
+	util.PrintHeader(ae.stdout, "Backing Up Etcd Cluster", '=')
+	if err := ae.execute(t); err != nil {
+		return nil, err
+	}
+
+	snapshotFile := filepath.Join(backupDir, cc.EtcdSnapshotName+etcdSnapshotExtension)
+	snapshot, err := newEtcdSnapshot(p, cc.EtcdSnapshotName, created, snapshotFile)
+	if err != nil {
+		return nil, err
+	}
+	if err := writeEtcdSnapshotMetadata(snapshot, snapshotFile); err != nil {
+		return nil, err
+	}
+	if retain > 0 {
+		pruned, err := pruneEtcdSnapshots(backupDir, retain)
+		if err != nil {
+			return nil, fmt.Errorf("error pruning old snapshots: %v", err)
+		}
+		for _, name := range pruned {
+			util.PrettyPrintOk(ae.stdout, "Removed old snapshot %q", name)
+		}
+	}
+	return snapshot, nil
+}
+
+// RestoreEtcd rebuilds the etcd cluster from the given snapshot
+func (ae *ansibleExecutor) RestoreEtcd(p *Plan, snapshotFile string) error {
+	absFile, err := filepath.Abs(snapshotFile)
+	if err != nil {
+		return fmt.Errorf("failed to determine absolute path to %s: %v", snapshotFile, err)
+	}
+	// the members are verified against the running cluster by the playbook
+	if _, err := ReadEtcdSnapshot(absFile); err != nil {
+		return err
+	}
+	cc, err := ae.buildClusterCatalog(p)
+	if err != nil {
+		return err
+	}
+	cc.EtcdSnapshotFile = absFile
+	t := task{
+		name:           "etcd-restore",
+		playbook:       "etcd-restore.yaml",
+		plan:           *p,
+		inventory:      buildInventoryFromPlan(p),
+		clusterCatalog: *cc,
+		explainer:      ae.defaultExplainer(),
+	}
+	util.PrintHeader(ae.stdout, "Restoring Etcd Cluster", '=')
+	return ae.execute(t)
+}
+
+// ReadEtcdSnapshot reads the metadata of the snapshot, and verifies the
+// integrity of the snapshot file against the recorded checksum.
+func ReadEtcdSnapshot(snapshotFile string) (*EtcdSnapshot, error) {
+	metadataFile := etcdSnapshotMetadataFile(snapshotFile)
+	b, err := ioutil.ReadFile(metadataFile)
+	if err != nil {
+		return nil, fmt.Errorf("error reading snapshot metadata: %v", err)
+	}
+	snapshot := &EtcdSnapshot{}
+	if err := json.Unmarshal(b, snapshot); err != nil {
+		return nil, fmt.Errorf("error unmarshaling snapshot metadata from %q: %v", metadataFile, err)
+	}
+	sum, err := sha256File(snapshotFile)
+	if err != nil {
+		return nil, fmt.Errorf("error calculating checksum of snapshot: %v", err)
+	}
+	if sum != snapshot.SHA256 {
+		return nil, fmt.Errorf("checksum of %q does not match the one recorded when the snapshot was taken", snapshotFile)
+	}
+	return snapshot, nil
+}
+
+func newEtcdSnapshot(p *Plan, name string, created time.Time, snapshotFile string) (*EtcdSnapshot, error) {
+	sum, err := sha256File(snapshotFile)
+	if err != nil {
+		return nil, fmt.Errorf("error calculating checksum of snapshot: %v", err)
+	}
+	snapshot := &EtcdSnapshot{
+		Name:              name,
+		Created:           created,
+		SHA256:            sum,
+		ClusterName:       p.Cluster.Name,
+		KubernetesVersion: p.Cluster.Version,
+	}
+	for _, n := range p.Etcd.Nodes {
+		snapshot.Members = append(snapshot.Members, EtcdMember{Host: n.Host, IP: n.IP, InternalIP: n.InternalIP})
+	}
+	return snapshot, nil
+}
+
+func writeEtcdSnapshotMetadata(snapshot *EtcdSnapshot, snapshotFile string) error {
+	b, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error marshaling snapshot metadata: %v", err)
+	}
+	metadataFile := etcdSnapshotMetadataFile(snapshotFile)
+	if err := ioutil.WriteFile(metadataFile, b, 0600); err != nil {
+		return fmt.Errorf("error writing snapshot metadata to %q: %v", metadataFile, err)
+	}
+	return nil
+}
+
+// pruneEtcdSnapshots removes the oldest snapshots in the directory, keeping
+// the latest retain snapshots. Returns the names of the removed snapshots.
+func pruneEtcdSnapshots(dir string, retain int) ([]string, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*"+etcdSnapshotMetadataExtension))
+	if err != nil {
+		return nil, err
+	}
+	var snapshots []EtcdSnapshot
+	for _, f := range files {
+		b, err := ioutil.ReadFile(f)
+		if err != nil {
+			return nil, fmt.Errorf("error reading snapshot metadata: %v", err)
+		}
+		var s EtcdSnapshot
+		if err := json.Unmarshal(b, &s); err != nil {
+			return nil, fmt.Errorf("error unmarshaling snapshot metadata from %q: %v", f, err)
+		}
+		snapshots = append(snapshots, s)
+	}
+	if len(snapshots) <= retain {
+		return nil, nil
+	}
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i].Created.After(snapshots[j].Created)
+	})
+	var pruned []string
+	for _, s := range snapshots[retain:] {
+		snapshotFile := filepath.Join(dir, s.Name+etcdSnapshotExtension)
+		if err := os.Remove(snapshotFile); err != nil && !os.IsNotExist(err) {
+			return pruned, err
+		}
+		if err := os.Remove(etcdSnapshotMetadataFile(snapshotFile)); err != nil {
+			return pruned, err
+		}
+		pruned = append(pruned, s.Name)
+	}
+	return pruned, nil
+}
+
+func etcdBackupDirectory(generatedAssetsDir string) string {
+	return filepath.Join(generatedAssetsDir, "etcd-backups")
+}
+
+func etcdSnapshotMetadataFile(snapshotFile string) string {
+	return strings.TrimSuffix(snapshotFile, etcdSnapshotExtension) + etcdSnapshotMetadataExtension
+}
+
+func sha256File(file string) (string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,116 @@
+package install
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func etcdTestPlan() *Plan {
+	return &Plan{
+		Cluster: Cluster{Name: "test", Version: "v1.10.1"},
+		Etcd: NodeGroup{
+			Nodes: []Node{
+				{Host: "etcd01", IP: "10.0.0.1", InternalIP: "192.168.0.1"},
+				{Host: "etcd02", IP: "10.0.0.2"},
+			},
+		},
+	}
+}
+
+func writeTestEtcdSnapshot(t *testing.T, dir string, name string, created time.Time) string {
+	file := filepath.Join(dir, name+etcdSnapshotExtension)
+	if err := ioutil.WriteFile(file, []byte("snapshot "+name), 0600); err != nil {
+		t.Fatalf("error writing snapshot: %v", err)
+	}
+	snapshot, err := newEtcdSnapshot(etcdTestPlan(), name, created, file)
+	if err != nil {
+		t.Fatalf("error creating snapshot metadata: %v", err)
+	}
+	if err := writeEtcdSnapshotMetadata(snapshot, file); err != nil {
+		t.Fatalf("error writing snapshot metadata: %v", err)
+	}
+	return file
+}
+
+func TestReadEtcdSnapshot(t *testing.T) {
+	dir := mustGetTempDir(t)
+	defer os.RemoveAll(dir)
+	created := time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)
+	file := writeTestEtcdSnapshot(t, dir, "snap", created)
+
+	snapshot, err := ReadEtcdSnapshot(file)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if snapshot.Name != "snap" || !snapshot.Created.Equal(created) || snapshot.ClusterName != "test" || snapshot.KubernetesVersion != "v1.10.1" {
+		t.Errorf("unexpected snapshot metadata: %+v", snapshot)
+	}
+	expectedMembers := []EtcdMember{
+		{Host: "etcd01", IP: "10.0.0.1", InternalIP: "192.168.0.1"},
+		{Host: "etcd02", IP: "10.0.0.2"},
+	}
+	if !reflect.DeepEqual(snapshot.Members, expectedMembers) {
+		t.Errorf("expected members %v, but got %v", expectedMembers, snapshot.Members)
+	}
+
+	// corrupt the snapshot
+	if err := ioutil.WriteFile(file, []byte("corrupted"), 0600); err != nil {
+		t.Fatalf("error writing snapshot: %v", err)
+	}
+	if _, err := ReadEtcdSnapshot(file); err == nil {
+		t.Errorf("expected an error when the checksum does not match")
+	}
+}
+
+func TestReadEtcdSnapshotMissingMetadata(t *testing.T) {
+	dir := mustGetTempDir(t)
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "snap.db")
+	if err := ioutil.WriteFile(file, []byte("snapshot"), 0600); err != nil {
+		t.Fatalf("error writing snapshot: %v", err)
+	}
+	if _, err := ReadEtcdSnapshot(file); err == nil {
+		t.Errorf("expected an error when the metadata is missing")
+	}
+}
+
+func TestPruneEtcdSnapshots(t *testing.T) {
+	dir := mustGetTempDir(t)
+	defer os.RemoveAll(dir)
+	base := time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)
+	names := []string{"second", "first", "fourth", "third"}
+	offsets := []int{2, 1, 4, 3}
+	for i, name := range names {
+		writeTestEtcdSnapshot(t, dir, name, base.Add(time.Duration(offsets[i])*time.Hour))
+	}
+
+	pruned, err := pruneEtcdSnapshots(dir, 2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(pruned, []string{"second", "first"}) {
+		t.Errorf("expected the oldest snapshots to be pruned, but got %v", pruned)
+	}
+	for _, name := range []string{"third", "fourth"} {
+		if _, err := os.Stat(filepath.Join(dir, name+etcdSnapshotExtension)); err != nil {
+			t.Errorf("expected snapshot %q to be kept: %v", name, err)
+		}
+	}
+	for _, name := range pruned {
+		if _, err := os.Stat(filepath.Join(dir, name+etcdSnapshotMetadataExtension)); !os.IsNotExist(err) {
+			t.Errorf("expected metadata of snapshot %q to be removed", name)
+		}
+	}
+
+	pruned, err = pruneEtcdSnapshots(dir, 2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(pruned) != 0 {
+		t.Errorf("expected no snapshots to be pruned, but got %v", pruned)
+	}
+}
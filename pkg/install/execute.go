@@ -21,7 +21,7 @@ import (
 // environment defined in the plan file
 type PreFlightExecutor interface {
 	RunPreFlightCheck(plan *Plan, nodes ...string) error
-	RunNewNodePreFlightCheck(Plan, Node) error
+	RunNewNodePreFlightCheck(p Plan, node Node, roles []string) error
 	RunUpgradePreFlightCheck(*Plan, ListableNode) error
 }
 
@@ -29,16 +29,19 @@ type PreFlightExecutor interface {
 type Executor interface {
 	PreFlightExecutor
 	Install(plan *Plan, restartServices bool, nodes ...string) error
+	ResumeInstall(plan *Plan, restartServices bool, nodes ...string) error
 	Reset(plan *Plan, nodes ...string) error
 	GenerateCertificates(p *Plan, useExistingCA bool) error
 	RunSmokeTest(*Plan) error
 	AddNode(plan *Plan, node Node, roles []string, restartServices bool) (*Plan, error)
+	RemoveNode(plan *Plan, node Node) (*Plan, error)
 	RunPlay(name string, plan *Plan, restartServices bool, nodes ...string) error
 	AddVolume(*Plan, StorageVolume) error
 	DeleteVolume(*Plan, string) error
 	UpgradeNodes(plan Plan, nodesToUpgrade []ListableNode, onlineUpgrade bool, maxParallelWorkers int, restartServices bool) error
 	ValidateControlPlane(plan Plan) error
 	UpgradeClusterServices(plan Plan) error
+	RotateCertificates(plan *Plan, rotateCA bool) error
 }
 
 // DiagnosticsExecutor will run diagnostics on the nodes after an install
@@ -75,27 +78,28 @@ func NewExecutor(stdout io.Writer, errOut io.Writer, options ExecutorOptions) (E
 
 	// Setup the console output format
 	var outFormat ansible.OutputFormat
+	var eventsOut io.Writer
 	switch options.OutputFormat {
 	case "raw":
 		outFormat = ansible.RawFormat
 	case "simple":
 		outFormat = ansible.JSONLinesFormat
+	case "json":
+		// stdout is reserved for the event stream
+		outFormat = ansible.JSONLinesFormat
+		eventsOut = stdout
+		stdout = errOut
 	default:
 		return nil, fmt.Errorf("Output format %q is not supported", options.OutputFormat)
 	}
 	certsDir := filepath.Join(options.GeneratedAssetsDirectory, "keys")
-	pki := &LocalPKI{
-		CACsr: filepath.Join(ansibleDir, "playbooks", "tls", "ca-csr.json"),
-		GeneratedCertsDirectory: certsDir,
-		Log: stdout,
-	}
 	return &ansibleExecutor{
 		options:             options,
 		stdout:              stdout,
+		eventsOut:           eventsOut,
 		consoleOutputFormat: outFormat,
 		ansibleDir:          ansibleDir,
 		certsDir:            certsDir,
-		pki:                 pki,
 	}, nil
 }
 
@@ -107,11 +111,17 @@ func NewPreFlightExecutor(stdout io.Writer, errOut io.Writer, options ExecutorOp
 	}
 	// Setup the console output format
 	var outFormat ansible.OutputFormat
+	var eventsOut io.Writer
 	switch options.OutputFormat {
 	case "raw":
 		outFormat = ansible.RawFormat
 	case "simple":
 		outFormat = ansible.JSONLinesFormat
+	case "json":
+		// stdout is reserved for the event stream
+		outFormat = ansible.JSONLinesFormat
+		eventsOut = stdout
+		stdout = errOut
 	default:
 		return nil, fmt.Errorf("Output format %q is not supported", options.OutputFormat)
 	}
@@ -119,6 +129,7 @@ func NewPreFlightExecutor(stdout io.Writer, errOut io.Writer, options ExecutorOp
 	return &ansibleExecutor{
 		options:             options,
 		stdout:              stdout,
+		eventsOut:           eventsOut,
 		consoleOutputFormat: outFormat,
 		ansibleDir:          ansibleDir,
 	}, nil
@@ -158,12 +169,17 @@ func NewDiagnosticsExecutor(stdout io.Writer, errOut io.Writer, options Executor
 }
 
 type ansibleExecutor struct {
-	options             ExecutorOptions
-	stdout              io.Writer
+	options ExecutorOptions
+	stdout  io.Writer
+	// eventsOut receives the machine-readable event stream when the output
+	// format is json. Human-readable output is written to stdout.
+	eventsOut           io.Writer
 	consoleOutputFormat ansible.OutputFormat
 	ansibleDir          string
 	certsDir            string
-	pki                 PKI
+	// pki is the PKI used to issue certificates. When nil, the PKI is
+	// determined by the certificates backend defined in the plan.
+	pki PKI
 
 	// Hook for testing purposes.. default implementation is used at runtime
 	runnerExplainerFactory func(explain.AnsibleEventExplainer, io.Writer) (ansible.Runner, *explain.AnsibleEventStreamExplainer, error)
@@ -184,6 +200,12 @@ type task struct {
 	plan Plan
 	// run the task on specific nodes
 	limit []string
+	// checkpoint records the plays that completed in the run directory,
+	// when set
+	checkpoint *checkpoint
+	// generatedPlaybook is written to the run directory under the playbook
+	// filename and run instead of the playbook of the same name, when set
+	generatedPlaybook []byte
 }
 
 // execute will run the given task, and setup all what's needed for us to run ansible.
@@ -191,15 +213,40 @@ func (ae *ansibleExecutor) execute(t task) error {
 	if ae.options.DryRun {
 		return nil
 	}
+	// Ansible authenticates through the ssh-agent when the keys are encrypted
+	if err := t.plan.Cluster.SSH.prepareKeys(t.plan.GetUniqueNodes()); err != nil {
+		return fmt.Errorf("error preparing SSH keys: %v", err)
+	}
+	start := time.Now()
 	runDirectory, err := ae.createRunDirectory(t.name)
 	if err != nil {
 		return fmt.Errorf("error creating working directory for %q: %v", t.name, err)
 	}
+	// Record the run in the history before it starts, so that interrupted
+	// runs are listed as well
+	record, err := newRunRecord(ae.options.RunsDirectory, runDirectory, t, start)
+	if err != nil {
+		return fmt.Errorf("error creating run record: %v", err)
+	}
+	if err = record.write(runDirectory); err != nil {
+		return fmt.Errorf("error recording run in %q: %v", runDirectory, err)
+	}
+	err = ae.runPlaybook(t, runDirectory)
+	record.finish(time.Now(), err)
+	if werr := record.write(runDirectory); werr != nil {
+		util.PrettyPrintWarn(ae.stdout, "Error recording the outcome of the run in %q: %v", runDirectory, werr)
+	}
+	return err
+}
+
+// runPlaybook runs the playbook of the task, and keeps the plan file and the
+// ansible log in the run directory
+func (ae *ansibleExecutor) runPlaybook(t task, runDirectory string) error {
 	// Save the plan file that was used for this execution
 	fp := FilePlanner{
 		File: filepath.Join(runDirectory, "kismatic-cluster.yaml"),
 	}
-	if err = fp.Write(&t.plan); err != nil {
+	if err := fp.Write(&t.plan); err != nil {
 		return fmt.Errorf("error recording plan file to %s: %v", fp.File, err)
 	}
 	ansibleLogFilename := filepath.Join(runDirectory, "ansible.log")
@@ -207,6 +254,27 @@ func (ae *ansibleExecutor) execute(t task) error {
 	if err != nil {
 		return fmt.Errorf("error creating ansible log file %q: %v", ansibleLogFilename, err)
 	}
+	if t.generatedPlaybook != nil {
+		playbook, err := filepath.Abs(filepath.Join(runDirectory, t.playbook))
+		if err != nil {
+			return fmt.Errorf("error getting the path of playbook %q: %v", t.playbook, err)
+		}
+		if err := ioutil.WriteFile(playbook, t.generatedPlaybook, 0644); err != nil {
+			return fmt.Errorf("error writing playbook %q: %v", playbook, err)
+		}
+		t.playbook = playbook
+	}
+	if ae.eventsOut != nil {
+		t.explainer = explain.JSONLinesExplainer(t.name, ae.eventsOut)
+	}
+	if t.checkpoint != nil {
+		t.explainer = &checkpointExplainer{
+			explainer:    t.explainer,
+			out:          ae.stdout,
+			checkpoint:   *t.checkpoint,
+			runDirectory: runDirectory,
+		}
+	}
 	runner, explainer, err := ae.ansibleRunnerWithExplainer(t.explainer, ansibleLogFile, runDirectory)
 	if err != nil {
 		return err
@@ -233,44 +301,55 @@ func (ae *ansibleExecutor) execute(t task) error {
 	return nil
 }
 
+// pkiForPlan returns the PKI that issues the certificates of the cluster
+func (ae *ansibleExecutor) pkiForPlan(p *Plan) (PKI, error) {
+	if ae.pki != nil {
+		return ae.pki, nil
+	}
+	return NewPKI(p, filepath.Join(ae.ansibleDir, "playbooks", "tls", "ca-csr.json"), ae.certsDir, ae.stdout)
+}
+
 // GenerateCertificatesprivate generates keys and certificates for the cluster, if needed
 func (ae *ansibleExecutor) GenerateCertificates(p *Plan, useExistingCA bool) error {
 	if err := os.MkdirAll(ae.certsDir, 0777); err != nil {
 		return fmt.Errorf("error creating directory %s for storing TLS assets: %v", ae.certsDir, err)
 	}
+	pki, err := ae.pkiForPlan(p)
+	if err != nil {
+		return err
+	}
 
 	// Generate cluster Certificate Authority
 	util.PrintHeader(ae.stdout, "Configuring Certificates", '=')
 
 	var clusterCACert *tls.CA
-	var err error
 	if useExistingCA {
-		exists, err := ae.pki.CertificateAuthorityExists()
+		exists, err := pki.CertificateAuthorityExists()
 		if err != nil {
 			return fmt.Errorf("error checking if CA exists: %v", err)
 		}
 		if !exists {
 			return errors.New("The Certificate Authority is required, but it was not found.")
 		}
-		clusterCACert, err = ae.pki.GetClusterCA()
+		clusterCACert, err = pki.GetClusterCA()
 		if err != nil {
 			return fmt.Errorf("error reading CA certificate: %v", err)
 		}
 
 	} else {
-		clusterCACert, err = ae.pki.GenerateClusterCA(p)
+		clusterCACert, err = pki.GenerateClusterCA(p)
 		if err != nil {
 			return fmt.Errorf("error generating CA for the cluster: %v", err)
 		}
 	}
 
-	proxyClientCACert, err := ae.pki.GenerateProxyClientCA(p)
+	proxyClientCACert, err := pki.GenerateProxyClientCA(p)
 	if err != nil {
 		return fmt.Errorf("error generating CA for the proxy client: %v", err)
 	}
 
 	// Generate node and user certificates
-	err = ae.pki.GenerateClusterCertificates(p, clusterCACert, proxyClientCACert)
+	err = pki.GenerateClusterCertificates(p, clusterCACert, proxyClientCACert)
 	if err != nil {
 		return fmt.Errorf("error generating certificates for the cluster: %v", err)
 	}
@@ -289,6 +368,10 @@ func (ae *ansibleExecutor) Install(p *Plan, restartServices bool, nodes ...strin
 	if restartServices {
 		cc.EnableRestart()
 	}
+	cp, err := newCheckpoint("kubernetes.yaml", p, nodes)
+	if err != nil {
+		return err
+	}
 	t := task{
 		name:           "apply",
 		playbook:       "kubernetes.yaml",
@@ -297,6 +380,7 @@ func (ae *ansibleExecutor) Install(p *Plan, restartServices bool, nodes ...strin
 		clusterCatalog: *cc,
 		explainer:      ae.defaultExplainer(),
 		limit:          nodes,
+		checkpoint:     cp,
 	}
 	util.PrintHeader(ae.stdout, "Installing Cluster", '=')
 	return ae.execute(t)
@@ -356,7 +440,7 @@ func (ae *ansibleExecutor) RunPreFlightCheck(p *Plan, nodes ...string) error {
 }
 
 // RunNewNodePreFlightCheck runs the preflight checks against a new node
-func (ae *ansibleExecutor) RunNewNodePreFlightCheck(p Plan, node Node) error {
+func (ae *ansibleExecutor) RunNewNodePreFlightCheck(p Plan, node Node, roles []string) error {
 	cc, err := ae.buildClusterCatalog(&p)
 	if err != nil {
 		return err
@@ -373,8 +457,7 @@ func (ae *ansibleExecutor) RunNewNodePreFlightCheck(p Plan, node Node) error {
 		return err
 	}
 
-	p.Worker.ExpectedCount++
-	p.Worker.Nodes = append(p.Worker.Nodes, node)
+	p = AddNodeToPlan(p, node, roles)
 	t = task{
 		name:           "add-node-preflight",
 		playbook:       "preflight.yaml",
@@ -839,6 +922,14 @@ func (ae *ansibleExecutor) buildClusterCatalog(p *Plan) (*ansible.ClusterCatalog
 
 	cc.Rescheduler.Enabled = !p.AddOns.Rescheduler.Disable
 
+	// hardware minimums of the pre-flight checks, the inspector defaults are used when unset
+	cc.Preflight.EtcdMinimumCPUs = p.Cluster.Preflight.EtcdMinimumCPUs
+	cc.Preflight.MasterMinimumCPUs = p.Cluster.Preflight.MasterMinimumCPUs
+	cc.Preflight.WorkerMinimumCPUs = p.Cluster.Preflight.WorkerMinimumCPUs
+	cc.Preflight.EtcdMinimumMemoryBytes = p.Cluster.Preflight.EtcdMinimumMemoryBytes
+	cc.Preflight.MasterMinimumMemoryBytes = p.Cluster.Preflight.MasterMinimumMemoryBytes
+	cc.Preflight.WorkerMinimumMemoryBytes = p.Cluster.Preflight.WorkerMinimumMemoryBytes
+
 	// merge node labels
 	// cannot use inventory file because nodes share roles
 	// set it to a map[host][]key=value
@@ -915,6 +1006,10 @@ func (ae *ansibleExecutor) defaultExplainer() explain.AnsibleEventExplainer {
 	case ansible.RawFormat:
 		out = ioutil.Discard
 	}
+	// the JSON lines explainer is used instead when the task is executed
+	if ae.eventsOut != nil {
+		out = ioutil.Discard
+	}
 	return explain.DefaultExplainer(ae.options.Verbose, out)
 }
 
@@ -926,6 +1021,10 @@ func (ae *ansibleExecutor) preflightExplainer() explain.AnsibleEventExplainer {
 	case ansible.RawFormat:
 		out = ioutil.Discard
 	}
+	// the JSON lines explainer is used instead when the task is executed
+	if ae.eventsOut != nil {
+		out = ioutil.Discard
+	}
 	return explain.PreflightExplainer(ae.options.Verbose, out)
 }
 
@@ -985,14 +1084,19 @@ func buildInventoryFromPlan(p *Plan) ansible.Inventory {
 
 // Converts plan node to ansible node
 func installNodeToAnsibleNode(n *Node, s *SSHConfig) ansible.Node {
-	return ansible.Node{
+	sshConfig := s.forNode(*n)
+	node := ansible.Node{
 		Host:          n.Host,
 		PublicIP:      n.IP,
 		InternalIP:    n.InternalIP,
-		SSHPrivateKey: s.Key,
-		SSHUser:       s.User,
-		SSHPort:       s.Port,
+		SSHPrivateKey: sshConfig.Key,
+		SSHUser:       sshConfig.User,
+		SSHPort:       sshConfig.Port,
+	}
+	if b := sshConfig.bastion(); b != nil {
+		node.SSHProxyCommand = b.ProxyCommand()
 	}
+	return node
 }
 
 // Prepend each line of the incoming stream with a timestamp
@@ -0,0 +1,144 @@
+package explain
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/apprenda/kismatic/pkg/ansible"
+)
+
+// Statuses of the events written by the JSON lines explainer
+const (
+	StatusStarted     = "started"
+	StatusFinished    = "finished"
+	StatusOK          = "ok"
+	StatusFailed      = "failed"
+	StatusIgnored     = "ignored"
+	StatusRetrying    = "retrying"
+	StatusSkipped     = "skipped"
+	StatusUnreachable = "unreachable"
+)
+
+// Event is the machine-readable representation of an ansible event
+type Event struct {
+	// Timestamp of the event
+	Timestamp time.Time `json:"timestamp"`
+	// Phase of the operation that is running the playbook, such as "apply" or "add-node"
+	Phase string `json:"phase"`
+	// Type of the ansible event
+	Type string `json:"type"`
+	// Play that is running, if any
+	Play string `json:"play,omitempty"`
+	// Task that is running, if any
+	Task string `json:"task,omitempty"`
+	// Node the event is about, if any
+	Node string `json:"node,omitempty"`
+	// Item of the task the event is about, if any
+	Item string `json:"item,omitempty"`
+	// Status of the phase, play, task or node
+	Status string `json:"status"`
+	// Duration of the task on the node, or of the whole playbook when it
+	// finishes, in seconds
+	Duration float64 `json:"duration,omitempty"`
+	// Error message returned by the node, if the task failed
+	Error string `json:"error,omitempty"`
+	// Stdout captured when the task failed
+	Stdout string `json:"stdout,omitempty"`
+	// Stderr captured when the task failed
+	Stderr string `json:"stderr,omitempty"`
+}
+
+// JSONLinesExplainer returns an explainer that writes one JSON object per
+// event, for consumption by other programs
+func JSONLinesExplainer(phase string, out io.Writer) AnsibleEventExplainer {
+	return &jsonLinesExplainer{
+		phase:   phase,
+		encoder: json.NewEncoder(out),
+		now:     time.Now,
+	}
+}
+
+type jsonLinesExplainer struct {
+	phase         string
+	encoder       *json.Encoder
+	now           func() time.Time
+	currentPlay   string
+	currentTask   string
+	playbookStart time.Time
+	taskStart     time.Time
+}
+
+func (e *jsonLinesExplainer) ExplainEvent(ansibleEvent ansible.Event) {
+	now := e.now()
+	ev := Event{
+		Timestamp: now,
+		Phase:     e.phase,
+		Type:      ansibleEvent.Type(),
+		Play:      e.currentPlay,
+		Task:      e.currentTask,
+	}
+	runnerResult := func(status string, host string) {
+		ev.Status = status
+		ev.Node = host
+		ev.Duration = now.Sub(e.taskStart).Seconds()
+	}
+	switch event := ansibleEvent.(type) {
+	case *ansible.PlaybookStartEvent:
+		e.playbookStart = now
+		ev.Status = StatusStarted
+	case *ansible.PlaybookEndEvent:
+		ev.Status = StatusFinished
+		ev.Duration = now.Sub(e.playbookStart).Seconds()
+	case *ansible.PlayStartEvent:
+		e.currentPlay = event.Name
+		e.currentTask = ""
+		ev.Play = event.Name
+		ev.Task = ""
+		ev.Status = StatusStarted
+	case *ansible.TaskStartEvent:
+		e.currentTask = event.Name
+		e.taskStart = now
+		ev.Task = event.Name
+		ev.Status = StatusStarted
+	case *ansible.HandlerTaskStartEvent:
+		e.currentTask = event.Name
+		e.taskStart = now
+		ev.Task = event.Name
+		ev.Status = StatusStarted
+	case *ansible.RunnerOKEvent:
+		runnerResult(StatusOK, event.Host)
+	case *ansible.RunnerItemOKEvent:
+		runnerResult(StatusOK, event.Host)
+		ev.Item = event.Result.Item
+	case *ansible.RunnerFailedEvent:
+		runnerResult(StatusFailed, event.Host)
+		if event.IgnoreErrors {
+			ev.Status = StatusIgnored
+		}
+		ev.Error = event.Result.Message
+		ev.Stdout = event.Result.Stdout
+		ev.Stderr = event.Result.Stderr
+	case *ansible.RunnerItemFailedEvent:
+		runnerResult(StatusFailed, event.Host)
+		if event.IgnoreErrors {
+			ev.Status = StatusIgnored
+		}
+		ev.Item = event.Result.Item
+		ev.Error = event.Result.Message
+		ev.Stdout = event.Result.Stdout
+		ev.Stderr = event.Result.Stderr
+	case *ansible.RunnerItemRetryEvent:
+		runnerResult(StatusRetrying, event.Host)
+		ev.Item = event.Result.Item
+	case *ansible.RunnerSkippedEvent:
+		runnerResult(StatusSkipped, event.Host)
+	case *ansible.RunnerUnreachableEvent:
+		runnerResult(StatusUnreachable, event.Host)
+		ev.Error = event.Result.Message
+	default:
+		return
+	}
+	// the event stream is best effort, and must not interrupt the playbook
+	e.encoder.Encode(ev)
+}
@@ -0,0 +1,71 @@
+package explain
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/apprenda/kismatic/pkg/ansible"
+)
+
+func TestJSONLinesExplainer(t *testing.T) {
+	out := &bytes.Buffer{}
+	e := JSONLinesExplainer("add-node", out).(*jsonLinesExplainer)
+	start := time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := start
+	e.now = func() time.Time {
+		clock = clock.Add(time.Second)
+		return clock
+	}
+
+	play := &ansible.PlayStartEvent{}
+	play.Name = "Install Kubelet"
+	task := &ansible.TaskStartEvent{}
+	task.Name = "start kubelet service"
+	ok := &ansible.RunnerOKEvent{}
+	ok.Host = "worker01"
+	failed := &ansible.RunnerFailedEvent{}
+	failed.Host = "worker02"
+	failed.Result.Message = "non-zero return code"
+	failed.Result.Stderr = "kubelet failed to start"
+	events := []ansible.Event{
+		&ansible.PlaybookStartEvent{},
+		play,
+		task,
+		ok,
+		failed,
+		&ansible.PlaybookEndEvent{},
+	}
+	for _, ev := range events {
+		e.ExplainEvent(ev)
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != len(events) {
+		t.Fatalf("expected one line per event, but got %d lines:\n%s", len(lines), out.String())
+	}
+	var got []Event
+	for _, l := range lines {
+		var ev Event
+		if err := json.Unmarshal([]byte(l), &ev); err != nil {
+			t.Fatalf("line %q is not a valid JSON event: %v", l, err)
+		}
+		// the timestamps are checked through the durations
+		ev.Timestamp = time.Time{}
+		got = append(got, ev)
+	}
+	expected := []Event{
+		{Phase: "add-node", Type: "Playbook Start", Status: StatusStarted},
+		{Phase: "add-node", Type: "Play Start", Play: "Install Kubelet", Status: StatusStarted},
+		{Phase: "add-node", Type: "Task Start", Play: "Install Kubelet", Task: "start kubelet service", Status: StatusStarted},
+		{Phase: "add-node", Type: "Runner OK", Play: "Install Kubelet", Task: "start kubelet service", Node: "worker01", Status: StatusOK, Duration: 1},
+		{Phase: "add-node", Type: "Runner Failed", Play: "Install Kubelet", Task: "start kubelet service", Node: "worker02", Status: StatusFailed, Duration: 2, Error: "non-zero return code", Stderr: "kubelet failed to start"},
+		{Phase: "add-node", Type: "Playbook End", Play: "Install Kubelet", Task: "start kubelet service", Status: StatusFinished, Duration: 5},
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("unexpected events:\nexpected %+v\ngot      %+v", expected, got)
+	}
+}
@@ -58,6 +58,9 @@ func (exp *updatingPreflightExplainer) ExplainEvent(ansibleEvent ansible.Event)
 			} else if !r.Success {
 				util.PrintColor(buf, util.Red, "   - %s\n", r.Name)
 			}
+			if !r.Success && r.Remediation != "" {
+				util.PrintColor(buf, util.Red, "     %s\n", r.Remediation)
+			}
 		}
 		fmt.Fprintf(exp.out.Bypass(), buf.String())
 		exp.explainer.failureOccurred = true
@@ -87,6 +90,9 @@ func (exp *verbosePreflightExplainer) ExplainEvent(ansibleEvent ansible.Event) {
 			} else if !r.Success {
 				util.PrintColor(exp.out, util.Red, "   - %s\n", r.Name)
 			}
+			if !r.Success && r.Remediation != "" {
+				util.PrintColor(exp.out, util.Red, "     %s\n", r.Remediation)
+			}
 		}
 		util.PrintColor(exp.out, util.Green, "=> Successful pre-flight checks:\n")
 		for _, r := range results {
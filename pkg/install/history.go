@@ -0,0 +1,237 @@
+package install
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/user"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/apprenda/kismatic/pkg/ansible"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// runRecordFilename is the name of the file in the run directory that
+// records who ran the task, against which nodes, and how it ended
+const runRecordFilename = "run.yaml"
+
+// Outcomes of a run
+const (
+	RunOutcomeRunning   = "running"
+	RunOutcomeSucceeded = "succeeded"
+	RunOutcomeFailed    = "failed"
+)
+
+// readOnlyTasks are the tasks that inspect the cluster without changing it
+var readOnlyTasks = map[string]bool{
+	"preflight":              true,
+	"add-node-preflight":     true,
+	"upgrade-preflight":      true,
+	"copy-inspector":         true,
+	"smoketest":              true,
+	"add-node-smoke-test":    true,
+	"validate-control-plane": true,
+	"diagnose":               true,
+	"etcd-backup":            true,
+}
+
+// RunRecord is the entry of the run history that describes a task that was
+// run against the cluster
+type RunRecord struct {
+	// ID of the run, which is the path of the run directory relative to the
+	// runs directory
+	ID string `yaml:"id" json:"id"`
+	// Task that was run, such as "apply" or "add-node"
+	Task string `yaml:"task" json:"task"`
+	// Command line of the kismatic command that ran the task. The values of
+	// the secret fields set with --set are masked.
+	Command string `yaml:"command" json:"command"`
+	// User that ran the command
+	User string `yaml:"user" json:"user"`
+	// PlanHash is the hash of the plan the task was run with
+	PlanHash string `yaml:"plan_hash" json:"planHash"`
+	// KismaticVersion is the version of kismatic that ran the task
+	KismaticVersion string `yaml:"kismatic_version" json:"kismaticVersion"`
+	// Nodes targeted by the task
+	Nodes []string `yaml:"nodes" json:"nodes"`
+	// Start time of the run
+	Start time.Time `yaml:"start" json:"start"`
+	// End time of the run, zero if it is still running or was interrupted
+	End time.Time `yaml:"end,omitempty" json:"end,omitempty"`
+	// Outcome of the run
+	Outcome string `yaml:"outcome" json:"outcome"`
+	// Error returned by the run, if it failed
+	Error string `yaml:"error,omitempty" json:"error,omitempty"`
+	// AnsibleLog is the path to the ansible log of the run
+	AnsibleLog string `yaml:"ansible_log" json:"ansibleLog"`
+}
+
+// ReadOnly returns true if the task inspects the cluster without changing it
+func (r RunRecord) ReadOnly() bool {
+	return readOnlyTasks[r.Task]
+}
+
+// Duration returns how long the run took, or has been running for
+func (r RunRecord) Duration(now time.Time) time.Duration {
+	if r.End.IsZero() {
+		return now.Sub(r.Start)
+	}
+	return r.End.Sub(r.Start)
+}
+
+func newRunRecord(runsDirectory, runDirectory string, t task, start time.Time) (*RunRecord, error) {
+	id, err := filepath.Rel(runsDirectory, runDirectory)
+	if err != nil {
+		return nil, fmt.Errorf("error getting the ID of run %q: %v", runDirectory, err)
+	}
+	hash, err := planHash(&t.plan)
+	if err != nil {
+		return nil, err
+	}
+	nodes := t.limit
+	if len(nodes) == 0 {
+		nodes = inventoryHosts(t.inventory)
+	}
+	return &RunRecord{
+		ID:              filepath.ToSlash(id),
+		Task:            t.name,
+		Command:         strings.Join(maskSecretValues(os.Args), " "),
+		User:            currentUsername(),
+		PlanHash:        hash,
+		KismaticVersion: KismaticVersion.String(),
+		Nodes:           nodes,
+		Start:           start,
+		Outcome:         RunOutcomeRunning,
+		AnsibleLog:      filepath.Join(runDirectory, "ansible.log"),
+	}, nil
+}
+
+// maskedSecretValue replaces the secrets in the recorded command line
+const maskedSecretValue = "********"
+
+// maskSecretValues returns the command line arguments, with the values that
+// --set assigns to the secret fields of the plan masked
+func maskSecretValues(args []string) []string {
+	secrets := secretFields(&Plan{AddOns: AddOns{CNI: &CNI{}}})
+	mask := func(assignment string) string {
+		parts := strings.SplitN(assignment, "=", 2)
+		if len(parts) != 2 {
+			return assignment
+		}
+		path, err := parsePlanPath(parts[0])
+		if err != nil {
+			return assignment
+		}
+		keys := make([]string, len(path))
+		for i, s := range path {
+			keys[i] = s.key
+		}
+		if _, ok := secrets[strings.Join(keys, ".")]; !ok {
+			return assignment
+		}
+		return parts[0] + "=" + maskedSecretValue
+	}
+	masked := make([]string, len(args))
+	for i, arg := range args {
+		switch {
+		case i > 0 && args[i-1] == "--set":
+			masked[i] = mask(arg)
+		case strings.HasPrefix(arg, "--set="):
+			masked[i] = "--set=" + mask(strings.TrimPrefix(arg, "--set="))
+		default:
+			masked[i] = arg
+		}
+	}
+	return masked
+}
+
+// finish records the end of the run, and the error it returned
+func (r *RunRecord) finish(end time.Time, err error) {
+	r.End = end
+	r.Outcome = RunOutcomeSucceeded
+	if err != nil {
+		r.Outcome = RunOutcomeFailed
+		r.Error = err.Error()
+	}
+}
+
+func (r RunRecord) write(runDirectory string) error {
+	b, err := yaml.Marshal(r)
+	if err != nil {
+		return fmt.Errorf("error marshalling run record: %v", err)
+	}
+	return ioutil.WriteFile(filepath.Join(runDirectory, runRecordFilename), b, 0644)
+}
+
+func readRunRecord(runDirectory string) (*RunRecord, error) {
+	b, err := ioutil.ReadFile(filepath.Join(runDirectory, runRecordFilename))
+	if err != nil {
+		return nil, err
+	}
+	r := &RunRecord{}
+	if err := yaml.Unmarshal(b, r); err != nil {
+		return nil, fmt.Errorf("error unmarshalling run record: %v", err)
+	}
+	return r, nil
+}
+
+func currentUsername() string {
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		return u.Username
+	}
+	return os.Getenv("USER")
+}
+
+func inventoryHosts(inv ansible.Inventory) []string {
+	seen := map[string]bool{}
+	hosts := []string{}
+	for _, r := range inv.Roles {
+		for _, n := range r.Nodes {
+			if !seen[n.Host] {
+				seen[n.Host] = true
+				hosts = append(hosts, n.Host)
+			}
+		}
+	}
+	return hosts
+}
+
+// ListRuns returns the run history recorded in the runs directory, with the
+// most recent run first. Runs that were made by versions of kismatic that did
+// not record the history are not returned.
+func ListRuns(runsDirectory string) ([]RunRecord, error) {
+	matches, err := filepath.Glob(filepath.Join(runsDirectory, "*", "*", runRecordFilename))
+	if err != nil {
+		return nil, fmt.Errorf("error listing runs in %q: %v", runsDirectory, err)
+	}
+	runs := []RunRecord{}
+	for _, m := range matches {
+		r, err := readRunRecord(filepath.Dir(m))
+		if err != nil {
+			return nil, fmt.Errorf("error reading run record %q: %v", m, err)
+		}
+		runs = append(runs, *r)
+	}
+	sort.SliceStable(runs, func(i, j int) bool { return runs[i].Start.After(runs[j].Start) })
+	return runs, nil
+}
+
+// GetRun returns the run with the given ID from the runs directory
+func GetRun(runsDirectory string, id string) (*RunRecord, error) {
+	// IDs are relative to the runs directory
+	clean := filepath.Clean(filepath.FromSlash(id))
+	if filepath.IsAbs(clean) || clean == "." || strings.HasPrefix(clean, "..") {
+		return nil, fmt.Errorf("%q is not a valid run ID", id)
+	}
+	r, err := readRunRecord(filepath.Join(runsDirectory, clean))
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("run %q was not found in %q", id, runsDirectory)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading run %q: %v", id, err)
+	}
+	return r, nil
+}
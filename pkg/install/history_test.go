@@ -0,0 +1,113 @@
+package install
+
+import (
+	"errors"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/apprenda/kismatic/pkg/ansible"
+)
+
+func TestExecuteRecordsRunHistory(t *testing.T) {
+	var playbooks []string
+	var catalogs []ansible.ClusterCatalog
+	e, dir := removeNodeTestExecutor(t, &playbooks, &catalogs)
+	defer os.RemoveAll(dir)
+	p := removeNodeTestPlan()
+
+	applyTask := task{
+		name:      "apply",
+		playbook:  "kubernetes.yaml",
+		plan:      *p,
+		inventory: buildInventoryFromPlan(p),
+		explainer: nopExplainer{},
+		limit:     []string{"worker01"},
+	}
+	if err := e.execute(applyTask); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	e.runnerExplainerFactory = fakeRunnerExplainer(errors.New("exit status 2"))
+	resetTask := task{
+		name:      "reset",
+		playbook:  "reset.yaml",
+		plan:      *p,
+		inventory: buildInventoryFromPlan(p),
+		explainer: nopExplainer{},
+	}
+	if err := e.execute(resetTask); err == nil {
+		t.Fatalf("expected an error, but didn't get one")
+	}
+
+	runs, err := ListRuns(dir)
+	if err != nil {
+		t.Fatalf("unexpected error listing runs: %v", err)
+	}
+	if len(runs) != 2 {
+		t.Fatalf("expected 2 runs, but got %d", len(runs))
+	}
+	reset, apply := runs[0], runs[1]
+	if reset.Task != "reset" || apply.Task != "apply" {
+		t.Fatalf("expected the most recent run first, but got %q and %q", reset.Task, apply.Task)
+	}
+	hash, err := planHash(p)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if apply.PlanHash != hash {
+		t.Errorf("expected plan hash %q, but got %q", hash, apply.PlanHash)
+	}
+	if apply.Outcome != RunOutcomeSucceeded || apply.Error != "" || apply.End.IsZero() {
+		t.Errorf("expected the apply run to succeed, but got %+v", apply)
+	}
+	if !reflect.DeepEqual(apply.Nodes, []string{"worker01"}) {
+		t.Errorf("expected the apply run to target the nodes it was limited to, but got %v", apply.Nodes)
+	}
+	if !strings.HasPrefix(apply.ID, "apply/") || !strings.HasSuffix(apply.AnsibleLog, "ansible.log") {
+		t.Errorf("unexpected ID %q or ansible log %q", apply.ID, apply.AnsibleLog)
+	}
+	if reset.Outcome != RunOutcomeFailed || !strings.Contains(reset.Error, "exit status 2") {
+		t.Errorf("expected the reset run to fail, but got %+v", reset)
+	}
+	expectedNodes := []string{"etcd01", "etcd02", "master01", "master02", "worker01", "worker02"}
+	for _, n := range expectedNodes {
+		if !contains(n, reset.Nodes) {
+			t.Errorf("expected the reset run to target all the nodes %v, but got %v", expectedNodes, reset.Nodes)
+			break
+		}
+	}
+
+	got, err := GetRun(dir, apply.ID)
+	if err != nil {
+		t.Fatalf("unexpected error getting run %q: %v", apply.ID, err)
+	}
+	if got.ID != apply.ID || got.Task != "apply" {
+		t.Errorf("expected run %q, but got %+v", apply.ID, got)
+	}
+	for _, id := range []string{"apply/2000-01-01-00-00-00", "../apply", ""} {
+		if _, err := GetRun(dir, id); err == nil {
+			t.Errorf("expected an error getting run %q, but didn't get one", id)
+		}
+	}
+}
+
+func TestMaskSecretValues(t *testing.T) {
+	args := []string{
+		"kismatic", "install", "apply",
+		"--set", "cluster.admin_password=hunter2",
+		"--set=docker_registry.password=hunter2",
+		"--set", "cluster.name=production",
+		"--set", `add_ons.cni.options.weave."password"=hunter2`,
+	}
+	expected := []string{
+		"kismatic", "install", "apply",
+		"--set", "cluster.admin_password=********",
+		"--set=docker_registry.password=********",
+		"--set", "cluster.name=production",
+		"--set", `add_ons.cni.options.weave."password"=********`,
+	}
+	if masked := maskSecretValues(args); !reflect.DeepEqual(masked, expected) {
+		t.Errorf("expected %v, but got %v", expected, masked)
+	}
+}
@@ -0,0 +1,126 @@
+package install
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/apprenda/kismatic/pkg/ssh"
+)
+
+const (
+	// DefaultNodeQueryConcurrency is the default number of nodes that are queried in parallel
+	DefaultNodeQueryConcurrency = 10
+	// DefaultNodeQueryTimeout is the default time allowed for querying a single node
+	DefaultNodeQueryTimeout = 30 * time.Second
+)
+
+// NodeQueryOptions control how the nodes of a cluster are queried
+type NodeQueryOptions struct {
+	// Concurrency is the maximum number of nodes that are queried in parallel
+	Concurrency int
+	// Timeout is the time allowed for connecting to and querying a single node
+	Timeout time.Duration
+}
+
+func (o NodeQueryOptions) withDefaults() NodeQueryOptions {
+	if o.Concurrency < 1 {
+		o.Concurrency = DefaultNodeQueryConcurrency
+	}
+	if o.Timeout <= 0 {
+		o.Timeout = DefaultNodeQueryTimeout
+	}
+	return o
+}
+
+// NodeQuery gathers information from a node using the given SSH client
+type NodeQuery func(client ssh.Client, node Node) (interface{}, error)
+
+// NodeQueryResult is the result of querying a single node. Err is set if the
+// query failed on the node.
+type NodeQueryResult struct {
+	Node   Node
+	Output interface{}
+	Err    error
+}
+
+// QueryNodes runs the query against the nodes over SSH, in parallel. A failure on
+// one node does not stop the query on other nodes. The results are returned in the
+// same order as the nodes.
+func QueryNodes(plan *Plan, nodes []Node, opts NodeQueryOptions, query NodeQuery) []NodeQueryResult {
+	q := nodeQuerier{client: plan.sshClient, after: time.After}
+	return q.queryNodes(nodes, opts, query)
+}
+
+type nodeQuerier struct {
+	client func(Node) (ssh.Client, error)
+	after  func(time.Duration) <-chan time.Time
+}
+
+func (q nodeQuerier) queryNodes(nodes []Node, opts NodeQueryOptions, query NodeQuery) []NodeQueryResult {
+	opts = opts.withDefaults()
+	results := make([]NodeQueryResult, len(nodes))
+	// limits the number of nodes that are queried at the same time
+	sem := make(chan struct{}, opts.Concurrency)
+	var wg sync.WaitGroup
+	wg.Add(len(nodes))
+	for i, node := range nodes {
+		sem <- struct{}{}
+		go func(i int, node Node) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			out, err := q.queryNodeWithTimeout(node, opts.Timeout, query)
+			results[i] = NodeQueryResult{Node: node, Output: out, Err: err}
+		}(i, node)
+	}
+	wg.Wait()
+	return results
+}
+
+// queryNodeWithTimeout runs the query against the node. If the query does not
+// complete in time, the SSH client is cancelled, and the function returns once
+// the query has stopped, so that no work is left running on the node.
+func (q nodeQuerier) queryNodeWithTimeout(node Node, timeout time.Duration, query NodeQuery) (interface{}, error) {
+	type result struct {
+		out interface{}
+		err error
+	}
+	var mu sync.Mutex
+	var client ssh.Client
+	var timedOut bool
+	// cancel the client if it supports it. Otherwise, the query is left to
+	// run to completion.
+	cancel := func() {
+		if c, ok := client.(ssh.Canceler); ok {
+			c.Cancel()
+		}
+	}
+	done := make(chan result, 1)
+	go func() {
+		c, err := q.client(node)
+		if err != nil {
+			done <- result{err: fmt.Errorf("error creating SSH client: %v", err)}
+			return
+		}
+		mu.Lock()
+		client = c
+		if timedOut {
+			cancel()
+		}
+		mu.Unlock()
+		out, err := query(c, node)
+		c.Close()
+		done <- result{out: out, err: err}
+	}()
+	select {
+	case r := <-done:
+		return r.out, r.err
+	case <-q.after(timeout):
+		mu.Lock()
+		timedOut = true
+		cancel()
+		mu.Unlock()
+		<-done
+		return nil, fmt.Errorf("timed out after %v", timeout)
+	}
+}
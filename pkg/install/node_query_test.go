@@ -0,0 +1,181 @@
+package install
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/apprenda/kismatic/pkg/ssh"
+)
+
+type fakeSSHClient struct {
+	outputs map[string]string
+	err     error
+}
+
+func (c fakeSSHClient) Output(pty bool, args ...string) (string, error) {
+	out, ok := c.outputs[strings.Join(args, " ")]
+	if !ok {
+		return "cat: No such file or directory", errors.New("exit status 1")
+	}
+	return out, c.err
+}
+
+func (c fakeSSHClient) Shell(pty bool, args ...string) error { return c.err }
+
+func (c fakeSSHClient) Close() error { return nil }
+
+func nodeQueryTestNodes(count int) []Node {
+	nodes := make([]Node, count)
+	for i := range nodes {
+		nodes[i] = Node{Host: fmt.Sprintf("node%02d", i), IP: fmt.Sprintf("10.0.0.%d", i)}
+	}
+	return nodes
+}
+
+// cancellableSSHClient is a fake client that records its cancellation
+type cancellableSSHClient struct {
+	fakeSSHClient
+	cancelled chan struct{}
+}
+
+func (c cancellableSSHClient) Cancel() { close(c.cancelled) }
+
+func TestQueryNodesConcurrency(t *testing.T) {
+	nodes := nodeQueryTestNodes(20)
+	var mu sync.Mutex
+	var running, maxRunning int
+	started := make(chan struct{})
+	release := make(chan struct{})
+	query := func(c ssh.Client, n Node) (interface{}, error) {
+		mu.Lock()
+		running++
+		if running > maxRunning {
+			maxRunning = running
+		}
+		mu.Unlock()
+		started <- struct{}{}
+		<-release
+		mu.Lock()
+		running--
+		mu.Unlock()
+		return n.Host, nil
+	}
+	q := nodeQuerier{
+		client: func(Node) (ssh.Client, error) { return fakeSSHClient{}, nil },
+		after:  time.After,
+	}
+
+	var results []NodeQueryResult
+	finished := make(chan struct{})
+	go func() {
+		results = q.queryNodes(nodes, NodeQueryOptions{Concurrency: 3}, query)
+		close(finished)
+	}()
+	// wait for the first 3 queries to start, and then release them one at a time.
+	// a new query can only start once a running one has been released.
+	for i := 0; i < 3; i++ {
+		<-started
+	}
+	for i := 3; i < len(nodes); i++ {
+		release <- struct{}{}
+		<-started
+	}
+	for i := 0; i < 3; i++ {
+		release <- struct{}{}
+	}
+	<-finished
+
+	if maxRunning != 3 {
+		t.Errorf("expected 3 nodes to be queried in parallel, but got %d", maxRunning)
+	}
+	// the results are in the same order as the nodes
+	for i, r := range results {
+		if r.Err != nil {
+			t.Errorf("unexpected error for node %q: %v", r.Node.Host, r.Err)
+		}
+		if r.Output != nodes[i].Host {
+			t.Errorf("expected the result of node %q at index %d, but got %v", nodes[i].Host, i, r.Output)
+		}
+	}
+}
+
+func TestQueryNodesPartialFailure(t *testing.T) {
+	nodes := nodeQueryTestNodes(4)
+	// the timeout expires once node03 is running its query
+	expired := make(chan time.Time)
+	query := func(c ssh.Client, n Node) (interface{}, error) {
+		switch n.Host {
+		case "node01":
+			return nil, errors.New("query failed")
+		case "node03":
+			close(expired)
+			// blocks until the client is cancelled
+			<-c.(cancellableSSHClient).cancelled
+			return nil, errors.New("session closed")
+		}
+		return n.Host, nil
+	}
+	q := nodeQuerier{
+		client: func(n Node) (ssh.Client, error) {
+			switch n.Host {
+			case "node02":
+				return nil, errors.New("connection refused")
+			case "node03":
+				return cancellableSSHClient{cancelled: make(chan struct{})}, nil
+			}
+			return fakeSSHClient{}, nil
+		},
+		after: func(time.Duration) <-chan time.Time { return expired },
+	}
+
+	// query one node at a time, so that the timeout only applies to node03
+	results := q.queryNodes(nodes, NodeQueryOptions{Concurrency: 1}, query)
+	if results[0].Err != nil || results[0].Output != "node00" {
+		t.Errorf("expected node00 to be queried successfully, but got %+v", results[0])
+	}
+	for i, expected := range map[int]string{1: "query failed", 2: "connection refused", 3: "timed out"} {
+		if results[i].Err == nil || !strings.Contains(results[i].Err.Error(), expected) {
+			t.Errorf("expected %q error for %s, but got %v", expected, results[i].Node.Host, results[i].Err)
+		}
+	}
+}
+
+func TestClusterVersionWithFailedNodes(t *testing.T) {
+	plan := &Plan{}
+	nodes := nodeQueryTestNodes(3)
+	plan.Worker.Nodes = nodes
+	client := func(n Node) (ssh.Client, error) {
+		switch n.Host {
+		case "node00":
+			return fakeSSHClient{outputs: map[string]string{"cat /etc/kismatic-version": "v1.9.0"}}, nil
+		case "node01":
+			return fakeSSHClient{outputs: map[string]string{
+				"cat /etc/kismatic-version":   "v1.10.0",
+				"cat /etc/component-versions": "kubernetes: v1.10.1",
+			}}, nil
+		}
+		return nil, errors.New("connection refused")
+	}
+
+	q := nodeQuerier{client: client, after: time.After}
+	cv := clusterVersion(plan, q.queryNodes(nodes, NodeQueryOptions{}, nodeVersions))
+	if len(cv.Nodes) != 2 {
+		t.Fatalf("expected 2 nodes, but got %+v", cv.Nodes)
+	}
+	if cv.Nodes[1].ComponentVersions.Kubernetes != "v1.10.1" {
+		t.Errorf("expected kubernetes version v1.10.1, but got %q", cv.Nodes[1].ComponentVersions.Kubernetes)
+	}
+	if cv.EarliestVersion.String() != "1.9.0" || cv.LatestVersion.String() != "1.10.0" || !cv.IsTransitioning {
+		t.Errorf("unexpected cluster version: earliest %v, latest %v, transitioning %v", cv.EarliestVersion, cv.LatestVersion, cv.IsTransitioning)
+	}
+	if len(cv.FailedNodes) != 1 || cv.FailedNodes[0].Node.Host != "node02" {
+		t.Errorf("expected node02 to fail, but got %+v", cv.FailedNodes)
+	}
+	if err := cv.FailedNodesErr(); err == nil || !strings.Contains(err.Error(), "node02") {
+		t.Errorf("expected an error listing node02, but got %v", err)
+	}
+}
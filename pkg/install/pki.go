@@ -43,6 +43,10 @@ type PKI interface {
 	GenerateProxyClientCA(p *Plan) (*tls.CA, error)
 	GetProxyClientCA() (*tls.CA, error)
 	GenerateClusterCertificates(p *Plan, clusterCA *tls.CA, proxyClientCA *tls.CA) error
+	RotateClusterCA(p *Plan) (*tls.CA, error)
+	RotateProxyClientCA(p *Plan) (*tls.CA, error)
+	RotateClusterCertificates(p *Plan, clusterCA *tls.CA, proxyClientCA *tls.CA) error
+	ValidateClusterCertificates(p *Plan) (warns []error, errs []error)
 	NodeCertificateExists(node Node) (bool, error)
 	GenerateNodeCertificate(plan *Plan, node Node, ca *tls.CA) error
 	GenerateCertificate(name string, validityPeriod string, commonName string, subjectAlternateNames []string, organizations []string, ca *tls.CA, overwrite bool) (bool, error)
@@ -82,11 +86,9 @@ func (s certificateSpec) equal(other certificateSpec) bool {
 	sort.Strings(thisSAN)
 	sort.Strings(otherSAN)
 
-	for _, x := range thisSAN {
-		for _, y := range otherSAN {
-			if x != y {
-				return false
-			}
+	for i := range thisSAN {
+		if thisSAN[i] != otherSAN[i] {
+			return false
 		}
 	}
 	// Compare organizations
@@ -98,11 +100,9 @@ func (s certificateSpec) equal(other certificateSpec) bool {
 	sort.Strings(thisOrgs)
 	sort.Strings(otherOrgs)
 
-	for _, x := range thisOrgs {
-		for _, y := range otherOrgs {
-			if x != y {
-				return false
-			}
+	for i := range thisOrgs {
+		if thisOrgs[i] != otherOrgs[i] {
+			return false
 		}
 	}
 	return true
@@ -243,6 +243,68 @@ func (lp *LocalPKI) GenerateClusterCertificates(p *Plan, clusterCA *tls.CA, prox
 	return nil
 }
 
+// RotateClusterCA replaces the cluster CA with a new one
+func (lp *LocalPKI) RotateClusterCA(p *Plan) (*tls.CA, error) {
+	util.PrettyPrintOk(lp.Log, "Generating new cluster Certificate Authority")
+	key, cert, err := tls.NewCACert(lp.CACsr, p.Cluster.Name, p.Cluster.Certificates.CAExpiry)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create CA Cert: %v", err)
+	}
+	if err = tls.WriteCert(key, cert, "ca", lp.GeneratedCertsDirectory); err != nil {
+		return nil, fmt.Errorf("error writing CA files: %v", err)
+	}
+	return &tls.CA{
+		Cert: cert,
+		Key:  key,
+	}, nil
+}
+
+// RotateProxyClientCA replaces the proxy-client CA with a new one
+func (lp *LocalPKI) RotateProxyClientCA(p *Plan) (*tls.CA, error) {
+	util.PrettyPrintOk(lp.Log, "Generating new proxy-client Certificate Authority")
+	key, cert, err := tls.NewCACert(lp.CACsr, proxyClientCACommonName, p.Cluster.Certificates.CAExpiry)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create proxy-client CA Cert: %v", err)
+	}
+	if err = tls.WriteCert(key, cert, "proxy-client-ca", lp.GeneratedCertsDirectory); err != nil {
+		return nil, fmt.Errorf("error writing proxy-client CA files: %v", err)
+	}
+	return &tls.CA{
+		Cert: cert,
+		Key:  key,
+	}, nil
+}
+
+// RotateClusterCertificates reissues all certificates required for the cluster
+// described in the plan file, replacing the existing ones. The service account
+// signing key is kept, as replacing it would invalidate all service account tokens.
+func (lp *LocalPKI) RotateClusterCertificates(p *Plan, clusterCA *tls.CA, proxyClientCA *tls.CA) error {
+	if lp.Log == nil {
+		lp.Log = ioutil.Discard
+	}
+	manifest, err := p.certSpecs(clusterCA, proxyClientCA)
+	if err != nil {
+		return err
+	}
+	for _, s := range manifest {
+		if s.filename == serviceAccountCertFilename {
+			exists, err := tls.CertKeyPairExists(s.filename, lp.GeneratedCertsDirectory)
+			if err != nil {
+				return err
+			}
+			if exists {
+				util.PrettyPrintSkipped(lp.Log, "Keeping existing key for %s", s.description)
+				continue
+			}
+		}
+		if err := generateCert(lp.GeneratedCertsDirectory, s, p.Cluster.Certificates.Expiry); err != nil {
+			return err
+		}
+		util.PrettyPrintOk(lp.Log, "Generated certificate for %s", s.description)
+	}
+	return nil
+}
+
 // Validates that the certificate was generated by us. If so, renames it
 // to make a backup and returns true. Otherwise returns false.
 func renamePre133AdminCert(filename, dir string) (bool, error) {
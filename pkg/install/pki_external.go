@@ -0,0 +1,258 @@
+package install
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	ktls "github.com/apprenda/kismatic/pkg/tls"
+	"github.com/apprenda/kismatic/pkg/util"
+)
+
+const (
+	pkiBackendLocal        = "local"
+	pkiBackendIntermediate = "intermediate"
+	pkiBackendVault        = "vault"
+
+	vaultTokenEnv     = "VAULT_TOKEN"
+	defaultVaultMount = "pki"
+)
+
+var errExternalCARotation = errors.New("the cluster Certificate Authority is managed outside of KET, and cannot be rotated")
+
+// NewPKI returns the PKI that issues the certificates of the cluster,
+// according to the certificates backend defined in the plan
+func NewPKI(p *Plan, caCsr string, certsDir string, log io.Writer) (PKI, error) {
+	local := LocalPKI{
+		CACsr:                   caCsr,
+		GeneratedCertsDirectory: certsDir,
+		Log:                     log,
+	}
+	certs := p.Cluster.Certificates
+	switch certs.Backend {
+	case "", pkiBackendLocal:
+		return &local, nil
+	case pkiBackendIntermediate:
+		if certs.IntermediateCA == nil {
+			return nil, errors.New("the intermediate CA is not defined in the plan file")
+		}
+		return &ExternalPKI{LocalPKI: local, IntermediateCA: certs.IntermediateCA}, nil
+	case pkiBackendVault:
+		if certs.Vault == nil {
+			return nil, errors.New("the vault configuration is not defined in the plan file")
+		}
+		return &ExternalPKI{LocalPKI: local, Vault: certs.Vault}, nil
+	}
+	return nil, fmt.Errorf("certificates backend %q is not supported", certs.Backend)
+}
+
+// ExternalPKI issues the cluster certificates with an existing Certificate
+// Authority that is managed outside of KET. The CA is either an intermediate
+// CA whose certificate and key are provided by the user, or the PKI secrets
+// engine of HashiCorp Vault. The CA's certificate is written to the generated
+// certificates directory, but its private key is not.
+// The proxy-client CA is not used outside of the cluster, and is generated
+// locally.
+type ExternalPKI struct {
+	LocalPKI
+	// IntermediateCA is the CA that signs the certificates. Can be nil if Vault is set.
+	IntermediateCA *IntermediateCA
+	// Vault is the PKI secrets engine that signs the certificates. Can be nil
+	// if IntermediateCA is set.
+	Vault *VaultPKIConfig
+}
+
+// CertificateAuthorityExists returns true if the external CA is available
+func (ep *ExternalPKI) CertificateAuthorityExists() (bool, error) {
+	if ep.Vault != nil {
+		// the CA is managed by vault
+		return true, nil
+	}
+	for _, f := range []string{ep.IntermediateCA.Cert, ep.IntermediateCA.Key} {
+		if _, err := os.Stat(f); err != nil {
+			if os.IsNotExist(err) {
+				return false, nil
+			}
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// GenerateClusterCA returns the external CA, as it is never generated by KET
+func (ep *ExternalPKI) GenerateClusterCA(p *Plan) (*ktls.CA, error) {
+	util.PrettyPrintOk(ep.Log, "Using external cluster Certificate Authority")
+	return ep.GetClusterCA()
+}
+
+// GetClusterCA returns the external CA, and writes its certificate to the
+// generated certificates directory
+func (ep *ExternalPKI) GetClusterCA() (*ktls.CA, error) {
+	var ca *ktls.CA
+	if ep.Vault != nil {
+		signer, err := newVaultSigner(*ep.Vault)
+		if err != nil {
+			return nil, err
+		}
+		cert, err := signer.caCert()
+		if err != nil {
+			return nil, fmt.Errorf("error getting CA certificate from vault: %v", err)
+		}
+		ca = &ktls.CA{Cert: cert, Signer: signer}
+	} else {
+		cert, err := ioutil.ReadFile(ep.IntermediateCA.Cert)
+		if err != nil {
+			return nil, fmt.Errorf("error reading intermediate CA certificate: %v", err)
+		}
+		key, err := ioutil.ReadFile(ep.IntermediateCA.Key)
+		if err != nil {
+			return nil, fmt.Errorf("error reading intermediate CA key: %v", err)
+		}
+		ca = &ktls.CA{Cert: cert, Key: key}
+	}
+	if err := util.CreateDir(ep.GeneratedCertsDirectory, 0744); err != nil {
+		return nil, err
+	}
+	if err := ioutil.WriteFile(filepath.Join(ep.GeneratedCertsDirectory, "ca.pem"), ca.Cert, 0644); err != nil {
+		return nil, fmt.Errorf("error writing CA certificate: %v", err)
+	}
+	return ca, nil
+}
+
+// RotateClusterCA returns an error, as the external CA cannot be replaced by KET
+func (ep *ExternalPKI) RotateClusterCA(p *Plan) (*ktls.CA, error) {
+	return nil, errExternalCARotation
+}
+
+// vaultSigner signs certificates with the PKI secrets engine of HashiCorp Vault
+type vaultSigner struct {
+	address string
+	mount   string
+	role    string
+	token   string
+	client  *http.Client
+}
+
+func newVaultSigner(config VaultPKIConfig) (*vaultSigner, error) {
+	token := os.Getenv(vaultTokenEnv)
+	if config.TokenFile != "" {
+		b, err := ioutil.ReadFile(config.TokenFile)
+		if err != nil {
+			return nil, fmt.Errorf("error reading vault token: %v", err)
+		}
+		token = strings.TrimSpace(string(b))
+	}
+	if token == "" {
+		return nil, fmt.Errorf("the vault token was not found in the token file nor in the %s environment variable", vaultTokenEnv)
+	}
+	transport := &http.Transport{Proxy: http.ProxyFromEnvironment}
+	if config.CACert != "" {
+		b, err := ioutil.ReadFile(config.CACert)
+		if err != nil {
+			return nil, fmt.Errorf("error reading vault CA certificate: %v", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(b) {
+			return nil, fmt.Errorf("no certificates were found in %q", config.CACert)
+		}
+		transport.TLSClientConfig = &tls.Config{RootCAs: pool}
+	}
+	mount := strings.Trim(config.Mount, "/")
+	if mount == "" {
+		mount = defaultVaultMount
+	}
+	return &vaultSigner{
+		address: strings.TrimSuffix(config.Address, "/"),
+		mount:   mount,
+		role:    config.Role,
+		token:   token,
+		client:  &http.Client{Transport: transport, Timeout: 30 * time.Second},
+	}, nil
+}
+
+type vaultResponse struct {
+	Data   map[string]interface{} `json:"data"`
+	Errors []string               `json:"errors"`
+}
+
+// Sign signs the CSR with the sign-verbatim endpoint, which keeps the subject
+// of the request. The Kubernetes components rely on the organizations in the
+// subject for authorization.
+func (v *vaultSigner) Sign(csr []byte, expiry time.Duration) ([]byte, error) {
+	path := "sign-verbatim"
+	if v.role != "" {
+		path = path + "/" + v.role
+	}
+	body, err := json.Marshal(map[string]string{
+		"csr":    string(csr),
+		"ttl":    expiry.String(),
+		"format": "pem",
+	})
+	if err != nil {
+		return nil, err
+	}
+	resp, err := v.do("POST", path, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	vr := vaultResponse{}
+	if err := json.Unmarshal(resp, &vr); err != nil {
+		return nil, fmt.Errorf("error unmarshaling vault response: %v", err)
+	}
+	cert, ok := vr.Data["certificate"].(string)
+	if !ok || cert == "" {
+		return nil, errors.New("vault response does not contain a certificate")
+	}
+	return []byte(strings.TrimSpace(cert) + "\n"), nil
+}
+
+// caCert returns the PEM encoded certificate of the CA
+func (v *vaultSigner) caCert() ([]byte, error) {
+	cert, err := v.do("GET", "ca/pem", nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(bytes.TrimSpace(cert)) == 0 {
+		return nil, fmt.Errorf("the PKI secrets engine mounted at %q does not have a CA certificate", v.mount)
+	}
+	return cert, nil
+}
+
+func (v *vaultSigner) do(method string, path string, body io.Reader) ([]byte, error) {
+	url := fmt.Sprintf("%s/v1/%s/%s", v.address, v.mount, path)
+	req, err := http.NewRequest(method, url, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Vault-Token", v.token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error making request to vault: %v", err)
+	}
+	defer resp.Body.Close()
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading vault response: %v", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		vr := vaultResponse{}
+		if err := json.Unmarshal(b, &vr); err == nil && len(vr.Errors) > 0 {
+			return nil, fmt.Errorf("%s %s failed with status %d: %s", method, url, resp.StatusCode, strings.Join(vr.Errors, "; "))
+		}
+		return nil, fmt.Errorf("%s %s failed with status %d", method, url, resp.StatusCode)
+	}
+	return b, nil
+}
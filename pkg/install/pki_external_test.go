@@ -0,0 +1,248 @@
+package install
+
+import (
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/apprenda/kismatic/pkg/tls"
+	"github.com/cloudflare/cfssl/helpers"
+)
+
+// fakeVault is a stub of the Vault PKI secrets engine mounted at "pki"
+type fakeVault struct {
+	t      *testing.T
+	token  string
+	caKey  []byte
+	caCert []byte
+	signed int
+}
+
+func (v *fakeVault) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("X-Vault-Token") != v.token {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`{"errors":["permission denied"]}`))
+		return
+	}
+	switch {
+	case r.Method == "GET" && r.URL.Path == "/v1/pki/ca/pem":
+		w.Write(v.caCert)
+	case r.Method == "POST" && r.URL.Path == "/v1/pki/sign-verbatim/kismatic":
+		req := map[string]string{}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			v.t.Errorf("error decoding sign request: %v", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		ttl, err := time.ParseDuration(req["ttl"])
+		if err != nil {
+			v.t.Errorf("invalid TTL in sign request: %v", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		cert := v.sign([]byte(req["csr"]), ttl)
+		v.signed++
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"data": map[string]string{"certificate": string(cert), "issuing_ca": string(v.caCert)},
+		})
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"errors":[]}`))
+	}
+}
+
+func (v *fakeVault) sign(csrPEM []byte, ttl time.Duration) []byte {
+	block, _ := pem.Decode(csrPEM)
+	if block == nil {
+		v.t.Fatalf("invalid CSR in sign request")
+	}
+	csr, err := x509.ParseCertificateRequest(block.Bytes)
+	if err != nil {
+		v.t.Fatalf("error parsing CSR: %v", err)
+	}
+	caCert, err := helpers.ParseCertificatePEM(v.caCert)
+	if err != nil {
+		v.t.Fatalf("error parsing CA certificate: %v", err)
+	}
+	caKey, err := helpers.ParsePrivateKeyPEMWithPassword(v.caKey, nil)
+	if err != nil {
+		v.t.Fatalf("error parsing CA key: %v", err)
+	}
+	tpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      csr.Subject,
+		DNSNames:     csr.DNSNames,
+		IPAddresses:  csr.IPAddresses,
+		NotBefore:    time.Now().Add(-time.Minute),
+		NotAfter:     time.Now().Add(ttl),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tpl, caCert, csr.PublicKey, caKey)
+	if err != nil {
+		v.t.Fatalf("error signing certificate: %v", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+func newFakeVault(t *testing.T) (*fakeVault, *httptest.Server) {
+	key, cert, err := tls.NewCACert("test/ca-csr.json", "vaultCA", "1h")
+	if err != nil {
+		t.Fatalf("error generating CA for test: %v", err)
+	}
+	v := &fakeVault{t: t, token: "secret-token", caKey: key, caCert: cert}
+	return v, httptest.NewServer(v)
+}
+
+func generateCertificatesWithPKI(t *testing.T, pki PKI, p *Plan) {
+	ca, err := pki.GenerateClusterCA(p)
+	if err != nil {
+		t.Fatalf("error getting CA: %v", err)
+	}
+	proxyClientCA, err := pki.GenerateProxyClientCA(p)
+	if err != nil {
+		t.Fatalf("error generating proxy-client CA: %v", err)
+	}
+	if err := pki.GenerateClusterCertificates(p, ca, proxyClientCA); err != nil {
+		t.Fatalf("error generating cluster certificates: %v", err)
+	}
+}
+
+func TestExternalPKIVault(t *testing.T) {
+	vault, server := newFakeVault(t)
+	defer server.Close()
+	dir := mustGetTempDir(t)
+	defer os.RemoveAll(dir)
+	tokenFile := filepath.Join(dir, "token")
+	if err := ioutil.WriteFile(tokenFile, []byte(vault.token+"\n"), 0600); err != nil {
+		t.Fatalf("error writing token file: %v", err)
+	}
+
+	p := certificatesTestPlan()
+	p.Cluster.Certificates.Backend = "vault"
+	p.Cluster.Certificates.Vault = &VaultPKIConfig{
+		Address:   server.URL,
+		Role:      "kismatic",
+		TokenFile: tokenFile,
+	}
+	certsDir := filepath.Join(dir, "keys")
+	pki, err := NewPKI(p, "test/ca-csr.json", certsDir, ioutil.Discard)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := pki.(*ExternalPKI); !ok {
+		t.Fatalf("expected an external PKI, but got %T", pki)
+	}
+	generateCertificatesWithPKI(t, pki, p)
+
+	if vault.signed == 0 {
+		t.Errorf("expected certificates to be signed by vault")
+	}
+	caCert := mustReadCertFile(filepath.Join(certsDir, "ca.pem"), t)
+	if caCert.Subject.CommonName != "vaultCA" {
+		t.Errorf("expected the vault CA certificate to be written, but got %q", caCert.Subject.CommonName)
+	}
+	if _, err := os.Stat(filepath.Join(certsDir, "ca-key.pem")); !os.IsNotExist(err) {
+		t.Errorf("expected the CA private key to not be written")
+	}
+	admin := mustReadCertFile(filepath.Join(certsDir, "admin.pem"), t)
+	if err := admin.CheckSignatureFrom(caCert); err != nil {
+		t.Errorf("expected the admin certificate to be signed by the vault CA: %v", err)
+	}
+	if len(admin.Subject.Organization) != 1 || admin.Subject.Organization[0] != adminGroup {
+		t.Errorf("expected the admin certificate to keep its organization, but got %v", admin.Subject.Organization)
+	}
+	// the proxy-client CA is generated locally
+	proxyClientCA := mustReadCertFile(filepath.Join(certsDir, "proxy-client-ca.pem"), t)
+	proxyClient := mustReadCertFile(filepath.Join(certsDir, "proxy-client.pem"), t)
+	if err := proxyClient.CheckSignatureFrom(proxyClientCA); err != nil {
+		t.Errorf("expected the proxy-client certificate to be signed by the local proxy-client CA: %v", err)
+	}
+
+	if _, err := pki.RotateClusterCA(p); err == nil {
+		t.Errorf("expected an error when rotating an external CA")
+	}
+}
+
+func TestExternalPKIVaultInvalidToken(t *testing.T) {
+	_, server := newFakeVault(t)
+	defer server.Close()
+	dir := mustGetTempDir(t)
+	defer os.RemoveAll(dir)
+	tokenFile := filepath.Join(dir, "token")
+	if err := ioutil.WriteFile(tokenFile, []byte("wrong"), 0600); err != nil {
+		t.Fatalf("error writing token file: %v", err)
+	}
+	pki := &ExternalPKI{
+		LocalPKI: LocalPKI{GeneratedCertsDirectory: filepath.Join(dir, "keys"), Log: ioutil.Discard},
+		Vault:    &VaultPKIConfig{Address: server.URL, TokenFile: tokenFile},
+	}
+	if _, err := pki.GetClusterCA(); err == nil {
+		t.Errorf("expected an error when the vault token is invalid")
+	}
+}
+
+func TestExternalPKIIntermediateCA(t *testing.T) {
+	dir := mustGetTempDir(t)
+	defer os.RemoveAll(dir)
+	key, cert, err := tls.NewCACert("test/ca-csr.json", "intermediateCA", "1h")
+	if err != nil {
+		t.Fatalf("error generating CA for test: %v", err)
+	}
+	if err := tls.WriteCert(key, cert, "intermediate", dir); err != nil {
+		t.Fatalf("error writing CA: %v", err)
+	}
+
+	p := certificatesTestPlan()
+	p.Cluster.Certificates.Backend = "intermediate"
+	p.Cluster.Certificates.IntermediateCA = &IntermediateCA{
+		Cert: filepath.Join(dir, "intermediate.pem"),
+		Key:  filepath.Join(dir, "intermediate-key.pem"),
+	}
+	certsDir := filepath.Join(dir, "keys")
+	pki, err := NewPKI(p, "test/ca-csr.json", certsDir, ioutil.Discard)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	exists, err := pki.CertificateAuthorityExists()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !exists {
+		t.Errorf("expected the intermediate CA to exist")
+	}
+	generateCertificatesWithPKI(t, pki, p)
+
+	caCert := mustReadCertFile(filepath.Join(certsDir, "ca.pem"), t)
+	if caCert.Subject.CommonName != "intermediateCA" {
+		t.Errorf("expected the intermediate CA certificate to be written, but got %q", caCert.Subject.CommonName)
+	}
+	if _, err := os.Stat(filepath.Join(certsDir, "ca-key.pem")); !os.IsNotExist(err) {
+		t.Errorf("expected the CA private key to not be copied")
+	}
+	apiServer := mustReadCertFile(filepath.Join(certsDir, "master01-apiserver.pem"), t)
+	if err := apiServer.CheckSignatureFrom(caCert); err != nil {
+		t.Errorf("expected the API server certificate to be signed by the intermediate CA: %v", err)
+	}
+}
+
+func TestNewPKIUnsupportedBackend(t *testing.T) {
+	p := certificatesTestPlan()
+	p.Cluster.Certificates.Backend = "foo"
+	if _, err := NewPKI(p, "test/ca-csr.json", "keys", ioutil.Discard); err == nil {
+		t.Errorf("expected an error for an unsupported backend")
+	}
+	p.Cluster.Certificates.Backend = "vault"
+	if _, err := NewPKI(p, "test/ca-csr.json", "keys", ioutil.Discard); err == nil {
+		t.Errorf("expected an error when the vault configuration is missing")
+	}
+}
@@ -1148,3 +1148,67 @@ func TestGenerateCertificate(t *testing.T) {
 		}
 	}
 }
+
+func TestRotateClusterCertificates(t *testing.T) {
+	pki := getPKI(t)
+	defer cleanup(pki.GeneratedCertsDirectory, t)
+
+	p := getPlan()
+	ca, err := pki.GenerateClusterCA(p)
+	if err != nil {
+		t.Fatalf("error generating CA for test: %v", err)
+	}
+	proxyClientCA, err := pki.GenerateProxyClientCA(p)
+	if err != nil {
+		t.Fatalf("error generating proxy-client CA for test: %v", err)
+	}
+	if err = pki.GenerateClusterCertificates(p, ca, proxyClientCA); err != nil {
+		t.Fatalf("error generating cluster certificates: %v", err)
+	}
+	readAll := func() map[string][]byte {
+		files, err := ioutil.ReadDir(pki.GeneratedCertsDirectory)
+		if err != nil {
+			t.Fatalf("error listing files in generated certs dir: %v", err)
+		}
+		contents := map[string][]byte{}
+		for _, f := range files {
+			b, err := ioutil.ReadFile(filepath.Join(pki.GeneratedCertsDirectory, f.Name()))
+			if err != nil {
+				t.Fatalf("error reading file: %v", err)
+			}
+			contents[f.Name()] = b
+		}
+		return contents
+	}
+	before := readAll()
+
+	newCA, err := pki.RotateClusterCA(p)
+	if err != nil {
+		t.Fatalf("error rotating CA: %v", err)
+	}
+	newProxyClientCA, err := pki.RotateProxyClientCA(p)
+	if err != nil {
+		t.Fatalf("error rotating proxy-client CA: %v", err)
+	}
+	if err = pki.RotateClusterCertificates(p, newCA, newProxyClientCA); err != nil {
+		t.Fatalf("error rotating cluster certificates: %v", err)
+	}
+	after := readAll()
+
+	for name, b := range before {
+		replaced := string(after[name]) != string(b)
+		serviceAccount := strings.HasPrefix(name, serviceAccountCertFilename)
+		if serviceAccount && replaced {
+			t.Errorf("expected %s to be kept, but it was replaced", name)
+		}
+		if !serviceAccount && !replaced {
+			t.Errorf("expected %s to be replaced, but it was kept", name)
+		}
+	}
+
+	newCACert := mustReadCertFile(filepath.Join(pki.GeneratedCertsDirectory, "ca.pem"), t)
+	cert := mustReadCertFile(filepath.Join(pki.GeneratedCertsDirectory, "etcd01-etcd.pem"), t)
+	if err := cert.CheckSignatureFrom(newCACert); err != nil {
+		t.Errorf("expected certificate to be signed by the new CA: %v", err)
+	}
+}
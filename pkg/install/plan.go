@@ -5,7 +5,9 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
+	"net"
 	"os"
 	"regexp"
 	"strings"
@@ -31,6 +33,39 @@ type PlanTemplateOptions struct {
 	StorageNodes    int
 	AdditionalFiles int
 	AdminPassword   string
+	// The options below are set to their defaults when empty
+	CNIProvider      string
+	CalicoMode       string
+	DNSProvider      string
+	PodCIDRBlock     string
+	ServiceCIDRBlock string
+}
+
+// validate returns an error if the template options are not valid
+func (o PlanTemplateOptions) validate() error {
+	if o.CNIProvider != "" && !util.Contains(o.CNIProvider, cniProviders()) {
+		return fmt.Errorf("%q is not a valid CNI provider. Options are %v", o.CNIProvider, cniProviders())
+	}
+	if o.CalicoMode != "" {
+		if o.CNIProvider != "" && o.CNIProvider != cniProviderCalico {
+			return fmt.Errorf("the Calico mode cannot be set when the CNI provider is %q", o.CNIProvider)
+		}
+		if !util.Contains(o.CalicoMode, calicoMode()) {
+			return fmt.Errorf("%q is not a valid Calico mode. Options are %v", o.CalicoMode, calicoMode())
+		}
+	}
+	if o.DNSProvider != "" && !util.Contains(o.DNSProvider, dnsProviders()) {
+		return fmt.Errorf("%q is not a valid DNS provider. Options are %v", o.DNSProvider, dnsProviders())
+	}
+	for _, cidr := range []string{o.PodCIDRBlock, o.ServiceCIDRBlock} {
+		if cidr == "" {
+			continue
+		}
+		if _, _, err := net.ParseCIDR(cidr); err != nil {
+			return fmt.Errorf("%q is not a valid CIDR block: %v", cidr, err)
+		}
+	}
+	return nil
 }
 
 // PlanReadWriter is capable of reading/writing a Plan
@@ -48,67 +83,59 @@ type Planner interface {
 // FilePlanner is a file-based installation planner
 type FilePlanner struct {
 	File string
+	// Overlays are plan files that are deep-merged into the plan file, in order
+	Overlays []string
+	// Values are assignments such as "cluster.name=production" that are set
+	// after the overlays are merged
+	Values []string
 }
 
 // Read the plan from the file system
 func (fp *FilePlanner) Read() (*Plan, error) {
-	d, err := ioutil.ReadFile(fp.File)
-	if err != nil {
-		return nil, fmt.Errorf("could not read file: %v", err)
+	p, _, err := fp.read()
+	return p, err
+}
+
+// read the plan from the file system, and return the description of the
+// migrations that were applied to it
+func (fp *FilePlanner) read() (*Plan, []string, error) {
+	var d []byte
+	var err error
+	if fp.merged() {
+		d, err = mergePlanFiles(fp.File, fp.Overlays, fp.Values)
+		if err != nil {
+			return nil, nil, err
+		}
+	} else {
+		d, err = ioutil.ReadFile(fp.File)
+		if err != nil {
+			return nil, nil, fmt.Errorf("could not read file: %v", err)
+		}
 	}
 
 	p := &Plan{}
 	if err = yaml.Unmarshal(d, p); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal plan: %v", err)
+		return nil, nil, fmt.Errorf("failed to unmarshal plan: %v", err)
 	}
-
-	// read deprecated fields and set it the new version of the cluster file
-	readDeprecatedFields(p)
-
-	// set nil values to defaults
-	setDefaults(p)
-
-	return p, nil
-}
-
-func readDeprecatedFields(p *Plan) {
-	// only set if not already being set by the user
-	// package_manager moved from features: to add_ons: after KET v1.3.3
-	if p.Features != nil && p.Features.PackageManager != nil {
-		p.AddOns.PackageManager.Disable = !p.Features.PackageManager.Enabled
-		// KET v1.3.3 did not have a provider field
-		p.AddOns.PackageManager.Provider = ket133PackageManagerProvider
-	}
-	// allow_package_installation renamed to disable_package_installation after KET v1.4.0
-	if p.Cluster.AllowPackageInstallation != nil {
-		p.Cluster.DisablePackageInstallation = !*p.Cluster.AllowPackageInstallation
+	// unknown fields are reported when the plan is validated
+	if p.unknownFields, err = unknownPlanFields(d); err != nil {
+		return nil, nil, fmt.Errorf("failed to unmarshal plan: %v", err)
 	}
 
-	// Only read the deprecated dashboard field if the new one is not set
-	if p.AddOns.DashboardDeprecated != nil && p.AddOns.Dashboard == nil {
-		p.AddOns.Dashboard = &Dashboard{
-			Disable: p.AddOns.DashboardDeprecated.Disable,
-		}
+	// move deprecated fields to the current version of the plan file
+	changes, err := migratePlan(p)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	if p.DockerRegistry.Server == "" && p.DockerRegistry.Address != "" && p.DockerRegistry.Port != 0 {
-		p.DockerRegistry.Server = fmt.Sprintf("%s:%d", p.DockerRegistry.Address, p.DockerRegistry.Port)
+	if err = resolveSecrets(p); err != nil {
+		return nil, nil, err
 	}
 
-	if p.Docker.Storage.DirectLVM != nil && p.Docker.Storage.DirectLVM.Enabled && (p.Docker.Storage.Opts == nil || len(p.Docker.Storage.Opts) == 0) {
-		p.Docker.Storage.Driver = "devicemapper"
-		p.Docker.Storage.Opts = map[string]string{
-			"dm.thinpooldev":           "/dev/mapper/docker-thinpool",
-			"dm.use_deferred_removal":  "true",
-			"dm.use_deferred_deletion": fmt.Sprintf("%t", p.Docker.Storage.DirectLVM.EnableDeferredDeletion),
-		}
-		p.Docker.Storage.DirectLVMBlockDevice.Path = p.Docker.Storage.DirectLVM.BlockDevice
-		p.Docker.Storage.DirectLVMBlockDevice.ThinpoolPercent = "95"
-		p.Docker.Storage.DirectLVMBlockDevice.ThinpoolMetaPercent = "1"
-		p.Docker.Storage.DirectLVMBlockDevice.ThinpoolAutoextendThreshold = "80"
-		p.Docker.Storage.DirectLVMBlockDevice.ThinpoolAutoextendPercent = "20"
-		p.Docker.Storage.DirectLVM = nil
-	}
+	// set nil values to defaults
+	setDefaults(p)
+
+	return p, changes, nil
 }
 
 func setDefaults(p *Plan) {
@@ -156,10 +183,6 @@ func setDefaults(p *Plan) {
 		p.AddOns.CNI.Provider = cniProviderCalico
 		p.AddOns.CNI.Options.Calico.Mode = "overlay"
 		p.AddOns.CNI.Options.Calico.LogLevel = "info"
-		// read KET <v1.5.0 plan option
-		if p.Cluster.Networking.Type != "" {
-			p.AddOns.CNI.Options.Calico.Mode = p.Cluster.Networking.Type
-		}
 	}
 	if p.AddOns.CNI.Options.Calico.LogLevel == "" {
 		p.AddOns.CNI.Options.Calico.LogLevel = "info"
@@ -189,19 +212,12 @@ func setDefaults(p *Plan) {
 	if p.AddOns.HeapsterMonitoring.Options.Heapster.Replicas == 0 {
 		p.AddOns.HeapsterMonitoring.Options.Heapster.Replicas = 2
 	}
-	// read field from KET < v1.5.0
-	if p.AddOns.HeapsterMonitoring.Options.HeapsterReplicas != 0 {
-		p.AddOns.HeapsterMonitoring.Options.Heapster.Replicas = p.AddOns.HeapsterMonitoring.Options.HeapsterReplicas
-	}
 	if p.AddOns.HeapsterMonitoring.Options.Heapster.Sink == "" {
 		p.AddOns.HeapsterMonitoring.Options.Heapster.Sink = "influxdb:http://heapster-influxdb.kube-system.svc:8086"
 	}
 	if p.AddOns.HeapsterMonitoring.Options.Heapster.ServiceType == "" {
 		p.AddOns.HeapsterMonitoring.Options.Heapster.ServiceType = "ClusterIP"
 	}
-	if p.AddOns.HeapsterMonitoring.Options.InfluxDBPVCName != "" {
-		p.AddOns.HeapsterMonitoring.Options.InfluxDB.PVCName = p.AddOns.HeapsterMonitoring.Options.InfluxDBPVCName
-	}
 
 	if p.Cluster.Certificates.CAExpiry == "" {
 		p.Cluster.Certificates.CAExpiry = defaultCAExpiry
@@ -222,24 +238,44 @@ func setDefaults(p *Plan) {
 
 var yamlKeyRE = regexp.MustCompile(`[^a-zA-Z]*([a-z_\-\/A-Z.\d]+)[ ]*:`)
 
+// merged returns true if the plan is merged from multiple sources
+func (fp *FilePlanner) merged() bool {
+	return len(fp.Overlays) > 0 || len(fp.Values) > 0
+}
+
 // Write the plan to the file system
 func (fp *FilePlanner) Write(p *Plan) error {
+	if fp.merged() {
+		return fmt.Errorf("the plan cannot be written to %q, as overlays or values are merged into it", fp.File)
+	}
+	// fail before the plan file is truncated
+	if err := checkChangedSecrets(p); err != nil {
+		return err
+	}
+	f, err := os.Create(fp.File)
+	if err != nil {
+		return fmt.Errorf("error making plan file: %v", err)
+	}
+	defer f.Close()
+	return WritePlan(f, p)
+}
+
+// WritePlan writes the plan, along with comments that describe its fields
+func WritePlan(out io.Writer, p *Plan) error {
 	// make a copy of the global comment map
 	oneTimeComments := map[string][]string{}
 	for k, v := range commentMap {
 		oneTimeComments[k] = v
 	}
-	bytez, marshalErr := yaml.Marshal(p)
+	// never write the secrets that were resolved from references
+	if err := checkChangedSecrets(p); err != nil {
+		return err
+	}
+	bytez, marshalErr := yaml.Marshal(p.withSecretRefs())
 	if marshalErr != nil {
 		return fmt.Errorf("error marshalling plan to yaml: %v", marshalErr)
 	}
 
-	f, err := os.Create(fp.File)
-	if err != nil {
-		return fmt.Errorf("error making plan file: %v", err)
-	}
-	defer f.Close()
-
 	// the stack keeps track of the object we are in
 	// for example, when we are inside cluster.networking, looking at the key 'foo'
 	// the stack will have [cluster, networking, foo]
@@ -269,7 +305,7 @@ func (fp *FilePlanner) Write(p *Plan) error {
 			// Add a new line if we are leaving a major indentation block
 			// (leaving a struct)..
 			if indent < prevIndent {
-				f.WriteString("\n")
+				io.WriteString(out, "\n")
 				// suppress the new line that would be added if this
 				// field has a comment
 				addNewLineBeforeComment = false
@@ -288,7 +324,7 @@ func (fp *FilePlanner) Write(p *Plan) error {
 
 			// Full key match (e.g. "cluster.networking.pod_cidr")
 			if thiscomment, ok := oneTimeComments[strings.Join(s.s, ".")]; ok {
-				if _, err := f.WriteString(getCommentedLine(text, thiscomment, addNewLineBeforeComment)); err != nil {
+				if _, err := io.WriteString(out, getCommentedLine(text, thiscomment, addNewLineBeforeComment)); err != nil {
 					return err
 				}
 				delete(oneTimeComments, matched[1])
@@ -297,7 +333,7 @@ func (fp *FilePlanner) Write(p *Plan) error {
 			}
 		}
 		// we don't want to comment this line... just print it out
-		if _, err := f.WriteString(text + "\n"); err != nil {
+		if _, err := io.WriteString(out, text+"\n"); err != nil {
 			return err
 		}
 		addNewLineBeforeComment = true
@@ -343,6 +379,9 @@ func (fp *FilePlanner) PlanExists() bool {
 
 // WritePlanTemplate writes an installation plan with pre-filled defaults.
 func WritePlanTemplate(planTemplateOpts PlanTemplateOptions, w PlanReadWriter) error {
+	if err := planTemplateOpts.validate(); err != nil {
+		return err
+	}
 	p := buildPlanFromTemplateOptions(planTemplateOpts)
 	if err := w.Write(&p); err != nil {
 		return fmt.Errorf("error writing installation plan template: %v", err)
@@ -354,6 +393,7 @@ func WritePlanTemplate(planTemplateOpts PlanTemplateOptions, w PlanReadWriter) e
 // template options
 func buildPlanFromTemplateOptions(templateOpts PlanTemplateOptions) Plan {
 	p := Plan{}
+	p.PlanVersion = currentPlanVersion
 	p.Cluster.Name = "kubernetes"
 	p.Cluster.Version = kubernetesVersionString
 	p.Cluster.AdminPassword = templateOpts.AdminPassword
@@ -367,7 +407,13 @@ func buildPlanFromTemplateOptions(templateOpts PlanTemplateOptions) Plan {
 
 	// Set Networking defaults
 	p.Cluster.Networking.PodCIDRBlock = "172.16.0.0/16"
+	if templateOpts.PodCIDRBlock != "" {
+		p.Cluster.Networking.PodCIDRBlock = templateOpts.PodCIDRBlock
+	}
 	p.Cluster.Networking.ServiceCIDRBlock = "172.20.0.0/16"
+	if templateOpts.ServiceCIDRBlock != "" {
+		p.Cluster.Networking.ServiceCIDRBlock = templateOpts.ServiceCIDRBlock
+	}
 	p.Cluster.Networking.UpdateHostsFiles = false
 
 	// Set Certificate defaults
@@ -391,13 +437,24 @@ func buildPlanFromTemplateOptions(templateOpts PlanTemplateOptions) Plan {
 	// CNI
 	p.AddOns.CNI = &CNI{}
 	p.AddOns.CNI.Provider = cniProviderCalico
-	p.AddOns.CNI.Options.Calico.Mode = "overlay"
-	p.AddOns.CNI.Options.Calico.LogLevel = "info"
-	p.AddOns.CNI.Options.Calico.WorkloadMTU = 1500
-	p.AddOns.CNI.Options.Calico.FelixInputMTU = 1440
-	p.AddOns.CNI.Options.Calico.IPAutodetectionMethod = "first-found"
+	if templateOpts.CNIProvider != "" {
+		p.AddOns.CNI.Provider = templateOpts.CNIProvider
+	}
+	if p.AddOns.CNI.Provider == cniProviderCalico {
+		p.AddOns.CNI.Options.Calico.Mode = "overlay"
+		if templateOpts.CalicoMode != "" {
+			p.AddOns.CNI.Options.Calico.Mode = templateOpts.CalicoMode
+		}
+		p.AddOns.CNI.Options.Calico.LogLevel = "info"
+		p.AddOns.CNI.Options.Calico.WorkloadMTU = 1500
+		p.AddOns.CNI.Options.Calico.FelixInputMTU = 1440
+		p.AddOns.CNI.Options.Calico.IPAutodetectionMethod = "first-found"
+	}
 	// DNS
 	p.AddOns.DNS.Provider = "kubedns"
+	if templateOpts.DNSProvider != "" {
+		p.AddOns.DNS.Provider = templateOpts.DNSProvider
+	}
 	p.AddOns.DNS.Options.Replicas = 2
 	// Heapster
 	p.AddOns.HeapsterMonitoring = &HeapsterMonitoring{}
@@ -0,0 +1,337 @@
+package install
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// appliedPlanFilename is the name of the snapshot of the last plan that was
+// applied successfully, stored in the generated assets directory
+const appliedPlanFilename = "last-applied-plan.yaml"
+
+// PlanChangeAction is the type of change made to a plan field
+type PlanChangeAction string
+
+const (
+	// PlanChangeAdded means that the field was added to the plan
+	PlanChangeAdded PlanChangeAction = "added"
+	// PlanChangeRemoved means that the field was removed from the plan
+	PlanChangeRemoved PlanChangeAction = "removed"
+	// PlanChangeModified means that the value of the field was changed
+	PlanChangeModified PlanChangeAction = "modified"
+)
+
+// PlanChange is a difference between the applied plan and the current plan
+type PlanChange struct {
+	// Category of the change, such as nodes, options or add-ons
+	Category string `json:"category"`
+	// Field that was changed
+	Field string `json:"field"`
+	// Action that was performed on the field
+	Action PlanChangeAction `json:"action"`
+	// Old value of the field. Empty if the field was added.
+	Old string `json:"old,omitempty"`
+	// New value of the field. Empty if the field was removed.
+	New string `json:"new,omitempty"`
+	// Immutable is true if the field cannot be changed on an existing cluster
+	Immutable bool `json:"immutable"`
+	// RequiresRotation is true if the change is to a certificate that exists
+	// already. Existing certificates are not replaced when the plan is
+	// applied, they must be reissued with "certificates rotate".
+	RequiresRotation bool `json:"requiresRotation"`
+}
+
+func (c PlanChange) String() string {
+	switch c.Action {
+	case PlanChangeAdded:
+		return fmt.Sprintf("+ %s: %s", c.Field, c.New)
+	case PlanChangeRemoved:
+		return fmt.Sprintf("- %s: %s", c.Field, c.Old)
+	}
+	return fmt.Sprintf("~ %s: %s => %s", c.Field, c.Old, c.New)
+}
+
+// PlanDiff is the list of differences between the applied plan and the current plan
+type PlanDiff struct {
+	Changes []PlanChange `json:"changes"`
+}
+
+// Empty returns true if the plans are equivalent
+func (d PlanDiff) Empty() bool {
+	return len(d.Changes) == 0
+}
+
+// RotationChanges returns the changes that require the certificates to be rotated
+func (d PlanDiff) RotationChanges() []PlanChange {
+	var changes []PlanChange
+	for _, c := range d.Changes {
+		if c.RequiresRotation {
+			changes = append(changes, c)
+		}
+	}
+	return changes
+}
+
+// ImmutableChanges returns the changes that cannot be applied to an existing cluster
+func (d PlanDiff) ImmutableChanges() []PlanChange {
+	var changes []PlanChange
+	for _, c := range d.Changes {
+		if c.Immutable {
+			changes = append(changes, c)
+		}
+	}
+	return changes
+}
+
+func (d *PlanDiff) add(category, field string, old, new string, immutable bool) {
+	if old == new {
+		return
+	}
+	action := PlanChangeModified
+	switch {
+	case old == "":
+		action = PlanChangeAdded
+	case new == "":
+		action = PlanChangeRemoved
+	}
+	d.Changes = append(d.Changes, PlanChange{
+		Category:  category,
+		Field:     field,
+		Action:    action,
+		Old:       old,
+		New:       new,
+		Immutable: immutable,
+	})
+}
+
+// SaveAppliedPlan stores a snapshot of the plan in the generated assets
+// directory. The snapshot is the baseline of the next plan diff.
+func SaveAppliedPlan(p *Plan, generatedAssetsDir string) error {
+	if err := os.MkdirAll(generatedAssetsDir, 0700); err != nil {
+		return fmt.Errorf("error creating directory %q: %v", generatedAssetsDir, err)
+	}
+	fp := &FilePlanner{File: filepath.Join(generatedAssetsDir, appliedPlanFilename)}
+	if err := fp.Write(p); err != nil {
+		return fmt.Errorf("error writing applied plan: %v", err)
+	}
+	return nil
+}
+
+// ReadAppliedPlan returns the snapshot of the last plan that was applied
+// successfully. Returns nil if a plan has not been applied yet.
+func ReadAppliedPlan(generatedAssetsDir string) (*Plan, error) {
+	fp := &FilePlanner{File: filepath.Join(generatedAssetsDir, appliedPlanFilename)}
+	if !fp.PlanExists() {
+		return nil, nil
+	}
+	p, err := fp.Read()
+	if err != nil {
+		return nil, fmt.Errorf("error reading applied plan: %v", err)
+	}
+	return p, nil
+}
+
+// UpdateAppliedPlan applies the change to the snapshot of the last plan that
+// was applied successfully, such as a node that was added or removed. Does
+// nothing if a plan has not been applied yet.
+func UpdateAppliedPlan(generatedAssetsDir string, change func(Plan) Plan) error {
+	applied, err := ReadAppliedPlan(generatedAssetsDir)
+	if err != nil {
+		return err
+	}
+	if applied == nil {
+		return nil
+	}
+	updated := change(*applied)
+	return SaveAppliedPlan(&updated, generatedAssetsDir)
+}
+
+// DiffPlans returns the differences between the plan that was applied to the
+// cluster and the current plan
+func DiffPlans(applied *Plan, current *Plan) (PlanDiff, error) {
+	d := PlanDiff{}
+
+	// Nodes
+	groups := []struct {
+		name    string
+		applied []Node
+		current []Node
+	}{
+		{"etcd", applied.Etcd.Nodes, current.Etcd.Nodes},
+		{"master", applied.Master.Nodes, current.Master.Nodes},
+		{"worker", applied.Worker.Nodes, current.Worker.Nodes},
+		{"ingress", applied.Ingress.Nodes, current.Ingress.Nodes},
+		{"storage", applied.Storage.Nodes, current.Storage.Nodes},
+	}
+	for _, g := range groups {
+		diffNodes(&d, g.name, g.applied, g.current)
+	}
+
+	// Networking
+	d.add("networking", "cluster.networking.pod_cidr_block", applied.Cluster.Networking.PodCIDRBlock, current.Cluster.Networking.PodCIDRBlock, true)
+	d.add("networking", "cluster.networking.service_cidr_block", applied.Cluster.Networking.ServiceCIDRBlock, current.Cluster.Networking.ServiceCIDRBlock, true)
+
+	// Option overrides
+	overrides := []struct {
+		field   string
+		applied map[string]string
+		current map[string]string
+	}{
+		{"cluster.kube_apiserver.option_overrides", applied.Cluster.APIServerOptions.Overrides, current.Cluster.APIServerOptions.Overrides},
+		{"cluster.kube_controller_manager.option_overrides", applied.Cluster.KubeControllerManagerOptions.Overrides, current.Cluster.KubeControllerManagerOptions.Overrides},
+		{"cluster.kube_scheduler.option_overrides", applied.Cluster.KubeSchedulerOptions.Overrides, current.Cluster.KubeSchedulerOptions.Overrides},
+		{"cluster.kube_proxy.option_overrides", applied.Cluster.KubeProxyOptions.Overrides, current.Cluster.KubeProxyOptions.Overrides},
+		{"cluster.kubelet.option_overrides", applied.Cluster.KubeletOptions.Overrides, current.Cluster.KubeletOptions.Overrides},
+	}
+	for _, o := range overrides {
+		diffOverrides(&d, "options", o.field, o.applied, o.current)
+	}
+
+	// Add-ons
+	diffAddOns(&d, applied.AddOns, current.AddOns)
+
+	// Certificates
+	if err := diffCertificates(&d, applied, current); err != nil {
+		return d, err
+	}
+	return d, nil
+}
+
+func diffNodes(d *PlanDiff, group string, applied []Node, current []Node) {
+	appliedByHost := map[string]Node{}
+	for _, n := range applied {
+		appliedByHost[n.Host] = n
+	}
+	currentByHost := map[string]Node{}
+	for _, n := range current {
+		currentByHost[n.Host] = n
+	}
+	field := func(host string) string { return fmt.Sprintf("%s.nodes[%s]", group, host) }
+	for _, n := range current {
+		old, ok := appliedByHost[n.Host]
+		if !ok {
+			d.add("nodes", field(n.Host), "", nodeString(n), false)
+			continue
+		}
+		// the IP addresses of a node are part of its certificates and of the
+		// configuration of the other nodes
+		d.add("nodes", field(n.Host), nodeString(old), nodeString(n), true)
+		diffOverrides(d, "nodes", field(n.Host)+".kubelet.option_overrides", old.KubeletOptions.Overrides, n.KubeletOptions.Overrides)
+	}
+	for _, n := range applied {
+		if _, ok := currentByHost[n.Host]; !ok {
+			d.add("nodes", field(n.Host), nodeString(n), "", false)
+		}
+	}
+}
+
+func nodeString(n Node) string {
+	if n.InternalIP == "" {
+		return fmt.Sprintf("ip=%s", n.IP)
+	}
+	return fmt.Sprintf("ip=%s,internalip=%s", n.IP, n.InternalIP)
+}
+
+func diffOverrides(d *PlanDiff, category string, field string, applied map[string]string, current map[string]string) {
+	keys := map[string]bool{}
+	for k := range applied {
+		keys[k] = true
+	}
+	for k := range current {
+		keys[k] = true
+	}
+	sorted := make([]string, 0, len(keys))
+	for k := range keys {
+		sorted = append(sorted, k)
+	}
+	sort.Strings(sorted)
+	for _, k := range sorted {
+		d.add(category, fmt.Sprintf("%s.%s", field, k), applied[k], current[k], false)
+	}
+}
+
+func diffAddOns(d *PlanDiff, applied AddOns, current AddOns) {
+	enabled := func(disabled bool) string {
+		if disabled {
+			return "disabled"
+		}
+		return "enabled"
+	}
+	cni := func(a AddOns) (string, string) {
+		if a.CNI == nil {
+			return enabled(false), cniProviderCalico
+		}
+		return enabled(a.CNI.Disable), a.CNI.Provider
+	}
+	appliedCNI, appliedCNIProvider := cni(applied)
+	currentCNI, currentCNIProvider := cni(current)
+	d.add("add-ons", "add_ons.cni", appliedCNI, currentCNI, false)
+	// the pod network cannot be replaced on an existing cluster
+	d.add("add-ons", "add_ons.cni.provider", appliedCNIProvider, currentCNIProvider, true)
+
+	// the DNS provider, heapster and the dashboard are set to their defaults
+	// when the plan is read
+	dnsProvider := func(a AddOns) string {
+		if a.DNS.Provider == "" {
+			return "kubedns"
+		}
+		return a.DNS.Provider
+	}
+	d.add("add-ons", "add_ons.dns", enabled(applied.DNS.Disable), enabled(current.DNS.Disable), false)
+	d.add("add-ons", "add_ons.dns.provider", dnsProvider(applied), dnsProvider(current), false)
+	d.add("add-ons", "add_ons.heapster", enabled(applied.HeapsterMonitoring != nil && applied.HeapsterMonitoring.Disable), enabled(current.HeapsterMonitoring != nil && current.HeapsterMonitoring.Disable), false)
+	d.add("add-ons", "add_ons.metrics_server", enabled(applied.MetricsServer.Disable), enabled(current.MetricsServer.Disable), false)
+	d.add("add-ons", "add_ons.dashboard", enabled(applied.Dashboard != nil && applied.Dashboard.Disable), enabled(current.Dashboard != nil && current.Dashboard.Disable), false)
+	d.add("add-ons", "add_ons.package_manager", enabled(applied.PackageManager.Disable), enabled(current.PackageManager.Disable), false)
+	d.add("add-ons", "add_ons.package_manager.provider", applied.PackageManager.Provider, current.PackageManager.Provider, false)
+	d.add("add-ons", "add_ons.rescheduler", enabled(applied.Rescheduler.Disable), enabled(current.Rescheduler.Disable), false)
+}
+
+// diffCertificates adds the certificates that will be generated when the
+// current plan is applied, either because they are new, or because their
+// subject or alternative names changed.
+func diffCertificates(d *PlanDiff, applied *Plan, current *Plan) error {
+	appliedSpecs, err := applied.certSpecs(nil, nil)
+	if err != nil {
+		return fmt.Errorf("error building certificate list of the applied plan: %v", err)
+	}
+	currentSpecs, err := current.certSpecs(nil, nil)
+	if err != nil {
+		return fmt.Errorf("error building certificate list of the current plan: %v", err)
+	}
+	appliedByFilename := map[string]certificateSpec{}
+	for _, s := range appliedSpecs {
+		appliedByFilename[s.filename] = s
+	}
+	for _, s := range currentSpecs {
+		old, ok := appliedByFilename[s.filename]
+		if !ok {
+			d.add("certificates", s.filename, "", certSpecString(s), false)
+			continue
+		}
+		before := len(d.Changes)
+		d.add("certificates", s.filename, certSpecString(old), certSpecString(s), false)
+		if len(d.Changes) > before {
+			d.Changes[before].RequiresRotation = true
+		}
+	}
+	return nil
+}
+
+func certSpecString(s certificateSpec) string {
+	str := "CN=" + s.commonName
+	orgs := append([]string{}, s.organizations...)
+	sort.Strings(orgs)
+	for _, o := range orgs {
+		str += ",O=" + o
+	}
+	if len(s.subjectAlternateNames) > 0 {
+		sans := append([]string{}, s.subjectAlternateNames...)
+		sort.Strings(sans)
+		str += " SANs=" + strings.Join(sans, ",")
+	}
+	return str
+}
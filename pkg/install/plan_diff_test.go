@@ -0,0 +1,159 @@
+package install
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestDiffPlansNoChanges(t *testing.T) {
+	p := certificatesTestPlan()
+	d, err := DiffPlans(p, certificatesTestPlan())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !d.Empty() {
+		t.Errorf("expected no changes, but got %v", d.Changes)
+	}
+}
+
+func TestDiffPlans(t *testing.T) {
+	applied := certificatesTestPlan()
+	applied.Cluster.APIServerOptions.Overrides = map[string]string{"v": "3", "runtime-config": "batch/v2alpha1=true"}
+	current := certificatesTestPlan()
+	current.Cluster.APIServerOptions.Overrides = map[string]string{"v": "5", "audit-log-path": "/var/log/audit.log"}
+	current.Cluster.Networking.ServiceCIDRBlock = "172.30.0.0/16"
+	current.AddOns.Dashboard = &Dashboard{Disable: true}
+	current.Worker.Nodes = append(current.Worker.Nodes, Node{Host: "worker02", IP: "10.0.0.4"})
+
+	d, err := DiffPlans(applied, current)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []PlanChange{
+		{Category: "nodes", Field: "worker.nodes[worker02]", Action: PlanChangeAdded, New: "ip=10.0.0.4"},
+		{Category: "networking", Field: "cluster.networking.service_cidr_block", Action: PlanChangeModified, Old: applied.Cluster.Networking.ServiceCIDRBlock, New: "172.30.0.0/16", Immutable: true},
+		{Category: "options", Field: "cluster.kube_apiserver.option_overrides.audit-log-path", Action: PlanChangeAdded, New: "/var/log/audit.log"},
+		{Category: "options", Field: "cluster.kube_apiserver.option_overrides.runtime-config", Action: PlanChangeRemoved, Old: "batch/v2alpha1=true"},
+		{Category: "options", Field: "cluster.kube_apiserver.option_overrides.v", Action: PlanChangeModified, Old: "3", New: "5"},
+		{Category: "add-ons", Field: "add_ons.dashboard", Action: PlanChangeModified, Old: "enabled", New: "disabled"},
+	}
+	var nonCertChanges []PlanChange
+	certs := map[string]bool{}
+	rotate := map[string]bool{}
+	for _, c := range d.Changes {
+		if c.Category == "certificates" {
+			certs[c.Field] = true
+			rotate[c.Field] = c.RequiresRotation
+			continue
+		}
+		nonCertChanges = append(nonCertChanges, c)
+	}
+	if !reflect.DeepEqual(nonCertChanges, expected) {
+		t.Errorf("unexpected changes:\nexpected %v\ngot      %v", expected, nonCertChanges)
+	}
+	// the new worker gets a kubelet certificate, and the service CIDR is part
+	// of the API server certificate
+	for _, c := range []string{"worker02-kubelet", "master01-apiserver"} {
+		if !certs[c] {
+			t.Errorf("expected certificate %q to be regenerated, but got %v", c, certs)
+		}
+	}
+	// the API server certificate exists already, so it must be rotated
+	if rotate["worker02-kubelet"] || !rotate["master01-apiserver"] {
+		t.Errorf("expected only the existing certificate to require rotation, but got %v", rotate)
+	}
+	if immutable := d.ImmutableChanges(); len(immutable) != 1 {
+		t.Errorf("expected 1 immutable change, but got %v", immutable)
+	}
+}
+
+func TestDiffPlansNodeChanges(t *testing.T) {
+	applied := certificatesTestPlan()
+	current := certificatesTestPlan()
+	current.Worker.Nodes[0].IP = "10.0.0.10"
+	current.Etcd.Nodes = nil
+
+	d, err := DiffPlans(applied, current)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var nodes []PlanChange
+	for _, c := range d.Changes {
+		if c.Category == "nodes" {
+			nodes = append(nodes, c)
+		}
+	}
+	expected := []PlanChange{
+		{Category: "nodes", Field: "etcd.nodes[etcd01]", Action: PlanChangeRemoved, Old: "ip=10.0.0.1"},
+		{Category: "nodes", Field: "worker.nodes[worker01]", Action: PlanChangeModified, Old: "ip=10.0.0.3", New: "ip=10.0.0.10", Immutable: true},
+	}
+	if !reflect.DeepEqual(nodes, expected) {
+		t.Errorf("unexpected node changes:\nexpected %v\ngot      %v", expected, nodes)
+	}
+}
+
+func TestSaveAndReadAppliedPlan(t *testing.T) {
+	dir := mustGetTempDir(t)
+	defer os.RemoveAll(dir)
+
+	p, err := ReadAppliedPlan(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if p != nil {
+		t.Errorf("expected a nil plan when a plan was not applied")
+	}
+
+	applied := certificatesTestPlan()
+	if err := SaveAppliedPlan(applied, dir); err != nil {
+		t.Fatalf("error saving applied plan: %v", err)
+	}
+	p, err = ReadAppliedPlan(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	d, err := DiffPlans(p, applied)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !d.Empty() {
+		t.Errorf("expected the saved plan to match the applied plan, but got %v", d.Changes)
+	}
+}
+
+func TestUpdateAppliedPlan(t *testing.T) {
+	dir := mustGetTempDir(t)
+	defer os.RemoveAll(dir)
+
+	newNode := Node{Host: "worker2", IP: "10.0.0.20"}
+	addNode := func(p Plan) Plan { return AddNodeToPlan(p, newNode, []string{"worker"}) }
+
+	// Nothing to update when a plan was not applied
+	if err := UpdateAppliedPlan(dir, addNode); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if p, _ := ReadAppliedPlan(dir); p != nil {
+		t.Errorf("expected the applied plan to not be created")
+	}
+
+	applied := certificatesTestPlan()
+	if err := SaveAppliedPlan(applied, dir); err != nil {
+		t.Fatalf("error saving applied plan: %v", err)
+	}
+	if err := UpdateAppliedPlan(dir, addNode); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	p, err := ReadAppliedPlan(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := addNode(*applied)
+	d, err := DiffPlans(p, &expected)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !d.Empty() {
+		t.Errorf("expected the applied plan to include the new node, but got %v", d.Changes)
+	}
+}
@@ -0,0 +1,172 @@
+package install
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// yamlField is a field of a struct, as it is marshalled to YAML
+type yamlField struct {
+	name string
+	typ  reflect.Type
+}
+
+// yamlFields returns the fields of the struct type that are marshalled to
+// YAML, following the same rules as the yaml package: the key is the name in
+// the yaml tag, or the lowercased field name if there is none.
+func yamlFields(t reflect.Type) []yamlField {
+	var fields []yamlField
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			// unexported fields are ignored
+			continue
+		}
+		tag := f.Tag.Get("yaml")
+		if tag == "-" {
+			continue
+		}
+		name := strings.Split(tag, ",")[0]
+		if strings.Contains(tag, ",inline") {
+			fields = append(fields, yamlFields(f.Type)...)
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(f.Name)
+		}
+		fields = append(fields, yamlField{name: name, typ: f.Type})
+	}
+	return fields
+}
+
+// unknownField is a key of the plan file that does not correspond to any
+// field of the plan
+type unknownField struct {
+	path       string
+	suggestion string
+}
+
+func (f unknownField) Error() string {
+	if f.suggestion != "" {
+		return fmt.Sprintf("Unknown field %q, did you mean %q?", f.path, f.suggestion)
+	}
+	return fmt.Sprintf("Unknown field %q", f.path)
+}
+
+// unknownPlanFields returns the keys of the plan file that are ignored when
+// it is unmarshalled, such as misspelled field names
+func unknownPlanFields(d []byte) ([]unknownField, error) {
+	var raw yaml.MapSlice
+	if err := yaml.Unmarshal(d, &raw); err != nil {
+		return nil, err
+	}
+	return unknownFields("", raw, reflect.TypeOf(Plan{})), nil
+}
+
+func unknownFields(path string, value interface{}, t reflect.Type) []unknownField {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	var unknown []unknownField
+	switch t.Kind() {
+	case reflect.Struct:
+		m, ok := value.(yaml.MapSlice)
+		if !ok {
+			return nil
+		}
+		fields := yamlFields(t)
+		for _, item := range m {
+			key := fmt.Sprint(item.Key)
+			f, ok := findYAMLField(fields, key)
+			if !ok {
+				unknown = append(unknown, unknownField{path: joinFieldPath(path, key), suggestion: suggestField(fields, key)})
+				continue
+			}
+			unknown = append(unknown, unknownFields(joinFieldPath(path, key), item.Value, f.typ)...)
+		}
+	case reflect.Slice, reflect.Array:
+		items, ok := value.([]interface{})
+		if !ok {
+			return nil
+		}
+		for i, item := range items {
+			unknown = append(unknown, unknownFields(fmt.Sprintf("%s[%d]", path, i), item, t.Elem())...)
+		}
+	case reflect.Map:
+		m, ok := value.(yaml.MapSlice)
+		if !ok {
+			return nil
+		}
+		for _, item := range m {
+			unknown = append(unknown, unknownFields(joinFieldPath(path, fmt.Sprint(item.Key)), item.Value, t.Elem())...)
+		}
+	}
+	return unknown
+}
+
+func findYAMLField(fields []yamlField, name string) (yamlField, bool) {
+	for _, f := range fields {
+		if f.name == name {
+			return f, true
+		}
+	}
+	return yamlField{}, false
+}
+
+func joinFieldPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// suggestField returns the name of the field that is closest to the unknown
+// key, or an empty string if none of them are close enough
+func suggestField(fields []yamlField, key string) string {
+	maxDistance := len(key) / 4
+	if maxDistance < 2 {
+		maxDistance = 2
+	}
+	var suggestion string
+	best := maxDistance + 1
+	for _, f := range fields {
+		if d := editDistance(strings.ToLower(key), f.name); d < best {
+			best = d
+			suggestion = f.name
+		}
+	}
+	return suggestion
+}
+
+// editDistance returns the Levenshtein distance between the two strings
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = minInt(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
+
+func minInt(x int, xs ...int) int {
+	for _, y := range xs {
+		if y < x {
+			x = y
+		}
+	}
+	return x
+}
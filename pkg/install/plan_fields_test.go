@@ -0,0 +1,76 @@
+package install
+
+import (
+	"io/ioutil"
+	"reflect"
+	"testing"
+)
+
+func TestUnknownPlanFields(t *testing.T) {
+	plan := `cluster:
+  name: kubernetes
+  kubelete:
+    option_overrides:
+      max-pods: 100
+  kube_apiserver:
+    option_override:
+      v: 3
+docker:
+  logs:
+    opts:
+      max-size: 50m
+master:
+  load_balanced_fqnd: master.example.com
+  nodes:
+  - host: master01
+    ip: 10.0.0.1
+    kubelet:
+      option_overrides:
+        max-pods: 10
+  - host: master02
+    ipaddress: 10.0.0.2
+unknown_section: true
+`
+	unknown, err := unknownPlanFields([]byte(plan))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []unknownField{
+		{path: "cluster.kubelete", suggestion: "kubelet"},
+		{path: "cluster.kube_apiserver.option_override", suggestion: "option_overrides"},
+		{path: "master.load_balanced_fqnd", suggestion: "load_balanced_fqdn"},
+		{path: "master.nodes[1].ipaddress"},
+		{path: "unknown_section"},
+	}
+	if !reflect.DeepEqual(unknown, expected) {
+		t.Errorf("expected unknown fields %v, but got %v", expected, unknown)
+	}
+}
+
+func TestUnknownPlanFieldsTemplates(t *testing.T) {
+	for _, f := range []string{"./test/plan-template.golden.yaml", "./test/plan-template-with-storage.golden.yaml"} {
+		d, err := ioutil.ReadFile(f)
+		if err != nil {
+			t.Fatalf("error reading plan file: %v", err)
+		}
+		unknown, err := unknownPlanFields(d)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(unknown) != 0 {
+			t.Errorf("expected no unknown fields in %s, but got %v", f, unknown)
+		}
+	}
+}
+
+func TestValidatePlanUnknownFields(t *testing.T) {
+	p := validPlan()
+	p.unknownFields = []unknownField{{path: "cluster.kubelete", suggestion: "kubelet"}}
+	ok, errs := ValidatePlan(&p)
+	if ok {
+		t.Fatalf("expected the plan with unknown fields to be invalid")
+	}
+	if errs[0].Error() != `Unknown field "cluster.kubelete", did you mean "kubelet"?` {
+		t.Errorf("unexpected error: %v", errs[0])
+	}
+}
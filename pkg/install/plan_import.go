@@ -0,0 +1,382 @@
+package install
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/apprenda/kismatic/pkg/ansible"
+	"github.com/apprenda/kismatic/pkg/util"
+)
+
+// Formats of the sources that a plan can be imported from
+const (
+	ImportFormatINI       = "ini"
+	ImportFormatCSV       = "csv"
+	ImportFormatTerraform = "terraform"
+)
+
+// Variables, tags and columns of the sources that describe the roles,
+// labels and taints of the nodes
+const (
+	importRolesKey  = "kismatic_roles"
+	importLabelsKey = "kismatic_labels"
+	importTaintsKey = "kismatic_taints"
+)
+
+// planRoles are the roles of the node groups of the plan
+var planRoles = []string{"etcd", "master", "worker", "ingress", "storage"}
+
+// importGroupRoles maps the names of the groups of an INI inventory to roles.
+// Groups that are not in the map are ignored.
+var importGroupRoles = map[string]string{
+	"etcd":               "etcd",
+	"master":             "master",
+	"masters":            "master",
+	"kube-master":        "master",
+	"kube_control_plane": "master",
+	"worker":             "worker",
+	"workers":            "worker",
+	"nodes":              "worker",
+	"kube-node":          "worker",
+	"kube_node":          "worker",
+	"ingress":            "ingress",
+	"storage":            "storage",
+}
+
+// PlanImportOptions are the options of a plan import
+type PlanImportOptions struct {
+	// Source is the file the nodes are imported from
+	Source string
+	// Format of the source. If empty, the format is inferred from the
+	// extension of the source.
+	Format string
+	// AdminPassword of the plan
+	AdminPassword string
+}
+
+// importedNode is a node of the source, along with its roles
+type importedNode struct {
+	node  Node
+	roles []string
+	ssh   SSHConfig
+}
+
+// ImportPlan builds a plan with the defaults of the plan template, and the
+// nodes of an Ansible INI inventory, a CSV file or a Terraform state file
+func ImportPlan(opts PlanImportOptions) (*Plan, error) {
+	format := opts.Format
+	if format == "" {
+		format = importFormat(opts.Source)
+	}
+	f, err := os.Open(opts.Source)
+	if err != nil {
+		return nil, fmt.Errorf("could not read file: %v", err)
+	}
+	defer f.Close()
+	var nodes []importedNode
+	switch format {
+	case ImportFormatINI:
+		nodes, err = importINI(f)
+	case ImportFormatCSV:
+		nodes, err = importCSV(f)
+	case ImportFormatTerraform:
+		nodes, err = importTerraformState(f)
+	default:
+		return nil, fmt.Errorf("%q is not a supported format, the options are %q, %q and %q", format, ImportFormatINI, ImportFormatCSV, ImportFormatTerraform)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error importing %q: %v", opts.Source, err)
+	}
+	if len(nodes) == 0 {
+		return nil, fmt.Errorf("no nodes were found in %q", opts.Source)
+	}
+
+	p := buildPlanFromTemplateOptions(PlanTemplateOptions{AdminPassword: opts.AdminPassword})
+	sshSet := false
+	for _, n := range nodes {
+		for _, r := range n.roles {
+			if !util.Contains(r, planRoles) {
+				return nil, fmt.Errorf("node %q has the unknown role %q, the options are %s", n.node.Host, r, strings.Join(planRoles, ", "))
+			}
+		}
+		p = AddNodeToPlan(p, n.node, n.roles)
+		// the SSH configuration of the cluster is the one of the first node
+		// that has one
+		if !sshSet && n.ssh != (SSHConfig{}) {
+			if n.ssh.User != "" {
+				p.Cluster.SSH.User = n.ssh.User
+			}
+			if n.ssh.Key != "" {
+				p.Cluster.SSH.Key = n.ssh.Key
+			}
+			if n.ssh.Port != 0 {
+				p.Cluster.SSH.Port = n.ssh.Port
+			}
+			sshSet = true
+		}
+	}
+	// a single master node does not need a load balancer
+	if len(p.Master.Nodes) == 1 {
+		p.Master.LoadBalancedFQDN = p.Master.Nodes[0].IP
+		p.Master.LoadBalancedShortName = p.Master.Nodes[0].IP
+	}
+	return &p, nil
+}
+
+// importFormat returns the format of the source, based on its extension
+func importFormat(source string) string {
+	switch strings.ToLower(filepath.Ext(source)) {
+	case ".csv":
+		return ImportFormatCSV
+	case ".tfstate", ".json":
+		return ImportFormatTerraform
+	}
+	return ImportFormatINI
+}
+
+// importINI returns the nodes of an Ansible INI inventory. The roles of the
+// nodes are the groups they belong to.
+func importINI(r io.Reader) ([]importedNode, error) {
+	inv, err := ansible.ParseINI(r)
+	if err != nil {
+		return nil, err
+	}
+	var nodes []importedNode
+	index := map[string]int{}
+	for _, role := range inv.Roles {
+		roleName, ok := importGroupRoles[role.Name]
+		if !ok {
+			continue
+		}
+		for _, n := range role.Nodes {
+			if i, ok := index[n.Host]; ok {
+				if !util.Contains(roleName, nodes[i].roles) {
+					nodes[i].roles = append(nodes[i].roles, roleName)
+				}
+				continue
+			}
+			node, err := importedNodeFromVars(n.Host, n.PublicIP, n.InternalIP, n.Vars)
+			if err != nil {
+				return nil, err
+			}
+			node.roles = append(node.roles, roleName)
+			node.ssh = SSHConfig{User: n.SSHUser, Key: n.SSHPrivateKey, Port: n.SSHPort}
+			index[n.Host] = len(nodes)
+			nodes = append(nodes, node)
+		}
+	}
+	return nodes, nil
+}
+
+// importCSV returns the nodes of a CSV file, with a header that names the
+// host, ip, internal_ip, roles, labels and taints columns
+func importCSV(r io.Reader) ([]importedNode, error) {
+	records, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+	columns := map[string]int{}
+	for i, c := range records[0] {
+		columns[strings.ToLower(strings.TrimSpace(c))] = i
+	}
+	for _, c := range []string{"host", "ip", "roles"} {
+		if _, ok := columns[c]; !ok {
+			return nil, fmt.Errorf("the header does not have the required %q column", c)
+		}
+	}
+	field := func(record []string, column string) string {
+		if i, ok := columns[column]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+	var nodes []importedNode
+	for i, record := range records[1:] {
+		vars := map[string]string{
+			importRolesKey:  field(record, "roles"),
+			importLabelsKey: field(record, "labels"),
+			importTaintsKey: field(record, "taints"),
+		}
+		n, err := importedNodeFromVars(field(record, "host"), field(record, "ip"), field(record, "internal_ip"), vars)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", i+2, err)
+		}
+		if len(n.roles) == 0 {
+			return nil, fmt.Errorf("line %d: node %q does not have any roles", i+2, n.node.Host)
+		}
+		nodes = append(nodes, n)
+	}
+	return nodes, nil
+}
+
+// terraformState is the subset of the Terraform state that describes the
+// resources, in the format of Terraform 0.12 and later ("resources") and of
+// earlier versions ("modules")
+type terraformState struct {
+	Resources []struct {
+		Mode      string
+		Type      string
+		Name      string
+		Instances []struct {
+			Attributes map[string]interface{}
+		}
+	}
+	Modules []struct {
+		Resources map[string]struct {
+			Type    string
+			Primary struct {
+				Attributes map[string]string
+			}
+		}
+	}
+}
+
+// Attributes of the Terraform resources that are used as the hostname, IP
+// and internal IP of the nodes, in order of preference
+var (
+	terraformHostAttributes       = []string{"tags.Name", "labels.name", "hostname", "name", "private_dns"}
+	terraformIPAttributes         = []string{"public_ip", "ipv4_address", "access_ip_v4", "default_ip_address", "private_ip"}
+	terraformInternalIPAttributes = []string{"private_ip", "ipv4_address_private", "network_interface.0.network_ip"}
+)
+
+// importTerraformState returns the nodes of a Terraform state file. Only
+// the resources with a kismatic_roles tag or label are nodes.
+func importTerraformState(r io.Reader) ([]importedNode, error) {
+	var state terraformState
+	if err := json.NewDecoder(r).Decode(&state); err != nil {
+		return nil, fmt.Errorf("error decoding Terraform state: %v", err)
+	}
+	// the attributes of each resource, flattened to the format of the earlier
+	// versions of Terraform, such as "tags.Name"
+	var resources []map[string]string
+	for _, res := range state.Resources {
+		if res.Mode != "" && res.Mode != "managed" {
+			continue
+		}
+		for _, inst := range res.Instances {
+			attrs := map[string]string{}
+			flattenTerraformAttributes("", inst.Attributes, attrs)
+			resources = append(resources, attrs)
+		}
+	}
+	for _, m := range state.Modules {
+		// sort the resources, as their order in the state is not stable
+		keys := make([]string, 0, len(m.Resources))
+		for k := range m.Resources {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if strings.HasPrefix(k, "data.") {
+				continue
+			}
+			resources = append(resources, m.Resources[k].Primary.Attributes)
+		}
+	}
+
+	var nodes []importedNode
+	for _, attrs := range resources {
+		vars := map[string]string{}
+		for _, key := range []string{importRolesKey, importLabelsKey, importTaintsKey} {
+			vars[key] = firstAttribute(attrs, "tags."+key, "labels."+key)
+		}
+		if vars[importRolesKey] == "" {
+			continue
+		}
+		host := firstAttribute(attrs, terraformHostAttributes...)
+		ip := firstAttribute(attrs, terraformIPAttributes...)
+		internalIP := firstAttribute(attrs, terraformInternalIPAttributes...)
+		n, err := importedNodeFromVars(host, ip, internalIP, vars)
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, n)
+	}
+	return nodes, nil
+}
+
+func flattenTerraformAttributes(prefix string, v interface{}, attrs map[string]string) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, e := range v {
+			flattenTerraformAttributes(prefix+k+".", e, attrs)
+		}
+	case []interface{}:
+		for i, e := range v {
+			flattenTerraformAttributes(prefix+strconv.Itoa(i)+".", e, attrs)
+		}
+	case nil:
+	default:
+		attrs[strings.TrimSuffix(prefix, ".")] = fmt.Sprint(v)
+	}
+}
+
+func firstAttribute(attrs map[string]string, keys ...string) string {
+	for _, k := range keys {
+		if v := attrs[k]; v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// importedNodeFromVars returns a node, with the roles, labels and taints of
+// the variables
+func importedNodeFromVars(host, ip, internalIP string, vars map[string]string) (importedNode, error) {
+	n := importedNode{node: Node{Host: host, IP: ip, InternalIP: internalIP}}
+	if host == "" {
+		return n, fmt.Errorf("a node with IP %q does not have a hostname", ip)
+	}
+	if ip == "" {
+		return n, fmt.Errorf("node %q does not have an IP address", host)
+	}
+	if internalIP == ip {
+		n.node.InternalIP = ""
+	}
+	for _, r := range splitImportList(vars[importRolesKey]) {
+		if role, ok := importGroupRoles[r]; ok {
+			r = role
+		}
+		n.roles = append(n.roles, r)
+	}
+	for _, l := range splitImportList(vars[importLabelsKey]) {
+		parts := strings.SplitN(l, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return n, fmt.Errorf("node %q: label %q must be in the form key=value", host, l)
+		}
+		if n.node.Labels == nil {
+			n.node.Labels = map[string]string{}
+		}
+		n.node.Labels[parts[0]] = parts[1]
+	}
+	for _, t := range splitImportList(vars[importTaintsKey]) {
+		// taints are in the form key=value:effect, the value is optional
+		i := strings.LastIndex(t, ":")
+		if i <= 0 {
+			return n, fmt.Errorf("node %q: taint %q must be in the form key=value:effect", host, t)
+		}
+		taint := Taint{Key: t[:i], Effect: t[i+1:]}
+		if parts := strings.SplitN(taint.Key, "=", 2); len(parts) == 2 {
+			taint.Key, taint.Value = parts[0], parts[1]
+		}
+		n.node.Taints = append(n.node.Taints, taint)
+	}
+	return n, nil
+}
+
+// splitImportList splits a list separated by commas, semicolons or spaces
+func splitImportList(s string) []string {
+	return strings.FieldsFunc(s, func(c rune) bool {
+		return c == ',' || c == ';' || c == ' '
+	})
+}
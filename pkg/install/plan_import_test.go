@@ -0,0 +1,145 @@
+package install
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestImportPlanINI(t *testing.T) {
+	dir := mustGetTempDir(t)
+	source := writeTestPlanFile(t, dir, "hosts", `[etcd]
+"master01" ansible_host="10.0.0.1" internal_ipv4="192.168.0.1" ansible_ssh_private_key_file="id_rsa" ansible_port=2222 ansible_user="alice"
+[master]
+"master01" ansible_host="10.0.0.1" internal_ipv4="192.168.0.1" ansible_ssh_private_key_file="id_rsa" ansible_port=2222 ansible_user="alice"
+[workers]
+worker01 ansible_host=10.0.0.2 kismatic_labels="env=prod,zone=a" kismatic_taints="dedicated=db:NoSchedule"
+[bastion]
+bastion01 ansible_host=10.0.0.100
+`)
+	p, err := ImportPlan(PlanImportOptions{Source: source})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if p.Etcd.ExpectedCount != 1 || p.Master.ExpectedCount != 1 || p.Worker.ExpectedCount != 1 || p.Ingress.ExpectedCount != 0 {
+		t.Errorf("unexpected node counts: etcd %d, master %d, worker %d, ingress %d", p.Etcd.ExpectedCount, p.Master.ExpectedCount, p.Worker.ExpectedCount, p.Ingress.ExpectedCount)
+	}
+	master := Node{Host: "master01", IP: "10.0.0.1", InternalIP: "192.168.0.1"}
+	if !reflect.DeepEqual(p.Master.Nodes[0], master) || !reflect.DeepEqual(p.Etcd.Nodes[0], master) {
+		t.Errorf("expected the master node to be %+v, but got %+v", master, p.Master.Nodes[0])
+	}
+	if p.Master.LoadBalancedFQDN != "10.0.0.1" {
+		t.Errorf("expected the load balanced FQDN to be the IP of the master, but got %q", p.Master.LoadBalancedFQDN)
+	}
+	worker := Node{
+		Host:   "worker01",
+		IP:     "10.0.0.2",
+		Labels: map[string]string{"env": "prod", "zone": "a"},
+		Taints: []Taint{{Key: "dedicated", Value: "db", Effect: "NoSchedule"}},
+	}
+	if !reflect.DeepEqual(p.Worker.Nodes[0], worker) {
+		t.Errorf("expected the worker node to be %+v, but got %+v", worker, p.Worker.Nodes[0])
+	}
+	ssh := SSHConfig{User: "alice", Key: "id_rsa", Port: 2222}
+	if p.Cluster.SSH != ssh {
+		t.Errorf("expected the SSH configuration to be %+v, but got %+v", ssh, p.Cluster.SSH)
+	}
+}
+
+func TestImportPlanCSV(t *testing.T) {
+	dir := mustGetTempDir(t)
+	source := writeTestPlanFile(t, dir, "nodes.csv", `host,ip,internal_ip,roles,labels,taints
+node01,10.0.0.1,10.0.0.1,"etcd,master,worker",,
+node02,10.0.0.2,192.168.0.2,ingress;storage,rack=r1,gpu:NoExecute
+`)
+	p, err := ImportPlan(PlanImportOptions{Source: source})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(p.Etcd.Nodes) != 1 || len(p.Master.Nodes) != 1 || len(p.Worker.Nodes) != 1 || len(p.Ingress.Nodes) != 1 || len(p.Storage.Nodes) != 1 {
+		t.Fatalf("unexpected node groups: %+v", p)
+	}
+	if p.Worker.Nodes[0].InternalIP != "" {
+		t.Errorf("expected an internal IP equal to the IP to be dropped, but got %q", p.Worker.Nodes[0].InternalIP)
+	}
+	storage := Node{
+		Host:       "node02",
+		IP:         "10.0.0.2",
+		InternalIP: "192.168.0.2",
+		Labels:     map[string]string{"rack": "r1"},
+		Taints:     []Taint{{Key: "gpu", Effect: "NoExecute"}},
+	}
+	if !reflect.DeepEqual(p.Storage.Nodes[0], storage) {
+		t.Errorf("expected the storage node to be %+v, but got %+v", storage, p.Storage.Nodes[0])
+	}
+}
+
+func TestImportPlanTerraformState(t *testing.T) {
+	dir := mustGetTempDir(t)
+	v4 := writeTestPlanFile(t, dir, "v4.tfstate", `{
+  "version": 4,
+  "resources": [
+    {
+      "mode": "managed",
+      "type": "aws_instance",
+      "name": "master",
+      "instances": [
+        {"attributes": {"public_ip": "52.0.0.1", "private_ip": "10.0.0.1", "tags": {"Name": "master01", "kismatic_roles": "etcd,master"}}}
+      ]
+    },
+    {
+      "mode": "managed",
+      "type": "aws_security_group",
+      "name": "cluster",
+      "instances": [{"attributes": {"name": "cluster"}}]
+    }
+  ]
+}`)
+	v3 := writeTestPlanFile(t, dir, "v3.tfstate", `{
+  "version": 3,
+  "modules": [
+    {
+      "resources": {
+        "aws_instance.master": {
+          "type": "aws_instance",
+          "primary": {"attributes": {"public_ip": "52.0.0.1", "private_ip": "10.0.0.1", "tags.%": "2", "tags.Name": "master01", "tags.kismatic_roles": "etcd,master"}}
+        }
+      }
+    }
+  ]
+}`)
+	for _, source := range []string{v4, v3} {
+		p, err := ImportPlan(PlanImportOptions{Source: source})
+		if err != nil {
+			t.Fatalf("unexpected error importing %s: %v", source, err)
+		}
+		master := Node{Host: "master01", IP: "52.0.0.1", InternalIP: "10.0.0.1"}
+		if len(p.Master.Nodes) != 1 || !reflect.DeepEqual(p.Master.Nodes[0], master) || len(p.Etcd.Nodes) != 1 || len(p.Worker.Nodes) != 0 {
+			t.Errorf("%s: expected a single etcd and master node %+v, but got %+v", source, master, p.Master.Nodes)
+		}
+	}
+}
+
+func TestImportPlanInvalid(t *testing.T) {
+	dir := mustGetTempDir(t)
+	tests := []struct {
+		file    string
+		content string
+	}{
+		{"no-nodes.ini", "[bastion]\nbastion01\n"},
+		{"unknown-role.csv", "host,ip,roles\nnode01,10.0.0.1,database\n"},
+		{"no-roles.csv", "host,ip,roles\nnode01,10.0.0.1,\n"},
+		{"no-ip-column.csv", "host,roles\nnode01,worker\n"},
+		{"invalid-label.csv", "host,ip,roles,labels\nnode01,10.0.0.1,worker,env\n"},
+		{"invalid-taint.csv", "host,ip,roles,taints\nnode01,10.0.0.1,worker,dedicated=db\n"},
+		{"invalid.tfstate", "{"},
+	}
+	for _, test := range tests {
+		source := writeTestPlanFile(t, dir, test.file, test.content)
+		if _, err := ImportPlan(PlanImportOptions{Source: source}); err == nil {
+			t.Errorf("%s: expected an error, but didn't get one", test.file)
+		}
+	}
+	if _, err := ImportPlan(PlanImportOptions{Source: writeTestPlanFile(t, dir, "nodes.txt", ""), Format: "xml"}); err == nil {
+		t.Errorf("expected an error with an unknown format, but didn't get one")
+	}
+}
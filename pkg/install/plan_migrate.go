@@ -0,0 +1,213 @@
+package install
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// planMigration changes a plan from the previous version of the plan file
+// to the given version
+type planMigration struct {
+	// version of the plan file after the migration
+	version int
+	// description of the change, for the user
+	description string
+	// migrate changes the plan, and returns true if the plan was changed
+	migrate func(p *Plan) bool
+}
+
+// planMigrations are the migrations of the plan file, ordered by version.
+// New migrations must be added to the end of the list.
+var planMigrations = []planMigration{
+	{1, "features.package_manager moved to add_ons.package_manager", migrateFeaturesPackageManager},
+	{2, "cluster.allow_package_installation replaced by cluster.disable_package_installation", migrateAllowPackageInstallation},
+	{3, "cluster.networking.type moved to add_ons.cni.options.calico.mode", migrateNetworkingType},
+	{4, "add_ons.heapster.options.heapster_replicas moved to add_ons.heapster.options.heapster.replicas", migrateHeapsterReplicas},
+	{5, "add_ons.heapster.options.influxdb_pvc_name moved to add_ons.heapster.options.influxdb.pvc_name", migrateInfluxDBPVCName},
+	{6, "add_ons.dashbard renamed to add_ons.dashboard", migrateDashboard},
+	{7, "docker_registry.address and docker_registry.port replaced by docker_registry.server", migrateDockerRegistryServer},
+	{8, "docker.storage.direct_lvm replaced by docker.storage.direct_lvm_block_device", migrateDirectLVM},
+}
+
+// currentPlanVersion is the version of the plan file written by this version
+// of kismatic
+var currentPlanVersion = planMigrations[len(planMigrations)-1].version
+
+// migratePlan applies the migrations of the plan file that are newer than the
+// version of the plan, and sets the version of the plan to the current one.
+// Returns the description of the migrations that changed the plan.
+func migratePlan(p *Plan) ([]string, error) {
+	if p.PlanVersion > currentPlanVersion {
+		return nil, fmt.Errorf("plan file version %d is not supported by this version of kismatic, which supports up to version %d", p.PlanVersion, currentPlanVersion)
+	}
+	var changes []string
+	for _, m := range planMigrations {
+		if m.version <= p.PlanVersion {
+			continue
+		}
+		if m.migrate(p) {
+			changes = append(changes, m.description)
+		}
+		p.PlanVersion = m.version
+	}
+	return changes, nil
+}
+
+// package_manager moved from features: to add_ons: after KET v1.3.3
+func migrateFeaturesPackageManager(p *Plan) bool {
+	if p.Features == nil {
+		return false
+	}
+	if p.Features.PackageManager != nil {
+		p.AddOns.PackageManager.Disable = !p.Features.PackageManager.Enabled
+		// KET v1.3.3 did not have a provider field
+		p.AddOns.PackageManager.Provider = ket133PackageManagerProvider
+	}
+	p.Features = nil
+	return true
+}
+
+// allow_package_installation renamed to disable_package_installation after KET v1.4.0
+func migrateAllowPackageInstallation(p *Plan) bool {
+	if p.Cluster.AllowPackageInstallation == nil {
+		return false
+	}
+	p.Cluster.DisablePackageInstallation = !*p.Cluster.AllowPackageInstallation
+	p.Cluster.AllowPackageInstallation = nil
+	return true
+}
+
+// the calico mode was read from cluster.networking.type before KET v1.5.0.
+// It is only used when the CNI add-on is not configured.
+func migrateNetworkingType(p *Plan) bool {
+	if p.Cluster.Networking.Type == "" {
+		return false
+	}
+	if p.AddOns.CNI == nil {
+		p.AddOns.CNI = &CNI{Provider: cniProviderCalico}
+		p.AddOns.CNI.Options.Calico.Mode = p.Cluster.Networking.Type
+	}
+	p.Cluster.Networking.Type = ""
+	return true
+}
+
+// heapster_replicas moved to heapster.replicas in KET v1.5.0
+func migrateHeapsterReplicas(p *Plan) bool {
+	if p.AddOns.HeapsterMonitoring == nil || p.AddOns.HeapsterMonitoring.Options.HeapsterReplicas == 0 {
+		return false
+	}
+	p.AddOns.HeapsterMonitoring.Options.Heapster.Replicas = p.AddOns.HeapsterMonitoring.Options.HeapsterReplicas
+	p.AddOns.HeapsterMonitoring.Options.HeapsterReplicas = 0
+	return true
+}
+
+// influxdb_pvc_name moved to influxdb.pvc_name in KET v1.5.0
+func migrateInfluxDBPVCName(p *Plan) bool {
+	if p.AddOns.HeapsterMonitoring == nil || p.AddOns.HeapsterMonitoring.Options.InfluxDBPVCName == "" {
+		return false
+	}
+	p.AddOns.HeapsterMonitoring.Options.InfluxDB.PVCName = p.AddOns.HeapsterMonitoring.Options.InfluxDBPVCName
+	p.AddOns.HeapsterMonitoring.Options.InfluxDBPVCName = ""
+	return true
+}
+
+// the misspelled dashbard field is only read if the new one is not set
+func migrateDashboard(p *Plan) bool {
+	if p.AddOns.DashboardDeprecated == nil {
+		return false
+	}
+	if p.AddOns.Dashboard == nil {
+		p.AddOns.Dashboard = &Dashboard{
+			Disable: p.AddOns.DashboardDeprecated.Disable,
+		}
+	}
+	p.AddOns.DashboardDeprecated = nil
+	return true
+}
+
+func migrateDockerRegistryServer(p *Plan) bool {
+	if p.DockerRegistry.Address == "" && p.DockerRegistry.Port == 0 {
+		return false
+	}
+	if p.DockerRegistry.Server == "" && p.DockerRegistry.Address != "" && p.DockerRegistry.Port != 0 {
+		p.DockerRegistry.Server = fmt.Sprintf("%s:%d", p.DockerRegistry.Address, p.DockerRegistry.Port)
+	}
+	p.DockerRegistry.Address = ""
+	p.DockerRegistry.Port = 0
+	return true
+}
+
+// direct_lvm is only migrated when it is enabled and no storage options are
+// set. Otherwise, it is kept so that it can be validated.
+func migrateDirectLVM(p *Plan) bool {
+	if p.Docker.Storage.DirectLVM == nil || !p.Docker.Storage.DirectLVM.Enabled || len(p.Docker.Storage.Opts) != 0 {
+		return false
+	}
+	p.Docker.Storage.Driver = "devicemapper"
+	p.Docker.Storage.Opts = map[string]string{
+		"dm.thinpooldev":           "/dev/mapper/docker-thinpool",
+		"dm.use_deferred_removal":  "true",
+		"dm.use_deferred_deletion": fmt.Sprintf("%t", p.Docker.Storage.DirectLVM.EnableDeferredDeletion),
+	}
+	p.Docker.Storage.DirectLVMBlockDevice.Path = p.Docker.Storage.DirectLVM.BlockDevice
+	p.Docker.Storage.DirectLVMBlockDevice.ThinpoolPercent = "95"
+	p.Docker.Storage.DirectLVMBlockDevice.ThinpoolMetaPercent = "1"
+	p.Docker.Storage.DirectLVMBlockDevice.ThinpoolAutoextendThreshold = "80"
+	p.Docker.Storage.DirectLVMBlockDevice.ThinpoolAutoextendPercent = "20"
+	p.Docker.Storage.DirectLVM = nil
+	return true
+}
+
+// PlanMigration is the result of migrating a plan file to the current version
+type PlanMigration struct {
+	// FromVersion is the version of the plan file before the migration
+	FromVersion int
+	// ToVersion is the version of the plan file after the migration
+	ToVersion int
+	// Changes are the descriptions of the migrations that changed the plan
+	Changes []string
+	// Original is the content of the plan file before the migration
+	Original []byte
+	// Migrated is the content of the plan file after the migration
+	Migrated []byte
+}
+
+// MigratePlanFile migrates the plan file to the current version of the plan
+// file. The plan file is not changed: the migrated content is returned.
+func MigratePlanFile(file string) (*PlanMigration, error) {
+	fp := &FilePlanner{File: file}
+	original, err := ioutil.ReadFile(fp.File)
+	if err != nil {
+		return nil, fmt.Errorf("could not read file: %v", err)
+	}
+	var version struct {
+		PlanVersion int `yaml:"plan_version"`
+	}
+	if err := yaml.Unmarshal(original, &version); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal plan: %v", err)
+	}
+	p, changes, err := fp.read()
+	if err != nil {
+		return nil, err
+	}
+	m := &PlanMigration{
+		FromVersion: version.PlanVersion,
+		ToVersion:   p.PlanVersion,
+		Changes:     changes,
+		Original:    original,
+		Migrated:    original,
+	}
+	if m.FromVersion == m.ToVersion {
+		return m, nil
+	}
+	// the plan is written the same way as when it is updated by the other commands
+	var b bytes.Buffer
+	if err := WritePlan(&b, p); err != nil {
+		return nil, err
+	}
+	m.Migrated = b.Bytes()
+	return m, nil
+}
@@ -0,0 +1,115 @@
+package install
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPlanMigrationsOrdered(t *testing.T) {
+	for i, m := range planMigrations {
+		if m.version != i+1 {
+			t.Errorf("expected migration %q to have version %d, but got %d", m.description, i+1, m.version)
+		}
+	}
+}
+
+func TestMigratePlan(t *testing.T) {
+	allow := true
+	p := &Plan{}
+	p.Cluster.AllowPackageInstallation = &allow
+	p.Cluster.Networking.Type = "routed"
+	p.DockerRegistry.Address = "registry.example.com"
+	p.DockerRegistry.Port = 5000
+	p.AddOns.HeapsterMonitoring = &HeapsterMonitoring{}
+	p.AddOns.HeapsterMonitoring.Options.HeapsterReplicas = 3
+
+	changes, err := migratePlan(p)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expectedChanges := []string{planMigrations[1].description, planMigrations[2].description, planMigrations[3].description, planMigrations[6].description}
+	if !reflect.DeepEqual(changes, expectedChanges) {
+		t.Errorf("expected changes %v, but got %v", expectedChanges, changes)
+	}
+	if p.PlanVersion != currentPlanVersion {
+		t.Errorf("expected plan version %d, but got %d", currentPlanVersion, p.PlanVersion)
+	}
+	if p.Cluster.AllowPackageInstallation != nil || p.Cluster.DisablePackageInstallation {
+		t.Errorf("expected allow_package_installation to be replaced by disable_package_installation")
+	}
+	if p.Cluster.Networking.Type != "" || p.AddOns.CNI == nil || p.AddOns.CNI.Options.Calico.Mode != "routed" {
+		t.Errorf("expected cluster.networking.type to be moved to the calico mode")
+	}
+	if p.DockerRegistry.Server != "registry.example.com:5000" || p.DockerRegistry.Address != "" || p.DockerRegistry.Port != 0 {
+		t.Errorf("expected the docker registry address and port to be replaced by the server, but got %+v", p.DockerRegistry)
+	}
+	if p.AddOns.HeapsterMonitoring.Options.Heapster.Replicas != 3 || p.AddOns.HeapsterMonitoring.Options.HeapsterReplicas != 0 {
+		t.Errorf("expected heapster_replicas to be moved to heapster.replicas, but got %+v", p.AddOns.HeapsterMonitoring.Options)
+	}
+
+	// migrations older than the version of the plan are not applied
+	p = &Plan{PlanVersion: 2}
+	p.Cluster.Networking.Type = "routed"
+	p.Features = &Features{PackageManager: &DeprecatedPackageManager{Enabled: false}}
+	if _, err := migratePlan(p); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if p.Features == nil || p.AddOns.PackageManager.Disable {
+		t.Errorf("expected the migration of version 1 to be skipped")
+	}
+	if p.Cluster.Networking.Type != "" {
+		t.Errorf("expected the migration of version 3 to be applied")
+	}
+
+	p = &Plan{PlanVersion: currentPlanVersion + 1}
+	if _, err := migratePlan(p); err == nil {
+		t.Errorf("expected an error migrating a plan of a newer version, but didn't get one")
+	}
+}
+
+func TestMigratePlanFile(t *testing.T) {
+	dir := mustGetTempDir(t)
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "kismatic-cluster.yaml")
+	old := "cluster:\n  name: kubernetes\ndocker_registry:\n  address: registry.example.com\n  port: 5000\n"
+	if err := ioutil.WriteFile(file, []byte(old), 0644); err != nil {
+		t.Fatalf("error writing plan file: %v", err)
+	}
+
+	m, err := MigratePlanFile(file)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if m.FromVersion != 0 || m.ToVersion != currentPlanVersion {
+		t.Errorf("expected a migration from version 0 to %d, but got %d to %d", currentPlanVersion, m.FromVersion, m.ToVersion)
+	}
+	if string(m.Original) != old {
+		t.Errorf("expected the original plan file, but got %s", m.Original)
+	}
+	if !strings.Contains(string(m.Migrated), "server: registry.example.com:5000") || strings.Contains(string(m.Migrated), "address:") {
+		t.Errorf("expected the migrated plan file to use the server field, but got:\n%s", m.Migrated)
+	}
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		t.Fatalf("error reading plan file: %v", err)
+	}
+	if string(b) != old {
+		t.Errorf("expected the plan file to be unchanged")
+	}
+
+	// a migrated plan file is at the current version
+	if err := ioutil.WriteFile(file, m.Migrated, 0644); err != nil {
+		t.Fatalf("error writing plan file: %v", err)
+	}
+	m, err = MigratePlanFile(file)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if m.FromVersion != currentPlanVersion || len(m.Changes) != 0 {
+		t.Errorf("expected the migrated plan file to be at the current version, but got %+v", m)
+	}
+}
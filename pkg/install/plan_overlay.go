@@ -0,0 +1,257 @@
+package install
+
+import (
+	"fmt"
+	"io/ioutil"
+	"reflect"
+	"strconv"
+	"strings"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// mergePlanFiles deep-merges the overlays into the plan file, in order, and
+// then sets the values. Mappings are merged key by key, while lists and
+// scalars of the overlays replace the ones of the plan file.
+func mergePlanFiles(planFile string, overlays []string, values []string) ([]byte, error) {
+	merged, err := readPlanMapping(planFile)
+	if err != nil {
+		return nil, err
+	}
+	for _, o := range overlays {
+		overlay, err := readPlanMapping(o)
+		if err != nil {
+			return nil, err
+		}
+		merged = mergeMappings(merged, overlay)
+	}
+	for _, v := range values {
+		if merged, err = setPlanValue(merged, v); err != nil {
+			return nil, err
+		}
+	}
+	d, err := yaml.Marshal(merged)
+	if err != nil {
+		return nil, fmt.Errorf("error marshalling merged plan: %v", err)
+	}
+	return d, nil
+}
+
+func readPlanMapping(file string) (yaml.MapSlice, error) {
+	d, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("could not read file: %v", err)
+	}
+	var m yaml.MapSlice
+	if err := yaml.Unmarshal(d, &m); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal %q: %v", file, err)
+	}
+	return m, nil
+}
+
+// mergeMappings returns the base mapping with the keys of the overlay
+// merged into it
+func mergeMappings(base, overlay yaml.MapSlice) yaml.MapSlice {
+	merged := make(yaml.MapSlice, len(base))
+	copy(merged, base)
+	for _, item := range overlay {
+		i := mappingIndex(merged, item.Key)
+		if i < 0 {
+			merged = append(merged, item)
+			continue
+		}
+		baseValue, baseIsMapping := merged[i].Value.(yaml.MapSlice)
+		overlayValue, overlayIsMapping := item.Value.(yaml.MapSlice)
+		if baseIsMapping && overlayIsMapping {
+			merged[i].Value = mergeMappings(baseValue, overlayValue)
+			continue
+		}
+		merged[i].Value = item.Value
+	}
+	return merged
+}
+
+func mappingIndex(m yaml.MapSlice, key interface{}) int {
+	for i, item := range m {
+		if fmt.Sprint(item.Key) == fmt.Sprint(key) {
+			return i
+		}
+	}
+	return -1
+}
+
+// planPathSegment is a segment of the path of a plan field, such as "nodes[0]"
+type planPathSegment struct {
+	key string
+	// index of the list item, or -1 if the segment is not a list item
+	index int
+}
+
+// parsePlanPath splits the path of a plan field into its segments. Segments
+// are separated by dots, and can be double-quoted to include dots, as in
+// `docker.storage.opts."dm.thinpooldev"`. A segment can end with the index of
+// a list item, as in "nodes[0]".
+func parsePlanPath(path string) ([]planPathSegment, error) {
+	var segments []planPathSegment
+	rest := path
+	for {
+		s := planPathSegment{index: -1}
+		if strings.HasPrefix(rest, `"`) {
+			end := strings.Index(rest[1:], `"`)
+			if end < 0 {
+				return nil, fmt.Errorf("%q has an unterminated quote", path)
+			}
+			s.key = rest[1 : end+1]
+			rest = rest[end+2:]
+		} else {
+			end := strings.IndexAny(rest, ".[")
+			if end < 0 {
+				end = len(rest)
+			}
+			s.key = rest[:end]
+			rest = rest[end:]
+			if strings.ContainsAny(s.key, `]"`) {
+				return nil, fmt.Errorf("%q is not a valid path segment", s.key)
+			}
+		}
+		if s.key == "" {
+			return nil, fmt.Errorf("%q has an empty path segment", path)
+		}
+		if strings.HasPrefix(rest, "[") {
+			end := strings.Index(rest, "]")
+			if end < 0 {
+				return nil, fmt.Errorf("%q has an unterminated list index", path)
+			}
+			index, err := strconv.Atoi(rest[1:end])
+			if err != nil || index < 0 {
+				return nil, fmt.Errorf("%q is not a valid list index", rest[1:end])
+			}
+			s.index = index
+			rest = rest[end+1:]
+		}
+		segments = append(segments, s)
+		if rest == "" {
+			return segments, nil
+		}
+		if rest[0] != '.' {
+			return nil, fmt.Errorf("%q is not a valid path", path)
+		}
+		rest = rest[1:]
+	}
+}
+
+// setPlanValue sets the value of an assignment such as
+// "cluster.networking.pod_cidr_block=172.16.0.0/16" or
+// "master.nodes[0].ip=10.0.0.1" in the plan. Keys that contain dots are
+// double-quoted, as in `master.nodes[0].labels."kubernetes.io/role"=master`.
+// The value is a string, unless the field of the plan is of another type, such
+// as a number, a boolean or a list, in which case the value is parsed as YAML.
+func setPlanValue(m yaml.MapSlice, assignment string) (yaml.MapSlice, error) {
+	parts := strings.SplitN(assignment, "=", 2)
+	if len(parts) != 2 || parts[0] == "" {
+		return nil, fmt.Errorf("%q is not a valid value, it must be in the form path=value", assignment)
+	}
+	path, err := parsePlanPath(parts[0])
+	if err != nil {
+		return nil, err
+	}
+	var value interface{} = parts[1]
+	if t := planFieldType(path); t != nil && t.Kind() != reflect.String && t.Kind() != reflect.Interface {
+		if err := yaml.Unmarshal([]byte(parts[1]), &value); err != nil {
+			return nil, fmt.Errorf("the value of %q is not valid YAML: %v", parts[0], err)
+		}
+	}
+	result, err := setPathValue(m, path, value)
+	if err != nil {
+		return nil, fmt.Errorf("error setting %q: %v", parts[0], err)
+	}
+	return result.(yaml.MapSlice), nil
+}
+
+func setPathValue(node interface{}, path []planPathSegment, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	key := path[0].key
+	m, ok := node.(yaml.MapSlice)
+	if !ok && node != nil {
+		return nil, fmt.Errorf("%q is not a mapping", key)
+	}
+	i := mappingIndex(m, key)
+	var current interface{}
+	if i >= 0 {
+		current = m[i].Value
+	}
+	var newValue interface{}
+	if path[0].index < 0 {
+		v, err := setPathValue(current, path[1:], value)
+		if err != nil {
+			return nil, err
+		}
+		newValue = v
+	} else {
+		// only the existing items of a list can be set
+		list, ok := current.([]interface{})
+		index := path[0].index
+		if !ok || index >= len(list) {
+			return nil, fmt.Errorf("%q does not have an item at index %d", key, index)
+		}
+		item, err := setPathValue(list[index], path[1:], value)
+		if err != nil {
+			return nil, err
+		}
+		newList := make([]interface{}, len(list))
+		copy(newList, list)
+		newList[index] = item
+		newValue = newList
+	}
+	result := make(yaml.MapSlice, len(m))
+	copy(result, m)
+	if i >= 0 {
+		result[i].Value = newValue
+	} else {
+		result = append(result, yaml.MapItem{Key: key, Value: newValue})
+	}
+	return result, nil
+}
+
+// planFieldType returns the type of the plan field at the path, or nil if
+// the path does not match a field of the plan
+func planFieldType(path []planPathSegment) reflect.Type {
+	t := reflect.TypeOf(Plan{})
+	for _, segment := range path {
+		for t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		switch t.Kind() {
+		case reflect.Struct:
+			var found bool
+			for _, f := range yamlFields(t) {
+				if f.name == segment.key {
+					t, found = f.typ, true
+					break
+				}
+			}
+			if !found {
+				return nil
+			}
+		case reflect.Map:
+			t = t.Elem()
+		default:
+			return nil
+		}
+		if segment.index >= 0 {
+			for t.Kind() == reflect.Ptr {
+				t = t.Elem()
+			}
+			if t.Kind() != reflect.Slice {
+				return nil
+			}
+			t = t.Elem()
+		}
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
@@ -0,0 +1,156 @@
+package install
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func writeTestPlanFile(t *testing.T, dir, name, content string) string {
+	file := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(file, []byte(content), 0644); err != nil {
+		t.Fatalf("error writing %s: %v", name, err)
+	}
+	return file
+}
+
+func TestFilePlannerReadOverlays(t *testing.T) {
+	dir := mustGetTempDir(t)
+	base := writeTestPlanFile(t, dir, "base.yaml", `cluster:
+  name: kubernetes
+  networking:
+    pod_cidr_block: 172.16.0.0/16
+    service_cidr_block: 172.20.0.0/16
+master:
+  expected_count: 1
+  nodes:
+  - host: master01
+    ip: 10.0.0.1
+`)
+	overlay := writeTestPlanFile(t, dir, "production.yaml", `cluster:
+  name: production
+  networking:
+    pod_cidr_block: 10.100.0.0/16
+`)
+	fp := &FilePlanner{
+		File:     base,
+		Overlays: []string{overlay},
+		Values:   []string{"master.nodes[0].ip=10.0.0.2", "cluster.networking.service_cidr_block=10.200.0.0/16"},
+	}
+	p, err := fp.Read()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if p.Cluster.Name != "production" {
+		t.Errorf("expected the name of the overlay, but got %q", p.Cluster.Name)
+	}
+	if p.Cluster.Networking.PodCIDRBlock != "10.100.0.0/16" {
+		t.Errorf("expected the pod CIDR of the overlay, but got %q", p.Cluster.Networking.PodCIDRBlock)
+	}
+	if p.Cluster.Networking.ServiceCIDRBlock != "10.200.0.0/16" {
+		t.Errorf("expected the service CIDR that was set, but got %q", p.Cluster.Networking.ServiceCIDRBlock)
+	}
+	if len(p.Master.Nodes) != 1 || p.Master.Nodes[0].Host != "master01" || p.Master.Nodes[0].IP != "10.0.0.2" {
+		t.Errorf("expected the IP of the master node to be set, but got %+v", p.Master.Nodes)
+	}
+
+	if err := fp.Write(p); err == nil {
+		t.Errorf("expected an error writing a merged plan, but didn't get one")
+	}
+}
+
+func TestMergeMappingsReplacesLists(t *testing.T) {
+	dir := mustGetTempDir(t)
+	base := writeTestPlanFile(t, dir, "base.yaml", "worker:\n  expected_count: 2\n  nodes:\n  - host: worker01\n  - host: worker02\n")
+	overlay := writeTestPlanFile(t, dir, "overlay.yaml", "worker:\n  nodes:\n  - host: worker03\n")
+	fp := &FilePlanner{File: base, Overlays: []string{overlay}}
+	p, err := fp.Read()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if p.Worker.ExpectedCount != 2 {
+		t.Errorf("expected the expected count of the plan file to be kept, but got %d", p.Worker.ExpectedCount)
+	}
+	if len(p.Worker.Nodes) != 1 || p.Worker.Nodes[0].Host != "worker03" {
+		t.Errorf("expected the nodes to be replaced by the overlay, but got %+v", p.Worker.Nodes)
+	}
+}
+
+func TestSetPlanValueTypes(t *testing.T) {
+	dir := mustGetTempDir(t)
+	base := writeTestPlanFile(t, dir, "base.yaml", "cluster:\n  name: kubernetes\nworker:\n  expected_count: 1\n  nodes:\n  - host: worker01\n")
+	fp := &FilePlanner{
+		File: base,
+		Values: []string{
+			// strings are not parsed as YAML, which would turn them into an octal number and a boolean
+			"cluster.name=01234",
+			"cluster.version=no",
+			"worker.nodes[0].labels.zone=no",
+			// fields of other types are parsed as YAML
+			"worker.expected_count=3",
+			"cluster.disconnected_installation=true",
+		},
+	}
+	p, err := fp.Read()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if p.Cluster.Name != "01234" {
+		t.Errorf("expected cluster name %q, but got %q", "01234", p.Cluster.Name)
+	}
+	if p.Cluster.Version != "no" {
+		t.Errorf("expected cluster version %q, but got %q", "no", p.Cluster.Version)
+	}
+	if p.Worker.Nodes[0].Labels["zone"] != "no" {
+		t.Errorf("expected label %q, but got %q", "no", p.Worker.Nodes[0].Labels["zone"])
+	}
+	if p.Worker.ExpectedCount != 3 {
+		t.Errorf("expected worker count 3, but got %d", p.Worker.ExpectedCount)
+	}
+	if !p.Cluster.DisconnectedInstallation {
+		t.Error("expected disconnected installation to be set")
+	}
+}
+
+func TestSetPlanValueDottedKeys(t *testing.T) {
+	dir := mustGetTempDir(t)
+	base := writeTestPlanFile(t, dir, "base.yaml", "docker:\n  storage:\n    driver: devicemapper\nworker:\n  nodes:\n  - host: worker01\n")
+	fp := &FilePlanner{
+		File: base,
+		Values: []string{
+			`docker.storage.opts."dm.thinpooldev"=/dev/mapper/docker-thinpool`,
+			`worker.nodes[0].labels."kubernetes.io/role"=worker`,
+		},
+	}
+	p, err := fp.Read()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if v := p.Docker.Storage.Opts["dm.thinpooldev"]; v != "/dev/mapper/docker-thinpool" {
+		t.Errorf("expected the storage option to be set, but got %v", p.Docker.Storage.Opts)
+	}
+	if v := p.Worker.Nodes[0].Labels["kubernetes.io/role"]; v != "worker" {
+		t.Errorf("expected the label to be set, but got %v", p.Worker.Nodes[0].Labels)
+	}
+}
+
+func TestSetPlanValueInvalid(t *testing.T) {
+	dir := mustGetTempDir(t)
+	base := writeTestPlanFile(t, dir, "base.yaml", "cluster:\n  name: kubernetes\nmaster:\n  nodes:\n  - host: master01\n")
+	tests := []string{
+		"cluster.name",
+		"=foo",
+		"master.nodes[1].ip=10.0.0.1",
+		"cluster.name.first=foo",
+		"cluster..name=foo",
+		`cluster."name=foo`,
+		"master.nodes[a].ip=10.0.0.1",
+		`cluster.""=foo`,
+	}
+	for _, v := range tests {
+		fp := &FilePlanner{File: base, Values: []string{v}}
+		if _, err := fp.Read(); err == nil {
+			t.Errorf("expected an error setting %q, but didn't get one", v)
+		}
+	}
+}
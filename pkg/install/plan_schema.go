@@ -0,0 +1,65 @@
+package install
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+)
+
+// PlanJSONSchema returns the JSON Schema of the plan file, generated from
+// the fields of the plan. It can be used by editors to complete and lint
+// plan files.
+func PlanJSONSchema() ([]byte, error) {
+	schema := typeSchema(reflect.TypeOf(Plan{}))
+	schema["$schema"] = "http://json-schema.org/draft-07/schema#"
+	schema["title"] = "Kismatic plan file"
+	b, err := json.MarshalIndent(schema, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("error marshalling schema: %v", err)
+	}
+	return b, nil
+}
+
+func typeSchema(t reflect.Type) map[string]interface{} {
+	switch t.Kind() {
+	case reflect.Ptr:
+		return typeSchema(t.Elem())
+	case reflect.Struct:
+		properties := map[string]interface{}{}
+		for _, f := range yamlFields(t) {
+			properties[f.name] = typeSchema(f.typ)
+		}
+		return map[string]interface{}{
+			"type":                 "object",
+			"properties":           properties,
+			"additionalProperties": false,
+		}
+	case reflect.Map:
+		values := typeSchema(t.Elem())
+		// maps of strings, such as the option overrides, are written with
+		// unquoted numbers and booleans, which are read as strings
+		if t.Elem().Kind() == reflect.String {
+			values = map[string]interface{}{"type": []string{"string", "number", "boolean"}}
+		}
+		return map[string]interface{}{
+			"type":                 "object",
+			"additionalProperties": values,
+		}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{
+			"type":  "array",
+			"items": typeSchema(t.Elem()),
+		}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	}
+	// any value is accepted
+	return map[string]interface{}{}
+}
@@ -0,0 +1,52 @@
+package install
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestPlanJSONSchema(t *testing.T) {
+	b, err := PlanJSONSchema()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var schema map[string]interface{}
+	if err := json.Unmarshal(b, &schema); err != nil {
+		t.Fatalf("error unmarshalling schema: %v", err)
+	}
+	property := func(s map[string]interface{}, names ...string) map[string]interface{} {
+		for _, n := range names {
+			props, ok := s["properties"].(map[string]interface{})
+			if !ok {
+				t.Fatalf("expected %q to have properties", n)
+			}
+			if s, ok = props[n].(map[string]interface{}); !ok {
+				t.Fatalf("expected property %q in schema", n)
+			}
+		}
+		return s
+	}
+	if schema["additionalProperties"] != false {
+		t.Errorf("expected unknown properties of the plan to be rejected")
+	}
+	if typ := property(schema, "cluster", "kubelet")["type"]; typ != "object" {
+		t.Errorf("expected cluster.kubelet to be an object, but got %v", typ)
+	}
+	overrides := property(schema, "cluster", "kubelet", "option_overrides")
+	values := overrides["additionalProperties"].(map[string]interface{})["type"]
+	if !reflect.DeepEqual(values, []interface{}{"string", "number", "boolean"}) {
+		t.Errorf("expected option_overrides to be a map of strings, numbers or booleans, but got %v", overrides)
+	}
+	nodes := property(schema, "master", "nodes")
+	if nodes["type"] != "array" {
+		t.Errorf("expected master.nodes to be an array, but got %v", nodes["type"])
+	}
+	node := nodes["items"].(map[string]interface{})
+	if typ := property(node, "internalip")["type"]; typ != "string" {
+		t.Errorf("expected the internal IP of a node to be a string, but got %v", typ)
+	}
+	if typ := property(schema, "master", "expected_count")["type"]; typ != "integer" {
+		t.Errorf("expected master.expected_count to be an integer, but got %v", typ)
+	}
+}
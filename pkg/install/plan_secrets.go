@@ -0,0 +1,148 @@
+package install
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+)
+
+// Prefixes of the references to secrets that are accepted by the secret
+// fields of the plan file
+const (
+	secretRefEnv  = "env:"
+	secretRefFile = "file:"
+	secretRefExec = "exec:"
+)
+
+// secretRef is a reference to a secret, and the value it was resolved to
+type secretRef struct {
+	ref   string
+	value string
+}
+
+// secretFields returns the fields of the plan that accept references to
+// secrets, keyed by their path in the plan file
+func secretFields(p *Plan) map[string]*string {
+	fields := map[string]*string{
+		"cluster.admin_password":        &p.Cluster.AdminPassword,
+		"cluster.cloud_provider.config": &p.Cluster.CloudProvider.Config,
+		"docker_registry.password":      &p.DockerRegistry.Password,
+	}
+	if p.AddOns.CNI != nil {
+		fields["add_ons.cni.options.weave.password"] = &p.AddOns.CNI.Options.Weave.Password
+	}
+	return fields
+}
+
+// isSecretRef returns true if the value is a reference to a secret
+func isSecretRef(value string) bool {
+	return strings.HasPrefix(value, secretRefEnv) || strings.HasPrefix(value, secretRefFile) || strings.HasPrefix(value, secretRefExec)
+}
+
+// resolveSecrets replaces the references to secrets in the plan by the
+// secrets, and records the references so that they can be written back
+// instead of the secrets
+func resolveSecrets(p *Plan) error {
+	fields := secretFields(p)
+	// resolve the fields in a stable order, for the errors to be predictable
+	paths := make([]string, 0, len(fields))
+	for path := range fields {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		field := fields[path]
+		if !isSecretRef(*field) {
+			continue
+		}
+		value, err := resolveSecretRef(*field)
+		if err != nil {
+			return fmt.Errorf("error resolving %s: %v", path, err)
+		}
+		if p.secretRefs == nil {
+			p.secretRefs = map[string]secretRef{}
+		}
+		p.secretRefs[path] = secretRef{ref: *field, value: value}
+		*field = value
+	}
+	return nil
+}
+
+func resolveSecretRef(ref string) (string, error) {
+	switch {
+	case strings.HasPrefix(ref, secretRefEnv):
+		name := strings.TrimPrefix(ref, secretRefEnv)
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			return "", fmt.Errorf("environment variable %q is not set", name)
+		}
+		return value, nil
+	case strings.HasPrefix(ref, secretRefFile):
+		file := strings.TrimPrefix(ref, secretRefFile)
+		b, err := ioutil.ReadFile(file)
+		if err != nil {
+			return "", fmt.Errorf("could not read file: %v", err)
+		}
+		return strings.TrimRight(string(b), "\r\n"), nil
+	case strings.HasPrefix(ref, secretRefExec):
+		command := strings.TrimPrefix(ref, secretRefExec)
+		var stdout, stderr bytes.Buffer
+		cmd := exec.Command("sh", "-c", command)
+		cmd.Stdout = &stdout
+		cmd.Stderr = &stderr
+		if err := cmd.Run(); err != nil {
+			return "", fmt.Errorf("command %q failed: %v: %s", command, err, strings.TrimSpace(stderr.String()))
+		}
+		return strings.TrimRight(stdout.String(), "\r\n"), nil
+	}
+	return ref, nil
+}
+
+// changedSecrets returns the paths of the secrets that were resolved from
+// references, and changed afterwards
+func (p *Plan) changedSecrets() []string {
+	var changed []string
+	for path, field := range secretFields(p) {
+		if s, ok := p.secretRefs[path]; ok && *field != s.value {
+			changed = append(changed, path)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
+
+// checkChangedSecrets returns an error if a secret that was resolved from a
+// reference was changed, as it would be written in plaintext instead of the
+// reference
+func checkChangedSecrets(p *Plan) error {
+	changed := p.changedSecrets()
+	if len(changed) == 0 {
+		return nil
+	}
+	return fmt.Errorf("the plan is not written, as %s changed after being resolved from a reference "+
+		"and would be written in plaintext: update the referenced secret instead", strings.Join(changed, ", "))
+}
+
+// withSecretRefs returns a copy of the plan in which the secrets that were
+// resolved from references are replaced by the references. Secrets that were
+// changed after they were resolved are kept.
+func (p *Plan) withSecretRefs() *Plan {
+	if len(p.secretRefs) == 0 {
+		return p
+	}
+	c := *p
+	if c.AddOns.CNI != nil {
+		cni := *c.AddOns.CNI
+		c.AddOns.CNI = &cni
+	}
+	for path, field := range secretFields(&c) {
+		if s, ok := p.secretRefs[path]; ok && *field == s.value {
+			*field = s.ref
+		}
+	}
+	return &c
+}
@@ -0,0 +1,104 @@
+package install
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReadWritePlanSecretRefs(t *testing.T) {
+	dir := mustGetTempDir(t)
+	defer os.RemoveAll(dir)
+	secretFile := filepath.Join(dir, "registry-password")
+	if err := ioutil.WriteFile(secretFile, []byte("registry-secret\n"), 0600); err != nil {
+		t.Fatalf("error writing secret file: %v", err)
+	}
+	os.Setenv("KISMATIC_TEST_ADMIN_PASSWORD", "admin-secret")
+	defer os.Unsetenv("KISMATIC_TEST_ADMIN_PASSWORD")
+
+	file := filepath.Join(dir, "kismatic-cluster.yaml")
+	plan := `cluster:
+  admin_password: env:KISMATIC_TEST_ADMIN_PASSWORD
+docker_registry:
+  password: file:` + secretFile + `
+add_ons:
+  cni:
+    provider: weave
+    options:
+      weave:
+        password: exec:echo weave-secret
+`
+	if err := ioutil.WriteFile(file, []byte(plan), 0644); err != nil {
+		t.Fatalf("error writing plan file: %v", err)
+	}
+	fp := &FilePlanner{File: file}
+	p, err := fp.Read()
+	if err != nil {
+		t.Fatalf("unexpected error reading plan: %v", err)
+	}
+	if p.Cluster.AdminPassword != "admin-secret" {
+		t.Errorf("expected the admin password to be read from the environment, but got %q", p.Cluster.AdminPassword)
+	}
+	if p.DockerRegistry.Password != "registry-secret" {
+		t.Errorf("expected the registry password to be read from the file, but got %q", p.DockerRegistry.Password)
+	}
+	if p.AddOns.CNI.Options.Weave.Password != "weave-secret" {
+		t.Errorf("expected the weave password to be the output of the command, but got %q", p.AddOns.CNI.Options.Weave.Password)
+	}
+
+	// the references are written back
+	if err := fp.Write(p); err != nil {
+		t.Fatalf("unexpected error writing plan: %v", err)
+	}
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		t.Fatalf("error reading plan file: %v", err)
+	}
+	written := string(b)
+	for _, s := range []string{"admin_password: env:KISMATIC_TEST_ADMIN_PASSWORD", "password: exec:echo weave-secret", "password: file:" + secretFile} {
+		if !strings.Contains(written, s) {
+			t.Errorf("expected the plan file to contain %q, but got:\n%s", s, written)
+		}
+	}
+	for _, s := range []string{"admin-secret", "password: weave-secret"} {
+		if strings.Contains(written, s) {
+			t.Errorf("expected the plan file to not contain the secret %q", s)
+		}
+	}
+	if p.Cluster.AdminPassword != "admin-secret" {
+		t.Errorf("expected the plan to keep the resolved secret after it was written")
+	}
+
+	// a secret that was changed is not written in plaintext
+	p.DockerRegistry.Password = "new-registry-secret"
+	err = fp.Write(p)
+	if err == nil || !strings.Contains(err.Error(), "docker_registry.password") {
+		t.Errorf("expected an error about the changed secret, but got %v", err)
+	}
+	if b, _ := ioutil.ReadFile(file); strings.Contains(string(b), "new-registry-secret") {
+		t.Errorf("expected the changed secret to not be written to the plan file")
+	}
+}
+
+func TestReadPlanSecretRefErrors(t *testing.T) {
+	dir := mustGetTempDir(t)
+	defer os.RemoveAll(dir)
+	os.Unsetenv("KISMATIC_TEST_MISSING")
+	tests := []string{
+		"cluster:\n  admin_password: env:KISMATIC_TEST_MISSING\n",
+		"docker_registry:\n  password: file:" + filepath.Join(dir, "missing") + "\n",
+		"docker_registry:\n  password: 'exec:exit 1'\n",
+	}
+	for _, plan := range tests {
+		file := filepath.Join(dir, "kismatic-cluster.yaml")
+		if err := ioutil.WriteFile(file, []byte(plan), 0644); err != nil {
+			t.Fatalf("error writing plan file: %v", err)
+		}
+		fp := &FilePlanner{File: file}
+		if _, err := fp.Read(); err == nil {
+			t.Errorf("expected an error reading plan %q, but didn't get one", plan)
+		}
+	}
+}
@@ -48,7 +48,7 @@ func TestWritePlanTemplate(t *testing.T) {
 			t.Fatalf("error creating temp dir: %v", err)
 		}
 		file := filepath.Join(tmp, "kismatic-cluster.yaml")
-		fp := &FilePlanner{file}
+		fp := &FilePlanner{File: file}
 		if err = WritePlanTemplate(test.template, fp); err != nil {
 			t.Fatalf("error writing plan template: %v", err)
 		}
@@ -68,6 +68,55 @@ func TestWritePlanTemplate(t *testing.T) {
 	}
 }
 
+func TestWritePlanTemplateOptions(t *testing.T) {
+	fp := &fakePlanReadWriter{}
+	opts := PlanTemplateOptions{
+		EtcdNodes:        1,
+		MasterNodes:      1,
+		WorkerNodes:      1,
+		CNIProvider:      "weave",
+		DNSProvider:      "coredns",
+		PodCIDRBlock:     "10.100.0.0/16",
+		ServiceCIDRBlock: "10.200.0.0/16",
+	}
+	if err := WritePlanTemplate(opts, fp); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	p := fp.plan
+	if p.AddOns.CNI.Provider != "weave" || p.AddOns.CNI.Options.Calico.Mode != "" {
+		t.Errorf("expected the weave CNI provider without Calico options, but got %+v", p.AddOns.CNI)
+	}
+	if p.AddOns.DNS.Provider != "coredns" {
+		t.Errorf("expected the coredns DNS provider, but got %q", p.AddOns.DNS.Provider)
+	}
+	if p.Cluster.Networking.PodCIDRBlock != "10.100.0.0/16" || p.Cluster.Networking.ServiceCIDRBlock != "10.200.0.0/16" {
+		t.Errorf("expected the CIDR blocks to be set, but got %+v", p.Cluster.Networking)
+	}
+
+	invalid := []PlanTemplateOptions{
+		{CNIProvider: "flannel"},
+		{CNIProvider: "weave", CalicoMode: "routed"},
+		{CalicoMode: "bgp"},
+		{DNSProvider: "bind"},
+		{PodCIDRBlock: "10.100.0.0"},
+	}
+	for _, o := range invalid {
+		if err := WritePlanTemplate(o, fp); err == nil {
+			t.Errorf("expected an error with %+v, but didn't get one", o)
+		}
+	}
+}
+
+type fakePlanReadWriter struct {
+	plan *Plan
+}
+
+func (f *fakePlanReadWriter) Read() (*Plan, error) { return f.plan, nil }
+func (f *fakePlanReadWriter) Write(p *Plan) error {
+	f.plan = p
+	return nil
+}
+
 func TestReadWithDeprecated(t *testing.T) {
 	pm := &DeprecatedPackageManager{
 		Enabled: true,
@@ -78,7 +127,9 @@ func TestReadWithDeprecated(t *testing.T) {
 	}
 	b := false
 	p.Cluster.AllowPackageInstallation = &b
-	readDeprecatedFields(p)
+	if _, err := migratePlan(p); err != nil {
+		t.Fatalf("unexpected error migrating plan: %v", err)
+	}
 
 	// features.package_manager should be set to add_ons.package_manager
 	if p.AddOns.PackageManager.Disable || p.AddOns.PackageManager.Provider != "helm" {
@@ -188,7 +239,7 @@ func TestReadDeprecatedDashboard(t *testing.T) {
 			t.Fatalf("error writing plan file")
 		}
 
-		planner := FilePlanner{file}
+		planner := FilePlanner{File: file}
 		plan, err := planner.Read()
 		if err != nil {
 			t.Fatalf("error reading plan file")
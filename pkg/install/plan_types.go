@@ -59,6 +59,9 @@ func taintEffects() []string {
 
 // Plan is the installation plan that the user intends to execute
 type Plan struct {
+	// Version of the plan file. Plan files of previous versions are migrated
+	// to the current version when they are read.
+	PlanVersion int `yaml:"plan_version"`
 	// Kubernetes cluster configuration
 	// +required
 	Cluster Cluster
@@ -88,6 +91,11 @@ type Plan struct {
 	Storage OptionalNodeGroup
 	// NFS volumes of the cluster.
 	NFS *NFS `yaml:"nfs,omitempty"`
+	// unknownFields are the keys of the plan file that are ignored
+	unknownFields []unknownField
+	// secretRefs are the references the secrets of the plan were resolved
+	// from, keyed by the path of the field in the plan file
+	secretRefs map[string]secretRef
 }
 
 // Cluster describes a Kubernetes cluster
@@ -137,6 +145,32 @@ type Cluster struct {
 	KubeletOptions KubeletOptions `yaml:"kubelet"`
 	// The CloudProvider configuration for the cluster.
 	CloudProvider CloudProvider `yaml:"cloud_provider"`
+	// The minimum hardware of the nodes, verified by the pre-flight checks.
+	Preflight PreflightOptions `yaml:"preflight,omitempty"`
+}
+
+// PreflightOptions are the hardware minimums verified by the pre-flight checks.
+// The memory reported by a node does not include the memory reserved by the kernel,
+// so it is usually lower than the memory of the machine.
+type PreflightOptions struct {
+	// Minimum number of CPUs of the etcd nodes.
+	// +default=1
+	EtcdMinimumCPUs int `yaml:"etcd_minimum_cpus,omitempty"`
+	// Minimum number of CPUs of the master nodes.
+	// +default=1
+	MasterMinimumCPUs int `yaml:"master_minimum_cpus,omitempty"`
+	// Minimum number of CPUs of the worker, ingress and storage nodes.
+	// +default=1
+	WorkerMinimumCPUs int `yaml:"worker_minimum_cpus,omitempty"`
+	// Minimum memory of the etcd nodes, in bytes.
+	// +default=750000000
+	EtcdMinimumMemoryBytes int `yaml:"etcd_minimum_memory_bytes,omitempty"`
+	// Minimum memory of the master nodes, in bytes.
+	// +default=1500000000
+	MasterMinimumMemoryBytes int `yaml:"master_minimum_memory_bytes,omitempty"`
+	// Minimum memory of the worker, ingress and storage nodes, in bytes.
+	// +default=750000000
+	WorkerMinimumMemoryBytes int `yaml:"worker_minimum_memory_bytes,omitempty"`
 }
 
 type APIServerOptions struct {
@@ -211,6 +245,53 @@ type CertsConfig struct {
 	// For example: "17520h" for 2 years.
 	// +required.
 	CAExpiry string `yaml:"ca_expiry"`
+	// The PKI backend that issues the cluster certificates.
+	// When set to local, a self-signed Certificate Authority is generated.
+	// When set to intermediate, the certificates are signed by the intermediate
+	// Certificate Authority defined in intermediate_ca.
+	// When set to vault, the certificates are signed by the HashiCorp Vault
+	// PKI secrets engine defined in vault.
+	// +default=local
+	// +options=local,intermediate,vault
+	Backend string `yaml:"backend,omitempty"`
+	// The intermediate Certificate Authority that signs the cluster certificates
+	// when the backend is set to intermediate.
+	IntermediateCA *IntermediateCA `yaml:"intermediate_ca,omitempty"`
+	// The HashiCorp Vault PKI secrets engine that signs the cluster certificates
+	// when the backend is set to vault.
+	Vault *VaultPKIConfig `yaml:"vault,omitempty"`
+}
+
+// IntermediateCA is an existing Certificate Authority, whose certificate
+// and private key are provided by the user.
+type IntermediateCA struct {
+	// Path to the certificate of the Certificate Authority.
+	// +required
+	Cert string
+	// Path to the unencrypted private key of the Certificate Authority.
+	// +required
+	Key string
+}
+
+// VaultPKIConfig describes the HashiCorp Vault PKI secrets engine that signs
+// the cluster certificates. The Vault token is read from the file in token_file,
+// or from the VAULT_TOKEN environment variable.
+type VaultPKIConfig struct {
+	// The address of the Vault server. For example: "https://vault.example.com:8200"
+	// +required
+	Address string
+	// The path where the PKI secrets engine is mounted.
+	// +default=pki
+	Mount string `yaml:"mount,omitempty"`
+	// The role that constrains the certificates signed by Vault. The certificates
+	// are signed with the sign-verbatim endpoint, as the Kubernetes components
+	// rely on the organizations in the certificates' subjects.
+	Role string `yaml:"role,omitempty"`
+	// Path to the file that contains the Vault token.
+	TokenFile string `yaml:"token_file,omitempty"`
+	// Path to the CA certificate used to verify the Vault server's certificate.
+	// The system's trusted CAs are used when not set.
+	CACert string `yaml:"ca_cert,omitempty"`
 }
 
 // SSHConfig describes the cluster's SSH configuration for accessing nodes
@@ -220,12 +301,33 @@ type SSHConfig struct {
 	// +required
 	User string
 	// The absolute path of the SSH key that should be used for accessing the
-	// cluster nodes via SSH.
-	// +required
+	// cluster nodes via SSH. Encrypted keys are supported. When an ssh-agent is
+	// running, the key must be loaded in it. Otherwise, the passphrase is prompted for once.
+	// Can be left empty when the keys are provided by a running ssh-agent.
 	Key string `yaml:"ssh_key"`
 	// The port number on which cluster nodes are listening for SSH connections.
 	// +required
 	Port int `yaml:"ssh_port"`
+	// The bastion (jump) host through which all SSH connections to the
+	// cluster nodes are tunneled. Can be overridden for a specific node.
+	Bastion *BastionConfig `yaml:"bastion,omitempty"`
+}
+
+// BastionConfig describes an SSH bastion host that is used to reach nodes
+// that are not directly accessible from the machine running KET.
+type BastionConfig struct {
+	// The hostname or IP address of the bastion host.
+	// +required
+	Host string
+	// The user for accessing the bastion host via SSH.
+	// Defaults to the cluster's SSH user.
+	User string `yaml:"user,omitempty"`
+	// The absolute path of the SSH key that should be used for accessing
+	// the bastion host. Defaults to the cluster's SSH key.
+	Key string `yaml:"ssh_key,omitempty"`
+	// The port number on which the bastion host is listening for SSH connections.
+	// +default=22
+	Port int `yaml:"ssh_port,omitempty"`
 }
 
 // CloudProvider controls the Kubernetes cloud providers feature
@@ -629,6 +731,10 @@ type Node struct {
 	// Kubelet configuration applied to this node.
 	// If a node is repeated for multiple roles, the overrides cannot be different.
 	KubeletOptions KubeletOptions `yaml:"kubelet,omitempty"`
+	// The bastion host through which SSH connections to this node are tunneled.
+	// Takes precedence over the cluster's bastion configuration.
+	// If a node is repeated for multiple roles, the bastion cannot be different.
+	Bastion *BastionConfig `yaml:"bastion,omitempty"`
 }
 
 // Taint for nodes
@@ -824,7 +930,8 @@ func (p *Plan) GetSSHConnection(host string) (*SSHConnection, error) {
 		return nil, notFoundErr
 	}
 
-	return &SSHConnection{&p.Cluster.SSH, foundNode}, nil
+	sshConfig := p.Cluster.SSH.forNode(*foundNode)
+	return &SSHConnection{&sshConfig, foundNode}, nil
 }
 
 // GetSSHClient is a convience method that calls GetSSHConnection and returns an SSH client with the result
@@ -833,7 +940,7 @@ func (p *Plan) GetSSHClient(host string) (ssh.Client, error) {
 	if err != nil {
 		return nil, err
 	}
-	client, err := ssh.NewClient(con.Node.IP, con.SSHConfig.Port, con.SSHConfig.User, con.SSHConfig.Key)
+	client, err := con.client()
 	if err != nil {
 		return nil, fmt.Errorf("error creating SSH client for host %s: %v", host, err)
 	}
@@ -841,6 +948,80 @@ func (p *Plan) GetSSHClient(host string) (ssh.Client, error) {
 	return client, nil
 }
 
+// sshClient returns an SSH client for the given node
+func (p *Plan) sshClient(n Node) (ssh.Client, error) {
+	sshConfig := p.Cluster.SSH.forNode(n)
+	return SSHConnection{&sshConfig, &n}.client()
+}
+
+func (con SSHConnection) client() (ssh.Client, error) {
+	return ssh.NewClient(con.Node.IP, con.SSHConfig.Port, con.SSHConfig.User, con.SSHConfig.Key, con.SSHConfig.bastion())
+}
+
+// forNode returns the SSH configuration that applies to the given node.
+// The node's bastion takes precedence over the cluster's bastion, and any
+// missing bastion details are defaulted from the cluster's SSH configuration.
+func (s SSHConfig) forNode(n Node) SSHConfig {
+	bastion := s.Bastion
+	if n.Bastion != nil {
+		bastion = n.Bastion
+	}
+	if bastion == nil {
+		return s
+	}
+	b := *bastion
+	if b.User == "" {
+		b.User = s.User
+	}
+	if b.Key == "" {
+		b.Key = s.Key
+	}
+	if b.Port == 0 {
+		b.Port = 22
+	}
+	s.Bastion = &b
+	return s
+}
+
+// bastion returns the SSH bastion, or nil if nodes are reached directly
+func (s SSHConfig) bastion() *ssh.Bastion {
+	if s.Bastion == nil {
+		return nil
+	}
+	return &ssh.Bastion{
+		Host: s.Bastion.Host,
+		Port: s.Bastion.Port,
+		User: s.Bastion.User,
+		Key:  s.Bastion.Key,
+	}
+}
+
+// prepareKeys makes sure that the SSH keys used to reach the nodes can be used
+// by the SSH clients, prompting for the passphrase of encrypted keys if necessary.
+// Empty keys are skipped, as those are provided by the ssh-agent.
+func (s SSHConfig) prepareKeys(nodes []Node) error {
+	prepared := map[string]bool{}
+	prepare := func(key string) error {
+		if key == "" || prepared[key] {
+			return nil
+		}
+		prepared[key] = true
+		return ssh.PrepareKey(key)
+	}
+	for _, n := range nodes {
+		c := s.forNode(n)
+		if err := prepare(c.Key); err != nil {
+			return err
+		}
+		if c.Bastion != nil {
+			if err := prepare(c.Bastion.Key); err != nil {
+				return fmt.Errorf("bastion: %v", err)
+			}
+		}
+	}
+	return nil
+}
+
 func firstIfItExists(nodes []Node) *Node {
 	if len(nodes) > 0 {
 		return &nodes[0]
@@ -3,6 +3,7 @@ package install
 import (
 	"gopkg.in/yaml.v2"
 	"io/ioutil"
+	"strings"
 	"testing"
 )
 
@@ -13,3 +14,59 @@ func TestCanReadAPIServerOverrides(t *testing.T) {
 
 	assertEqual(t, p.Cluster.APIServerOptions.Overrides["runtime-config"], "beta/v2api=true,alpha/v1api=true")
 }
+
+func TestSSHConfigForNodeBastion(t *testing.T) {
+	cluster := SSHConfig{
+		User: "kismaticuser",
+		Key:  "/keys/cluster.pem",
+		Port: 22,
+		Bastion: &BastionConfig{
+			Host: "bastion.example.com",
+		},
+	}
+	// cluster bastion is defaulted from the cluster's SSH config
+	c := cluster.forNode(Node{Host: "worker01"})
+	expected := BastionConfig{Host: "bastion.example.com", User: "kismaticuser", Key: "/keys/cluster.pem", Port: 22}
+	if c.Bastion == nil || *c.Bastion != expected {
+		t.Errorf("expected bastion %+v, got %+v", expected, c.Bastion)
+	}
+	// the cluster's config must not be modified
+	if cluster.Bastion.User != "" {
+		t.Errorf("cluster bastion was modified")
+	}
+
+	// node bastion takes precedence
+	nodeBastion := &BastionConfig{Host: "10.0.0.1", User: "jumper", Port: 2222}
+	c = cluster.forNode(Node{Host: "worker02", Bastion: nodeBastion})
+	expected = BastionConfig{Host: "10.0.0.1", User: "jumper", Key: "/keys/cluster.pem", Port: 2222}
+	if c.Bastion == nil || *c.Bastion != expected {
+		t.Errorf("expected bastion %+v, got %+v", expected, c.Bastion)
+	}
+
+	// no bastion at all
+	cluster.Bastion = nil
+	if c = cluster.forNode(Node{Host: "worker03"}); c.Bastion != nil || c.bastion() != nil {
+		t.Errorf("expected no bastion, got %+v", c.Bastion)
+	}
+}
+
+func TestBuildInventoryWithBastion(t *testing.T) {
+	p := &Plan{}
+	p.Cluster.SSH = SSHConfig{User: "kismaticuser", Key: "/keys/cluster.pem", Port: 22}
+	p.Worker.Nodes = []Node{
+		{Host: "worker01", IP: "10.0.0.1"},
+		{Host: "worker02", IP: "10.0.0.2", Bastion: &BastionConfig{Host: "bastion"}},
+	}
+	inv := buildInventoryFromPlan(p)
+	for _, r := range inv.Roles {
+		if r.Name != "worker" {
+			continue
+		}
+		if r.Nodes[0].SSHProxyCommand != "" {
+			t.Errorf("expected no proxy command for worker01, got %q", r.Nodes[0].SSHProxyCommand)
+		}
+		if !strings.HasSuffix(r.Nodes[1].SSHProxyCommand, "-i /keys/cluster.pem -p 22 -W %h:%p kismaticuser@bastion") {
+			t.Errorf("unexpected proxy command for worker02: %q", r.Nodes[1].SSHProxyCommand)
+		}
+	}
+}
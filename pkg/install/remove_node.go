@@ -0,0 +1,232 @@
+package install
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/apprenda/kismatic/pkg/data"
+	"github.com/apprenda/kismatic/pkg/util"
+)
+
+type etcdMemberRemovalErr struct {
+	remaining int
+}
+
+func (e etcdMemberRemovalErr) Error() string {
+	return fmt.Sprintf("Removing this node will leave the etcd cluster with %d member(s). "+
+		"The etcd cluster will not tolerate the failure of a member.", e.remaining)
+}
+
+type masterNodeRemovalErr struct{}
+
+func (e masterNodeRemovalErr) Error() string {
+	return "Removing this node will leave a single master node in the cluster. " +
+		"The cluster will not tolerate the failure of a master node."
+}
+
+type masterNodeLoadBalancingRemovalErr struct{}
+
+func (e masterNodeLoadBalancingRemovalErr) Error() string {
+	return "This node is acting as the load balanced endpoint for the master nodes. " +
+		"Removing it will make the cluster unavailable."
+}
+
+type ingressNodeRemovalErr struct{}
+
+func (e ingressNodeRemovalErr) Error() string {
+	return "Removing this node will leave the cluster without ingress nodes. " +
+		"The services exposed through ingress will be unavailable."
+}
+
+type storageNodeRemovalErr struct {
+	volumes []string
+}
+
+func (e storageNodeRemovalErr) Error() string {
+	return fmt.Sprintf("Removing this node may result in data loss for the storage volumes that have bricks on it: %s.", strings.Join(e.volumes, ", "))
+}
+
+// DetectNodeRemovalSafety determines whether it's safe to remove a specific node
+// listed in the plan file. If any condition that could result in data or availability
+// loss is detected, the removal is deemed unsafe, and the conditions are returned as errors.
+// The gluster client is only used when the node is a storage node.
+func DetectNodeRemovalSafety(plan Plan, node Node, glusterClient data.GlusterClient) []error {
+	errs := []error{}
+	roles := plan.GetRolesForIP(node.IP)
+	for _, role := range roles {
+		switch role {
+		case "etcd":
+			if remaining := len(plan.Etcd.Nodes) - 1; remaining < 3 {
+				errs = append(errs, etcdMemberRemovalErr{remaining: remaining})
+			}
+		case "master":
+			if len(plan.Master.Nodes) < 3 {
+				errs = append(errs, masterNodeRemovalErr{})
+			}
+			lbFQDN := plan.Master.LoadBalancedFQDN
+			if lbFQDN == node.Host || lbFQDN == node.IP {
+				errs = append(errs, masterNodeLoadBalancingRemovalErr{})
+			}
+		case "ingress":
+			if len(plan.Ingress.Nodes) < 2 {
+				errs = append(errs, ingressNodeRemovalErr{})
+			}
+		case "storage":
+			volumes, err := volumesWithBricksOnNode(node, glusterClient)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("unable to determine node removal safety: %v", err))
+				continue
+			}
+			if len(volumes) > 0 {
+				errs = append(errs, storageNodeRemovalErr{volumes: volumes})
+			}
+		}
+	}
+	return errs
+}
+
+// volumesWithBricksOnNode returns the names of the storage volumes that have
+// at least one brick on the node
+func volumesWithBricksOnNode(node Node, glusterClient data.GlusterClient) ([]string, error) {
+	info, err := glusterClient.ListVolumes()
+	if err != nil {
+		return nil, err
+	}
+	// there are no volumes in the cluster
+	if info == nil || info.VolumeInfo == nil || info.VolumeInfo.Volumes == nil {
+		return nil, nil
+	}
+	var volumes []string
+	for _, v := range info.VolumeInfo.Volumes.Volume {
+		if v.Bricks == nil {
+			continue
+		}
+		for _, b := range v.Bricks.Brick {
+			// bricks are listed as host:path
+			host := strings.Split(b.Text, ":")[0]
+			if host == node.Host || host == node.IP || (node.InternalIP != "" && host == node.InternalIP) {
+				volumes = append(volumes, v.Name)
+				break
+			}
+		}
+	}
+	return volumes, nil
+}
+
+// RemoveNodeFromPlan returns a copy of the plan that does not include the node
+func RemoveNodeFromPlan(plan Plan, node Node) Plan {
+	remove := func(nodes []Node) ([]Node, bool) {
+		var kept []Node
+		var removed bool
+		for _, n := range nodes {
+			if n.Equal(node) {
+				removed = true
+				continue
+			}
+			kept = append(kept, n)
+		}
+		return kept, removed
+	}
+	var removed bool
+	if plan.Etcd.Nodes, removed = remove(plan.Etcd.Nodes); removed {
+		plan.Etcd.ExpectedCount--
+	}
+	if plan.Master.Nodes, removed = remove(plan.Master.Nodes); removed {
+		plan.Master.ExpectedCount--
+	}
+	if plan.Worker.Nodes, removed = remove(plan.Worker.Nodes); removed {
+		plan.Worker.ExpectedCount--
+	}
+	if plan.Ingress.Nodes, removed = remove(plan.Ingress.Nodes); removed {
+		plan.Ingress.ExpectedCount--
+	}
+	if plan.Storage.Nodes, removed = remove(plan.Storage.Nodes); removed {
+		plan.Storage.ExpectedCount--
+	}
+	return plan
+}
+
+// RemoveNode removes the node from the cluster described in the plan.
+// The node is drained and deleted from Kubernetes, removed from the etcd
+// clusters, and reset. If successful, the updated plan is returned.
+func (ae *ansibleExecutor) RemoveNode(originalPlan *Plan, node Node) (*Plan, error) {
+	roles := originalPlan.GetRolesForIP(node.IP)
+	if len(roles) == 0 {
+		return nil, fmt.Errorf("node %q was not found in the plan", node.Host)
+	}
+	updatedPlan := RemoveNodeFromPlan(*originalPlan, node)
+
+	// The node is removed using the original plan, as it must be part
+	// of the inventory
+	inventory := buildInventoryFromPlan(originalPlan)
+	cc, err := ae.buildClusterCatalog(originalPlan)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate ansible vars: %v", err)
+	}
+	cc.RemovedNode = node.Host
+	util.PrintHeader(ae.stdout, "Removing Node From Cluster", '=')
+	t := task{
+		name:           "remove-node",
+		playbook:       "remove-node.yaml",
+		plan:           *originalPlan,
+		inventory:      inventory,
+		clusterCatalog: *cc,
+		explainer:      ae.defaultExplainer(),
+	}
+	if err = ae.execute(t); err != nil {
+		return nil, fmt.Errorf("error running playbook: %v", err)
+	}
+
+	util.PrintHeader(ae.stdout, "Resetting Removed Node", '=')
+	t = task{
+		name:           "remove-node-reset",
+		playbook:       "reset.yaml",
+		plan:           *originalPlan,
+		inventory:      inventory,
+		clusterCatalog: *cc,
+		explainer:      ae.defaultExplainer(),
+		limit:          []string{node.Host},
+	}
+	if err = ae.execute(t); err != nil {
+		return nil, fmt.Errorf("error resetting node: %v", err)
+	}
+
+	// The remaining nodes are updated using the updated plan
+	inventory = buildInventoryFromPlan(&updatedPlan)
+	cc, err = ae.buildClusterCatalog(&updatedPlan)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate ansible vars: %v", err)
+	}
+	// The API servers are configured with the list of etcd members and the
+	// number of API servers in the cluster
+	if util.Contains("etcd", roles) || util.Contains("master", roles) {
+		util.PrintHeader(ae.stdout, "Updating Control Plane Endpoints", '=')
+		t = task{
+			name:           "remove-node-update-endpoints",
+			playbook:       "control-plane-endpoints.yaml",
+			plan:           updatedPlan,
+			inventory:      inventory,
+			clusterCatalog: *cc,
+			explainer:      ae.defaultExplainer(),
+		}
+		if err = ae.execute(t); err != nil {
+			return nil, fmt.Errorf("error updating control plane endpoints: %v", err)
+		}
+	}
+
+	if updatedPlan.Cluster.Networking.UpdateHostsFiles {
+		util.PrintHeader(ae.stdout, "Updating Hosts Files On All Nodes", '=')
+		t = task{
+			name:           "remove-node-update-hosts",
+			playbook:       "hosts.yaml",
+			plan:           updatedPlan,
+			inventory:      inventory,
+			clusterCatalog: *cc,
+			explainer:      ae.defaultExplainer(),
+		}
+		if err = ae.execute(t); err != nil {
+			return nil, fmt.Errorf("error updating hosts files on all nodes: %v", err)
+		}
+	}
+	return &updatedPlan, nil
+}
@@ -0,0 +1,285 @@
+package install
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/apprenda/kismatic/pkg/ansible"
+	"github.com/apprenda/kismatic/pkg/data"
+	"github.com/apprenda/kismatic/pkg/install/explain"
+)
+
+// playbookRecordingRunner records the playbooks that are run, along with
+// the nodes that they are limited to
+type playbookRecordingRunner struct {
+	fakeRunner
+	playbooks *[]string
+	catalogs  *[]ansible.ClusterCatalog
+}
+
+func (r *playbookRecordingRunner) StartPlaybook(playbookFile string, inventory ansible.Inventory, cc ansible.ClusterCatalog) (<-chan ansible.Event, error) {
+	*r.playbooks = append(*r.playbooks, playbookFile)
+	*r.catalogs = append(*r.catalogs, cc)
+	return r.fakeRunner.StartPlaybook(playbookFile, inventory, cc)
+}
+
+func (r *playbookRecordingRunner) StartPlaybookOnNode(playbookFile string, inventory ansible.Inventory, cc ansible.ClusterCatalog, node ...string) (<-chan ansible.Event, error) {
+	*r.playbooks = append(*r.playbooks, playbookFile+":"+node[0])
+	*r.catalogs = append(*r.catalogs, cc)
+	return r.fakeRunner.StartPlaybookOnNode(playbookFile, inventory, cc, node...)
+}
+
+func removeNodeTestExecutor(t *testing.T, playbooks *[]string, catalogs *[]ansible.ClusterCatalog) (*ansibleExecutor, string) {
+	dir := mustGetTempDir(t)
+	e := &ansibleExecutor{
+		options:             ExecutorOptions{RunsDirectory: dir},
+		stdout:              ioutil.Discard,
+		consoleOutputFormat: ansible.RawFormat,
+		certsDir:            dir,
+		runnerExplainerFactory: func(explain.AnsibleEventExplainer, io.Writer) (ansible.Runner, *explain.AnsibleEventStreamExplainer, error) {
+			return &playbookRecordingRunner{playbooks: playbooks, catalogs: catalogs}, &explain.AnsibleEventStreamExplainer{}, nil
+		},
+	}
+	return e, dir
+}
+
+// removeNodeTestPlan returns a plan with 2 nodes of each role, each node
+// having a unique IP address
+func removeNodeTestPlan() *Plan {
+	p := certificatesTestPlan()
+	p.Cluster.Version = "v1.10.1"
+	p.Etcd.Nodes = append(p.Etcd.Nodes, Node{Host: "etcd02", IP: "10.0.1.1"})
+	p.Master.Nodes = append(p.Master.Nodes, Node{Host: "master02", IP: "10.0.1.2"})
+	p.Worker.Nodes = append(p.Worker.Nodes, Node{Host: "worker02", IP: "10.0.1.3"})
+	return p
+}
+
+func TestRemoveNodeFromPlan(t *testing.T) {
+	p := removeNodeTestPlan()
+	p.Etcd.ExpectedCount = len(p.Etcd.Nodes)
+	p.Master.ExpectedCount = len(p.Master.Nodes)
+	p.Worker.ExpectedCount = len(p.Worker.Nodes)
+	// the same node is both a master and a worker
+	node := p.Master.Nodes[0]
+	p.Worker.Nodes = append(p.Worker.Nodes, node)
+	p.Worker.ExpectedCount++
+
+	updated := RemoveNodeFromPlan(*p, node)
+	if updated.Master.ExpectedCount != p.Master.ExpectedCount-1 || len(updated.Master.Nodes) != len(p.Master.Nodes)-1 {
+		t.Errorf("expected the node to be removed from the master nodes, but got %+v", updated.Master)
+	}
+	if updated.Worker.ExpectedCount != p.Worker.ExpectedCount-1 || len(updated.Worker.Nodes) != len(p.Worker.Nodes)-1 {
+		t.Errorf("expected the node to be removed from the worker nodes, but got %+v", updated.Worker)
+	}
+	if !reflect.DeepEqual(updated.Etcd, p.Etcd) {
+		t.Errorf("expected the etcd nodes to be unchanged, but got %+v", updated.Etcd)
+	}
+	for _, n := range updated.getAllNodes() {
+		if n.Equal(node) {
+			t.Errorf("expected node %q to be removed from the plan", node.Host)
+		}
+	}
+	// the original plan must not be modified
+	if len(p.Master.Nodes) != 2 {
+		t.Errorf("expected the original plan to be unchanged, but got %+v", p.Master)
+	}
+}
+
+// fakeGlusterClient returns volumes that have bricks on the given hosts
+type fakeGlusterClient struct {
+	bricks map[string][]string
+	err    error
+}
+
+func (g fakeGlusterClient) ListVolumes() (*data.GlusterVolumeInfoCliOutput, error) {
+	if g.err != nil {
+		return nil, g.err
+	}
+	if len(g.bricks) == 0 {
+		return nil, nil
+	}
+	volumes := &data.GlusterVolumes{}
+	for name, hosts := range g.bricks {
+		v := &data.GlusterVolume{Name: name, Bricks: &data.GlusterBricks{}}
+		for _, h := range hosts {
+			v.Bricks.Brick = append(v.Bricks.Brick, &data.GlusterBrick{Text: h + ":/data/" + name})
+		}
+		volumes.Volume = append(volumes.Volume, v)
+	}
+	return &data.GlusterVolumeInfoCliOutput{VolumeInfo: &data.GlusterVolumeInfo{Volumes: volumes}}, nil
+}
+
+func (g fakeGlusterClient) GetQuota(volume string) (*data.GlusterVolumeQuotaCliOutput, error) {
+	return nil, nil
+}
+
+func TestDetectNodeRemovalSafety(t *testing.T) {
+	tests := []struct {
+		name     string
+		plan     func() Plan
+		node     func(Plan) Node
+		gluster  fakeGlusterClient
+		expected []error
+	}{
+		{
+			name:     "worker node",
+			plan:     func() Plan { return *removeNodeTestPlan() },
+			node:     func(p Plan) Node { return p.Worker.Nodes[0] },
+			expected: []error{},
+		},
+		{
+			name:     "etcd member of a 2 member cluster",
+			plan:     func() Plan { return *removeNodeTestPlan() },
+			node:     func(p Plan) Node { return p.Etcd.Nodes[0] },
+			expected: []error{etcdMemberRemovalErr{remaining: 1}},
+		},
+		{
+			name: "etcd member of a 4 member cluster",
+			plan: func() Plan {
+				p := removeNodeTestPlan()
+				p.Etcd.Nodes = append(p.Etcd.Nodes, Node{Host: "etcd03", IP: "10.0.2.1"}, Node{Host: "etcd04", IP: "10.0.3.1"})
+				return *p
+			},
+			node:     func(p Plan) Node { return p.Etcd.Nodes[0] },
+			expected: []error{},
+		},
+		{
+			name: "master node acting as the load balancer",
+			plan: func() Plan {
+				p := removeNodeTestPlan()
+				p.Master.LoadBalancedFQDN = p.Master.Nodes[0].Host
+				return *p
+			},
+			node:     func(p Plan) Node { return p.Master.Nodes[0] },
+			expected: []error{masterNodeRemovalErr{}, masterNodeLoadBalancingRemovalErr{}},
+		},
+		{
+			name: "last ingress node",
+			plan: func() Plan {
+				p := removeNodeTestPlan()
+				p.Ingress.Nodes = []Node{{Host: "ingress01", IP: "10.0.0.5"}}
+				return *p
+			},
+			node:     func(p Plan) Node { return p.Ingress.Nodes[0] },
+			expected: []error{ingressNodeRemovalErr{}},
+		},
+		{
+			name: "one of two ingress nodes",
+			plan: func() Plan {
+				p := removeNodeTestPlan()
+				p.Ingress.Nodes = []Node{{Host: "ingress01", IP: "10.0.0.5"}, {Host: "ingress02", IP: "10.0.1.5"}}
+				return *p
+			},
+			node:     func(p Plan) Node { return p.Ingress.Nodes[0] },
+			expected: []error{},
+		},
+		{
+			name: "storage node without bricks",
+			plan: func() Plan {
+				p := removeNodeTestPlan()
+				p.Storage.Nodes = []Node{{Host: "storage01", IP: "10.0.0.4"}, {Host: "storage02", IP: "10.0.1.4"}}
+				return *p
+			},
+			node:     func(p Plan) Node { return p.Storage.Nodes[0] },
+			gluster:  fakeGlusterClient{bricks: map[string][]string{"data": {"10.0.1.4"}}},
+			expected: []error{},
+		},
+		{
+			name: "storage node with bricks",
+			plan: func() Plan {
+				p := removeNodeTestPlan()
+				p.Storage.Nodes = []Node{{Host: "storage01", IP: "10.0.0.4"}, {Host: "storage02", IP: "10.0.1.4"}}
+				return *p
+			},
+			node:     func(p Plan) Node { return p.Storage.Nodes[0] },
+			gluster:  fakeGlusterClient{bricks: map[string][]string{"data": {"10.0.0.4", "10.0.1.4"}}},
+			expected: []error{storageNodeRemovalErr{volumes: []string{"data"}}},
+		},
+		{
+			name: "storage node when the volumes cannot be listed",
+			plan: func() Plan {
+				p := removeNodeTestPlan()
+				p.Storage.Nodes = []Node{{Host: "storage01", IP: "10.0.0.4"}}
+				return *p
+			},
+			node:     func(p Plan) Node { return p.Storage.Nodes[0] },
+			gluster:  fakeGlusterClient{err: errors.New("gluster is not running")},
+			expected: []error{errors.New("unable to determine node removal safety: gluster is not running")},
+		},
+	}
+	for _, test := range tests {
+		p := test.plan()
+		errs := DetectNodeRemovalSafety(p, test.node(p), test.gluster)
+		if !reflect.DeepEqual(errs, test.expected) {
+			t.Errorf("%s: expected %v, but got %v", test.name, test.expected, errs)
+		}
+	}
+}
+
+func TestRemoveNode(t *testing.T) {
+	tests := []struct {
+		name              string
+		node              func(*Plan) Node
+		expectedPlaybooks []string
+	}{
+		{
+			name: "worker node",
+			node: func(p *Plan) Node { return p.Worker.Nodes[0] },
+			expectedPlaybooks: []string{
+				"remove-node.yaml",
+				"reset.yaml:worker01",
+			},
+		},
+		{
+			name: "etcd node",
+			node: func(p *Plan) Node { return p.Etcd.Nodes[1] },
+			expectedPlaybooks: []string{
+				"remove-node.yaml",
+				"reset.yaml:etcd02",
+				"control-plane-endpoints.yaml",
+			},
+		},
+	}
+	for _, test := range tests {
+		var playbooks []string
+		var catalogs []ansible.ClusterCatalog
+		e, dir := removeNodeTestExecutor(t, &playbooks, &catalogs)
+		defer os.RemoveAll(dir)
+		p := removeNodeTestPlan()
+		node := test.node(p)
+
+		updated, err := e.RemoveNode(p, node)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", test.name, err)
+		}
+		if !reflect.DeepEqual(playbooks, test.expectedPlaybooks) {
+			t.Errorf("%s: expected playbooks %v, but got %v", test.name, test.expectedPlaybooks, playbooks)
+		}
+		if catalogs[0].RemovedNode != node.Host {
+			t.Errorf("%s: expected the removed node to be %q, but got %q", test.name, node.Host, catalogs[0].RemovedNode)
+		}
+		if updated.HostExists(node.Host) {
+			t.Errorf("%s: expected node %q to be removed from the updated plan", test.name, node.Host)
+		}
+		if !p.HostExists(node.Host) {
+			t.Errorf("%s: expected the original plan to be unchanged", test.name)
+		}
+	}
+}
+
+func TestRemoveNodeNotInPlan(t *testing.T) {
+	var playbooks []string
+	var catalogs []ansible.ClusterCatalog
+	e, dir := removeNodeTestExecutor(t, &playbooks, &catalogs)
+	defer os.RemoveAll(dir)
+	if _, err := e.RemoveNode(removeNodeTestPlan(), Node{Host: "foo", IP: "10.0.0.100"}); err == nil {
+		t.Errorf("expected an error when removing a node that is not in the plan")
+	}
+	if len(playbooks) != 0 {
+		t.Errorf("expected no playbooks to run, but got %v", playbooks)
+	}
+}
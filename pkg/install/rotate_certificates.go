@@ -0,0 +1,148 @@
+package install
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/apprenda/kismatic/pkg/tls"
+	"github.com/apprenda/kismatic/pkg/util"
+)
+
+// RotateCertificates reissues the certificates of the cluster, deploys them to
+// the nodes, and restarts the cluster components so that they pick them up.
+// When rotateCA is true, the certificate authorities are replaced as well. In
+// that case, the nodes trust both the old and new CAs while the certificates
+// are being replaced, so that the components can keep talking to each other.
+// The existing certificates are backed up in the generated assets directory.
+func (ae *ansibleExecutor) RotateCertificates(p *Plan, rotateCA bool) error {
+	util.PrintHeader(ae.stdout, "Rotating Certificates", '=')
+	pki, err := ae.pkiForPlan(p)
+	if err != nil {
+		return err
+	}
+	// fail before touching the certificates if the CA cannot be rotated
+	if _, ok := pki.(*ExternalPKI); ok && rotateCA {
+		return errExternalCARotation
+	}
+	backupDir := fmt.Sprintf("%s.bak-%s", ae.certsDir, time.Now().UTC().Format("2006-01-02-15-04-05"))
+	if err := util.CopyDirectory(ae.certsDir, backupDir); err != nil {
+		return fmt.Errorf("error backing up existing certificates: %v", err)
+	}
+	util.PrettyPrintOk(ae.stdout, "Backed up existing certificates to %q", backupDir)
+
+	clusterCA, err := pki.GetClusterCA()
+	if err != nil {
+		return err
+	}
+	proxyClientCA, err := pki.GetProxyClientCA()
+	if err != nil {
+		return err
+	}
+	if !rotateCA {
+		if err := pki.RotateClusterCertificates(p, clusterCA, proxyClientCA); err != nil {
+			return fmt.Errorf("error generating certificates for the cluster: %v", err)
+		}
+		return ae.deployCertificates(p, ae.certsDir, "Deploying Certificates")
+	}
+
+	newClusterCA, err := pki.RotateClusterCA(p)
+	if err != nil {
+		return fmt.Errorf("error generating CA for the cluster: %v", err)
+	}
+	newProxyClientCA, err := pki.RotateProxyClientCA(p)
+	if err != nil {
+		return fmt.Errorf("error generating CA for the proxy client: %v", err)
+	}
+	bundles := map[string][]byte{
+		"ca":              caBundle(clusterCA, newClusterCA),
+		"proxy-client-ca": caBundle(proxyClientCA, newProxyClientCA),
+	}
+	// 1. Trust the old and new CAs, while still using the old certificates
+	if err := ae.deployCertificatesWithCABundles(p, backupDir, bundles, "Deploying New Certificate Authorities"); err != nil {
+		return err
+	}
+	// 2. Use certificates signed by the new CAs, while still trusting the old CAs
+	if err := pki.RotateClusterCertificates(p, newClusterCA, newProxyClientCA); err != nil {
+		return fmt.Errorf("error generating certificates for the cluster: %v", err)
+	}
+	if err := ae.deployCertificatesWithCABundles(p, ae.certsDir, bundles, "Deploying Certificates"); err != nil {
+		return err
+	}
+	// 3. Regenerate the service account tokens, which embed the CA that pods
+	// use to trust the API server, and restart the pods that mount them
+	if err := ae.rotateServiceAccountTokens(p); err != nil {
+		return err
+	}
+	// 4. Stop trusting the old CAs
+	return ae.deployCertificates(p, ae.certsDir, "Removing Old Certificate Authorities")
+}
+
+func (ae *ansibleExecutor) rotateServiceAccountTokens(p *Plan) error {
+	cc, err := ae.buildClusterCatalog(p)
+	if err != nil {
+		return err
+	}
+	t := task{
+		name:           "rotate-service-account-tokens",
+		playbook:       "rotate-service-account-tokens.yaml",
+		plan:           *p,
+		inventory:      buildInventoryFromPlan(p),
+		clusterCatalog: *cc,
+		explainer:      ae.defaultExplainer(),
+	}
+	util.PrintHeader(ae.stdout, "Regenerating Service Account Tokens", '=')
+	return ae.execute(t)
+}
+
+// deployCertificatesWithCABundles deploys the certificates in the directory,
+// replacing the CA certificates with the given bundles.
+func (ae *ansibleExecutor) deployCertificatesWithCABundles(p *Plan, certsDir string, bundles map[string][]byte, header string) error {
+	stagingDir, err := ioutil.TempDir("", "kismatic-certificates")
+	if err != nil {
+		return fmt.Errorf("error creating temporary directory for certificates: %v", err)
+	}
+	defer os.RemoveAll(stagingDir)
+	if err := util.CopyDirectory(certsDir, stagingDir); err != nil {
+		return fmt.Errorf("error copying certificates: %v", err)
+	}
+	for name, bundle := range bundles {
+		if err := ioutil.WriteFile(filepath.Join(stagingDir, name+".pem"), bundle, 0644); err != nil {
+			return fmt.Errorf("error writing CA bundle: %v", err)
+		}
+	}
+	return ae.deployCertificates(p, stagingDir, header)
+}
+
+func (ae *ansibleExecutor) deployCertificates(p *Plan, certsDir string, header string) error {
+	cc, err := ae.buildClusterCatalog(p)
+	if err != nil {
+		return err
+	}
+	tlsDir, err := filepath.Abs(certsDir)
+	if err != nil {
+		return fmt.Errorf("failed to determine absolute path to %s: %v", certsDir, err)
+	}
+	cc.TLSDirectory = tlsDir
+	t := task{
+		name:           "rotate-certificates",
+		playbook:       "rotate-certificates.yaml",
+		plan:           *p,
+		inventory:      buildInventoryFromPlan(p),
+		clusterCatalog: *cc,
+		explainer:      ae.defaultExplainer(),
+	}
+	util.PrintHeader(ae.stdout, header, '=')
+	return ae.execute(t)
+}
+
+// caBundle returns a PEM bundle that contains both CA certificates
+func caBundle(oldCA *tls.CA, newCA *tls.CA) []byte {
+	bundle := append([]byte{}, newCA.Cert...)
+	if len(bundle) > 0 && bundle[len(bundle)-1] != '\n' {
+		bundle = append(bundle, '\n')
+	}
+	return append(bundle, oldCA.Cert...)
+}
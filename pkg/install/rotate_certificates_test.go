@@ -0,0 +1,136 @@
+package install
+
+import (
+	"encoding/pem"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/apprenda/kismatic/pkg/ansible"
+	"github.com/apprenda/kismatic/pkg/install/explain"
+)
+
+// caCountingRunner records the number of CA certificates that would be
+// deployed to the nodes every time the certificates playbook is run, and
+// the playbooks that are run.
+type caCountingRunner struct {
+	fakeRunner
+	t         *testing.T
+	caCounts  *[]int
+	playbooks *[]string
+}
+
+func (r *caCountingRunner) StartPlaybook(playbookFile string, inventory ansible.Inventory, cc ansible.ClusterCatalog) (<-chan ansible.Event, error) {
+	*r.playbooks = append(*r.playbooks, playbookFile)
+	if playbookFile != "rotate-certificates.yaml" {
+		return r.fakeRunner.StartPlaybook(playbookFile, inventory, cc)
+	}
+	b, err := ioutil.ReadFile(filepath.Join(cc.TLSDirectory, "ca.pem"))
+	if err != nil {
+		r.t.Fatalf("error reading CA bundle: %v", err)
+	}
+	count := 0
+	for block, rest := pem.Decode(b); block != nil; block, rest = pem.Decode(rest) {
+		count++
+	}
+	*r.caCounts = append(*r.caCounts, count)
+	return r.fakeRunner.StartPlaybook(playbookFile, inventory, cc)
+}
+
+// rotateCertificatesTestExecutor returns an executor for a cluster with
+// existing certificates. The generated assets are kept in the returned
+// directory, which must be removed by the caller.
+func rotateCertificatesTestExecutor(t *testing.T, caCounts *[]int, playbooks *[]string) (*ansibleExecutor, *Plan, string) {
+	dir := mustGetTempDir(t)
+	pki := LocalPKI{
+		CACsr:                   "test/ca-csr.json",
+		GeneratedCertsDirectory: filepath.Join(dir, "keys"),
+		Log:                     ioutil.Discard,
+	}
+	p := getPlan()
+	p.Cluster.Version = "v1.10.1"
+	ca, err := pki.GenerateClusterCA(p)
+	if err != nil {
+		t.Fatalf("error generating CA for test: %v", err)
+	}
+	proxyClientCA, err := pki.GenerateProxyClientCA(p)
+	if err != nil {
+		t.Fatalf("error generating proxy-client CA for test: %v", err)
+	}
+	if err = pki.GenerateClusterCertificates(p, ca, proxyClientCA); err != nil {
+		t.Fatalf("error generating cluster certificates: %v", err)
+	}
+	e := &ansibleExecutor{
+		options:             ExecutorOptions{RunsDirectory: filepath.Join(dir, "runs")},
+		stdout:              ioutil.Discard,
+		consoleOutputFormat: ansible.RawFormat,
+		pki:                 &pki,
+		certsDir:            pki.GeneratedCertsDirectory,
+		runnerExplainerFactory: func(explain.AnsibleEventExplainer, io.Writer) (ansible.Runner, *explain.AnsibleEventStreamExplainer, error) {
+			return &caCountingRunner{t: t, caCounts: caCounts, playbooks: playbooks}, &explain.AnsibleEventStreamExplainer{}, nil
+		},
+	}
+	return e, p, dir
+}
+
+func TestRotateCertificates(t *testing.T) {
+	var caCounts []int
+	var playbooks []string
+	e, p, dir := rotateCertificatesTestExecutor(t, &caCounts, &playbooks)
+	defer cleanup(dir, t)
+
+	if err := e.RotateCertificates(p, false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(caCounts) != 1 || caCounts[0] != 1 {
+		t.Errorf("expected certificates to be deployed once with a single CA, got CA counts %v", caCounts)
+	}
+}
+
+func TestRotateCertificatesWithCA(t *testing.T) {
+	var caCounts []int
+	var playbooks []string
+	e, p, dir := rotateCertificatesTestExecutor(t, &caCounts, &playbooks)
+	defer cleanup(dir, t)
+
+	if err := e.RotateCertificates(p, true); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// both CAs are trusted while the certificates are replaced
+	expected := []int{2, 2, 1}
+	if len(caCounts) != len(expected) {
+		t.Fatalf("expected certificates to be deployed %d times, but were deployed %d times", len(expected), len(caCounts))
+	}
+	for i := range expected {
+		if caCounts[i] != expected[i] {
+			t.Errorf("deployment %d: expected %d CA certificates, got %d", i, expected[i], caCounts[i])
+		}
+	}
+	// the service account tokens are regenerated before the old CAs are removed
+	if len(playbooks) != 4 || playbooks[2] != "rotate-service-account-tokens.yaml" {
+		t.Errorf("expected the service account tokens to be regenerated before removing the old CAs, but the playbooks were %v", playbooks)
+	}
+}
+
+func TestRotateCertificatesWithExternalCA(t *testing.T) {
+	var caCounts []int
+	var playbooks []string
+	e, p, dir := rotateCertificatesTestExecutor(t, &caCounts, &playbooks)
+	defer cleanup(dir, t)
+	e.pki = &ExternalPKI{LocalPKI: *e.pki.(*LocalPKI), IntermediateCA: &IntermediateCA{}}
+
+	if err := e.RotateCertificates(p, true); err != errExternalCARotation {
+		t.Fatalf("expected the rotation of the external CA to be rejected, but got %v", err)
+	}
+	backups, err := filepath.Glob(e.certsDir + ".bak-*")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(backups) != 0 {
+		t.Errorf("expected the certificates to not be backed up, but found %v", backups)
+	}
+	if len(caCounts) != 0 {
+		t.Errorf("expected no certificates to be deployed, but were deployed %d times", len(caCounts))
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"net"
+	"net/url"
 	"os"
 	"path/filepath"
 	"reflect"
@@ -72,7 +73,7 @@ func ValidateSSHConnection(con *SSHConnection, prefix string) (bool, []error) {
 }
 
 // ValidateCertificates checks if certificates exist and are valid
-func ValidateCertificates(p *Plan, pki *LocalPKI) (bool, []error) {
+func ValidateCertificates(p *Plan, pki PKI) (bool, []error) {
 	v := newValidator()
 
 	warn, err := pki.ValidateClusterCertificates(p)
@@ -137,6 +138,9 @@ func (v *validator) valid() (bool, []error) {
 func (p *Plan) validate() (bool, []error) {
 	v := newValidator()
 
+	for _, f := range p.unknownFields {
+		v.addError(f)
+	}
 	v.validate(&p.Cluster)
 	v.validate(&p.DockerRegistry)
 	if p.Cluster.DisconnectedInstallation && !p.PrivateRegistryProvided() {
@@ -192,7 +196,29 @@ func (c *Cluster) validate() (bool, []error) {
 	v.validate(&c.KubeSchedulerOptions)
 	v.validate(&c.KubeletOptions)
 	v.validate(&c.CloudProvider)
+	v.validate(&c.Preflight)
+
+	return v.valid()
+}
 
+func (p *PreflightOptions) validate() (bool, []error) {
+	v := newValidator()
+	minimums := []struct {
+		field string
+		value int
+	}{
+		{"etcd_minimum_cpus", p.EtcdMinimumCPUs},
+		{"master_minimum_cpus", p.MasterMinimumCPUs},
+		{"worker_minimum_cpus", p.WorkerMinimumCPUs},
+		{"etcd_minimum_memory_bytes", p.EtcdMinimumMemoryBytes},
+		{"master_minimum_memory_bytes", p.MasterMinimumMemoryBytes},
+		{"worker_minimum_memory_bytes", p.WorkerMinimumMemoryBytes},
+	}
+	for _, m := range minimums {
+		if m.value < 0 {
+			v.addError(fmt.Errorf("Preflight %s %d invalid, cannot be negative", m.field, m.value))
+		}
+	}
 	return v.valid()
 }
 
@@ -222,6 +248,60 @@ func (c *CertsConfig) validate() (bool, []error) {
 	if _, err := time.ParseDuration(c.CAExpiry); c.CAExpiry != "" && err != nil { // don't error when empty for backwards compat
 		v.addError(fmt.Errorf("Invalid CA certificate expiry %q provider: %v", c.CAExpiry, err))
 	}
+	switch c.Backend {
+	case "", pkiBackendLocal:
+	case pkiBackendIntermediate:
+		if c.IntermediateCA == nil {
+			v.addError(errors.New("Intermediate CA field is required when the backend is intermediate"))
+		} else {
+			v.validateWithErrPrefix("Intermediate CA", c.IntermediateCA)
+		}
+	case pkiBackendVault:
+		if c.Vault == nil {
+			v.addError(errors.New("Vault field is required when the backend is vault"))
+		} else {
+			v.validateWithErrPrefix("Vault", c.Vault)
+		}
+	default:
+		v.addError(fmt.Errorf("Certificates backend %q is not supported", c.Backend))
+	}
+	return v.valid()
+}
+
+func (ca *IntermediateCA) validate() (bool, []error) {
+	v := newValidator()
+	if ca.Cert == "" {
+		v.addError(errors.New("Cert field is required"))
+	} else if _, err := os.Stat(ca.Cert); err != nil {
+		v.addError(fmt.Errorf("Certificate file was not found at %q", ca.Cert))
+	}
+	if ca.Key == "" {
+		v.addError(errors.New("Key field is required"))
+	} else if _, err := os.Stat(ca.Key); err != nil {
+		v.addError(fmt.Errorf("Key file was not found at %q", ca.Key))
+	}
+	return v.valid()
+}
+
+func (vc *VaultPKIConfig) validate() (bool, []error) {
+	v := newValidator()
+	if vc.Address == "" {
+		v.addError(errors.New("Address field is required"))
+	} else if u, err := url.Parse(vc.Address); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		v.addError(fmt.Errorf("Address %q is not a valid http(s) URL", vc.Address))
+	}
+	if vc.TokenFile != "" {
+		if _, err := os.Stat(vc.TokenFile); err != nil {
+			v.addError(fmt.Errorf("Token file was not found at %q", vc.TokenFile))
+		}
+	} else if os.Getenv(vaultTokenEnv) == "" {
+		v.addError(fmt.Errorf("Token file field is required when the %s environment variable is not set", vaultTokenEnv))
+	}
+	if vc.CACert != "" {
+		if _, err := os.Stat(vc.CACert); err != nil {
+			v.addError(fmt.Errorf("CA certificate file was not found at %q", vc.CACert))
+		}
+	}
 	return v.valid()
 }
 
@@ -230,18 +310,43 @@ func (s *SSHConfig) validate() (bool, []error) {
 	if s.User == "" {
 		v.addError(errors.New("SSH user field is required"))
 	}
-	if s.Key == "" {
-		v.addError(errors.New("SSH key field is required"))
-	}
-	if _, err := os.Stat(s.Key); os.IsNotExist(err) {
-		v.addError(fmt.Errorf("SSH Key file was not found at %q", s.Key))
+	// the key can be omitted when the keys are provided by an ssh-agent
+	if s.Key == "" && !ssh.AgentAvailable() {
+		v.addError(errors.New("SSH key field is required when an ssh-agent is not running"))
 	}
-	if !filepath.IsAbs(s.Key) {
-		v.addError(errors.New("SSH Key field must be an absolute path"))
+	if s.Key != "" {
+		if _, err := os.Stat(s.Key); os.IsNotExist(err) {
+			v.addError(fmt.Errorf("SSH Key file was not found at %q", s.Key))
+		}
+		if !filepath.IsAbs(s.Key) {
+			v.addError(errors.New("SSH Key field must be an absolute path"))
+		}
 	}
 	if s.Port < 1 || s.Port > 65535 {
 		v.addError(fmt.Errorf("SSH port %d is invalid. Port must be in the range 1-65535", s.Port))
 	}
+	if s.Bastion != nil {
+		v.validateWithErrPrefix("Bastion", s.Bastion)
+	}
+	return v.valid()
+}
+
+func (b *BastionConfig) validate() (bool, []error) {
+	v := newValidator()
+	if b.Host == "" {
+		v.addError(errors.New("Bastion host field is required"))
+	}
+	if b.Key != "" {
+		if _, err := os.Stat(b.Key); os.IsNotExist(err) {
+			v.addError(fmt.Errorf("SSH Key file was not found at %q", b.Key))
+		}
+		if !filepath.IsAbs(b.Key) {
+			v.addError(errors.New("SSH Key field must be an absolute path"))
+		}
+	}
+	if b.Port < 0 || b.Port > 65535 {
+		v.addError(fmt.Errorf("SSH port %d is invalid. Port must be in the range 1-65535", b.Port))
+	}
 	return v.valid()
 }
 
@@ -333,7 +438,7 @@ func (h *HeapsterMonitoring) validate() (bool, []error) {
 	v := newValidator()
 	if h != nil && !h.Disable {
 		if h.Options.Heapster.Replicas <= 0 {
-			v.addError(fmt.Errorf("Heapster replicas %d is not valid, must be greater than 0", h.Options.HeapsterReplicas))
+			v.addError(fmt.Errorf("Heapster replicas %d is not valid, must be greater than 0", h.Options.Heapster.Replicas))
 		}
 		if !util.Contains(h.Options.Heapster.ServiceType, serviceTypes()) {
 			v.addError(fmt.Errorf("Heapster Service Type %q is not a valid option %v", h.Options.Heapster.ServiceType, serviceTypes()))
@@ -366,7 +471,8 @@ func (p *PackageManager) validate() (bool, []error) {
 func (s sshConnectionSet) validate() (bool, []error) {
 	v := newValidator()
 
-	err := ssh.ValidUnencryptedPrivateKey(s.SSHConfig.Key)
+	// encrypted keys are decrypted once, before connecting to the nodes in parallel
+	err := s.SSHConfig.prepareKeys(s.Nodes)
 	if err != nil {
 		v.addError(fmt.Errorf("SSH key validation error: %v", err))
 	} else {
@@ -375,16 +481,17 @@ func (s sshConnectionSet) validate() (bool, []error) {
 		// number of nodes
 		wg.Add(len(s.Nodes))
 		for _, node := range s.Nodes {
-			go func(ip string) {
+			go func(node Node) {
 				defer wg.Done()
-				sshErr := ssh.TestConnection(ip, s.SSHConfig.Port, s.SSHConfig.User, s.SSHConfig.Key)
+				c := s.SSHConfig.forNode(node)
+				sshErr := ssh.TestConnection(node.IP, c.Port, c.User, c.Key, c.bastion())
 				// Need to send something the buffered channel
 				if sshErr != nil {
-					errQueue <- fmt.Errorf("SSH connectivity validation failed for %q: %v", ip, sshErr)
+					errQueue <- fmt.Errorf("SSH connectivity validation failed for %q: %v", node.IP, sshErr)
 				} else {
 					errQueue <- nil
 				}
-			}(node.IP)
+			}(node)
 		}
 
 		// Wait for all nodes to complete, then close channel
@@ -412,6 +519,7 @@ func (nl nodeList) validate() (bool, []error) {
 	v := newValidator()
 	v.addError(validateNoDuplicateNodeInfo(nl.Nodes)...)
 	v.addError(validateKubeletOptionsDefinedOnce(nl.Nodes)...)
+	v.addError(validateBastionDefinedOnce(nl.Nodes)...)
 	return v.valid()
 }
 
@@ -456,6 +564,19 @@ func validateKubeletOptionsDefinedOnce(nodes []Node) []error {
 	return errs
 }
 
+func validateBastionDefinedOnce(nodes []Node) []error {
+	errs := []error{}
+	seenNodes := map[string]*BastionConfig{}
+	for _, n := range nodes {
+		if val, ok := seenNodes[n.HashCode()]; ok && !reflect.DeepEqual(val, n.Bastion) {
+			errs = append(errs, fmt.Errorf("Cannot redefine bastion for node %q", n.Host))
+		} else {
+			seenNodes[n.HashCode()] = n.Bastion
+		}
+	}
+	return errs
+}
+
 func (ng *NodeGroup) validate() (bool, []error) {
 	v := newValidator()
 	if ng == nil || len(ng.Nodes) <= 0 {
@@ -566,6 +687,9 @@ func (n *Node) validate() (bool, []error) {
 			v.addError(fmt.Errorf("Node taint effect %q is not valid. Valid effects are: %v", taint.Effect, taintEffects()))
 		}
 	}
+	if n.Bastion != nil {
+		v.validateWithErrPrefix("Bastion", n.Bastion)
+	}
 	return v.valid()
 }
 
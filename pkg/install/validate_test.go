@@ -2,6 +2,7 @@ package install
 
 import (
 	"fmt"
+	"os"
 	"testing"
 )
 
@@ -1610,6 +1611,37 @@ func TestCloudProvider(t *testing.T) {
 	}
 }
 
+func TestPreflightOptions(t *testing.T) {
+	tests := []struct {
+		p     PreflightOptions
+		valid bool
+	}{
+		{
+			p:     PreflightOptions{},
+			valid: true,
+		},
+		{
+			p: PreflightOptions{
+				MasterMinimumCPUs:        2,
+				MasterMinimumMemoryBytes: 4000000000,
+			},
+			valid: true,
+		},
+		{
+			p: PreflightOptions{
+				WorkerMinimumCPUs: -1,
+			},
+			valid: false,
+		},
+	}
+	for i, test := range tests {
+		ok, _ := test.p.validate()
+		if ok != test.valid {
+			t.Errorf("test %d: expect %t, but got %t", i, test.valid, ok)
+		}
+	}
+}
+
 func TestNodeLabels(t *testing.T) {
 	tests := []struct {
 		n     Node
@@ -2078,6 +2110,111 @@ func TestNodeKubeletOptions(t *testing.T) {
 	}
 }
 
+func TestNodeBastion(t *testing.T) {
+	tests := []struct {
+		nl    nodeList
+		valid bool
+	}{
+		{
+			nl: nodeList{
+				[]Node{
+					{Host: "host1", IP: "10.0.0.1", Bastion: &BastionConfig{Host: "bastion"}},
+					{Host: "host2", IP: "10.0.0.2"},
+				},
+			},
+			valid: true,
+		},
+		{
+			nl: nodeList{
+				[]Node{
+					{Host: "host1", IP: "10.0.0.1", Bastion: &BastionConfig{Host: "bastion"}},
+					{Host: "host1", IP: "10.0.0.1", Bastion: &BastionConfig{Host: "bastion"}},
+				},
+			},
+			valid: true,
+		},
+		{
+			nl: nodeList{
+				[]Node{
+					{Host: "host1", IP: "10.0.0.1", Bastion: &BastionConfig{Host: "bastion"}},
+					{Host: "host1", IP: "10.0.0.1", Bastion: &BastionConfig{Host: "other-bastion"}},
+				},
+			},
+			valid: false,
+		},
+		{
+			nl: nodeList{
+				[]Node{
+					{Host: "host1", IP: "10.0.0.1", Bastion: &BastionConfig{Host: "bastion"}},
+					{Host: "host1", IP: "10.0.0.1"},
+				},
+			},
+			valid: false,
+		},
+	}
+	for i, test := range tests {
+		ok, _ := test.nl.validate()
+		if ok != test.valid {
+			t.Errorf("test %d: expect %t, but got %t", i, test.valid, ok)
+		}
+	}
+}
+
+func TestBastionConfigValidation(t *testing.T) {
+	tests := []struct {
+		b     BastionConfig
+		valid bool
+	}{
+		{b: BastionConfig{Host: "bastion"}, valid: true},
+		{b: BastionConfig{Host: "bastion", User: "jumper", Key: "/bin/sh", Port: 2222}, valid: true},
+		{b: BastionConfig{}, valid: false},
+		{b: BastionConfig{Host: "bastion", Key: "relative/key"}, valid: false},
+		{b: BastionConfig{Host: "bastion", Key: "/does/not/exist"}, valid: false},
+		{b: BastionConfig{Host: "bastion", Port: 70000}, valid: false},
+	}
+	for i, test := range tests {
+		ok, _ := test.b.validate()
+		if ok != test.valid {
+			t.Errorf("test %d: expect %t, but got %t", i, test.valid, ok)
+		}
+	}
+}
+
+func TestCertsConfigBackendValidation(t *testing.T) {
+	os.Setenv(vaultTokenEnv, "")
+	defer os.Unsetenv(vaultTokenEnv)
+	tests := []struct {
+		c     CertsConfig
+		valid bool
+	}{
+		{c: CertsConfig{Expiry: "1h"}, valid: true},
+		{c: CertsConfig{Expiry: "1h", Backend: "local"}, valid: true},
+		{c: CertsConfig{Expiry: "1h", Backend: "foo"}, valid: false},
+		{c: CertsConfig{Expiry: "1h", Backend: "intermediate"}, valid: false},
+		{c: CertsConfig{Expiry: "1h", Backend: "intermediate", IntermediateCA: &IntermediateCA{Cert: "/bin/sh", Key: "/bin/sh"}}, valid: true},
+		{c: CertsConfig{Expiry: "1h", Backend: "intermediate", IntermediateCA: &IntermediateCA{Cert: "/bin/sh"}}, valid: false},
+		{c: CertsConfig{Expiry: "1h", Backend: "intermediate", IntermediateCA: &IntermediateCA{Cert: "/bin/sh", Key: "/does/not/exist"}}, valid: false},
+		{c: CertsConfig{Expiry: "1h", Backend: "vault"}, valid: false},
+		{c: CertsConfig{Expiry: "1h", Backend: "vault", Vault: &VaultPKIConfig{Address: "https://vault:8200", TokenFile: "/bin/sh"}}, valid: true},
+		{c: CertsConfig{Expiry: "1h", Backend: "vault", Vault: &VaultPKIConfig{Address: "https://vault:8200"}}, valid: false},
+		{c: CertsConfig{Expiry: "1h", Backend: "vault", Vault: &VaultPKIConfig{Address: "vault:8200", TokenFile: "/bin/sh"}}, valid: false},
+		{c: CertsConfig{Expiry: "1h", Backend: "vault", Vault: &VaultPKIConfig{Address: "https://vault:8200", TokenFile: "/bin/sh", CACert: "/does/not/exist"}}, valid: false},
+	}
+	for i, test := range tests {
+		ok, _ := test.c.validate()
+		if ok != test.valid {
+			t.Errorf("test %d: expect %t, but got %t", i, test.valid, ok)
+		}
+	}
+
+	// the token can be provided in the environment
+	os.Setenv(vaultTokenEnv, "token")
+	c := CertsConfig{Expiry: "1h", Backend: "vault", Vault: &VaultPKIConfig{Address: "https://vault:8200"}}
+	if ok, errs := c.validate(); !ok {
+		t.Errorf("expected the config to be valid when the token is in the environment: %v", errs)
+	}
+}
+
 func TestValidateFile(t *testing.T) {
 	tests := []struct {
 		srcPath        string
@@ -0,0 +1,297 @@
+package ssh
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+const sshAuthSockEnv = "SSH_AUTH_SOCK"
+
+// PassphrasePrompt is used to obtain the passphrase of an encrypted private key.
+// By default, the passphrase is read from the terminal.
+var PassphrasePrompt = terminalPassphrasePrompt
+
+// keyAgent is an ssh-agent running within this process. It holds the
+// decrypted private keys, and is exported to child processes (the ssh binary
+// and Ansible) through SSH_AUTH_SOCK.
+// When the operator's agent is used instead, a single connection to it is
+// kept open in conn.
+var keyAgent = struct {
+	sync.Mutex
+	keyring  agent.Agent
+	listener net.Listener
+	dir      string
+	loaded   map[string]bool
+	conn     net.Conn
+	client   agent.Agent
+}{loaded: map[string]bool{}}
+
+// AgentAvailable returns true if an ssh-agent is reachable through SSH_AUTH_SOCK
+func AgentAvailable() bool {
+	conn, err := dialAgent()
+	if err != nil {
+		return false
+	}
+	conn.Close()
+	return true
+}
+
+func dialAgent() (net.Conn, error) {
+	sock := os.Getenv(sshAuthSockEnv)
+	if sock == "" {
+		return nil, fmt.Errorf("%s is not set", sshAuthSockEnv)
+	}
+	return net.Dial("unix", sock)
+}
+
+// PrepareKey makes sure that the private key can be used for authentication,
+// both by the native client and by processes that run the ssh binary.
+// Unencrypted keys are used as they are. An encrypted key is expected to be
+// held by the ssh-agent, if one is running, and an error is returned if the
+// agent does not hold it. Otherwise, the passphrase is prompted for once, and
+// the decrypted key is served by an agent that runs within this process for
+// its remaining lifetime.
+// An empty file means that all keys are to be obtained from the ssh-agent.
+func PrepareKey(file string) error {
+	if file == "" {
+		if !AgentAvailable() {
+			return errors.New("an SSH key was not provided, and an ssh-agent is not running")
+		}
+		return nil
+	}
+	if err := ValidPrivateKey(file); err != nil {
+		return err
+	}
+	buffer, err := ioutil.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	encrypted, err := isEncrypted(buffer)
+	if err != nil {
+		return err
+	}
+	if !encrypted {
+		return nil
+	}
+
+	keyAgent.Lock()
+	defer keyAgent.Unlock()
+	if keyAgent.loaded[file] {
+		return nil
+	}
+	// rely on the operator's agent, which must hold the key. The key is not
+	// decrypted to find out whether the agent holds it: it is matched through
+	// the public key next to it or, if there is none, through the comment
+	// that ssh-add gives the keys it loads.
+	if keyAgent.listener == nil && AgentAvailable() {
+		pub, err := publicKey(file)
+		if err != nil {
+			pub = nil
+		}
+		held, err := agentHoldsKey(file, pub)
+		if err != nil {
+			return err
+		}
+		if !held && pub == nil {
+			return fmt.Errorf("encrypted key %q not found in ssh-agent, add it with 'ssh-add %s', or place its public key in %s.pub", file, file, file)
+		}
+		if !held {
+			return fmt.Errorf("encrypted key %q not loaded in ssh-agent, add it with 'ssh-add %s'", file, file)
+		}
+		keyAgent.loaded[file] = true
+		return nil
+	}
+	key, err := decryptKey(file, buffer)
+	if err != nil {
+		return err
+	}
+	if err := startAgent(); err != nil {
+		return err
+	}
+	if err := keyAgent.keyring.Add(agent.AddedKey{PrivateKey: key, Comment: file}); err != nil {
+		return fmt.Errorf("error adding SSH key to agent: %v", err)
+	}
+	keyAgent.loaded[file] = true
+	return nil
+}
+
+// decryptKey prompts for the passphrase of the encrypted key, and returns the
+// decrypted key
+func decryptKey(file string, buffer []byte) (interface{}, error) {
+	passphrase, err := PassphrasePrompt(file)
+	if err != nil {
+		return nil, fmt.Errorf("error reading passphrase for %q: %v", file, err)
+	}
+	key, err := ssh.ParseRawPrivateKeyWithPassphrase(buffer, passphrase)
+	if err != nil {
+		return nil, fmt.Errorf("error decrypting SSH key %q: %v", file, err)
+	}
+	return key, nil
+}
+
+// publicKey reads the public key from the ".pub" file next to the private key
+func publicKey(file string) (ssh.PublicKey, error) {
+	buffer, err := ioutil.ReadFile(file + ".pub")
+	if err != nil {
+		return nil, err
+	}
+	pub, _, _, _, err := ssh.ParseAuthorizedKey(buffer)
+	return pub, err
+}
+
+// agentHoldsKey returns true if the ssh-agent holds the key. The key is
+// identified by its public key, or by its file name when the public key is nil.
+// Must be called with the keyAgent lock held.
+func agentHoldsKey(file string, pub ssh.PublicKey) (bool, error) {
+	client, err := agentClient()
+	if err != nil {
+		return false, err
+	}
+	keys, err := client.List()
+	if err != nil {
+		return false, fmt.Errorf("error listing the keys of the ssh-agent: %v", err)
+	}
+	abs, _ := filepath.Abs(file)
+	for _, k := range keys {
+		if pub != nil && bytes.Equal(k.Marshal(), pub.Marshal()) {
+			return true, nil
+		}
+		if pub == nil && (k.Comment == file || k.Comment == abs) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// agentClient returns a client of the ssh-agent. The in-process agent is used
+// directly when it is running. Otherwise, the agent reachable through
+// SSH_AUTH_SOCK is connected to once, and the connection is kept open until
+// StopAgent is called.
+// Must be called with the keyAgent lock held.
+func agentClient() (agent.Agent, error) {
+	if keyAgent.keyring != nil {
+		return keyAgent.keyring, nil
+	}
+	if keyAgent.client == nil {
+		conn, err := dialAgent()
+		if err != nil {
+			return nil, err
+		}
+		keyAgent.conn = conn
+		keyAgent.client = agent.NewClient(conn)
+	}
+	return keyAgent.client, nil
+}
+
+// startAgent starts the in-process agent, if it's not running already.
+// Must be called with the keyAgent lock held.
+func startAgent() error {
+	if keyAgent.listener != nil {
+		return nil
+	}
+	dir, err := ioutil.TempDir("", "kismatic-ssh-agent")
+	if err != nil {
+		return fmt.Errorf("error creating directory for the ssh-agent socket: %v", err)
+	}
+	sock := filepath.Join(dir, "agent.sock")
+	l, err := net.Listen("unix", sock)
+	if err != nil {
+		os.RemoveAll(dir)
+		return fmt.Errorf("error starting ssh-agent: %v", err)
+	}
+	keyring := agent.NewKeyring()
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				agent.ServeAgent(keyring, conn)
+				conn.Close()
+			}()
+		}
+	}()
+	keyAgent.keyring = keyring
+	keyAgent.listener = l
+	keyAgent.dir = dir
+	return os.Setenv(sshAuthSockEnv, sock)
+}
+
+// StopAgent stops the in-process agent, if it was started, and cleans up its
+// socket. The connection to the operator's agent is closed.
+func StopAgent() {
+	keyAgent.Lock()
+	defer keyAgent.Unlock()
+	keyAgent.loaded = map[string]bool{}
+	if keyAgent.conn != nil {
+		keyAgent.conn.Close()
+		keyAgent.conn = nil
+		keyAgent.client = nil
+	}
+	if keyAgent.listener == nil {
+		return
+	}
+	keyAgent.listener.Close()
+	os.RemoveAll(keyAgent.dir)
+	keyAgent.listener = nil
+	keyAgent.keyring = nil
+}
+
+// signers returns the signers that can be used to authenticate with the given key.
+// Signers held by the ssh-agent are included, if an agent is running.
+func signers(file string) ([]ssh.Signer, error) {
+	var signers []ssh.Signer
+	if file != "" {
+		buffer, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		encrypted, err := isEncrypted(buffer)
+		if err != nil {
+			return nil, err
+		}
+		// encrypted keys are provided by the agent
+		if !encrypted {
+			signer, err := ssh.ParsePrivateKey(buffer)
+			if err != nil {
+				return nil, fmt.Errorf("Parse SSH key error: %v", err)
+			}
+			signers = append(signers, signer)
+		}
+	}
+	keyAgent.Lock()
+	defer keyAgent.Unlock()
+	client, err := agentClient()
+	if err != nil {
+		return signers, nil
+	}
+	// the agent's signers use the shared connection, which stays open
+	// until StopAgent is called
+	agentSigners, err := client.Signers()
+	if err != nil {
+		return nil, fmt.Errorf("error getting keys from ssh-agent: %v", err)
+	}
+	return append(signers, agentSigners...), nil
+}
+
+func terminalPassphrasePrompt(file string) ([]byte, error) {
+	fd := int(os.Stdin.Fd())
+	if !terminal.IsTerminal(fd) {
+		return nil, fmt.Errorf("cannot prompt for the passphrase, as stdin is not a terminal. Add the key to an ssh-agent instead")
+	}
+	fmt.Fprintf(os.Stderr, "Enter passphrase for key '%s': ", file)
+	passphrase, err := terminal.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	return passphrase, err
+}
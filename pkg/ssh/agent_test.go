@@ -0,0 +1,282 @@
+package ssh
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+// withoutAgent runs the test with the in-process agent stopped, and no
+// external agent available. The environment is restored afterwards.
+func withoutAgent(t *testing.T, passphrase string) (prompts *int, restore func()) {
+	sock, hadSock := os.LookupEnv(sshAuthSockEnv)
+	os.Unsetenv(sshAuthSockEnv)
+	origPrompt := PassphrasePrompt
+	count := 0
+	PassphrasePrompt = func(file string) ([]byte, error) {
+		count++
+		if passphrase == "" {
+			return nil, errors.New("no passphrase")
+		}
+		return []byte(passphrase), nil
+	}
+	return &count, func() {
+		StopAgent()
+		PassphrasePrompt = origPrompt
+		if hadSock {
+			os.Setenv(sshAuthSockEnv, sock)
+		} else {
+			os.Unsetenv(sshAuthSockEnv)
+		}
+	}
+}
+
+// withExternalAgent runs the test with an ssh-agent that holds the given keys,
+// as if it was started by the operator. The environment is restored afterwards.
+func withExternalAgent(t *testing.T, keys ...interface{}) (prompts *int, restore func()) {
+	prompts, restoreEnv := withoutAgent(t, "secret")
+	dir, err := ioutil.TempDir("", "ssh-agent-test")
+	if err != nil {
+		t.Fatalf("error creating temp dir: %v", err)
+	}
+	keyring := agent.NewKeyring()
+	for _, k := range keys {
+		added, ok := k.(agent.AddedKey)
+		if !ok {
+			added = agent.AddedKey{PrivateKey: k}
+		}
+		if err := keyring.Add(added); err != nil {
+			t.Fatalf("error adding key to agent: %v", err)
+		}
+	}
+	l, err := net.Listen("unix", filepath.Join(dir, "agent.sock"))
+	if err != nil {
+		t.Fatalf("error starting agent: %v", err)
+	}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go agent.ServeAgent(keyring, conn)
+		}
+	}()
+	os.Setenv(sshAuthSockEnv, l.Addr().String())
+	return prompts, func() {
+		l.Close()
+		os.RemoveAll(dir)
+		restoreEnv()
+	}
+}
+
+func writeEncryptedTestKey(t *testing.T, dir string, passphrase string) (string, ssh.PublicKey) {
+	keyFile, pub, _ := writeEncryptedTestKeyPair(t, dir, passphrase)
+	return keyFile, pub
+}
+
+// writeEncryptedTestKeyPair writes an encrypted private key, and returns the
+// decrypted private key along with its public key
+func writeEncryptedTestKeyPair(t *testing.T, dir string, passphrase string) (string, ssh.PublicKey, *rsa.PrivateKey) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("error generating key: %v", err)
+	}
+	block, err := x509.EncryptPEMBlock(rand.Reader, "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(key), []byte(passphrase), x509.PEMCipherAES256)
+	if err != nil {
+		t.Fatalf("error encrypting key: %v", err)
+	}
+	keyFile := filepath.Join(dir, "id_rsa_encrypted")
+	if err := ioutil.WriteFile(keyFile, pem.EncodeToMemory(block), 0600); err != nil {
+		t.Fatalf("error writing key: %v", err)
+	}
+	pub, err := ssh.NewPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatalf("error getting public key: %v", err)
+	}
+	return keyFile, pub, key
+}
+
+func TestPrepareKeyUnencrypted(t *testing.T) {
+	prompts, restore := withoutAgent(t, "")
+	defer restore()
+	dir, err := ioutil.TempDir("", "ssh-agent-test")
+	if err != nil {
+		t.Fatalf("error creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	keyFile, _ := writeTestKey(t, dir)
+
+	if err := PrepareKey(keyFile); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if *prompts != 0 {
+		t.Errorf("expected no passphrase prompts, got %d", *prompts)
+	}
+	if AgentAvailable() {
+		t.Errorf("agent was started for an unencrypted key")
+	}
+}
+
+func TestPrepareKeyEmptyWithoutAgent(t *testing.T) {
+	_, restore := withoutAgent(t, "")
+	defer restore()
+	if err := PrepareKey(""); err == nil {
+		t.Errorf("expected an error when no key is given and no agent is running")
+	}
+}
+
+func TestNativeClientEncryptedKey(t *testing.T) {
+	prompts, restore := withoutAgent(t, "secret")
+	defer restore()
+	dir, err := ioutil.TempDir("", "ssh-agent-test")
+	if err != nil {
+		t.Fatalf("error creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	keyFile, pub := writeEncryptedTestKey(t, dir, "secret")
+	server := newTestServer(t, pub)
+	defer server.close()
+
+	for i := 0; i < 2; i++ {
+		client, err := NewNativeClient("127.0.0.1", server.port(), "kismaticuser", keyFile, nil)
+		if err != nil {
+			t.Fatalf("error creating client: %v", err)
+		}
+		out, err := client.Output(false, "echo", "hello")
+		client.Close()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if out != "hello\n" {
+			t.Errorf("expected %q, but got %q", "hello\n", out)
+		}
+	}
+	if *prompts != 1 {
+		t.Errorf("expected the passphrase to be prompted for once, got %d", *prompts)
+	}
+	if !AgentAvailable() {
+		t.Errorf("expected SSH_AUTH_SOCK to point to the in-process agent")
+	}
+
+	// an empty key is served by the agent
+	client, err := NewNativeClient("127.0.0.1", server.port(), "kismaticuser", "", nil)
+	if err != nil {
+		t.Fatalf("error creating client: %v", err)
+	}
+	defer client.Close()
+	if _, err := client.Output(false, "echo", "hello"); err != nil {
+		t.Errorf("unexpected error authenticating with the agent: %v", err)
+	}
+}
+
+func TestPrepareKeyWrongPassphrase(t *testing.T) {
+	_, restore := withoutAgent(t, "wrong")
+	defer restore()
+	dir, err := ioutil.TempDir("", "ssh-agent-test")
+	if err != nil {
+		t.Fatalf("error creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	keyFile, _ := writeEncryptedTestKey(t, dir, "secret")
+
+	if err := PrepareKey(keyFile); err == nil {
+		t.Errorf("expected an error when the passphrase is wrong")
+	}
+}
+
+func TestPrepareKeyEncryptedHeldByAgent(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ssh-agent-test")
+	if err != nil {
+		t.Fatalf("error creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	keyFile, pub, key := writeEncryptedTestKeyPair(t, dir, "secret")
+	if err := ioutil.WriteFile(keyFile+".pub", ssh.MarshalAuthorizedKey(pub), 0644); err != nil {
+		t.Fatalf("error writing public key: %v", err)
+	}
+	prompts, restore := withExternalAgent(t, key)
+	defer restore()
+
+	if err := PrepareKey(keyFile); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if *prompts != 0 {
+		t.Errorf("expected no passphrase prompts, got %d", *prompts)
+	}
+}
+
+func TestPrepareKeyEncryptedNotHeldByAgent(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ssh-agent-test")
+	if err != nil {
+		t.Fatalf("error creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	keyFile, pub, _ := writeEncryptedTestKeyPair(t, dir, "secret")
+	if err := ioutil.WriteFile(keyFile+".pub", ssh.MarshalAuthorizedKey(pub), 0644); err != nil {
+		t.Fatalf("error writing public key: %v", err)
+	}
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("error generating key: %v", err)
+	}
+	prompts, restore := withExternalAgent(t, otherKey)
+	defer restore()
+
+	err = PrepareKey(keyFile)
+	if err == nil || !strings.Contains(err.Error(), "not loaded in ssh-agent") {
+		t.Errorf("expected an error about the key not being loaded in the ssh-agent, but got %v", err)
+	}
+	if *prompts != 0 {
+		t.Errorf("expected no passphrase prompts, got %d", *prompts)
+	}
+}
+
+func TestPrepareKeyEncryptedWithoutPublicKeyFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ssh-agent-test")
+	if err != nil {
+		t.Fatalf("error creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	keyFile, _, key := writeEncryptedTestKeyPair(t, dir, "secret")
+	// ssh-add uses the file name as the comment of the key
+	prompts, restore := withExternalAgent(t, agent.AddedKey{PrivateKey: key, Comment: keyFile})
+	defer restore()
+
+	if err := PrepareKey(keyFile); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if *prompts != 0 {
+		t.Errorf("expected no passphrase prompts, got %d", *prompts)
+	}
+}
+
+func TestPrepareKeyEncryptedWithoutPublicKeyFileNotFound(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ssh-agent-test")
+	if err != nil {
+		t.Fatalf("error creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	keyFile, _, key := writeEncryptedTestKeyPair(t, dir, "secret")
+	prompts, restore := withExternalAgent(t, agent.AddedKey{PrivateKey: key, Comment: "some other key"})
+	defer restore()
+
+	err = PrepareKey(keyFile)
+	if err == nil || !strings.Contains(err.Error(), "ssh-add") || !strings.Contains(err.Error(), keyFile+".pub") {
+		t.Errorf("expected an error with a hint to add the key or its public key, but got %v", err)
+	}
+	if *prompts != 0 {
+		t.Errorf("expected no passphrase prompts, got %d", *prompts)
+	}
+}
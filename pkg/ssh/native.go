@@ -0,0 +1,433 @@
+package ssh
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+const (
+	// DefaultConnectTimeout is the time allowed for establishing the TCP
+	// connection and completing the SSH handshake with a node.
+	DefaultConnectTimeout = 10 * time.Second
+	// DefaultConnectionAttempts is the number of times a connection is
+	// attempted before giving up.
+	DefaultConnectionAttempts = 3
+)
+
+// NativeClient is an SSH client implemented on top of golang.org/x/crypto/ssh.
+// It does not depend on the ssh binary being available in the PATH.
+// The connection to the node is established on first use, and reused by
+// all subsequent commands until the client is closed.
+type NativeClient struct {
+	// Host is the address of the node
+	Host string
+	// Port is the port on which the node's SSH server is listening
+	Port int
+	// User is the user used to log into the node
+	User string
+	// Bastion is the optional host through which the connection is tunneled
+	Bastion *Bastion
+	// ConnectTimeout is the maximum amount of time a connection attempt can take
+	ConnectTimeout time.Duration
+	// ConnectionAttempts is the number of times to attempt to connect to the node
+	ConnectionAttempts uint
+	// Stdin, Stdout and Stderr are bound to the remote session when running Shell.
+	// They default to the process' standard streams.
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+
+	config        *ssh.ClientConfig
+	bastionConfig *ssh.ClientConfig
+	mu            sync.Mutex
+	conn          *ssh.Client
+	bastionConn   *ssh.Client
+
+	sessionsMu sync.Mutex
+	sessions   map[*ssh.Session]bool
+	cancelled  bool
+	// cancel is closed when the client is cancelled, to abort connection attempts
+	cancel chan struct{}
+}
+
+// NewNativeClient returns an SSH client that authenticates with the given
+// private key, and with the keys held by the ssh-agent, if one is running.
+// No connection is made until a command is run.
+// The bastion is optional, and can be nil when the node is directly reachable.
+func NewNativeClient(host string, port int, user string, key string, bastion *Bastion) (*NativeClient, error) {
+	config, err := clientConfig(user, key)
+	if err != nil {
+		return nil, err
+	}
+	c := &NativeClient{
+		Host:               host,
+		Port:               port,
+		User:               user,
+		Bastion:            bastion,
+		ConnectTimeout:     DefaultConnectTimeout,
+		ConnectionAttempts: DefaultConnectionAttempts,
+		Stdin:              os.Stdin,
+		Stdout:             os.Stdout,
+		Stderr:             os.Stderr,
+		config:             config,
+	}
+	if bastion != nil {
+		c.bastionConfig, err = clientConfig(bastion.User, bastion.Key)
+		if err != nil {
+			return nil, fmt.Errorf("bastion: %v", err)
+		}
+	}
+	return c, nil
+}
+
+func clientConfig(user string, key string) (*ssh.ClientConfig, error) {
+	if err := PrepareKey(key); err != nil {
+		return nil, err
+	}
+	signers, err := signers(key)
+	if err != nil {
+		return nil, err
+	}
+	return &ssh.ClientConfig{
+		User: user,
+		Auth: []ssh.AuthMethod{ssh.PublicKeys(signers...)},
+		// equivalent of StrictHostKeyChecking=no and UserKnownHostsFile=/dev/null
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+	}, nil
+}
+
+// Output runs the command on the node, and returns the combined stdout and stderr
+func (c *NativeClient) Output(pty bool, args ...string) (string, error) {
+	session, err := c.newSession()
+	if err != nil {
+		return "", err
+	}
+	defer c.closeSession(session)
+	if pty {
+		if err := requestPTY(session, 80, 40); err != nil {
+			return "", err
+		}
+	}
+	output, err := session.CombinedOutput(strings.Join(args, " "))
+	return string(output), err
+}
+
+// Shell runs the command on the node, streaming the session's stdin, stdout
+// and stderr. An interactive login shell is started when no command is given.
+func (c *NativeClient) Shell(pty bool, args ...string) error {
+	session, err := c.newSession()
+	if err != nil {
+		return err
+	}
+	defer c.closeSession(session)
+	session.Stdin = c.Stdin
+	session.Stdout = c.Stdout
+	session.Stderr = c.Stderr
+
+	interactive := len(args) == 0
+	if pty || interactive {
+		width, height := 80, 40
+		if f, ok := c.Stdin.(*os.File); ok && terminal.IsTerminal(int(f.Fd())) {
+			fd := int(f.Fd())
+			if w, h, err := terminal.GetSize(fd); err == nil {
+				width, height = w, h
+			}
+			state, err := terminal.MakeRaw(fd)
+			if err != nil {
+				return fmt.Errorf("error setting terminal to raw mode: %v", err)
+			}
+			defer terminal.Restore(fd, state)
+		}
+		if err := requestPTY(session, width, height); err != nil {
+			return err
+		}
+	}
+
+	if interactive {
+		if err := session.Shell(); err != nil {
+			return fmt.Errorf("error starting shell: %v", err)
+		}
+		return session.Wait()
+	}
+	return session.Run(strings.Join(args, " "))
+}
+
+// Close closes the underlying connection to the node, if any
+func (c *NativeClient) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var err error
+	if c.conn != nil {
+		err = c.conn.Close()
+		c.conn = nil
+	}
+	if c.bastionConn != nil {
+		c.bastionConn.Close()
+		c.bastionConn = nil
+	}
+	return err
+}
+
+// Cancel closes the sessions that are open on the node, aborts the connection
+// attempt in progress, and prevents new sessions from being created. Unlike
+// Close, the client cannot be reused afterwards.
+func (c *NativeClient) Cancel() {
+	c.sessionsMu.Lock()
+	defer c.sessionsMu.Unlock()
+	if c.cancelled {
+		return
+	}
+	c.cancelled = true
+	if c.cancel != nil {
+		close(c.cancel)
+	}
+	for session := range c.sessions {
+		session.Close()
+	}
+}
+
+// cancelledChan returns a channel that is closed when the client is cancelled
+func (c *NativeClient) cancelledChan() <-chan struct{} {
+	c.sessionsMu.Lock()
+	defer c.sessionsMu.Unlock()
+	if c.cancel == nil {
+		c.cancel = make(chan struct{})
+		if c.cancelled {
+			close(c.cancel)
+		}
+	}
+	return c.cancel
+}
+
+// newSession opens a new session on the node, keeping track of it so that it
+// can be closed if the client is cancelled
+func (c *NativeClient) newSession() (*ssh.Session, error) {
+	session, err := c.openSession()
+	if err != nil {
+		return nil, err
+	}
+	c.sessionsMu.Lock()
+	defer c.sessionsMu.Unlock()
+	if c.cancelled {
+		session.Close()
+		return nil, errCancelled
+	}
+	if c.sessions == nil {
+		c.sessions = map[*ssh.Session]bool{}
+	}
+	c.sessions[session] = true
+	return session, nil
+}
+
+func (c *NativeClient) closeSession(session *ssh.Session) {
+	c.sessionsMu.Lock()
+	delete(c.sessions, session)
+	c.sessionsMu.Unlock()
+	session.Close()
+}
+
+func (c *NativeClient) openSession() (*ssh.Session, error) {
+	c.sessionsMu.Lock()
+	cancelled := c.cancelled
+	c.sessionsMu.Unlock()
+	if cancelled {
+		return nil, errCancelled
+	}
+	conn, err := c.connect()
+	if err != nil {
+		return nil, err
+	}
+	session, err := conn.NewSession()
+	if err == nil {
+		return session, nil
+	}
+	// The cached connection might have been dropped by the remote end.
+	// Reconnect once before giving up.
+	c.dropConn(conn)
+	if conn, err = c.connect(); err != nil {
+		return nil, err
+	}
+	session, err = conn.NewSession()
+	if err != nil {
+		return nil, fmt.Errorf("error creating SSH session: %v", err)
+	}
+	return session, nil
+}
+
+// dropConn discards the connection if it is still the cached one, so that the
+// next session reconnects. The sessions that are open on a newer connection
+// are left untouched.
+func (c *NativeClient) dropConn(conn *ssh.Client) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.conn != conn {
+		return
+	}
+	conn.Close()
+	c.conn = nil
+}
+
+func (c *NativeClient) connect() (*ssh.Client, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.conn != nil {
+		return c.conn, nil
+	}
+	cancel := c.cancelledChan()
+	addr := net.JoinHostPort(c.Host, strconv.Itoa(c.Port))
+	var err error
+	// the attempts are not retried with the retry package, as waiting
+	// between attempts must be interrupted when the client is cancelled
+	for attempt := uint(1); ; attempt++ {
+		if err = c.dialNode(addr, cancel); err == nil || err == errCancelled || attempt >= c.ConnectionAttempts {
+			break
+		}
+		select {
+		case <-time.After(time.Second):
+		case <-cancel:
+			err = errCancelled
+		}
+		if err == errCancelled {
+			break
+		}
+	}
+	if err == errCancelled {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error connecting to %s: %v", addr, err)
+	}
+	return c.conn, nil
+}
+
+// dialNode connects to the node, through the bastion if there is one
+func (c *NativeClient) dialNode(addr string, cancel <-chan struct{}) error {
+	var err error
+	if c.Bastion == nil {
+		c.conn, err = dial(nil, addr, c.config, c.ConnectTimeout, cancel)
+		return err
+	}
+	if c.bastionConn == nil {
+		bastionAddr := net.JoinHostPort(c.Bastion.Host, strconv.Itoa(c.Bastion.Port))
+		c.bastionConn, err = dial(nil, bastionAddr, c.bastionConfig, c.ConnectTimeout, cancel)
+		if err == errCancelled {
+			return err
+		}
+		if err != nil {
+			return fmt.Errorf("error connecting to bastion %s: %v", bastionAddr, err)
+		}
+	}
+	c.conn, err = dial(c.bastionConn, addr, c.config, c.ConnectTimeout, cancel)
+	if err != nil {
+		// start over with a fresh connection to the bastion
+		c.bastionConn.Close()
+		c.bastionConn = nil
+	}
+	return err
+}
+
+// dial establishes an SSH connection, directly or tunneled through the given
+// client, making sure that the connection and the SSH handshake complete
+// within the timeout. The attempt is aborted when the cancel channel is closed.
+func dial(via *ssh.Client, addr string, config *ssh.ClientConfig, timeout time.Duration, cancel <-chan struct{}) (*ssh.Client, error) {
+	type result struct {
+		client *ssh.Client
+		err    error
+	}
+	done := make(chan result, 1)
+	var conn net.Conn
+	var mu sync.Mutex
+	go func() {
+		var c net.Conn
+		var err error
+		if via != nil {
+			c, err = via.Dial("tcp", addr)
+		} else {
+			c, err = net.DialTimeout("tcp", addr, timeout)
+		}
+		if err != nil {
+			done <- result{err: err}
+			return
+		}
+		mu.Lock()
+		conn = c
+		mu.Unlock()
+		sshConn, chans, reqs, err := ssh.NewClientConn(c, addr, config)
+		if err != nil {
+			c.Close()
+			done <- result{err: err}
+			return
+		}
+		done <- result{client: ssh.NewClient(sshConn, chans, reqs)}
+	}()
+	// abort unblocks the handshake, if it is in progress, and discards the
+	// connection if it completes anyway
+	abort := func() {
+		mu.Lock()
+		if conn != nil {
+			conn.Close()
+		}
+		mu.Unlock()
+		go func() {
+			if r := <-done; r.client != nil {
+				r.client.Close()
+			}
+		}()
+	}
+	var expired <-chan time.Time
+	if timeout > 0 {
+		expired = time.After(timeout)
+	}
+	select {
+	case r := <-done:
+		return r.client, r.err
+	case <-expired:
+		abort()
+		return nil, fmt.Errorf("timed out after %v", timeout)
+	case <-cancel:
+		abort()
+		return nil, errCancelled
+	}
+}
+
+func requestPTY(session *ssh.Session, width, height int) error {
+	modes := ssh.TerminalModes{
+		ssh.ECHO:          1,
+		ssh.TTY_OP_ISPEED: 14400,
+		ssh.TTY_OP_OSPEED: 14400,
+	}
+	term := os.Getenv("TERM")
+	if term == "" {
+		term = "xterm"
+	}
+	if err := session.RequestPty(term, height, width, modes); err != nil {
+		return fmt.Errorf("error requesting pseudo-terminal: %v", err)
+	}
+	return nil
+}
+
+// ExitStatus returns the exit status of the remote command that resulted in
+// the given error. The second return value is false if the error was not
+// caused by the remote command exiting with a non-zero status.
+func ExitStatus(err error) (int, bool) {
+	switch e := err.(type) {
+	case *ssh.ExitError:
+		return e.ExitStatus(), true
+	case *exec.ExitError:
+		if status, ok := e.Sys().(syscall.WaitStatus); ok {
+			return status.ExitStatus(), true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,484 @@
+package ssh
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// testServer is an in-process SSH server that understands a couple of commands:
+// - "echo ARGS" writes ARGS to stdout
+// - "fail CODE MSG" writes MSG to stderr and exits with CODE
+// - "hang" blocks until the session is closed by the client
+// It also forwards "direct-tcpip" channels, so that it can be used as a bastion.
+type testServer struct {
+	listener    net.Listener
+	config      *ssh.ServerConfig
+	connections int32
+	tunnels     int32
+}
+
+func newTestServer(t *testing.T, authorizedKey ssh.PublicKey) *testServer {
+	hostKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("error generating host key: %v", err)
+	}
+	hostSigner, err := ssh.NewSignerFromKey(hostKey)
+	if err != nil {
+		t.Fatalf("error creating host key signer: %v", err)
+	}
+	config := &ssh.ServerConfig{
+		PublicKeyCallback: func(c ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			if bytes.Equal(key.Marshal(), authorizedKey.Marshal()) {
+				return nil, nil
+			}
+			return nil, fmt.Errorf("unknown public key for %q", c.User())
+		},
+	}
+	config.AddHostKey(hostSigner)
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("error listening: %v", err)
+	}
+	s := &testServer{listener: l, config: config}
+	go s.serve()
+	return s
+}
+
+func (s *testServer) port() int {
+	return s.listener.Addr().(*net.TCPAddr).Port
+}
+
+func (s *testServer) close() {
+	s.listener.Close()
+}
+
+func (s *testServer) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handleConn(conn)
+	}
+}
+
+func (s *testServer) handleConn(conn net.Conn) {
+	_, chans, reqs, err := ssh.NewServerConn(conn, s.config)
+	if err != nil {
+		conn.Close()
+		return
+	}
+	atomic.AddInt32(&s.connections, 1)
+	go ssh.DiscardRequests(reqs)
+	for newChan := range chans {
+		switch newChan.ChannelType() {
+		case "session":
+			channel, requests, err := newChan.Accept()
+			if err != nil {
+				continue
+			}
+			go handleSession(channel, requests)
+		case "direct-tcpip":
+			atomic.AddInt32(&s.tunnels, 1)
+			go handleDirectTCPIP(newChan)
+		default:
+			newChan.Reject(ssh.UnknownChannelType, "unsupported channel type")
+		}
+	}
+}
+
+func handleDirectTCPIP(newChan ssh.NewChannel) {
+	var target struct {
+		Host       string
+		Port       uint32
+		OriginHost string
+		OriginPort uint32
+	}
+	if err := ssh.Unmarshal(newChan.ExtraData(), &target); err != nil {
+		newChan.Reject(ssh.ConnectionFailed, err.Error())
+		return
+	}
+	conn, err := net.Dial("tcp", net.JoinHostPort(target.Host, fmt.Sprintf("%d", target.Port)))
+	if err != nil {
+		newChan.Reject(ssh.ConnectionFailed, err.Error())
+		return
+	}
+	channel, requests, err := newChan.Accept()
+	if err != nil {
+		conn.Close()
+		return
+	}
+	go ssh.DiscardRequests(requests)
+	go func() {
+		io.Copy(conn, channel)
+		conn.Close()
+	}()
+	go func() {
+		io.Copy(channel, conn)
+		channel.Close()
+	}()
+}
+
+func handleSession(channel ssh.Channel, requests <-chan *ssh.Request) {
+	defer channel.Close()
+	for req := range requests {
+		switch req.Type {
+		case "pty-req":
+			req.Reply(true, nil)
+		case "exec":
+			req.Reply(true, nil)
+			// payload is a length-prefixed string
+			cmd := string(req.Payload[4:])
+			status := runTestCommand(channel, cmd)
+			exit := make([]byte, 4)
+			binary.BigEndian.PutUint32(exit, status)
+			channel.SendRequest("exit-status", false, exit)
+			return
+		default:
+			req.Reply(false, nil)
+		}
+	}
+}
+
+func runTestCommand(channel ssh.Channel, cmd string) uint32 {
+	fields := strings.Fields(cmd)
+	switch {
+	case len(fields) > 0 && fields[0] == "echo":
+		fmt.Fprintln(channel, strings.Join(fields[1:], " "))
+		return 0
+	case len(fields) > 2 && fields[0] == "fail":
+		var code uint32
+		fmt.Sscanf(fields[1], "%d", &code)
+		fmt.Fprintln(channel.Stderr(), strings.Join(fields[2:], " "))
+		return code
+	case len(fields) > 0 && fields[0] == "hang":
+		io.Copy(ioutil.Discard, channel)
+		return 0
+	default:
+		fmt.Fprintf(channel.Stderr(), "command not found: %s\n", cmd)
+		return 127
+	}
+}
+
+func writeTestKey(t *testing.T, dir string) (string, ssh.PublicKey) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("error generating key: %v", err)
+	}
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	keyFile := filepath.Join(dir, "id_rsa")
+	if err := ioutil.WriteFile(keyFile, keyPEM, 0600); err != nil {
+		t.Fatalf("error writing key: %v", err)
+	}
+	pub, err := ssh.NewPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatalf("error getting public key: %v", err)
+	}
+	return keyFile, pub
+}
+
+func newTestNativeClient(t *testing.T) (*NativeClient, *testServer, func()) {
+	dir, err := ioutil.TempDir("", "native-ssh-test")
+	if err != nil {
+		t.Fatalf("error creating temp dir: %v", err)
+	}
+	keyFile, pub := writeTestKey(t, dir)
+	server := newTestServer(t, pub)
+	client, err := NewNativeClient("127.0.0.1", server.port(), "kismaticuser", keyFile, nil)
+	if err != nil {
+		t.Fatalf("error creating client: %v", err)
+	}
+	return client, server, func() {
+		client.Close()
+		server.close()
+		os.RemoveAll(dir)
+	}
+}
+
+func TestNativeClientOutput(t *testing.T) {
+	client, _, cleanup := newTestNativeClient(t)
+	defer cleanup()
+
+	out, err := client.Output(false, "echo", "hello", "world")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out != "hello world\n" {
+		t.Errorf("expected %q, but got %q", "hello world\n", out)
+	}
+}
+
+func TestNativeClientOutputExitStatus(t *testing.T) {
+	client, _, cleanup := newTestNativeClient(t)
+	defer cleanup()
+
+	out, err := client.Output(false, "fail", "3", "something went wrong")
+	if err == nil {
+		t.Fatal("expected an error, but didn't get one")
+	}
+	status, ok := ExitStatus(err)
+	if !ok {
+		t.Fatalf("expected an exit status error, but got %v", err)
+	}
+	if status != 3 {
+		t.Errorf("expected exit status 3, but got %d", status)
+	}
+	if out != "something went wrong\n" {
+		t.Errorf("expected stderr to be included in the output, but got %q", out)
+	}
+}
+
+func TestNativeClientShellStreamsOutput(t *testing.T) {
+	client, _, cleanup := newTestNativeClient(t)
+	defer cleanup()
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+	client.Stdin = &bytes.Buffer{}
+	client.Stdout = stdout
+	client.Stderr = stderr
+
+	if err := client.Shell(false, "echo", "foo"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	err := client.Shell(false, "fail", "130", "interrupted")
+	if status, ok := ExitStatus(err); !ok || status != 130 {
+		t.Errorf("expected exit status 130, but got %v", err)
+	}
+	if stdout.String() != "foo\n" {
+		t.Errorf("expected stdout %q, but got %q", "foo\n", stdout.String())
+	}
+	if stderr.String() != "interrupted\n" {
+		t.Errorf("expected stderr %q, but got %q", "interrupted\n", stderr.String())
+	}
+}
+
+func TestNativeClientReusesConnection(t *testing.T) {
+	client, server, cleanup := newTestNativeClient(t)
+	defer cleanup()
+
+	for i := 0; i < 3; i++ {
+		if _, err := client.Output(false, "echo", "hi"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if n := atomic.LoadInt32(&server.connections); n != 1 {
+		t.Errorf("expected a single connection to be established, but got %d", n)
+	}
+}
+
+func TestNativeClientReconnectsAfterClose(t *testing.T) {
+	client, server, cleanup := newTestNativeClient(t)
+	defer cleanup()
+
+	if _, err := client.Output(false, "echo", "hi"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	client.Close()
+	if _, err := client.Output(false, "echo", "hi"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if n := atomic.LoadInt32(&server.connections); n != 2 {
+		t.Errorf("expected two connections to be established, but got %d", n)
+	}
+}
+
+func TestNativeClientCancel(t *testing.T) {
+	client, _, cleanup := newTestNativeClient(t)
+	defer cleanup()
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := client.Output(false, "hang")
+		done <- err
+	}()
+	// wait for the session to be opened before cancelling the client
+	for {
+		client.sessionsMu.Lock()
+		open := len(client.sessions)
+		client.sessionsMu.Unlock()
+		if open == 1 {
+			break
+		}
+		runtime.Gosched()
+	}
+	client.Cancel()
+	if err := <-done; err == nil {
+		t.Error("expected an error from the cancelled command, but didn't get one")
+	}
+	if _, err := client.Output(false, "echo", "hi"); err != errCancelled {
+		t.Errorf("expected commands to fail after the client was cancelled, but got %v", err)
+	}
+}
+
+func TestNativeClientCancelAbortsConnect(t *testing.T) {
+	dir, err := ioutil.TempDir("", "native-ssh-test")
+	if err != nil {
+		t.Fatalf("error creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	keyFile, _ := writeTestKey(t, dir)
+	// the listener accepts connections, but never completes the SSH handshake
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("error listening: %v", err)
+	}
+	defer l.Close()
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		if c, err := l.Accept(); err == nil {
+			accepted <- c
+		}
+	}()
+	client, err := NewNativeClient("127.0.0.1", l.Addr().(*net.TCPAddr).Port, "kismaticuser", keyFile, nil)
+	if err != nil {
+		t.Fatalf("error creating client: %v", err)
+	}
+	client.ConnectTimeout = time.Minute
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := client.Output(false, "echo", "hi")
+		done <- err
+	}()
+	c := <-accepted
+	defer c.Close()
+	client.Cancel()
+	select {
+	case err := <-done:
+		if err != errCancelled {
+			t.Errorf("expected the connection attempt to be cancelled, but got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("the connection attempt was not aborted when the client was cancelled")
+	}
+}
+
+func TestNativeClientDropsOnlyTheCachedConnection(t *testing.T) {
+	client, server, cleanup := newTestNativeClient(t)
+	defer cleanup()
+
+	if _, err := client.Output(false, "echo", "hi"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	stale := client.conn
+	defer stale.Close()
+	// another command replaced the connection in the meantime
+	client.mu.Lock()
+	client.conn = nil
+	client.mu.Unlock()
+	if _, err := client.Output(false, "echo", "hi"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	current := client.conn
+
+	client.dropConn(stale)
+	if client.conn != current {
+		t.Fatal("expected the current connection to be kept")
+	}
+	if _, err := client.Output(false, "echo", "hi"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if n := atomic.LoadInt32(&server.connections); n != 2 {
+		t.Errorf("expected two connections to be established, but got %d", n)
+	}
+
+	client.dropConn(current)
+	if client.conn != nil {
+		t.Error("expected the cached connection to be dropped")
+	}
+}
+
+func TestNativeClientWrongKey(t *testing.T) {
+	dir, err := ioutil.TempDir("", "native-ssh-test")
+	if err != nil {
+		t.Fatalf("error creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	_, pub := writeTestKey(t, dir)
+	server := newTestServer(t, pub)
+	defer server.close()
+
+	// overwrite the key with one the server doesn't know about
+	otherKey, _ := writeTestKey(t, dir)
+	client, err := NewNativeClient("127.0.0.1", server.port(), "kismaticuser", otherKey, nil)
+	if err != nil {
+		t.Fatalf("error creating client: %v", err)
+	}
+	client.ConnectionAttempts = 1
+	if _, err := client.Output(false, "echo", "hi"); err == nil {
+		t.Error("expected an authentication error, but didn't get one")
+	}
+}
+
+func TestNativeClientThroughBastion(t *testing.T) {
+	dir, err := ioutil.TempDir("", "native-ssh-test")
+	if err != nil {
+		t.Fatalf("error creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	keyFile, pub := writeTestKey(t, dir)
+	node := newTestServer(t, pub)
+	defer node.close()
+	bastion := newTestServer(t, pub)
+	defer bastion.close()
+
+	b := &Bastion{Host: "127.0.0.1", Port: bastion.port(), User: "jumper", Key: keyFile}
+	client, err := NewNativeClient("127.0.0.1", node.port(), "kismaticuser", keyFile, b)
+	if err != nil {
+		t.Fatalf("error creating client: %v", err)
+	}
+	defer client.Close()
+
+	for i := 0; i < 2; i++ {
+		out, err := client.Output(false, "echo", "through", "the", "bastion")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if out != "through the bastion\n" {
+			t.Errorf("expected %q, but got %q", "through the bastion\n", out)
+		}
+	}
+	if n := atomic.LoadInt32(&bastion.tunnels); n != 1 {
+		t.Errorf("expected a single tunnel through the bastion, but got %d", n)
+	}
+	if n := atomic.LoadInt32(&node.connections); n != 1 {
+		t.Errorf("expected a single connection to the node, but got %d", n)
+	}
+}
+
+func TestBastionProxyCommand(t *testing.T) {
+	b := Bastion{Host: "bastion.example.com", Port: 2222, User: "jumper", Key: "/keys/bastion.pem"}
+	cmd := b.ProxyCommand()
+	if !strings.HasPrefix(cmd, "ssh -F /dev/null ") {
+		t.Errorf("expected proxy command to invoke ssh with an empty config, got %q", cmd)
+	}
+	if !strings.HasSuffix(cmd, "-i /keys/bastion.pem -p 2222 -W %h:%p jumper@bastion.example.com") {
+		t.Errorf("unexpected proxy command %q", cmd)
+	}
+}
+
+func TestExitStatusNonExitError(t *testing.T) {
+	if _, ok := ExitStatus(fmt.Errorf("some error")); ok {
+		t.Error("expected ok to be false for an error that is not an exit error")
+	}
+	if _, ok := ExitStatus(nil); ok {
+		t.Error("expected ok to be false for a nil error")
+	}
+}
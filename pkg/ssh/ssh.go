@@ -1,13 +1,17 @@
 package ssh
 
 import (
+	"bytes"
 	"crypto/x509"
 	"encoding/pem"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"os/exec"
 	"runtime"
+	"strings"
+	"sync"
 
 	"golang.org/x/crypto/ssh"
 )
@@ -24,48 +28,104 @@ var baseSSHArgs = []string{
 	"-o", "ControlPath=none",
 }
 
+// Client runs commands on a remote node over SSH
 type Client interface {
 	Output(pty bool, args ...string) (string, error)
 	Shell(pty bool, args ...string) error
+	// Close releases the connection to the node, if one is kept open
+	Close() error
 }
 
+// Canceler is implemented by the clients that can terminate the commands
+// they are running on the node
+type Canceler interface {
+	// Cancel terminates the running commands. Commands that are run after
+	// the client has been cancelled fail immediately.
+	Cancel()
+}
+
+var errCancelled = errors.New("the SSH client was cancelled")
+
 type ExternalClient struct {
 	BaseArgs   []string
 	BinaryPath string
 	cmd        *exec.Cmd
+
+	mu        sync.Mutex
+	running   map[*exec.Cmd]bool
+	cancelled bool
+}
+
+// Bastion is an intermediate (jump) host through which the connection
+// to the target node is tunneled.
+type Bastion struct {
+	// Host is the address of the bastion
+	Host string
+	// Port is the port on which the bastion's SSH server is listening
+	Port int
+	// User is the user used to log into the bastion
+	User string
+	// Key is the path to the private key used to log into the bastion
+	Key string
+}
+
+// ProxyCommand returns the ssh ProxyCommand that tunnels a connection
+// through the bastion host.
+func (b Bastion) ProxyCommand() string {
+	args := append([]string{"ssh"}, baseSSHArgs...)
+	if b.Key != "" {
+		args = append(args, "-i", b.Key)
+	}
+	args = append(args, "-p", fmt.Sprintf("%d", b.Port), "-W", "%h:%p", fmt.Sprintf("%s@%s", b.User, b.Host))
+	return strings.Join(args, " ")
 }
 
 // TestConnection connects to ip:port as user with key and immediately exits.
-func TestConnection(ip string, port int, user, key string) error {
-	client, err := NewClient(ip, port, user, key)
+// The connection goes through the bastion, if one is provided.
+func TestConnection(ip string, port int, user, key string, bastion *Bastion) error {
+	client, err := NewClient(ip, port, user, key, bastion)
 	if err != nil {
 		return err
 	}
-
+	defer client.Close()
 	return client.Shell(false, "exit")
 }
 
-// NewClient verifies ssh is available in the PATH and returns an SSH client
-func NewClient(host string, port int, user string, key string) (Client, error) {
-	if err := ValidUnencryptedPrivateKey(key); err != nil {
+// NewClient returns an SSH client. The ssh binary is used if it is available
+// in the PATH, otherwise the native Go implementation is used.
+// The key can be empty, in which case the keys held by the ssh-agent are used.
+// The bastion is optional, and can be nil when the node is directly reachable.
+func NewClient(host string, port int, user string, key string, bastion *Bastion) (Client, error) {
+	if err := PrepareKey(key); err != nil {
 		return nil, err
 	}
+	if bastion != nil {
+		if err := PrepareKey(bastion.Key); err != nil {
+			return nil, fmt.Errorf("bastion: %v", err)
+		}
+	}
 
 	sshBinaryPath, err := exec.LookPath("ssh")
 	if err != nil {
-		return nil, fmt.Errorf("command not found: ssh")
+		return NewNativeClient(host, port, user, key, bastion)
 	}
 
-	return newExternalClient(sshBinaryPath, user, host, port, key)
+	return newExternalClient(sshBinaryPath, user, host, port, key, bastion)
 }
 
-func newExternalClient(sshBinaryPath string, user string, host string, port int, key string) (*ExternalClient, error) {
+func newExternalClient(sshBinaryPath string, user string, host string, port int, key string, bastion *Bastion) (*ExternalClient, error) {
 	// Get defailt args with user and host
 	args := append(baseSSHArgs, fmt.Sprintf("%s@%s", user, host))
 	// set port
 	args = append(args, "-p", fmt.Sprintf("%d", port))
 	// set key
-	args = append(args, "-i", key)
+	if key != "" {
+		args = append(args, "-i", key)
+	}
+	// tunnel through the bastion
+	if bastion != nil {
+		args = append(args, "-o", "ProxyCommand="+bastion.ProxyCommand())
+	}
 
 	client := &ExternalClient{
 		BinaryPath: sshBinaryPath,
@@ -83,8 +143,11 @@ func (client *ExternalClient) Output(pty bool, args ...string) (string, error) {
 	if pty {
 		cmd.Stdin = os.Stdin
 	}
-	output, err := cmd.CombinedOutput()
-	return string(output), err
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	err := client.run(cmd)
+	return output.String(), err
 }
 
 // Shell runs the ssh command, binding Stdin, Stdout and Stderr
@@ -94,7 +157,48 @@ func (client *ExternalClient) Shell(pty bool, args ...string) error {
 	cmd.Stdin = os.Stdin
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
-	return cmd.Run()
+	return client.run(cmd)
+}
+
+// Close does nothing, as the ssh binary opens a new connection for every command
+func (client *ExternalClient) Close() error {
+	return nil
+}
+
+// Cancel kills the ssh processes that are running, and prevents new ones from
+// being started
+func (client *ExternalClient) Cancel() {
+	client.mu.Lock()
+	defer client.mu.Unlock()
+	client.cancelled = true
+	for cmd := range client.running {
+		cmd.Process.Kill()
+	}
+}
+
+// run starts the command and waits for it to exit, keeping track of it
+// so that it can be killed if the client is cancelled
+func (client *ExternalClient) run(cmd *exec.Cmd) error {
+	client.mu.Lock()
+	if client.cancelled {
+		client.mu.Unlock()
+		return errCancelled
+	}
+	if err := cmd.Start(); err != nil {
+		client.mu.Unlock()
+		return err
+	}
+	if client.running == nil {
+		client.running = map[*exec.Cmd]bool{}
+	}
+	client.running[cmd] = true
+	client.mu.Unlock()
+
+	err := cmd.Wait()
+	client.mu.Lock()
+	delete(client.running, cmd)
+	client.mu.Unlock()
+	return err
 }
 
 func getSSHCmd(binaryPath string, pty bool, args ...string) *exec.Cmd {
@@ -104,8 +208,9 @@ func getSSHCmd(binaryPath string, pty bool, args ...string) *exec.Cmd {
 	return exec.Command(binaryPath, args...)
 }
 
-// ValidUnencryptedPrivateKey parses SSH private key
-func ValidUnencryptedPrivateKey(file string) error {
+// ValidPrivateKey verifies that the file contains an SSH private key, and that
+// the file has strict permissions. The key can be encrypted.
+func ValidPrivateKey(file string) error {
 	// Check private key before use it
 	fi, err := os.Stat(file)
 	if err != nil {
@@ -123,13 +228,11 @@ func ValidUnencryptedPrivateKey(file string) error {
 		return fmt.Errorf("Parse SSH key error")
 	}
 
-	if isEncrypted {
-		return fmt.Errorf("Encrypted SSH key is not permitted")
-	}
-
-	_, err = ssh.ParsePrivateKey(buffer)
-	if err != nil {
-		return fmt.Errorf("Parse SSH key error: %v", err)
+	if !isEncrypted {
+		_, err = ssh.ParsePrivateKey(buffer)
+		if err != nil {
+			return fmt.Errorf("Parse SSH key error: %v", err)
+		}
 	}
 
 	if runtime.GOOS != "windows" {
@@ -156,5 +259,11 @@ func isEncrypted(buffer []byte) (bool, error) {
 		return false, fmt.Errorf("Parse SSH key error")
 	}
 
-	return x509.IsEncryptedPEMBlock(block), nil
+	if x509.IsEncryptedPEMBlock(block) {
+		return true, nil
+	}
+	// keys in the OpenSSH format are encrypted without PEM headers
+	_, err := ssh.ParseRawPrivateKey(buffer)
+	_, missingPassphrase := err.(*ssh.PassphraseMissingError)
+	return missingPassphrase, nil
 }
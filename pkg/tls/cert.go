@@ -26,6 +26,17 @@ type CA struct {
 	Password string
 	// Cert is the CA's public certificate.
 	Cert []byte
+	// Signer signs certificates on behalf of the CA when its private key
+	// is not available locally. Can be nil if the Key is set.
+	Signer Signer
+}
+
+// A Signer signs certificate requests with a Certificate Authority that is
+// managed outside of KET, such as the PKI secrets engine of HashiCorp Vault.
+type Signer interface {
+	// Sign returns the PEM encoded certificate for the PEM encoded CSR,
+	// valid for the given duration.
+	Sign(csr []byte, expiry time.Duration) ([]byte, error)
 }
 
 // NewCert creates a new certificate/key pair using the CertificateAuthority provided
@@ -35,6 +46,13 @@ func NewCert(ca *CA, req csr.CertificateRequest, expiry time.Duration) (key, cer
 	if err != nil {
 		return nil, nil, fmt.Errorf("error processing CSR: %v", err)
 	}
+	if ca.Signer != nil {
+		cert, err = ca.Signer.Sign(csrBytes, expiry)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error signing certificate: %v", err)
+		}
+		return key, cert, nil
+	}
 	// Get CA private key
 	caPriv, err := helpers.ParsePrivateKeyPEMWithPassword(ca.Key, []byte(ca.Password))
 	if err != nil {
@@ -0,0 +1,128 @@
+package util
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+type diffLine struct {
+	kind byte
+	text string
+	// line numbers in the old and new text, before the line
+	oldLine int
+	newLine int
+}
+
+// UnifiedDiff returns the differences between the old and the new text in the
+// unified format, with the given number of lines of context around the
+// changes. Returns an empty string if the texts are equal.
+func UnifiedDiff(oldName, newName, oldText, newText string, context int) string {
+	lines := diffLines(splitLines(oldText), splitLines(newText))
+	var b bytes.Buffer
+	for i := 0; i < len(lines); {
+		if lines[i].kind == ' ' {
+			i++
+			continue
+		}
+		// extend the hunk until there are more than 2*context unchanged
+		// lines between two changes
+		start := i - context
+		if start < 0 {
+			start = 0
+		}
+		end := i
+		for end < len(lines) {
+			if lines[end].kind != ' ' {
+				end++
+				continue
+			}
+			next := end
+			for next < len(lines) && lines[next].kind == ' ' {
+				next++
+			}
+			if next == len(lines) || next-end > 2*context {
+				break
+			}
+			end = next
+		}
+		end += context
+		if end > len(lines) {
+			end = len(lines)
+		}
+		if b.Len() == 0 {
+			fmt.Fprintf(&b, "--- %s\n+++ %s\n", oldName, newName)
+		}
+		writeHunk(&b, lines[start:end])
+		i = end
+	}
+	return b.String()
+}
+
+func writeHunk(b *bytes.Buffer, lines []diffLine) {
+	var oldCount, newCount int
+	for _, l := range lines {
+		if l.kind != '+' {
+			oldCount++
+		}
+		if l.kind != '-' {
+			newCount++
+		}
+	}
+	oldStart, newStart := lines[0].oldLine, lines[0].newLine
+	if oldCount > 0 {
+		oldStart++
+	}
+	if newCount > 0 {
+		newStart++
+	}
+	fmt.Fprintf(b, "@@ -%d,%d +%d,%d @@\n", oldStart, oldCount, newStart, newCount)
+	for _, l := range lines {
+		fmt.Fprintf(b, "%c%s\n", l.kind, l.text)
+	}
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+// diffLines returns the lines of the longest common subsequence of the old
+// and new lines, marked as unchanged, along with the removed and added lines
+func diffLines(old, new []string) []diffLine {
+	// lcs[i][j] is the length of the longest common subsequence of old[i:] and new[j:]
+	lcs := make([][]int, len(old)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(new)+1)
+	}
+	for i := len(old) - 1; i >= 0; i-- {
+		for j := len(new) - 1; j >= 0; j-- {
+			if old[i] == new[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+	var lines []diffLine
+	i, j := 0, 0
+	for i < len(old) || j < len(new) {
+		switch {
+		case i < len(old) && j < len(new) && old[i] == new[j]:
+			lines = append(lines, diffLine{' ', old[i], i, j})
+			i++
+			j++
+		case j == len(new) || (i < len(old) && lcs[i+1][j] >= lcs[i][j+1]):
+			lines = append(lines, diffLine{'-', old[i], i, j})
+			i++
+		default:
+			lines = append(lines, diffLine{'+', new[j], i, j})
+			j++
+		}
+	}
+	return lines
+}
@@ -0,0 +1,42 @@
+package util
+
+import "testing"
+
+func TestUnifiedDiff(t *testing.T) {
+	tests := []struct {
+		name     string
+		old      string
+		new      string
+		expected string
+	}{
+		{
+			name: "equal",
+			old:  "a\nb\n",
+			new:  "a\nb\n",
+		},
+		{
+			name:     "changed line",
+			old:      "a\nb\nc\nd\ne\nf\ng\n",
+			new:      "a\nb\nc\nD\ne\nf\ng\n",
+			expected: "--- old\n+++ new\n@@ -2,5 +2,5 @@\n b\n c\n-d\n+D\n e\n f\n",
+		},
+		{
+			name:     "added and removed lines in separate hunks",
+			old:      "a\nb\nc\nd\ne\nf\ng\nh\n",
+			new:      "x\na\nb\nc\nd\ne\nf\ng\n",
+			expected: "--- old\n+++ new\n@@ -1,2 +1,3 @@\n+x\n a\n b\n@@ -6,3 +7,2 @@\n f\n g\n-h\n",
+		},
+		{
+			name:     "new file",
+			old:      "",
+			new:      "a\n",
+			expected: "--- old\n+++ new\n@@ -0,0 +1,1 @@\n+a\n",
+		},
+	}
+	for _, test := range tests {
+		got := UnifiedDiff("old", "new", test.old, test.new, 2)
+		if got != test.expected {
+			t.Errorf("%s: expected diff:\n%s\nbut got:\n%s", test.name, test.expected, got)
+		}
+	}
+}
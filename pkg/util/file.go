@@ -2,7 +2,10 @@ package util
 
 import (
 	"fmt"
+	"io"
+	"io/ioutil"
 	"os"
+	"path/filepath"
 )
 
 // BackupDirectory checks for existence of the $sourceDir and backs it up to backupDir
@@ -21,3 +24,42 @@ func BackupDirectory(sourceDir string, backupDir string) (bool, error) {
 	// Directory does not already exist, nothing to do
 	return backedup, nil
 }
+
+// CopyDirectory copies the regular files in sourceDir to destDir, preserving
+// their permissions. Subdirectories are not copied. destDir is created if it
+// does not exist.
+func CopyDirectory(sourceDir string, destDir string) error {
+	files, err := ioutil.ReadDir(sourceDir)
+	if err != nil {
+		return fmt.Errorf("Could not read %q directory: %v", sourceDir, err)
+	}
+	if err := os.MkdirAll(destDir, 0700); err != nil {
+		return fmt.Errorf("Could not create %q directory: %v", destDir, err)
+	}
+	for _, f := range files {
+		if !f.Mode().IsRegular() {
+			continue
+		}
+		if err := copyFile(filepath.Join(sourceDir, f.Name()), filepath.Join(destDir, f.Name()), f.Mode().Perm()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func copyFile(source string, dest string, perm os.FileMode) error {
+	in, err := os.Open(source)
+	if err != nil {
+		return fmt.Errorf("Could not open %q: %v", source, err)
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dest, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return fmt.Errorf("Could not create %q: %v", dest, err)
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return fmt.Errorf("Could not copy %q to %q: %v", source, dest, err)
+	}
+	return out.Close()
+}
@@ -40,3 +40,43 @@ func TestBackupClientDirectoryNotExists(t *testing.T) {
 		t.Errorf("Expected directory to not exist")
 	}
 }
+
+func TestCopyDirectory(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "ket-copydir-test")
+	if err != nil {
+		t.Fatalf("Error creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+	sourceDir := filepath.Join(tmpDir, "keys")
+	if err := os.Mkdir(sourceDir, 0755); err != nil {
+		t.Fatalf("Error creating source dir: %v", err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(sourceDir, "ca-key.pem"), []byte("key"), 0600); err != nil {
+		t.Fatalf("Error writing file: %v", err)
+	}
+	if err := os.Mkdir(filepath.Join(sourceDir, "subdir"), 0755); err != nil {
+		t.Fatalf("Error creating subdir: %v", err)
+	}
+
+	destDir := filepath.Join(tmpDir, "keys.bak")
+	if err := CopyDirectory(sourceDir, destDir); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	b, err := ioutil.ReadFile(filepath.Join(destDir, "ca-key.pem"))
+	if err != nil {
+		t.Fatalf("Expected file to be copied: %v", err)
+	}
+	if string(b) != "key" {
+		t.Errorf("Expected file contents %q, got %q", "key", string(b))
+	}
+	fi, err := os.Stat(filepath.Join(destDir, "ca-key.pem"))
+	if err != nil {
+		t.Fatalf("Error reading file info: %v", err)
+	}
+	if fi.Mode().Perm() != 0600 {
+		t.Errorf("Expected file mode %v, got %v", os.FileMode(0600), fi.Mode().Perm())
+	}
+	if _, err := os.Stat(filepath.Join(destDir, "subdir")); !os.IsNotExist(err) {
+		t.Errorf("Expected subdirectories not to be copied")
+	}
+}